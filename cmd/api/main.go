@@ -7,25 +7,23 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
+	"time"
 
+	"github.com/agandreev/avito-intern-assignment/internal/config"
 	"github.com/agandreev/avito-intern-assignment/internal/controller"
+	"github.com/agandreev/avito-intern-assignment/internal/domain"
+	"github.com/agandreev/avito-intern-assignment/internal/events"
 	"github.com/agandreev/avito-intern-assignment/internal/handlers"
+	"github.com/agandreev/avito-intern-assignment/internal/reconciler"
 	"github.com/agandreev/avito-intern-assignment/internal/repository"
+	"github.com/agandreev/avito-intern-assignment/internal/rpc"
+	"github.com/agandreev/avito-intern-assignment/internal/scheduler"
 	"github.com/agandreev/avito-intern-assignment/internal/service"
+	"github.com/agandreev/avito-intern-assignment/internal/stream"
+	"github.com/agandreev/avito-intern-assignment/internal/webhooks"
 	"github.com/sirupsen/logrus"
-	"github.com/spf13/viper"
-)
-
-const (
-	logFile    = "logs.txt"
-	configPath = "config.env"
-	apiKeyTag  = "API_KEY"
-	dbUser     = "DB_USER"
-	dbPSWD     = "DB_PSWD"
-	dbName     = "DB_NAME"
-	dbPort     = "DB_PORT"
-	srvPort    = "SRV_PORT"
 )
 
 // @title Balance control API
@@ -36,36 +34,159 @@ const (
 // @BasePath /
 func main() {
 	logger := logrus.New()
-	// add file logger
-	file, err := os.OpenFile(logFile, os.O_RDWR|os.O_APPEND|os.O_CREATE, 0660)
+
+	// load config
+	cfg, flags, err := config.Load(os.Args[0], os.Args[1:])
 	if err != nil {
 		logger.Fatalf(err.Error())
 	}
-	defer file.Close()
-	mw := io.MultiWriter(os.Stdout, file)
-	logger.SetOutput(mw)
+	if flags.PrintConfig {
+		fmt.Println(cfg)
+		return
+	}
 
-	// load config
-	apiKey, port, dbConfig, err := loadConfig()
+	// set up logger
+	closeLog, err := setupLogger(logger, cfg.Log)
 	if err != nil {
 		logger.Fatalf(err.Error())
 	}
+	defer closeLog()
 
 	// create service and run server
-	exchange := service.NewExchangeAPI(apiKey)
-	gbStorage := repository.NewGrossBookStorage(*dbConfig)
-	if err = gbStorage.Connect(); err != nil {
+	exchange := service.NewExchangeAPI(service.ExchangeConfig{
+		APIKey:  cfg.Exchange.APIKey,
+		URL:     cfg.Exchange.URL,
+		Timeout: cfg.Exchange.Timeout,
+		Breaker: service.BreakerConfig{
+			FailureThreshold: int(cfg.Exchange.BreakerFailures),
+			OpenTimeout:      cfg.Exchange.BreakerTimeout,
+			HalfOpenRequests: int(cfg.Exchange.BreakerProbes),
+			MonthlyQuota:     int64(cfg.Exchange.MonthlyQuota),
+		},
+		Rounding:   domain.RoundingMode(cfg.Exchange.Rounding),
+		SymbolsTTL: cfg.Exchange.SymbolsTTL,
+	})
+	connection := connectionConfig(cfg.DB)
+	connection.Notify = cfg.Stream.Notify
+	gbStorage := repository.NewGrossBookStorage(connection, logger)
+	if err = gbStorage.Connect(context.Background()); err != nil {
+		logger.Fatal(err)
+	}
+
+	balances := reconciler.NewReconciler(gbStorage, reconciler.Config{
+		Interval: cfg.Reconcile.Interval,
+		Correct:  cfg.Reconcile.Correct,
+	}, logger)
+
+	// run subcommand instead of server if it's set
+	if len(flags.Args) != 0 {
+		defer gbStorage.Shutdown()
+		switch flags.Args[0] {
+		case migrateCommand:
+			err = runMigrate(context.Background(), gbStorage, logger, flags.Args[1:])
+		case reconcileCommand:
+			err = runReconcile(context.Background(), balances, os.Stdout)
+		default:
+			err = fmt.Errorf("unknown command: %s", flags.Args[0])
+		}
+		if err != nil {
+			logger.Fatal(err)
+		}
+		return
+	}
+	if cfg.DB.AutoMigrate {
+		if err = runMigrate(context.Background(), gbStorage, logger,
+			[]string{upCommand}); err != nil {
+			logger.Fatal(err)
+		}
+	}
+
+	// run background workers
+	workersCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+	var workers sync.WaitGroup
+	runWorker := func(run func(ctx context.Context)) {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			run(workersCtx)
+		}()
+	}
+	// relay events from outbox
+	publisher, closePublisher, err := newPublisher(cfg.Outbox)
+	if err != nil {
 		logger.Fatal(err)
 	}
+	defer closePublisher()
+	if publisher != nil {
+		runWorker(events.NewRelay(gbStorage, publisher, cfg.Outbox.Interval,
+			int(cfg.Outbox.BatchSize), logger).Run)
+	}
+	// send webhook deliveries
+	runWorker(webhooks.NewDispatcher(gbStorage, webhooks.DispatcherConfig{
+		Interval:    cfg.Webhook.Interval,
+		BatchSize:   int(cfg.Webhook.BatchSize),
+		Timeout:     cfg.Webhook.Timeout,
+		MaxAttempts: int(cfg.Webhook.MaxAttempts),
+		Backoff:     cfg.Webhook.Backoff,
+		MaxBackoff:  cfg.Webhook.MaxBackoff,
+	}, logger).Run)
 
+	// feed balance streams by this replica's operations or by all replicas' ones
+	hub := stream.NewHub(int(cfg.Stream.Buffer))
 	gb := service.NewGrossBook(gbStorage, exchange, logger)
-	handler := handlers.NewHandler(gb, logger)
-	srv := controller.NewServer(*handler)
+	gb.MaxBatchSize = int(cfg.Batch.MaxSize)
+	gb.Limits = limits(cfg.Limits)
+	gb.AutoCreateUsers = cfg.Users.AutoCreate
+	gb.IdempotencyTTL = cfg.Idempotency.TTL
+	gb.AdjustmentTTL = cfg.Adjustment.TTL
+	if gb.Fees, err = fees(cfg.Fees); err != nil {
+		logger.Fatal(err)
+	}
+	// commission account is created before the first fee
+	if len(gb.Fees.Rules) != 0 {
+		if err = gbStorage.AddUser(context.Background(), gb.Fees.AccountID); err != nil {
+			logger.Fatal(err)
+		}
+	}
+	// verify balances against operations
+	if cfg.Reconcile.Interval > 0 {
+		runWorker(balances.Run)
+	}
+	// execute scheduled transfers
+	runWorker(scheduler.NewScheduler(gbStorage, gb, scheduler.Config{
+		Interval:  cfg.Scheduler.Interval,
+		BatchSize: int(cfg.Scheduler.BatchSize),
+		Lease:     cfg.Scheduler.Lease,
+	}, logger).Run)
+	if cfg.Stream.Notify {
+		runWorker(func(ctx context.Context) {
+			listenEvents(ctx, gbStorage, hub, logger)
+		})
+	} else {
+		gb.Notifier = hub
+	}
+
+	handler := handlers.NewHandler(gb, hub, logger, handlers.Config{
+		Timeout:         cfg.HTTP.HandlerTimeout,
+		StreamHeartbeat: cfg.Stream.Heartbeat,
+		// streams are closed before server's write timeout breaks them
+		StreamDuration: cfg.HTTP.WriteTimeout * 9 / 10,
+		AdminTokens:    actors(cfg.HTTP.AdminTokens),
+	})
+	// grpc api calls the same service on its own port
+	grpcServer := rpc.NewServer(gb, logger, rpc.Config{Tokens: actors(cfg.GRPC.Tokens)})
+	srv := controller.NewServer(*handler, grpcServer)
 	go func() {
-		if err = srv.Run(port); err != nil && err != http.ErrServerClosed {
+		if err := srv.Run(serverConfig(cfg.HTTP)); err != nil && err != http.ErrServerClosed {
 			logger.Fatalf("ERROR: running server is failed <%s>", err)
 		}
 	}()
+	go func() {
+		if err := srv.RunGRPC(cfg.GRPC.Address()); err != nil {
+			logger.Fatalf("ERROR: running grpc server is failed <%s>", err)
+		}
+	}()
 	logger.Print("Server is running")
 
 	// graceful shutdown
@@ -75,64 +196,121 @@ func main() {
 
 	logger.Print("Server is shutting down")
 
-	if err = srv.Shutdown(context.Background()); err != nil {
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.HTTP.ShutdownTimeout)
+	defer cancel()
+	stopWorkers()
+	workers.Wait()
+	if err = srv.Shutdown(ctx); err != nil {
 		logger.Errorf("ERROR: graceful shutdown is broken <%s>", err.Error())
 	}
 }
 
-// loadString loads a string value from config
-func loadString(name string) (string, error) {
-	value, ok := viper.Get(name).(string)
-	if !ok {
-		return "", fmt.Errorf("invalid %s type assertion", name)
+// listenEvents passes postgres notifications to hub and reconnects on failures.
+func listenEvents(ctx context.Context, storage *repository.GrossBookStorage,
+	hub *stream.Hub, logger *logrus.Logger) {
+	for {
+		err := storage.ListenEvents(ctx, func(event domain.Event) {
+			hub.Notify(event)
+		})
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Second):
+			logger.Printf("LISTEN ERROR: <%s>", err)
+		}
 	}
-	return value, nil
 }
 
-// loadConfig loads all values from config
-func loadConfig() (string, string, *repository.ConnectionConfig, error) {
-	viper.SetConfigFile(configPath)
-	if err := viper.ReadInConfig(); err != nil {
-		return "", "", nil, fmt.Errorf("can't load config: %w", err)
-	}
-	key, err := loadString(apiKeyTag)
+// setupLogger applies level, format and output from config and returns log file closer.
+func setupLogger(logger *logrus.Logger, logConfig config.LogConfig) (func(), error) {
+	level, err := logrus.ParseLevel(logConfig.Level)
 	if err != nil {
-		return "", "", nil, fmt.Errorf("can't load api key: %w", err)
+		return nil, fmt.Errorf("can't parse log level: %w", err)
 	}
-	port, err := loadString(srvPort)
-	if err != nil {
-		return "", "", nil, fmt.Errorf("can't load server port: %w", err)
+	logger.SetLevel(level)
+	if logConfig.Format == config.JSONFormat {
+		logger.SetFormatter(&logrus.JSONFormatter{})
 	}
-	dbConfig, err := loadDBVars()
+	if logConfig.File == "" {
+		return func() {}, nil
+	}
+	// add file logger
+	file, err := os.OpenFile(logConfig.File, os.O_RDWR|os.O_APPEND|os.O_CREATE, 0660)
 	if err != nil {
-		return "", "", nil, fmt.Errorf("can't load db vars: %w", err)
+		return nil, fmt.Errorf("can't open log file: %w", err)
 	}
-	return key, port, dbConfig, nil
+	logger.SetOutput(io.MultiWriter(os.Stdout, file))
+	return func() { _ = file.Close() }, nil
 }
 
-// loadDBVars loads all db values from config as repository.ConnectionConfig
-func loadDBVars() (*repository.ConnectionConfig, error) {
-	user, err := loadString(dbUser)
-	if err != nil {
-		return nil, err
+// connectionConfig converts config.DBConfig to repository.ConnectionConfig.
+func connectionConfig(dbConfig config.DBConfig) repository.ConnectionConfig {
+	return repository.ConnectionConfig{
+		Username: dbConfig.User,
+		Password: dbConfig.Password,
+		NameDB:   dbConfig.Name,
+		Host:     dbConfig.Host,
+		Port:     dbConfig.Port,
+		SSLMode:  dbConfig.SSLMode,
+		MaxConns: dbConfig.MaxConns,
+
+		MaxRetries:   int(dbConfig.Retries),
+		RetryBackoff: dbConfig.RetryBackoff,
 	}
-	password, err := loadString(dbPSWD)
-	if err != nil {
-		return nil, err
+}
+
+// limits converts config.LimitsConfig to domain.Limits skipping zero limits.
+func limits(limitsConfig config.LimitsConfig) domain.Limits {
+	limit := func(value float64) *float64 {
+		if value == 0 {
+			return nil
+		}
+		return &value
 	}
-	name, err := loadString(dbName)
-	if err != nil {
-		return nil, err
+	return domain.Limits{
+		MaxOperation:    limit(limitsConfig.MaxOperation),
+		DailyWithdraw:   limit(limitsConfig.DailyWithdraw),
+		MonthlyWithdraw: limit(limitsConfig.MonthlyWithdraw),
+		DailyTransfer:   limit(limitsConfig.DailyTransfer),
+		MonthlyTransfer: limit(limitsConfig.MonthlyTransfer),
 	}
-	port, err := loadString(dbPort)
-	if err != nil {
-		return nil, err
+}
+
+// fees converts config.FeesConfig to domain.FeeSchedule and validates rules.
+func fees(feesConfig config.FeesConfig) (domain.FeeSchedule, error) {
+	schedule := domain.FeeSchedule{AccountID: feesConfig.AccountID}
+	for _, rule := range feesConfig.Rules {
+		feeRule := domain.FeeRule{
+			Type:     domain.OperationType(rule.Type),
+			Currency: rule.Currency,
+			Percent:  rule.Percent,
+			Fixed:    rule.Fixed,
+			Min:      rule.Min,
+			Max:      rule.Max,
+		}
+		if err := feeRule.Validate(); err != nil {
+			return schedule, fmt.Errorf("can't load fee schedule: <%w>", err)
+		}
+		schedule.Rules = append(schedule.Rules, feeRule)
+	}
+	return schedule, nil
+}
+
+// actors converts config's actors' tokens to tokens' actors.
+func actors(tokens map[string]string) map[string]string {
+	result := make(map[string]string, len(tokens))
+	for actor, token := range tokens {
+		result[token] = actor
 	}
-	orderConfig := &repository.ConnectionConfig{
-		Username: user,
-		Password: password,
-		NameDB:   name,
-		Port:     port,
+	return result
+}
+
+// serverConfig converts config.HTTPConfig to controller.ServerConfig.
+func serverConfig(httpConfig config.HTTPConfig) controller.ServerConfig {
+	return controller.ServerConfig{
+		Address:      httpConfig.Address(),
+		ReadTimeout:  httpConfig.ReadTimeout,
+		WriteTimeout: httpConfig.WriteTimeout,
+		IdleTimeout:  httpConfig.IdleTimeout,
 	}
-	return orderConfig, nil
 }
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/agandreev/avito-intern-assignment/internal/repository"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	migrateCommand = "migrate"
+	upCommand      = "up"
+	downCommand    = "down"
+	statusCommand  = "status"
+)
+
+var errMigrateUsage = errors.New("usage: migrate up [version] | down [version] | status")
+
+// runMigrate executes migrate subcommand:
+// "up" applies migrations up to given or the latest version,
+// "down" reverts migrations down to given or the previous version,
+// "status" prints current and the latest versions.
+func runMigrate(ctx context.Context, storage *repository.GrossBookStorage,
+	logger *logrus.Logger, args []string) error {
+	if len(args) == 0 || len(args) > 2 {
+		return errMigrateUsage
+	}
+	current, err := storage.SchemaVersion(ctx)
+	if err != nil {
+		return err
+	}
+	latest, err := repository.LatestVersion()
+	if err != nil {
+		return err
+	}
+	var target int
+	switch args[0] {
+	case upCommand:
+		target = latest
+	case downCommand:
+		target = current - 1
+	case statusCommand:
+		logger.Printf("MIGRATE: current version <%d>, latest version <%d>", current, latest)
+		return nil
+	default:
+		return errMigrateUsage
+	}
+	if len(args) == 2 {
+		if target, err = strconv.Atoi(args[1]); err != nil {
+			return fmt.Errorf("incorrect version: %w", err)
+		}
+	}
+	if target < 0 {
+		target = 0
+	}
+	logger.Printf("MIGRATE: from <%d> to <%d> processing...", current, target)
+	if err = storage.Migrate(ctx, target); err != nil {
+		return err
+	}
+	logger.Printf("MIGRATE: to <%d> was processed successful", target)
+	return nil
+}
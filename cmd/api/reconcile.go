@@ -0,0 +1,37 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/agandreev/avito-intern-assignment/internal/reconciler"
+)
+
+const reconcileCommand = "reconcile"
+
+var errMismatches = errors.New("balances differ from operations")
+
+// runReconcile executes reconcile subcommand: it prints json report and fails if any
+// mismatched balance isn't corrected.
+func runReconcile(ctx context.Context, balances *reconciler.Reconciler,
+	out io.Writer) error {
+	report, err := balances.RunOnce(ctx)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("can't marshal report: %w", err)
+	}
+	if _, err = fmt.Fprintln(out, string(data)); err != nil {
+		return fmt.Errorf("can't print report: %w", err)
+	}
+	if report.Corrected != len(report.Mismatches) {
+		return fmt.Errorf("%d of %d users: %w", len(report.Mismatches)-report.Corrected,
+			len(report.Mismatches), errMismatches)
+	}
+	return nil
+}
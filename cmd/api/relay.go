@@ -0,0 +1,34 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/agandreev/avito-intern-assignment/internal/config"
+	"github.com/agandreev/avito-intern-assignment/internal/events"
+)
+
+const webhookTimeout = 10 * time.Second
+
+// newPublisher creates events.Publisher chosen in config and returns its closer.
+// It returns nil publisher if events relaying is disabled.
+func newPublisher(outboxConfig config.OutboxConfig) (events.Publisher, func(), error) {
+	switch outboxConfig.Publisher {
+	case config.StdoutPublisher:
+		return events.NewStdoutPublisher(), func() {}, nil
+	case config.FilePublisher:
+		publisher, err := events.NewFilePublisher(outboxConfig.File)
+		if err != nil {
+			return nil, nil, err
+		}
+		return publisher, func() { _ = publisher.Close() }, nil
+	case config.WebhookPublisher:
+		return events.NewWebhookPublisher(&http.Client{Timeout: webhookTimeout},
+			outboxConfig.WebhookURL), func() {}, nil
+	case config.NonePublisher:
+		return nil, func() {}, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown publisher: %s", outboxConfig.Publisher)
+	}
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/agandreev/avito-intern-assignment/internal/config"
+	"github.com/agandreev/avito-intern-assignment/internal/domain"
+	"github.com/agandreev/avito-intern-assignment/internal/reconciler"
+	"github.com/agandreev/avito-intern-assignment/internal/repository"
+	"github.com/agandreev/avito-intern-assignment/internal/service"
+	"github.com/agandreev/avito-intern-assignment/pkg/client"
+	"github.com/sirupsen/logrus"
+)
+
+// apiRetries limits retries of failed api requests.
+const apiRetries = 2
+
+var errUnsupported = errors.New("command isn't supported by api, use database")
+
+// backend describes operations of gbctl which are done by database or by api.
+type backend interface {
+	User(ctx context.Context, id int64) (*domain.User, error)
+	History(ctx context.Context, input domain.HistoryInput) ([]domain.RepositoryOperation,
+		error)
+	RequestAdjustment(ctx context.Context, userID int64, input domain.AdjustmentInput) (
+		*domain.AdjustmentRequest, error)
+	ApproveAdjustment(ctx context.Context, id int64, input domain.ReviewInput) (
+		*domain.AdjustmentRequest, error)
+	RejectAdjustment(ctx context.Context, id int64, input domain.ReviewInput) (
+		*domain.AdjustmentRequest, error)
+	Adjustments(ctx context.Context, filter domain.AdjustmentFilter) (
+		[]domain.AdjustmentRequest, error)
+	ChangeStatus(ctx context.Context, id int64, input domain.StatusInput) (*domain.User,
+		error)
+	Reconcile(ctx context.Context, correct bool) (*domain.ReconciliationReport, error)
+	Close()
+}
+
+// newBackend returns api backend if its url is set and database backend otherwise.
+func newBackend(ctx context.Context, opts *options, logger *logrus.Logger) (backend,
+	error) {
+	if opts.apiURL != "" {
+		return newAPIBackend(opts)
+	}
+	return newDBBackend(ctx, opts, logger)
+}
+
+// dbBackend calls service.GrossBook with repository.GrossBookStorage.
+type dbBackend struct {
+	storage *repository.GrossBookStorage
+	gb      *service.GrossBook
+	caller  domain.Caller
+	log     *logrus.Logger
+}
+
+// newDBBackend loads database settings as api does and connects to it.
+func newDBBackend(ctx context.Context, opts *options, logger *logrus.Logger) (*dbBackend,
+	error) {
+	var args []string
+	if opts.configPath != "" {
+		args = append(args, "--config", opts.configPath)
+	}
+	cfg, _, err := config.Load("gbctl", args)
+	if err != nil {
+		return nil, err
+	}
+	storage := repository.NewGrossBookStorage(connectionConfig(cfg.DB), logger)
+	if err = storage.Connect(ctx); err != nil {
+		return nil, err
+	}
+	return &dbBackend{
+		storage: storage,
+		gb:      service.NewGrossBook(storage, nil, logger),
+		caller:  domain.Caller{Actor: opts.actor},
+		log:     logger,
+	}, nil
+}
+
+func (db *dbBackend) User(ctx context.Context, id int64) (*domain.User, error) {
+	return db.gb.Balance(ctx, id)
+}
+
+func (db *dbBackend) History(ctx context.Context, input domain.HistoryInput) (
+	[]domain.RepositoryOperation, error) {
+	return db.gb.History(ctx, input.ID, input.Quantity, input.Mode, input.OperationFilter)
+}
+
+func (db *dbBackend) RequestAdjustment(ctx context.Context, userID int64,
+	input domain.AdjustmentInput) (*domain.AdjustmentRequest, error) {
+	return db.gb.RequestAdjustment(service.WithCaller(ctx, db.caller), userID, input)
+}
+
+func (db *dbBackend) ApproveAdjustment(ctx context.Context, id int64,
+	input domain.ReviewInput) (*domain.AdjustmentRequest, error) {
+	return db.gb.ApproveAdjustment(service.WithCaller(ctx, db.caller), id, input)
+}
+
+func (db *dbBackend) RejectAdjustment(ctx context.Context, id int64,
+	input domain.ReviewInput) (*domain.AdjustmentRequest, error) {
+	return db.gb.RejectAdjustment(service.WithCaller(ctx, db.caller), id, input)
+}
+
+func (db *dbBackend) Adjustments(ctx context.Context, filter domain.AdjustmentFilter) (
+	[]domain.AdjustmentRequest, error) {
+	return db.gb.Adjustments(ctx, filter)
+}
+
+func (db *dbBackend) ChangeStatus(ctx context.Context, id int64, input domain.StatusInput) (
+	*domain.User, error) {
+	return db.gb.ChangeStatus(service.WithCaller(ctx, db.caller), id, input)
+}
+
+func (db *dbBackend) Reconcile(ctx context.Context, correct bool) (
+	*domain.ReconciliationReport, error) {
+	return reconciler.NewReconciler(db.storage, reconciler.Config{Correct: correct},
+		db.log).RunOnce(ctx)
+}
+
+func (db *dbBackend) Close() {
+	db.storage.Shutdown()
+}
+
+// apiBackend calls balance api by client.Client.
+type apiBackend struct {
+	*client.Client
+}
+
+// newAPIBackend creates client.Client which sends operator as actor.
+func newAPIBackend(opts *options) (*apiBackend, error) {
+	apiClient, err := client.NewClient(client.Config{
+		BaseURL:    opts.apiURL,
+		Token:      opts.token,
+		Actor:      opts.actor,
+		MaxRetries: apiRetries,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &apiBackend{Client: apiClient}, nil
+}
+
+func (api *apiBackend) User(ctx context.Context, id int64) (*domain.User, error) {
+	return api.Balance(ctx, id)
+}
+
+func (api *apiBackend) Reconcile(context.Context, bool) (*domain.ReconciliationReport,
+	error) {
+	return nil, fmt.Errorf("can't reconcile balances: <%w>", errUnsupported)
+}
+
+func (api *apiBackend) Close() {}
+
+// connectionConfig converts config.DBConfig to repository.ConnectionConfig.
+func connectionConfig(dbConfig config.DBConfig) repository.ConnectionConfig {
+	return repository.ConnectionConfig{
+		Username: dbConfig.User,
+		Password: dbConfig.Password,
+		NameDB:   dbConfig.Name,
+		Host:     dbConfig.Host,
+		Port:     dbConfig.Port,
+		SSLMode:  dbConfig.SSLMode,
+		MaxConns: dbConfig.MaxConns,
+
+		MaxRetries:   int(dbConfig.Retries),
+		RetryBackoff: dbConfig.RetryBackoff,
+	}
+}
@@ -0,0 +1,339 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/agandreev/avito-intern-assignment/internal/domain"
+	"github.com/spf13/pflag"
+)
+
+const (
+	defaultHistoryLimit     = 100
+	defaultStatementLimit   = 10000
+	defaultAdjustmentsLimit = 100
+
+	dateLayout = "2006-01-02"
+)
+
+var errMismatches = errors.New("balances differ from operations")
+
+// command executes gbctl's subcommand with its arguments.
+type command func(ctx context.Context, gb backend, args []string, out *printer) error
+
+var commands = map[string]command{
+	"user":        userCommand,
+	"history":     historyCommand,
+	"adjust":      adjustCommand,
+	"approve":     approveCommand,
+	"reject":      rejectCommand,
+	"adjustments": adjustmentsCommand,
+	"freeze":      freezeCommand,
+	"unfreeze":    unfreezeCommand,
+	"reconcile":   reconcileCommand,
+	"statement":   statementCommand,
+}
+
+// execute runs command by its name.
+func execute(ctx context.Context, gb backend, args []string, out *printer) error {
+	run, ok := commands[args[0]]
+	if !ok {
+		return fmt.Errorf("unknown command <%s>: <%w>", args[0], errUsage)
+	}
+	return run(ctx, gb, args[1:], out)
+}
+
+// userCommand prints user's balance and status.
+func userCommand(ctx context.Context, gb backend, args []string, out *printer) error {
+	flagSet := newFlagSet("user")
+	id, err := parseUserArgs(flagSet, args)
+	if err != nil {
+		return err
+	}
+	user, err := gb.User(ctx, id)
+	if err != nil {
+		return err
+	}
+	return out.user(user)
+}
+
+// historyCommand prints user's operations filtered by type and period.
+func historyCommand(ctx context.Context, gb backend, args []string, out *printer) error {
+	flagSet := newFlagSet("history")
+	limit := flagSet.Int64("limit", defaultHistoryLimit, "operations quantity")
+	mode := flagSet.String("sort", string(domain.DateMode), "sorting mode: date or amount")
+	filter := filterFlags(flagSet)
+	id, err := parseUserArgs(flagSet, args)
+	if err != nil {
+		return err
+	}
+	input, err := filter(id, *limit)
+	if err != nil {
+		return err
+	}
+	input.Mode = domain.SortingMode(*mode)
+	operations, err := gb.History(ctx, *input)
+	if err != nil {
+		return err
+	}
+	return out.operations(operations)
+}
+
+// adjustCommand requests correction of user's balance which is applied after approval
+// by another operator.
+func adjustCommand(ctx context.Context, gb backend, args []string, out *printer) error {
+	flagSet := newFlagSet("adjust")
+	amount := flagSet.Float64("amount", 0, "signed amount, negative one is withdrawn")
+	reason := flagSet.String("reason", "", "reason of adjustment")
+	ticket := flagSet.String("ticket", "", "support ticket of adjustment")
+	id, err := parseUserArgs(flagSet, args)
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(*reason) == "" || strings.TrimSpace(*ticket) == "" {
+		return fmt.Errorf("reason and ticket are mandatory: <%w>", errUsage)
+	}
+	request, err := gb.RequestAdjustment(ctx, id, domain.AdjustmentInput{Amount: *amount,
+		Reason: *reason, Ticket: *ticket})
+	if err != nil {
+		return err
+	}
+	return out.adjustments([]domain.AdjustmentRequest{*request})
+}
+
+// approveCommand approves pending adjustment and applies it to user's balance.
+func approveCommand(ctx context.Context, gb backend, args []string, out *printer) error {
+	return reviewCommand(ctx, "approve", gb.ApproveAdjustment, args, out)
+}
+
+// rejectCommand rejects pending adjustment.
+func rejectCommand(ctx context.Context, gb backend, args []string, out *printer) error {
+	return reviewCommand(ctx, "reject", gb.RejectAdjustment, args, out)
+}
+
+// reviewCommand reviews adjustment by its id with optional comment.
+func reviewCommand(ctx context.Context, name string,
+	review func(ctx context.Context, id int64, input domain.ReviewInput) (
+		*domain.AdjustmentRequest, error), args []string, out *printer) error {
+	flagSet := newFlagSet(name)
+	comment := flagSet.String("comment", "", "reviewer's comment")
+	if err := flagSet.Parse(args); err != nil {
+		return fmt.Errorf("can't parse flags: <%w>", err)
+	}
+	if flagSet.NArg() != 1 {
+		return fmt.Errorf("command needs only adjustment's id: <%w>", errUsage)
+	}
+	id, err := strconv.ParseInt(flagSet.Arg(0), 10, 64)
+	if err != nil {
+		return fmt.Errorf("can't parse adjustment's id: <%w>", err)
+	}
+	request, err := review(ctx, id, domain.ReviewInput{Comment: *comment})
+	if err != nil {
+		return err
+	}
+	return out.adjustments([]domain.AdjustmentRequest{*request})
+}
+
+// adjustmentsCommand prints adjustments filtered by user and status.
+func adjustmentsCommand(ctx context.Context, gb backend, args []string, out *printer) error {
+	flagSet := newFlagSet("adjustments")
+	userID := flagSet.Int64("user", 0, "user's id")
+	status := flagSet.String("status", "", "status, e.g. pending")
+	limit := flagSet.Int64("limit", defaultAdjustmentsLimit, "adjustments quantity")
+	if err := flagSet.Parse(args); err != nil {
+		return fmt.Errorf("can't parse flags: <%w>", err)
+	}
+	requests, err := gb.Adjustments(ctx, domain.AdjustmentFilter{UserID: *userID,
+		Status: domain.AdjustmentStatus(strings.ToLower(*status)), Limit: *limit})
+	if err != nil {
+		return err
+	}
+	return out.adjustments(requests)
+}
+
+// freezeCommand freezes user's account.
+func freezeCommand(ctx context.Context, gb backend, args []string, out *printer) error {
+	flagSet := newFlagSet("freeze")
+	reason := flagSet.String("reason", "", "reason of freezing")
+	blockIncoming := flagSet.Bool("block-incoming", false, "forbid incoming money too")
+	id, err := parseUserArgs(flagSet, args)
+	if err != nil {
+		return err
+	}
+	return changeStatus(ctx, gb, id, domain.StatusInput{Status: domain.Frozen,
+		Reason: *reason, BlockIncoming: *blockIncoming}, out)
+}
+
+// unfreezeCommand makes user's account active again.
+func unfreezeCommand(ctx context.Context, gb backend, args []string, out *printer) error {
+	flagSet := newFlagSet("unfreeze")
+	reason := flagSet.String("reason", "", "reason of unfreezing")
+	id, err := parseUserArgs(flagSet, args)
+	if err != nil {
+		return err
+	}
+	return changeStatus(ctx, gb, id, domain.StatusInput{Status: domain.Active,
+		Reason: *reason}, out)
+}
+
+// changeStatus changes account's status and prints user.
+func changeStatus(ctx context.Context, gb backend, id int64, input domain.StatusInput,
+	out *printer) error {
+	if strings.TrimSpace(input.Reason) == "" {
+		return fmt.Errorf("reason is mandatory: <%w>", errUsage)
+	}
+	user, err := gb.ChangeStatus(ctx, id, input)
+	if err != nil {
+		return err
+	}
+	return out.user(user)
+}
+
+// reconcileCommand prints reconciliation report and fails if any mismatched balance
+// isn't corrected.
+func reconcileCommand(ctx context.Context, gb backend, args []string, out *printer) error {
+	flagSet := newFlagSet("reconcile")
+	correct := flagSet.Bool("correct", false, "correct mismatched balances")
+	if err := flagSet.Parse(args); err != nil {
+		return fmt.Errorf("can't parse flags: <%w>", err)
+	}
+	report, err := gb.Reconcile(ctx, *correct)
+	if err != nil {
+		return err
+	}
+	if err = out.report(report); err != nil {
+		return err
+	}
+	if report.Corrected != len(report.Mismatches) {
+		return fmt.Errorf("%d of %d users: <%w>", len(report.Mismatches)-report.Corrected,
+			len(report.Mismatches), errMismatches)
+	}
+	return nil
+}
+
+// statementCommand exports user's operations for period as csv or as json.
+func statementCommand(ctx context.Context, gb backend, args []string, out *printer) error {
+	flagSet := newFlagSet("statement")
+	limit := flagSet.Int64("limit", defaultStatementLimit, "operations quantity")
+	file := flagSet.String("file", "", "output file, stdout is used by default")
+	filter := filterFlags(flagSet)
+	id, err := parseUserArgs(flagSet, args)
+	if err != nil {
+		return err
+	}
+	input, err := filter(id, *limit)
+	if err != nil {
+		return err
+	}
+	input.Mode = domain.DateMode
+	operations, err := gb.History(ctx, *input)
+	if err != nil {
+		return err
+	}
+	writer := out.out
+	if *file != "" {
+		output, err := os.Create(*file)
+		if err != nil {
+			return fmt.Errorf("can't create statement: <%w>", err)
+		}
+		defer output.Close()
+		writer = output
+	}
+	if out.format == jsonOutput {
+		return writeJSON(writer, operations)
+	}
+	return writeStatement(writer, operations)
+}
+
+// writeStatement writes operations as csv with header.
+func writeStatement(out io.Writer, operations []domain.RepositoryOperation) error {
+	writer := csv.NewWriter(out)
+	if err := writer.Write([]string{"timestamp", "type", "amount", "fee",
+		"receiver_id"}); err != nil {
+		return fmt.Errorf("can't write statement: <%w>", err)
+	}
+	for _, operation := range operations {
+		receiver := ""
+		if operation.ReceiverID != 0 {
+			receiver = strconv.FormatInt(operation.ReceiverID, 10)
+		}
+		if err := writer.Write([]string{
+			operation.Timestamp.Format(time.RFC3339),
+			string(operation.Type),
+			strconv.FormatFloat(operation.Amount, 'f', 2, 64),
+			strconv.FormatFloat(operation.Fee, 'f', 2, 64),
+			receiver,
+		}); err != nil {
+			return fmt.Errorf("can't write statement: <%w>", err)
+		}
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("can't write statement: <%w>", err)
+	}
+	return nil
+}
+
+// newFlagSet returns command's flag set which reports errors instead of exiting.
+func newFlagSet(name string) *pflag.FlagSet {
+	flagSet := pflag.NewFlagSet(name, pflag.ContinueOnError)
+	flagSet.SetOutput(io.Discard)
+	return flagSet
+}
+
+// parseUserArgs parses command's flags and its only positional argument user's id.
+func parseUserArgs(flagSet *pflag.FlagSet, args []string) (int64, error) {
+	if err := flagSet.Parse(args); err != nil {
+		return 0, fmt.Errorf("can't parse flags: <%w>", err)
+	}
+	if flagSet.NArg() != 1 {
+		return 0, fmt.Errorf("command needs only user's id: <%w>", errUsage)
+	}
+	id, err := strconv.ParseInt(flagSet.Arg(0), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("can't parse user's id: <%w>", err)
+	}
+	return id, nil
+}
+
+// filterFlags adds history filter's flags and returns function which builds
+// domain.HistoryInput after parsing.
+func filterFlags(flagSet *pflag.FlagSet) func(id, limit int64) (*domain.HistoryInput,
+	error) {
+	operationType := flagSet.String("type", "", "operation type, e.g. DEPOSIT")
+	from := flagSet.String("from", "", "period's start, date or RFC3339 time")
+	to := flagSet.String("to", "", "period's end (exclusive), date or RFC3339 time")
+	return func(id, limit int64) (*domain.HistoryInput, error) {
+		input := &domain.HistoryInput{ID: id, Quantity: limit}
+		input.Type = domain.OperationType(strings.ToUpper(*operationType))
+		var err error
+		if input.From, err = parseTime(*from); err != nil {
+			return nil, err
+		}
+		if input.To, err = parseTime(*to); err != nil {
+			return nil, err
+		}
+		return input, nil
+	}
+}
+
+// parseTime parses date or RFC3339 time, empty value is nil.
+func parseTime(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	ts, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		if ts, err = time.Parse(dateLayout, value); err != nil {
+			return nil, fmt.Errorf("can't parse time <%s>: <%w>", value, errUsage)
+		}
+	}
+	return &ts, nil
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/agandreev/avito-intern-assignment/internal/domain"
+	"github.com/stretchr/testify/suite"
+)
+
+// fakeBackend keeps one user and records commands' inputs.
+type fakeBackend struct {
+	user       domain.User
+	operations []domain.RepositoryOperation
+	history    domain.HistoryInput
+	statuses   []domain.StatusInput
+	requests   []domain.AdjustmentRequest
+}
+
+func (gb *fakeBackend) User(context.Context, int64) (*domain.User, error) {
+	user := gb.user
+	return &user, nil
+}
+
+func (gb *fakeBackend) History(_ context.Context, input domain.HistoryInput) (
+	[]domain.RepositoryOperation, error) {
+	gb.history = input
+	return gb.operations, nil
+}
+
+func (gb *fakeBackend) RequestAdjustment(_ context.Context, userID int64,
+	input domain.AdjustmentInput) (*domain.AdjustmentRequest, error) {
+	request := domain.AdjustmentRequest{ID: int64(len(gb.requests) + 1), UserID: userID,
+		Amount: input.Amount, Reason: input.Reason, Ticket: input.Ticket,
+		Status: domain.AdjustmentPending}
+	gb.requests = append(gb.requests, request)
+	return &request, nil
+}
+
+func (gb *fakeBackend) ApproveAdjustment(_ context.Context, id int64,
+	input domain.ReviewInput) (*domain.AdjustmentRequest, error) {
+	request := &gb.requests[id-1]
+	request.Status, request.Comment = domain.AdjustmentApproved, input.Comment
+	gb.user.Amount += request.Amount
+	approved := *request
+	return &approved, nil
+}
+
+func (gb *fakeBackend) RejectAdjustment(_ context.Context, id int64,
+	input domain.ReviewInput) (*domain.AdjustmentRequest, error) {
+	request := &gb.requests[id-1]
+	request.Status, request.Comment = domain.AdjustmentRejected, input.Comment
+	rejected := *request
+	return &rejected, nil
+}
+
+func (gb *fakeBackend) Adjustments(_ context.Context, filter domain.AdjustmentFilter) (
+	[]domain.AdjustmentRequest, error) {
+	requests := make([]domain.AdjustmentRequest, 0)
+	for _, request := range gb.requests {
+		if filter.Status == "" || request.Status == filter.Status {
+			requests = append(requests, request)
+		}
+	}
+	return requests, nil
+}
+
+func (gb *fakeBackend) ChangeStatus(_ context.Context, _ int64, input domain.StatusInput) (
+	*domain.User, error) {
+	gb.statuses = append(gb.statuses, input)
+	gb.user.Status = input.Status
+	user := gb.user
+	return &user, nil
+}
+
+func (gb *fakeBackend) Reconcile(context.Context, bool) (*domain.ReconciliationReport,
+	error) {
+	return &domain.ReconciliationReport{UsersChecked: 1, Mismatches: []domain.BalanceMismatch{
+		{UserID: 1, Balance: 100, Ledger: 90, Difference: -10},
+	}}, nil
+}
+
+func (gb *fakeBackend) Close() {}
+
+type CommandsSuite struct {
+	suite.Suite
+	Backend *fakeBackend
+	Out     bytes.Buffer
+}
+
+func (suite *CommandsSuite) SetupTest() {
+	ts := time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC)
+	suite.Backend = &fakeBackend{
+		user: domain.User{ID: 1, Amount: 100, Currency: "RUB"},
+		operations: []domain.RepositoryOperation{
+			{InitiatorID: 1, Type: domain.Deposit, Amount: 100, Timestamp: ts},
+			{InitiatorID: 1, Type: domain.TransferOut, Amount: 10, Timestamp: ts, ReceiverID: 2},
+		},
+	}
+	suite.Out.Reset()
+}
+
+func (suite *CommandsSuite) execute(format string, args ...string) error {
+	return execute(context.Background(), suite.Backend, args,
+		&printer{out: &suite.Out, format: format})
+}
+
+func (suite *CommandsSuite) TestExecute_User() {
+	suite.Require().NoError(suite.execute(tableOutput, "user", "1"))
+	suite.Contains(suite.Out.String(), "100.00")
+	suite.Contains(suite.Out.String(), string(domain.Active))
+
+	suite.Out.Reset()
+	suite.Require().NoError(suite.execute(jsonOutput, "user", "1"))
+	var user domain.User
+	suite.Require().NoError(json.Unmarshal(suite.Out.Bytes(), &user))
+	suite.Equal(suite.Backend.user, user)
+
+	suite.ErrorIs(suite.execute(tableOutput, "user"), errUsage)
+	suite.ErrorIs(suite.execute(tableOutput, "unknown"), errUsage)
+}
+
+func (suite *CommandsSuite) TestExecute_History() {
+	suite.Require().NoError(suite.execute(tableOutput, "history", "1", "--type", "deposit",
+		"--from", "2022-01-01", "--to", "2022-02-01T00:00:00Z", "--limit", "5"))
+	input := suite.Backend.history
+	suite.Equal(int64(5), input.Quantity)
+	suite.Equal(domain.Deposit, input.Type)
+	suite.Require().NotNil(input.From)
+	suite.Equal(time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC), *input.From)
+	suite.Require().NotNil(input.To)
+	suite.Equal(3, strings.Count(suite.Out.String(), "\n"))
+
+	suite.ErrorIs(suite.execute(tableOutput, "history", "1", "--from", "yesterday"),
+		errUsage)
+}
+
+func (suite *CommandsSuite) TestExecute_Admin() {
+	// reason and ticket are mandatory
+	suite.ErrorIs(suite.execute(tableOutput, "adjust", "1", "--amount", "-30",
+		"--reason", "duplicate"), errUsage)
+	suite.Require().NoError(suite.execute(tableOutput, "adjust", "1", "--amount", "-30",
+		"--reason", "duplicate", "--ticket", "SUP-1"))
+	suite.Require().NoError(suite.execute(tableOutput, "adjust", "1", "--amount", "5",
+		"--reason", "bonus", "--ticket", "SUP-2"))
+	// request isn't applied before approval
+	suite.Equal(100.0, suite.Backend.user.Amount)
+	suite.ErrorIs(suite.execute(tableOutput, "approve"), errUsage)
+	suite.Require().NoError(suite.execute(tableOutput, "approve", "1", "--comment", "ok"))
+	suite.Require().NoError(suite.execute(tableOutput, "reject", "2"))
+	suite.Equal(70.0, suite.Backend.user.Amount)
+
+	suite.Out.Reset()
+	suite.Require().NoError(suite.execute(jsonOutput, "adjustments", "--status", "APPROVED"))
+	var requests []domain.AdjustmentRequest
+	suite.Require().NoError(json.Unmarshal(suite.Out.Bytes(), &requests))
+	suite.Require().Len(requests, 1)
+	suite.Equal("ok", requests[0].Comment)
+
+	suite.ErrorIs(suite.execute(tableOutput, "freeze", "1"), errUsage)
+	suite.Require().NoError(suite.execute(tableOutput, "freeze", "1", "--reason", "fraud",
+		"--block-incoming"))
+	suite.Require().NoError(suite.execute(tableOutput, "unfreeze", "1", "--reason", "ok"))
+	suite.Equal([]domain.StatusInput{
+		{Status: domain.Frozen, Reason: "fraud", BlockIncoming: true},
+		{Status: domain.Active, Reason: "ok"},
+	}, suite.Backend.statuses)
+
+	// uncorrected mismatches fail command
+	suite.ErrorIs(suite.execute(tableOutput, "reconcile"), errMismatches)
+	suite.Contains(suite.Out.String(), "-10.00")
+}
+
+func (suite *CommandsSuite) TestExecute_Statement() {
+	file := filepath.Join(suite.T().TempDir(), "statement.csv")
+	suite.Require().NoError(suite.execute(tableOutput, "statement", "1", "--file", file))
+	suite.Equal(domain.DateMode, suite.Backend.history.Mode)
+	data, err := os.ReadFile(file)
+	suite.Require().NoError(err)
+	suite.Equal("timestamp,type,amount,fee,receiver_id\n"+
+		"2022-01-02T03:04:05Z,DEPOSIT,100.00,0.00,\n"+
+		"2022-01-02T03:04:05Z,TRANSFER OUT,10.00,0.00,2\n", string(data))
+
+	suite.Require().NoError(suite.execute(jsonOutput, "statement", "1"))
+	var operations []domain.RepositoryOperation
+	suite.Require().NoError(json.Unmarshal(suite.Out.Bytes(), &operations))
+	suite.Equal(suite.Backend.operations, operations)
+}
+
+func TestCommandsSuite(t *testing.T) {
+	suite.Run(t, new(CommandsSuite))
+}
@@ -0,0 +1,118 @@
+// Command gbctl is an admin tool for operators. It works with database directly or with
+// balance api if its url is set.
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/pflag"
+)
+
+const usage = `usage: gbctl [flags] <command> [arguments]
+
+commands:
+  user <id>                                    shows user's balance and status
+  history <id> [--limit] [--type] [--from] [--to] [--sort]
+                                               shows user's operations
+  adjust <id> --amount <amount> --reason <reason> --ticket <ticket>
+                                               requests deposit of positive or withdrawal of
+                                               negative amount
+  approve <adjustment-id> [--comment]          approves and applies other operator's request
+  reject <adjustment-id> [--comment]           rejects adjustment request
+  adjustments [--user] [--status] [--limit]    shows adjustment requests
+  freeze <id> --reason <reason> [--block-incoming]
+                                               freezes user's account
+  unfreeze <id> --reason <reason>              unfreezes user's account
+  reconcile [--correct]                        verifies balances against operations
+  statement <id> [--from] [--to] [--limit] [--file]
+                                               exports operations as csv or json
+
+flags:
+`
+
+var errUsage = errors.New("incorrect usage")
+
+// options contains global flags of gbctl.
+type options struct {
+	configPath string
+	apiURL     string
+	actor      string
+	token      string
+	output     string
+	verbose    bool
+}
+
+func main() {
+	if err := run(context.Background(), os.Args[1:], os.Stdout, os.Stderr); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "gbctl: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+// run parses global flags, chooses backend and executes command.
+func run(ctx context.Context, args []string, out, errOut io.Writer) error {
+	opts, args, err := parseOptions(args, errOut)
+	if err != nil {
+		return err
+	}
+	logger := logrus.New()
+	logger.SetOutput(errOut)
+	if !opts.verbose {
+		logger.SetLevel(logrus.WarnLevel)
+	}
+	gb, err := newBackend(ctx, opts, logger)
+	if err != nil {
+		return err
+	}
+	defer gb.Close()
+	return execute(ctx, gb, args, &printer{out: out, format: opts.output})
+}
+
+// parseOptions parses global flags which precede command.
+func parseOptions(args []string, errOut io.Writer) (*options, []string, error) {
+	opts := &options{}
+	flagSet := pflag.NewFlagSet("gbctl", pflag.ContinueOnError)
+	flagSet.SetOutput(errOut)
+	flagSet.Usage = func() {
+		_, _ = fmt.Fprint(errOut, usage)
+		flagSet.PrintDefaults()
+	}
+	// command's flags are parsed by command itself
+	flagSet.SetInterspersed(false)
+	flagSet.StringVar(&opts.configPath, "config", "",
+		"config file of database connection (config.env by default)")
+	flagSet.StringVar(&opts.apiURL, "api-url", "",
+		"balance api url, database is used if it's empty")
+	flagSet.StringVar(&opts.actor, "actor", defaultActor(), "operator written to audit log")
+	flagSet.StringVar(&opts.token, "token", "", "api bearer token")
+	flagSet.StringVarP(&opts.output, "output", "o", tableOutput, "output format: table or json")
+	flagSet.BoolVarP(&opts.verbose, "verbose", "v", false, "print service logs")
+	if err := flagSet.Parse(args); err != nil {
+		return nil, nil, fmt.Errorf("can't parse flags: <%w>", err)
+	}
+	if opts.output != tableOutput && opts.output != jsonOutput {
+		return nil, nil, fmt.Errorf("unknown output <%s>: <%w>", opts.output, errUsage)
+	}
+	if strings.TrimSpace(opts.actor) == "" {
+		return nil, nil, fmt.Errorf("actor can't be empty: <%w>", errUsage)
+	}
+	if flagSet.NArg() == 0 {
+		flagSet.Usage()
+		return nil, nil, fmt.Errorf("command isn't set: <%w>", errUsage)
+	}
+	return opts, flagSet.Args(), nil
+}
+
+// defaultActor returns operator's system user name.
+func defaultActor() string {
+	if user := os.Getenv("USER"); user != "" {
+		return user
+	}
+	return "gbctl"
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/agandreev/avito-intern-assignment/internal/domain"
+)
+
+const (
+	tableOutput = "table"
+	jsonOutput  = "json"
+)
+
+// printer writes command's results as table or as json.
+type printer struct {
+	out    io.Writer
+	format string
+}
+
+// user prints domain.User's balance and status.
+func (printer *printer) user(user *domain.User) error {
+	return printer.print(user, func(table *tabwriter.Writer) {
+		_, _ = fmt.Fprintln(table, "ID\tAMOUNT\tCURRENCY\tSTATUS\tREASON")
+		_, _ = fmt.Fprintf(table, "%d\t%.2f\t%s\t%s\t%s\n", user.ID, user.Amount,
+			user.Currency, user.AccountStatus(), user.StatusReason)
+	})
+}
+
+// operations prints domain.RepositoryOperation list.
+func (printer *printer) operations(operations []domain.RepositoryOperation) error {
+	return printer.print(operations, func(table *tabwriter.Writer) {
+		_, _ = fmt.Fprintln(table, "TIME\tTYPE\tAMOUNT\tFEE\tRECEIVER")
+		for _, operation := range operations {
+			receiver := "-"
+			if operation.ReceiverID != 0 {
+				receiver = fmt.Sprint(operation.ReceiverID)
+			}
+			_, _ = fmt.Fprintf(table, "%s\t%s\t%.2f\t%.2f\t%s\n",
+				operation.Timestamp.Format(time.RFC3339), operation.Type, operation.Amount,
+				operation.Fee, receiver)
+		}
+	})
+}
+
+// adjustments prints domain.AdjustmentRequest list.
+func (printer *printer) adjustments(requests []domain.AdjustmentRequest) error {
+	return printer.print(requests, func(table *tabwriter.Writer) {
+		_, _ = fmt.Fprintln(table,
+			"ID\tUSER\tAMOUNT\tSTATUS\tTICKET\tCREATED BY\tREVIEWED BY\tEXPIRES")
+		for _, request := range requests {
+			reviewer := "-"
+			if request.ReviewedBy != "" {
+				reviewer = request.ReviewedBy
+			}
+			_, _ = fmt.Fprintf(table, "%d\t%d\t%.2f\t%s\t%s\t%s\t%s\t%s\n", request.ID,
+				request.UserID, request.Amount, request.Status, request.Ticket,
+				request.CreatedBy, reviewer, request.ExpiresAt.Format(time.RFC3339))
+		}
+	})
+}
+
+// report prints domain.ReconciliationReport's mismatches and totals.
+func (printer *printer) report(report *domain.ReconciliationReport) error {
+	return printer.print(report, func(table *tabwriter.Writer) {
+		_, _ = fmt.Fprintln(table, "USER\tBALANCE\tLEDGER\tDIFFERENCE\tCORRECTED")
+		for _, mismatch := range report.Mismatches {
+			_, _ = fmt.Fprintf(table, "%d\t%.2f\t%.2f\t%.2f\t%t\n", mismatch.UserID,
+				mismatch.Balance, mismatch.Ledger, mismatch.Difference, mismatch.Corrected)
+		}
+		_, _ = fmt.Fprintf(table, "checked: %d, mismatched: %d, corrected: %d\n",
+			report.UsersChecked, len(report.Mismatches), report.Corrected)
+		for _, reportError := range report.Errors {
+			_, _ = fmt.Fprintf(table, "error: %s\n", reportError)
+		}
+	})
+}
+
+// print writes value as indented json or fills table by rows.
+func (printer *printer) print(value interface{}, rows func(table *tabwriter.Writer)) error {
+	if printer.format == jsonOutput {
+		return writeJSON(printer.out, value)
+	}
+	table := tabwriter.NewWriter(printer.out, 0, 0, 2, ' ', 0)
+	rows(table)
+	if err := table.Flush(); err != nil {
+		return fmt.Errorf("can't print table: <%w>", err)
+	}
+	return nil
+}
+
+// writeJSON writes value as indented json.
+func writeJSON(out io.Writer, value interface{}) error {
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return fmt.Errorf("can't marshal output: <%w>", err)
+	}
+	if _, err = fmt.Fprintln(out, string(data)); err != nil {
+		return fmt.Errorf("can't print output: <%w>", err)
+	}
+	return nil
+}
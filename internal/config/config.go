@@ -0,0 +1,756 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cast"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+const (
+	DefaultPath = "config.env"
+
+	configFlag      = "config"
+	printConfigFlag = "print-config"
+
+	srvHost            = "SRV_HOST"
+	srvPort            = "SRV_PORT"
+	srvReadTimeout     = "SRV_READ_TIMEOUT"
+	srvWriteTimeout    = "SRV_WRITE_TIMEOUT"
+	srvIdleTimeout     = "SRV_IDLE_TIMEOUT"
+	srvHandlerTimeout  = "SRV_HANDLER_TIMEOUT"
+	srvShutdownTimeout = "SRV_SHUTDOWN_TIMEOUT"
+	srvAdminTokens     = "SRV_ADMIN_TOKENS"
+
+	grpcHost   = "GRPC_HOST"
+	grpcPort   = "GRPC_PORT"
+	grpcTokens = "GRPC_TOKENS"
+
+	dbHost     = "DB_HOST"
+	dbPort     = "DB_PORT"
+	dbUser     = "DB_USER"
+	dbPSWD     = "DB_PSWD"
+	dbName     = "DB_NAME"
+	dbSSLMode  = "DB_SSLMODE"
+	dbMaxConns = "DB_MAX_CONNS"
+	dbMigrate  = "DB_AUTO_MIGRATE"
+	dbRetries  = "DB_RETRIES"
+	dbBackoff  = "DB_RETRY_BACKOFF"
+
+	apiKeyTag = "API_KEY"
+	fxURL     = "FX_URL"
+	fxTimeout = "FX_TIMEOUT"
+
+	fxBreakerFailures = "FX_BREAKER_FAILURES"
+	fxBreakerTimeout  = "FX_BREAKER_TIMEOUT"
+	fxBreakerProbes   = "FX_BREAKER_PROBES"
+	fxMonthlyQuota    = "FX_MONTHLY_QUOTA"
+	fxRounding        = "FX_ROUNDING"
+	fxSymbolsTTL      = "FX_SYMBOLS_TTL"
+
+	outboxPublisher = "OUTBOX_PUBLISHER"
+	outboxFile      = "OUTBOX_FILE"
+	outboxWebhook   = "OUTBOX_WEBHOOK_URL"
+	outboxInterval  = "OUTBOX_INTERVAL"
+	outboxBatchSize = "OUTBOX_BATCH_SIZE"
+
+	webhookInterval    = "WEBHOOK_INTERVAL"
+	webhookBatchSize   = "WEBHOOK_BATCH_SIZE"
+	webhookTimeout     = "WEBHOOK_TIMEOUT"
+	webhookMaxAttempts = "WEBHOOK_MAX_ATTEMPTS"
+	webhookBackoff     = "WEBHOOK_BACKOFF"
+	webhookMaxBackoff  = "WEBHOOK_MAX_BACKOFF"
+
+	streamHeartbeat = "STREAM_HEARTBEAT"
+	streamBuffer    = "STREAM_BUFFER"
+	streamNotify    = "STREAM_NOTIFY"
+
+	batchMaxSize = "BATCH_MAX_SIZE"
+
+	schedulerInterval  = "SCHEDULER_INTERVAL"
+	schedulerBatchSize = "SCHEDULER_BATCH_SIZE"
+	schedulerLease     = "SCHEDULER_LEASE"
+
+	limitMaxOperation    = "LIMIT_MAX_OPERATION"
+	limitDailyWithdraw   = "LIMIT_DAILY_WITHDRAW"
+	limitMonthlyWithdraw = "LIMIT_MONTHLY_WITHDRAW"
+	limitDailyTransfer   = "LIMIT_DAILY_TRANSFER"
+	limitMonthlyTransfer = "LIMIT_MONTHLY_TRANSFER"
+
+	feeAccountID = "FEE_ACCOUNT_ID"
+	feeSchedule  = "FEE_SCHEDULE"
+
+	usersAutoCreate = "USERS_AUTO_CREATE"
+
+	idempotencyTTL = "IDEMPOTENCY_TTL"
+
+	adjustmentTTL = "ADJUSTMENT_TTL"
+
+	reconcileInterval = "RECONCILE_INTERVAL"
+	reconcileCorrect  = "RECONCILE_CORRECT"
+
+	logFile   = "LOG_FILE"
+	logLevel  = "LOG_LEVEL"
+	logFormat = "LOG_FORMAT"
+
+	TextFormat = "text"
+	JSONFormat = "json"
+
+	NonePublisher    = "none"
+	StdoutPublisher  = "stdout"
+	FilePublisher    = "file"
+	WebhookPublisher = "webhook"
+
+	redacted = "******"
+)
+
+var (
+	ErrInvalidConfig = errors.New("config is invalid")
+
+	sslModes   = []string{"disable", "allow", "prefer", "require", "verify-ca", "verify-full"}
+	publishers = []string{NonePublisher, StdoutPublisher, FilePublisher, WebhookPublisher}
+	roundings  = []string{"half-even", "half-up", "half-down", "down", "up"}
+)
+
+// option describes a single configuration key: its default value and its flag.
+type option struct {
+	key          string
+	defaultValue interface{}
+	usage        string
+}
+
+// options is a list of all supported keys. Environment variables have the same names
+// as keys, flags are lowercase keys with dashes instead of underscores.
+var options = []option{
+	{srvHost, "", "http server host"},
+	{srvPort, "8000", "http server port"},
+	{srvReadTimeout, 10 * time.Second, "http server read timeout"},
+	{srvWriteTimeout, 10 * time.Second, "http server write timeout"},
+	{srvIdleTimeout, 60 * time.Second, "http server idle timeout"},
+	{srvHandlerTimeout, 10 * time.Second, "http handler processing timeout"},
+	{srvShutdownTimeout, 15 * time.Second, "graceful shutdown timeout"},
+	{srvAdminTokens, "", "comma separated operator:token pairs of admin api (empty disables it)"},
+	{grpcHost, "", "grpc server host"},
+	{grpcPort, "9000", "grpc server port"},
+	{grpcTokens, "", "comma separated actor:token pairs of grpc callers (empty disables auth)"},
+	{dbHost, "localhost", "database host"},
+	{dbPort, "5432", "database port"},
+	{dbUser, "", "database user"},
+	{dbPSWD, "", "database password"},
+	{dbName, "", "database name"},
+	{dbSSLMode, "disable", "database ssl mode"},
+	{dbMaxConns, 10, "database pool size"},
+	{dbMigrate, false, "apply pending migrations on startup"},
+	{dbRetries, 3, "retries of transactions failed by serialization or connection errors"},
+	{dbBackoff, 50 * time.Millisecond, "delay before the first transaction retry"},
+	{apiKeyTag, "", "exchange rates provider api key"},
+	{fxURL, "http://api.exchangeratesapi.io/v1/", "exchange rates provider url"},
+	{fxTimeout, 10 * time.Second, "exchange rates provider request timeout"},
+	{fxBreakerFailures, 5, "consecutive provider failures which open circuit breaker"},
+	{fxBreakerTimeout, 30 * time.Second, "time after which open circuit breaker sends probes"},
+	{fxBreakerProbes, 1, "successful probes which close circuit breaker"},
+	{fxMonthlyQuota, 0, "provider requests per month (0 is unlimited)"},
+	{fxRounding, "half-even", "converted amounts rounding (half-even, half-up, half-down, down, up)"},
+	{fxSymbolsTTL, 24 * time.Hour, "time during which provider's currencies are cached"},
+	{outboxPublisher, NonePublisher, "outbox events publisher (none, stdout, file or webhook)"},
+	{outboxFile, "events.jsonl", "outbox events file for file publisher"},
+	{outboxWebhook, "", "outbox events url for webhook publisher"},
+	{outboxInterval, time.Second, "outbox polling interval"},
+	{outboxBatchSize, 100, "outbox events quantity relayed at once"},
+	{webhookInterval, time.Second, "webhook deliveries polling interval"},
+	{webhookBatchSize, 50, "webhook deliveries quantity sent at once"},
+	{webhookTimeout, 10 * time.Second, "webhook request timeout"},
+	{webhookMaxAttempts, 8, "webhook delivery attempts before dead state"},
+	{webhookBackoff, 10 * time.Second, "webhook delay after the first failure"},
+	{webhookMaxBackoff, time.Hour, "webhook maximal delay between attempts"},
+	{streamHeartbeat, 5 * time.Second, "balance stream heartbeat interval"},
+	{streamBuffer, 64, "balance stream subscription buffer size"},
+	{streamNotify, false, "share balance events between replicas by postgres notify"},
+	{batchMaxSize, 1000, "maximal quantity of operations in a batch"},
+	{schedulerInterval, 10 * time.Second, "scheduled transfers polling interval"},
+	{schedulerBatchSize, 50, "scheduled transfers quantity executed at once"},
+	{schedulerLease, time.Minute, "time during which claimed schedules are skipped by replicas"},
+	{limitMaxOperation, 0, "maximal amount of a single operation (0 is unlimited)"},
+	{limitDailyWithdraw, 0, "user's withdrawals per day (0 is unlimited)"},
+	{limitMonthlyWithdraw, 0, "user's withdrawals per month (0 is unlimited)"},
+	{limitDailyTransfer, 0, "user's outgoing transfers per day (0 is unlimited)"},
+	{limitMonthlyTransfer, 0, "user's outgoing transfers per month (0 is unlimited)"},
+	{feeAccountID, 0, "commission account which receives fees"},
+	{feeSchedule, "", "json list of fee rules with type, currency, percent, fixed, min and max"},
+	{usersAutoCreate, true, "create unknown users on their first deposit"},
+	{idempotencyTTL, 24 * time.Hour, "time after which idempotency keys can be reused (0 is never)"},
+	{adjustmentTTL, 72 * time.Hour, "time after which pending balance adjustments expire"},
+	{reconcileInterval, time.Hour, "balances reconciliation interval (0 disables it)"},
+	{reconcileCorrect, false, "correct balances which differ from operations"},
+	{logFile, "logs.txt", "log file path (empty to log only to stdout)"},
+	{logLevel, "info", "log level"},
+	{logFormat, TextFormat, "log format (text or json)"},
+}
+
+// Config contains all application settings.
+type Config struct {
+	HTTP        HTTPConfig        `json:"http"`
+	GRPC        GRPCConfig        `json:"grpc"`
+	DB          DBConfig          `json:"db"`
+	Exchange    ExchangeConfig    `json:"exchange"`
+	Outbox      OutboxConfig      `json:"outbox"`
+	Webhook     WebhookConfig     `json:"webhook"`
+	Stream      StreamConfig      `json:"stream"`
+	Batch       BatchConfig       `json:"batch"`
+	Scheduler   SchedulerConfig   `json:"scheduler"`
+	Limits      LimitsConfig      `json:"limits"`
+	Fees        FeesConfig        `json:"fees"`
+	Users       UsersConfig       `json:"users"`
+	Idempotency IdempotencyConfig `json:"idempotency"`
+	Adjustment  AdjustmentConfig  `json:"adjustment"`
+	Reconcile   ReconcileConfig   `json:"reconcile"`
+	Log         LogConfig         `json:"log"`
+}
+
+// HTTPConfig contains http server address and timeouts.
+type HTTPConfig struct {
+	Host            string        `json:"host"`
+	Port            string        `json:"port"`
+	ReadTimeout     time.Duration `json:"read_timeout"`
+	WriteTimeout    time.Duration `json:"write_timeout"`
+	IdleTimeout     time.Duration `json:"idle_timeout"`
+	HandlerTimeout  time.Duration `json:"handler_timeout"`
+	ShutdownTimeout time.Duration `json:"shutdown_timeout"`
+	// AdminTokens maps operators to their bearer tokens of admin api.
+	AdminTokens map[string]string `json:"admin_tokens,omitempty"`
+}
+
+// Address returns host:port pair for listening.
+func (config HTTPConfig) Address() string {
+	return net.JoinHostPort(config.Host, config.Port)
+}
+
+// GRPCConfig contains grpc server address and callers' tokens.
+type GRPCConfig struct {
+	Host string `json:"host"`
+	Port string `json:"port"`
+	// Tokens maps actors to their bearer tokens.
+	Tokens map[string]string `json:"tokens,omitempty"`
+}
+
+// Address returns host:port pair for listening.
+func (config GRPCConfig) Address() string {
+	return net.JoinHostPort(config.Host, config.Port)
+}
+
+// DBConfig contains database connection parameters.
+type DBConfig struct {
+	Host        string `json:"host"`
+	Port        string `json:"port"`
+	User        string `json:"user"`
+	Password    string `json:"password"`
+	Name        string `json:"name"`
+	SSLMode     string `json:"ssl_mode"`
+	MaxConns    int32  `json:"max_conns"`
+	AutoMigrate bool   `json:"auto_migrate"`
+	// Retries limits retries of transactions failed by transient errors.
+	Retries      int32         `json:"retries"`
+	RetryBackoff time.Duration `json:"retry_backoff"`
+}
+
+// ExchangeConfig contains exchange rates provider settings.
+type ExchangeConfig struct {
+	APIKey  string        `json:"api_key"`
+	URL     string        `json:"url"`
+	Timeout time.Duration `json:"timeout"`
+	// circuit breaker and quota of provider
+	BreakerFailures int32         `json:"breaker_failures"`
+	BreakerTimeout  time.Duration `json:"breaker_timeout"`
+	BreakerProbes   int32         `json:"breaker_probes"`
+	MonthlyQuota    int32         `json:"monthly_quota"`
+	// Rounding is applied to converted amounts by currency's minor units.
+	Rounding   string        `json:"rounding"`
+	SymbolsTTL time.Duration `json:"symbols_ttl"`
+}
+
+// OutboxConfig contains events relay settings.
+type OutboxConfig struct {
+	Publisher  string        `json:"publisher"`
+	File       string        `json:"file"`
+	WebhookURL string        `json:"webhook_url"`
+	Interval   time.Duration `json:"interval"`
+	BatchSize  int32         `json:"batch_size"`
+}
+
+// WebhookConfig contains webhook deliveries settings.
+type WebhookConfig struct {
+	Interval    time.Duration `json:"interval"`
+	BatchSize   int32         `json:"batch_size"`
+	Timeout     time.Duration `json:"timeout"`
+	MaxAttempts int32         `json:"max_attempts"`
+	Backoff     time.Duration `json:"backoff"`
+	MaxBackoff  time.Duration `json:"max_backoff"`
+}
+
+// StreamConfig contains balance stream settings.
+type StreamConfig struct {
+	Heartbeat time.Duration `json:"heartbeat"`
+	Buffer    int32         `json:"buffer"`
+	Notify    bool          `json:"notify"`
+}
+
+// BatchConfig contains batch operations settings.
+type BatchConfig struct {
+	MaxSize int32 `json:"max_size"`
+}
+
+// SchedulerConfig contains scheduled transfers settings.
+type SchedulerConfig struct {
+	Interval  time.Duration `json:"interval"`
+	BatchSize int32         `json:"batch_size"`
+	Lease     time.Duration `json:"lease"`
+}
+
+// LimitsConfig contains global operation limits, zero limit isn't applied.
+type LimitsConfig struct {
+	MaxOperation    float64 `json:"max_operation"`
+	DailyWithdraw   float64 `json:"daily_withdraw"`
+	MonthlyWithdraw float64 `json:"monthly_withdraw"`
+	DailyTransfer   float64 `json:"daily_transfer"`
+	MonthlyTransfer float64 `json:"monthly_transfer"`
+}
+
+// FeesConfig contains fee rules and commission account.
+type FeesConfig struct {
+	AccountID int64     `json:"account_id"`
+	Rules     []FeeRule `json:"rules"`
+}
+
+// FeeRule describes fee of operations with the same type and currency.
+type FeeRule struct {
+	Type     string  `json:"type"`
+	Currency string  `json:"currency,omitempty"`
+	Percent  float64 `json:"percent"`
+	Fixed    float64 `json:"fixed"`
+	Min      float64 `json:"min"`
+	Max      float64 `json:"max"`
+}
+
+// UsersConfig contains accounts creation settings.
+type UsersConfig struct {
+	AutoCreate bool `json:"auto_create"`
+}
+
+// IdempotencyConfig contains idempotency keys settings.
+type IdempotencyConfig struct {
+	TTL time.Duration `json:"ttl"`
+}
+
+// AdjustmentConfig contains balance adjustments settings.
+type AdjustmentConfig struct {
+	TTL time.Duration `json:"ttl"`
+}
+
+// ReconcileConfig contains balances reconciliation settings.
+type ReconcileConfig struct {
+	Interval time.Duration `json:"interval"`
+	Correct  bool          `json:"correct"`
+}
+
+// LogConfig contains logger settings.
+type LogConfig struct {
+	File   string `json:"file"`
+	Level  string `json:"level"`
+	Format string `json:"format"`
+}
+
+// Flags contains command line values which aren't part of Config.
+type Flags struct {
+	Path        string
+	PrintConfig bool
+	// Args contains positional arguments, e.g. subcommand.
+	Args []string
+}
+
+// ValidationError contains all problems found in Config.
+type ValidationError struct {
+	Problems []string
+}
+
+func (validationError ValidationError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrInvalidConfig,
+		strings.Join(validationError.Problems, "; "))
+}
+
+func (validationError ValidationError) Unwrap() error {
+	return ErrInvalidConfig
+}
+
+// Load builds Config from defaults, config file, environment and command line
+// arguments. The precedence is flags > environment > file > defaults.
+func Load(name string, args []string) (*Config, *Flags, error) {
+	v := viper.New()
+	flagSet := pflag.NewFlagSet(name, pflag.ContinueOnError)
+	flags := &Flags{}
+	flagSet.StringVar(&flags.Path, configFlag, DefaultPath, "config file path")
+	flagSet.BoolVar(&flags.PrintConfig, printConfigFlag, false,
+		"print resulting config with redacted secrets and exit")
+	for _, opt := range options {
+		v.SetDefault(opt.key, opt.defaultValue)
+		flagSet.String(flagName(opt.key), fmt.Sprint(opt.defaultValue), opt.usage)
+		// allow boolean flags without value
+		if _, ok := opt.defaultValue.(bool); ok {
+			flagSet.Lookup(flagName(opt.key)).NoOptDefVal = "true"
+		}
+		if err := v.BindPFlag(opt.key, flagSet.Lookup(flagName(opt.key))); err != nil {
+			return nil, nil, fmt.Errorf("can't bind flag: <%w>", err)
+		}
+	}
+	if err := flagSet.Parse(args); err != nil {
+		return nil, nil, fmt.Errorf("can't parse flags: <%w>", err)
+	}
+	flags.Args = flagSet.Args()
+	v.AutomaticEnv()
+
+	// config file is optional unless it was chosen explicitly
+	v.SetConfigFile(flags.Path)
+	if err := v.ReadInConfig(); err != nil {
+		_, statErr := os.Stat(flags.Path)
+		if flagSet.Changed(configFlag) || !os.IsNotExist(statErr) {
+			return nil, nil, fmt.Errorf("can't load config: <%w>", err)
+		}
+	}
+
+	// report conversion and validation problems together
+	config, problems := decode(v)
+	var validationError ValidationError
+	if err := config.Validate(); errors.As(err, &validationError) {
+		problems = append(problems, validationError.Problems...)
+	}
+	if len(problems) != 0 {
+		return nil, nil, ValidationError{Problems: problems}
+	}
+	return config, flags, nil
+}
+
+// decode reads all options from viper, collecting conversion problems.
+func decode(v *viper.Viper) (*Config, []string) {
+	var problems []string
+	duration := func(key string) time.Duration {
+		value, err := cast.ToDurationE(v.Get(key))
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s isn't a duration", key))
+		}
+		return value
+	}
+	boolean := func(key string) bool {
+		value, err := cast.ToBoolE(v.Get(key))
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s isn't a boolean", key))
+		}
+		return value
+	}
+	integer := func(key string) int32 {
+		value, err := cast.ToInt32E(v.Get(key))
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s isn't an integer", key))
+		}
+		return value
+	}
+	integer64 := func(key string) int64 {
+		value, err := cast.ToInt64E(v.Get(key))
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s isn't an integer", key))
+		}
+		return value
+	}
+	float := func(key string) float64 {
+		value, err := cast.ToFloat64E(v.Get(key))
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s isn't a number", key))
+		}
+		return value
+	}
+	config := &Config{
+		HTTP: HTTPConfig{
+			Host:            v.GetString(srvHost),
+			Port:            v.GetString(srvPort),
+			ReadTimeout:     duration(srvReadTimeout),
+			WriteTimeout:    duration(srvWriteTimeout),
+			IdleTimeout:     duration(srvIdleTimeout),
+			HandlerTimeout:  duration(srvHandlerTimeout),
+			ShutdownTimeout: duration(srvShutdownTimeout),
+		},
+		GRPC: GRPCConfig{
+			Host: v.GetString(grpcHost),
+			Port: v.GetString(grpcPort),
+		},
+		DB: DBConfig{
+			Host:         v.GetString(dbHost),
+			Port:         v.GetString(dbPort),
+			User:         v.GetString(dbUser),
+			Password:     v.GetString(dbPSWD),
+			Name:         v.GetString(dbName),
+			SSLMode:      v.GetString(dbSSLMode),
+			MaxConns:     integer(dbMaxConns),
+			AutoMigrate:  boolean(dbMigrate),
+			Retries:      integer(dbRetries),
+			RetryBackoff: duration(dbBackoff),
+		},
+		Exchange: ExchangeConfig{
+			APIKey:  v.GetString(apiKeyTag),
+			URL:     v.GetString(fxURL),
+			Timeout: duration(fxTimeout),
+
+			BreakerFailures: integer(fxBreakerFailures),
+			BreakerTimeout:  duration(fxBreakerTimeout),
+			BreakerProbes:   integer(fxBreakerProbes),
+			MonthlyQuota:    integer(fxMonthlyQuota),
+			Rounding:        v.GetString(fxRounding),
+			SymbolsTTL:      duration(fxSymbolsTTL),
+		},
+		Outbox: OutboxConfig{
+			Publisher:  v.GetString(outboxPublisher),
+			File:       v.GetString(outboxFile),
+			WebhookURL: v.GetString(outboxWebhook),
+			Interval:   duration(outboxInterval),
+			BatchSize:  integer(outboxBatchSize),
+		},
+		Webhook: WebhookConfig{
+			Interval:    duration(webhookInterval),
+			BatchSize:   integer(webhookBatchSize),
+			Timeout:     duration(webhookTimeout),
+			MaxAttempts: integer(webhookMaxAttempts),
+			Backoff:     duration(webhookBackoff),
+			MaxBackoff:  duration(webhookMaxBackoff),
+		},
+		Stream: StreamConfig{
+			Heartbeat: duration(streamHeartbeat),
+			Buffer:    integer(streamBuffer),
+			Notify:    boolean(streamNotify),
+		},
+		Batch: BatchConfig{
+			MaxSize: integer(batchMaxSize),
+		},
+		Scheduler: SchedulerConfig{
+			Interval:  duration(schedulerInterval),
+			BatchSize: integer(schedulerBatchSize),
+			Lease:     duration(schedulerLease),
+		},
+		Limits: LimitsConfig{
+			MaxOperation:    float(limitMaxOperation),
+			DailyWithdraw:   float(limitDailyWithdraw),
+			MonthlyWithdraw: float(limitMonthlyWithdraw),
+			DailyTransfer:   float(limitDailyTransfer),
+			MonthlyTransfer: float(limitMonthlyTransfer),
+		},
+		Fees: FeesConfig{
+			AccountID: integer64(feeAccountID),
+		},
+		Users: UsersConfig{
+			AutoCreate: boolean(usersAutoCreate),
+		},
+		Idempotency: IdempotencyConfig{
+			TTL: duration(idempotencyTTL),
+		},
+		Adjustment: AdjustmentConfig{
+			TTL: duration(adjustmentTTL),
+		},
+		Reconcile: ReconcileConfig{
+			Interval: duration(reconcileInterval),
+			Correct:  boolean(reconcileCorrect),
+		},
+		Log: LogConfig{
+			File:   v.GetString(logFile),
+			Level:  v.GetString(logLevel),
+			Format: v.GetString(logFormat),
+		},
+	}
+	if value := v.GetString(feeSchedule); value != "" {
+		if err := json.Unmarshal([]byte(value), &config.Fees.Rules); err != nil {
+			problems = append(problems, fmt.Sprintf("%s isn't a json list of rules",
+				feeSchedule))
+		}
+	}
+	config.HTTP.AdminTokens = tokens(v, srvAdminTokens, &problems)
+	config.GRPC.Tokens = tokens(v, grpcTokens, &problems)
+	return config, problems
+}
+
+// tokens parses comma separated actor:token pairs of key, empty value is nil map.
+func tokens(v *viper.Viper, key string, problems *[]string) map[string]string {
+	value := v.GetString(key)
+	if value == "" {
+		return nil
+	}
+	result := make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		parts := strings.SplitN(strings.TrimSpace(pair), ":", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			*problems = append(*problems, fmt.Sprintf("%s must be a list of actor:token pairs",
+				key))
+			break
+		}
+		result[parts[0]] = parts[1]
+	}
+	return result
+}
+
+// Validate checks all Config values and reports every found problem.
+func (config Config) Validate() error {
+	var problems []string
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			problems = append(problems, fmt.Sprintf(format, args...))
+		}
+	}
+	// http
+	check(isPort(config.HTTP.Port), "%s must be a port number", srvPort)
+	check(config.HTTP.ReadTimeout > 0, "%s must be positive", srvReadTimeout)
+	check(config.HTTP.WriteTimeout > 0, "%s must be positive", srvWriteTimeout)
+	check(config.HTTP.IdleTimeout > 0, "%s must be positive", srvIdleTimeout)
+	check(config.HTTP.HandlerTimeout > 0, "%s must be positive", srvHandlerTimeout)
+	check(config.HTTP.ShutdownTimeout > 0, "%s must be positive", srvShutdownTimeout)
+	// grpc
+	check(isPort(config.GRPC.Port), "%s must be a port number", grpcPort)
+	check(config.GRPC.Address() != config.HTTP.Address(), "%s must differ from %s",
+		grpcPort, srvPort)
+	// db
+	check(config.DB.Host != "", "%s is required", dbHost)
+	check(isPort(config.DB.Port), "%s must be a port number", dbPort)
+	check(config.DB.User != "", "%s is required", dbUser)
+	check(config.DB.Name != "", "%s is required", dbName)
+	check(contains(sslModes, config.DB.SSLMode), "%s must be one of %s",
+		dbSSLMode, strings.Join(sslModes, ", "))
+	check(config.DB.MaxConns > 0, "%s must be positive", dbMaxConns)
+	check(config.DB.Retries >= 0, "%s can't be negative", dbRetries)
+	check(config.DB.RetryBackoff >= 0, "%s can't be negative", dbBackoff)
+	// exchange
+	check(config.Exchange.APIKey != "", "%s is required", apiKeyTag)
+	check(isURL(config.Exchange.URL), "%s must be an absolute url", fxURL)
+	check(config.Exchange.Timeout > 0, "%s must be positive", fxTimeout)
+	check(config.Exchange.BreakerFailures > 0, "%s must be positive", fxBreakerFailures)
+	check(config.Exchange.BreakerTimeout > 0, "%s must be positive", fxBreakerTimeout)
+	check(config.Exchange.BreakerProbes > 0, "%s must be positive", fxBreakerProbes)
+	check(config.Exchange.MonthlyQuota >= 0, "%s can't be negative", fxMonthlyQuota)
+	check(contains(roundings, config.Exchange.Rounding), "%s must be one of %s",
+		fxRounding, strings.Join(roundings, ", "))
+	check(config.Exchange.SymbolsTTL > 0, "%s must be positive", fxSymbolsTTL)
+	// outbox
+	check(contains(publishers, config.Outbox.Publisher), "%s must be one of %s",
+		outboxPublisher, strings.Join(publishers, ", "))
+	check(config.Outbox.Publisher != FilePublisher || config.Outbox.File != "",
+		"%s is required for file publisher", outboxFile)
+	check(config.Outbox.Publisher != WebhookPublisher || isURL(config.Outbox.WebhookURL),
+		"%s must be an absolute url for webhook publisher", outboxWebhook)
+	check(config.Outbox.Interval > 0, "%s must be positive", outboxInterval)
+	check(config.Outbox.BatchSize > 0, "%s must be positive", outboxBatchSize)
+	// webhook
+	check(config.Webhook.Interval > 0, "%s must be positive", webhookInterval)
+	check(config.Webhook.BatchSize > 0, "%s must be positive", webhookBatchSize)
+	check(config.Webhook.Timeout > 0, "%s must be positive", webhookTimeout)
+	check(config.Webhook.MaxAttempts > 0, "%s must be positive", webhookMaxAttempts)
+	check(config.Webhook.Backoff > 0, "%s must be positive", webhookBackoff)
+	check(config.Webhook.MaxBackoff >= config.Webhook.Backoff,
+		"%s can't be less than %s", webhookMaxBackoff, webhookBackoff)
+	// stream
+	check(config.Stream.Heartbeat > 0 && config.Stream.Heartbeat < config.HTTP.WriteTimeout,
+		"%s must be positive and less than %s", streamHeartbeat, srvWriteTimeout)
+	check(config.Stream.Buffer > 0, "%s must be positive", streamBuffer)
+	// batch
+	check(config.Batch.MaxSize > 0, "%s must be positive", batchMaxSize)
+	// scheduler
+	check(config.Scheduler.Interval > 0, "%s must be positive", schedulerInterval)
+	check(config.Scheduler.BatchSize > 0, "%s must be positive", schedulerBatchSize)
+	check(config.Scheduler.Lease > 0, "%s must be positive", schedulerLease)
+	// limits
+	check(config.Limits.MaxOperation >= 0, "%s can't be negative", limitMaxOperation)
+	check(config.Limits.DailyWithdraw >= 0, "%s can't be negative", limitDailyWithdraw)
+	check(config.Limits.MonthlyWithdraw >= 0, "%s can't be negative", limitMonthlyWithdraw)
+	check(config.Limits.DailyTransfer >= 0, "%s can't be negative", limitDailyTransfer)
+	check(config.Limits.MonthlyTransfer >= 0, "%s can't be negative", limitMonthlyTransfer)
+	// fees
+	check(len(config.Fees.Rules) == 0 || config.Fees.AccountID > 0,
+		"%s must be positive if %s is set", feeAccountID, feeSchedule)
+	// idempotency
+	check(config.Idempotency.TTL >= 0, "%s can't be negative", idempotencyTTL)
+	// reconcile
+	check(config.Adjustment.TTL > 0, "%s must be positive", adjustmentTTL)
+	check(config.Reconcile.Interval >= 0, "%s can't be negative", reconcileInterval)
+	// log
+	_, err := logrus.ParseLevel(config.Log.Level)
+	check(err == nil, "%s is unknown", logLevel)
+	check(config.Log.Format == TextFormat || config.Log.Format == JSONFormat,
+		"%s must be %s or %s", logFormat, TextFormat, JSONFormat)
+
+	if len(problems) != 0 {
+		return ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+// Redacted returns Config copy without secrets.
+func (config Config) Redacted() Config {
+	if config.DB.Password != "" {
+		config.DB.Password = redacted
+	}
+	if config.Exchange.APIKey != "" {
+		config.Exchange.APIKey = redacted
+	}
+	config.HTTP.AdminTokens = redactTokens(config.HTTP.AdminTokens)
+	config.GRPC.Tokens = redactTokens(config.GRPC.Tokens)
+	return config
+}
+
+// redactTokens returns copy of actors' tokens without tokens.
+func redactTokens(tokens map[string]string) map[string]string {
+	if len(tokens) == 0 {
+		return tokens
+	}
+	result := make(map[string]string, len(tokens))
+	for actor := range tokens {
+		result[actor] = redacted
+	}
+	return result
+}
+
+// String returns Config as indented json with redacted secrets.
+func (config Config) String() string {
+	data, err := json.MarshalIndent(config.Redacted(), "", "  ")
+	if err != nil {
+		return err.Error()
+	}
+	return string(data)
+}
+
+// flagName converts option's key to flag's name.
+func flagName(key string) string {
+	return strings.ReplaceAll(strings.ToLower(key), "_", "-")
+}
+
+// isPort returns true if value is a correct port number.
+func isPort(value string) bool {
+	port, err := strconv.Atoi(value)
+	return err == nil && port > 0 && port <= 65535
+}
+
+// isURL returns true if value is an absolute url.
+func isURL(value string) bool {
+	parsed, err := url.Parse(value)
+	return err == nil && parsed.Scheme != "" && parsed.Host != ""
+}
+
+// contains returns true if values contain value.
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,113 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type ConfigSuite struct {
+	suite.Suite
+	Path string
+}
+
+func (suite *ConfigSuite) SetupTest() {
+	suite.Path = filepath.Join(suite.T().TempDir(), "config.env")
+	content := "API_KEY=key\nDB_USER=user\nDB_PSWD=passwd\nDB_NAME=fintech\n" +
+		"DB_PORT=5442\nSRV_PORT=8000\n"
+	suite.Require().NoError(os.WriteFile(suite.Path, []byte(content), 0600))
+}
+
+func (suite *ConfigSuite) TestLoad_Precedence() {
+	// file value is used
+	config, _, err := Load("test", []string{"--config", suite.Path})
+	suite.Require().NoError(err)
+	suite.Equal("5442", config.DB.Port)
+	suite.Equal("localhost", config.DB.Host)
+	suite.Equal(10*time.Second, config.HTTP.ReadTimeout)
+
+	// environment overrides file
+	suite.T().Setenv(dbPort, "5443")
+	config, _, err = Load("test", []string{"--config", suite.Path})
+	suite.Require().NoError(err)
+	suite.Equal("5443", config.DB.Port)
+
+	// flag overrides environment
+	config, _, err = Load("test", []string{"--config", suite.Path, "--db-port", "5444",
+		"--srv-read-timeout", "3s"})
+	suite.Require().NoError(err)
+	suite.Equal("5444", config.DB.Port)
+	suite.Equal(3*time.Second, config.HTTP.ReadTimeout)
+}
+
+func (suite *ConfigSuite) TestLoad_Validation() {
+	_, _, err := Load("test", []string{"--config", suite.Path, "--db-port", "port",
+		"--db-sslmode", "never", "--fx-timeout", "long", "--log-level", "loud"})
+	suite.ErrorIs(err, ErrInvalidConfig)
+	validationError, ok := err.(ValidationError)
+	suite.Require().True(ok)
+	// every problem must be reported at once
+	suite.Contains(validationError.Problems, "DB_PORT must be a port number")
+	suite.Contains(validationError.Problems, "FX_TIMEOUT isn't a duration")
+	suite.Contains(validationError.Problems, "LOG_LEVEL is unknown")
+	suite.Len(validationError.Problems, 5)
+
+	// explicit config file must exist
+	_, _, err = Load("test", []string{"--config", suite.Path + ".missing"})
+	suite.Error(err)
+}
+
+func (suite *ConfigSuite) TestLoad_FeeSchedule() {
+	suite.T().Setenv(feeSchedule, `[{"type":"WITHDRAW","percent":1.5,"min":10}]`)
+	_, _, err := Load("test", []string{"--config", suite.Path})
+	suite.ErrorIs(err, ErrInvalidConfig)
+
+	config, _, err := Load("test", []string{"--config", suite.Path, "--fee-account-id", "7"})
+	suite.Require().NoError(err)
+	suite.Equal(int64(7), config.Fees.AccountID)
+	suite.Equal([]FeeRule{{Type: "WITHDRAW", Percent: 1.5, Min: 10}}, config.Fees.Rules)
+
+	suite.T().Setenv(feeSchedule, "1.5%")
+	_, _, err = Load("test", []string{"--config", suite.Path, "--fee-account-id", "7"})
+	suite.ErrorIs(err, ErrInvalidConfig)
+}
+
+func (suite *ConfigSuite) TestLoad_GRPCTokens() {
+	suite.T().Setenv(grpcTokens, "billing:secret1, payouts:secret2")
+	config, _, err := Load("test", []string{"--config", suite.Path})
+	suite.Require().NoError(err)
+	suite.Equal(map[string]string{"billing": "secret1", "payouts": "secret2"},
+		config.GRPC.Tokens)
+	suite.NotContains(config.String(), "secret1")
+
+	suite.T().Setenv(srvAdminTokens, "alice:secret3")
+	config, _, err = Load("test", []string{"--config", suite.Path})
+	suite.Require().NoError(err)
+	suite.Equal(map[string]string{"alice": "secret3"}, config.HTTP.AdminTokens)
+	suite.NotContains(config.String(), "secret3")
+
+	suite.T().Setenv(grpcTokens, "secret1")
+	_, _, err = Load("test", []string{"--config", suite.Path})
+	suite.ErrorIs(err, ErrInvalidConfig)
+
+	// grpc and http servers can't share address
+	suite.T().Setenv(grpcTokens, "")
+	_, _, err = Load("test", []string{"--config", suite.Path, "--grpc-port", "8000"})
+	suite.ErrorIs(err, ErrInvalidConfig)
+}
+
+func (suite *ConfigSuite) TestConfig_Redacted() {
+	config, flags, err := Load("test", []string{"--config", suite.Path, "--print-config"})
+	suite.Require().NoError(err)
+	suite.True(flags.PrintConfig)
+	suite.NotContains(config.String(), "passwd")
+	suite.Contains(config.String(), redacted)
+	suite.Equal("passwd", config.DB.Password)
+}
+
+func TestConfigSuite(t *testing.T) {
+	suite.Run(t, new(ConfigSuite))
+}
@@ -2,37 +2,75 @@ package controller
 
 import (
 	"context"
+	"net"
 	"net/http"
 	"time"
 
 	"github.com/agandreev/avito-intern-assignment/internal/handlers"
+	"google.golang.org/grpc"
 )
 
-// Server represents http server structure with handler's implementations.
+// Server represents http and grpc servers structure with handler's implementations.
 type Server struct {
 	httpServer *http.Server
+	grpcServer *grpc.Server
 	handler    handlers.Handler
 }
 
-// NewServer creates Server pointer.
-func NewServer(handler handlers.Handler) *Server {
-	return &Server{handler: handler}
+// ServerConfig contains http server's address and timeouts.
+type ServerConfig struct {
+	Address      string
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+	IdleTimeout  time.Duration
 }
 
-// Run runs http server on chosen port with handlers from Server and sets timeouts.
-func (s *Server) Run(port string) error {
+// NewServer creates Server pointer. grpcServer is optional.
+func NewServer(handler handlers.Handler, grpcServer *grpc.Server) *Server {
+	return &Server{handler: handler, grpcServer: grpcServer}
+}
+
+// Run runs http server on chosen address with handlers from Server and sets timeouts.
+func (s *Server) Run(config ServerConfig) error {
 	s.httpServer = &http.Server{
-		Addr:         ":" + port,
+		Addr:         config.Address,
 		Handler:      s.handler.InitRoutes(),
-		ReadTimeout:  10 * time.Second,
-		WriteTimeout: 10 * time.Second,
+		ReadTimeout:  config.ReadTimeout,
+		WriteTimeout: config.WriteTimeout,
+		IdleTimeout:  config.IdleTimeout,
 	}
 
 	return s.httpServer.ListenAndServe()
 }
 
-// Shutdown gracefully stops all handler's goroutines and http server.
+// RunGRPC runs grpc server on chosen address. It returns nil after Shutdown.
+func (s *Server) RunGRPC(address string) error {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+	return s.grpcServer.Serve(listener)
+}
+
+// Shutdown gracefully stops http and grpc servers and then all handler's goroutines.
+// Calls which aren't finished before ctx is done are canceled.
 func (s *Server) Shutdown(ctx context.Context) error {
+	if s.grpcServer == nil {
+		err := s.httpServer.Shutdown(ctx)
+		s.handler.GB.Shutdown()
+		return err
+	}
+	stopped := make(chan struct{})
+	go func() {
+		s.grpcServer.GracefulStop()
+		close(stopped)
+	}()
+	err := s.httpServer.Shutdown(ctx)
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		s.grpcServer.Stop()
+	}
 	s.handler.GB.Shutdown()
-	return s.httpServer.Shutdown(ctx)
+	return err
 }
@@ -0,0 +1,172 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	AdjustmentPending  AdjustmentStatus = "pending"
+	AdjustmentApproved AdjustmentStatus = "approved"
+	AdjustmentRejected AdjustmentStatus = "rejected"
+	AdjustmentExpired  AdjustmentStatus = "expired"
+)
+
+var (
+	ErrIncorrectAdjustmentParams = errors.New("this adjustment is incorrect")
+	ErrNoSuchAdjustment          = errors.New("adjustment with this id doesn't exist")
+	ErrAdjustmentNotPending      = errors.New("adjustment is already reviewed")
+	ErrAdjustmentExpired         = errors.New("adjustment is expired")
+	ErrSelfApproval              = errors.New("adjustment can't be approved by its creator")
+)
+
+// AdjustmentStatus describes stage of AdjustmentRequest's review.
+type AdjustmentStatus string
+
+// AdjustmentInput represents operator's request to correct User's balance manually.
+// Positive Amount is credited and negative one is debited, Reason and Ticket are
+// mandatory.
+type AdjustmentInput struct {
+	Amount float64 `json:"amount"`
+	Reason string  `json:"reason"`
+	Ticket string  `json:"ticket"`
+}
+
+// ReviewInput represents checker's decision comment.
+type ReviewInput struct {
+	Comment string `json:"comment"`
+}
+
+// AdjustmentRequest represents manual correction of User's balance. It's created as
+// pending by one operator and is applied as Adjustment Operation only after approval by
+// another one.
+type AdjustmentRequest struct {
+	ID         int64            `json:"id"`
+	UserID     int64            `json:"user_id"`
+	Amount     float64          `json:"amount"`
+	Reason     string           `json:"reason"`
+	Ticket     string           `json:"ticket"`
+	Status     AdjustmentStatus `json:"status"`
+	CreatedBy  string           `json:"created_by"`
+	CreatedAt  time.Time        `json:"created_at"`
+	ExpiresAt  time.Time        `json:"expires_at"`
+	ReviewedBy string           `json:"reviewed_by,omitempty"`
+	ReviewedAt *time.Time       `json:"reviewed_at,omitempty"`
+	Comment    string           `json:"comment,omitempty"`
+}
+
+// AdjustmentFilter limits AdjustmentRequest's list. Zero values aren't applied.
+type AdjustmentFilter struct {
+	UserID int64            `json:"user_id,omitempty"`
+	Status AdjustmentStatus `json:"status,omitempty"`
+	Limit  int64            `json:"limit"`
+}
+
+// IsValid returns true if AdjustmentStatus is known.
+func (status AdjustmentStatus) IsValid() bool {
+	switch status {
+	case AdjustmentPending, AdjustmentApproved, AdjustmentRejected, AdjustmentExpired:
+		return true
+	}
+	return false
+}
+
+// Validate returns error if AdjustmentInput has no amount, reason or ticket.
+func (input AdjustmentInput) Validate() error {
+	if strings.TrimSpace(input.Reason) == "" || strings.TrimSpace(input.Ticket) == "" {
+		return fmt.Errorf("reason and ticket are mandatory: <%w>",
+			ErrIncorrectAdjustmentParams)
+	}
+	if len(input.Reason) > 255 || len(input.Ticket) > 255 {
+		return fmt.Errorf("reason or ticket is too long: <%w>", ErrIncorrectAdjustmentParams)
+	}
+	if input.Amount > -eps && input.Amount < eps {
+		return fmt.Errorf("amount can't be zero: <%w>", ErrIncorrectAdjustmentParams)
+	}
+	return nil
+}
+
+// Request returns pending AdjustmentRequest of User created by maker which expires
+// after ttl.
+func (input AdjustmentInput) Request(userID int64, maker string, ts time.Time,
+	ttl time.Duration) (*AdjustmentRequest, error) {
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+	if maker == "" || maker == AnonymousActor {
+		return nil, fmt.Errorf("operator must be identified: <%w>",
+			ErrIncorrectAdjustmentParams)
+	}
+	return &AdjustmentRequest{
+		UserID:    userID,
+		Amount:    input.Amount,
+		Reason:    input.Reason,
+		Ticket:    input.Ticket,
+		Status:    AdjustmentPending,
+		CreatedBy: maker,
+		CreatedAt: ts,
+		ExpiresAt: ts.Add(ttl),
+	}, nil
+}
+
+// Approve marks pending AdjustmentRequest as approved by checker, who must differ from its
+// creator.
+func (adjustment *AdjustmentRequest) Approve(checker string, input ReviewInput,
+	ts time.Time) error {
+	if checker == adjustment.CreatedBy {
+		return fmt.Errorf("adjustment <%d>: <%w>", adjustment.ID, ErrSelfApproval)
+	}
+	return adjustment.review(AdjustmentApproved, checker, input, ts)
+}
+
+// Reject marks pending AdjustmentRequest as rejected by checker. Creator can reject it too.
+func (adjustment *AdjustmentRequest) Reject(checker string, input ReviewInput,
+	ts time.Time) error {
+	return adjustment.review(AdjustmentRejected, checker, input, ts)
+}
+
+// review sets checker's decision of pending AdjustmentRequest which isn't expired.
+func (adjustment *AdjustmentRequest) review(status AdjustmentStatus, checker string,
+	input ReviewInput, ts time.Time) error {
+	if checker == "" || checker == AnonymousActor {
+		return fmt.Errorf("operator must be identified: <%w>", ErrIncorrectAdjustmentParams)
+	}
+	if len(input.Comment) > 255 {
+		return fmt.Errorf("comment is too long: <%w>", ErrIncorrectAdjustmentParams)
+	}
+	if adjustment.Status == AdjustmentExpired ||
+		(adjustment.Status == AdjustmentPending && !ts.Before(adjustment.ExpiresAt)) {
+		return fmt.Errorf("adjustment <%d>: <%w>", adjustment.ID, ErrAdjustmentExpired)
+	}
+	if adjustment.Status != AdjustmentPending {
+		return fmt.Errorf("adjustment <%d> is %s: <%w>", adjustment.ID, adjustment.Status,
+			ErrAdjustmentNotPending)
+	}
+	adjustment.Status = status
+	adjustment.ReviewedBy = checker
+	adjustment.ReviewedAt = &ts
+	adjustment.Comment = input.Comment
+	return nil
+}
+
+// Operation changes User's balance by AdjustmentRequest and returns Adjustment Operation
+// with signed Amount.
+func (adjustment AdjustmentRequest) Operation(user *User, ts time.Time) (*Operation, error) {
+	var err error
+	if adjustment.Amount < 0 {
+		err = user.Withdraw(-adjustment.Amount)
+	} else {
+		err = user.Deposit(adjustment.Amount)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("adjustment <%d> error: <%w>", adjustment.ID, err)
+	}
+	return &Operation{
+		Initiator: user,
+		Type:      Adjustment,
+		Amount:    adjustment.Amount,
+		Timestamp: ts,
+	}, nil
+}
@@ -0,0 +1,108 @@
+package domain
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type AdjustmentSuite struct {
+	suite.Suite
+	Request AdjustmentRequest
+	Now     time.Time
+}
+
+func (suite *AdjustmentSuite) SetupTest() {
+	suite.Now = time.Now()
+	request, err := AdjustmentInput{Amount: -30, Reason: "duplicated deposit",
+		Ticket: "SUP-42"}.Request(1, "maker", suite.Now, time.Hour)
+	suite.Require().NoError(err)
+	suite.Request = *request
+}
+
+func (suite *AdjustmentSuite) TestAdjustmentInput_Request() {
+	suite.Equal(AdjustmentPending, suite.Request.Status)
+	suite.Equal(suite.Now.Add(time.Hour), suite.Request.ExpiresAt)
+
+	// incorrect inputs
+	_, err := AdjustmentInput{Amount: 10, Ticket: "SUP-42"}.Request(1, "maker", suite.Now,
+		time.Hour)
+	suite.ErrorIs(err, ErrIncorrectAdjustmentParams)
+	_, err = AdjustmentInput{Amount: 10, Reason: "r"}.Request(1, "maker", suite.Now, time.Hour)
+	suite.ErrorIs(err, ErrIncorrectAdjustmentParams)
+	_, err = AdjustmentInput{Amount: 10, Reason: strings.Repeat("r", 256),
+		Ticket: "SUP-42"}.Request(1, "maker", suite.Now, time.Hour)
+	suite.ErrorIs(err, ErrIncorrectAdjustmentParams)
+	_, err = AdjustmentInput{Reason: "r", Ticket: "SUP-42"}.Request(1, "maker", suite.Now,
+		time.Hour)
+	suite.ErrorIs(err, ErrIncorrectAdjustmentParams)
+	// maker must be known to be checked by another operator
+	_, err = AdjustmentInput{Amount: 10, Reason: "r", Ticket: "SUP-42"}.Request(1,
+		AnonymousActor, suite.Now, time.Hour)
+	suite.ErrorIs(err, ErrIncorrectAdjustmentParams)
+}
+
+func (suite *AdjustmentSuite) TestAdjustmentRequest_Review() {
+	request := suite.Request
+	suite.ErrorIs(request.Approve("maker", ReviewInput{}, suite.Now), ErrSelfApproval)
+	suite.ErrorIs(request.Approve(AnonymousActor, ReviewInput{}, suite.Now),
+		ErrIncorrectAdjustmentParams)
+	suite.ErrorIs(request.Approve("checker", ReviewInput{}, suite.Now.Add(time.Hour)),
+		ErrAdjustmentExpired)
+	suite.Equal(AdjustmentPending, request.Status)
+
+	suite.Require().NoError(request.Approve("checker", ReviewInput{Comment: "ok"},
+		suite.Now))
+	suite.Equal(AdjustmentApproved, request.Status)
+	suite.Equal("checker", request.ReviewedBy)
+	suite.Equal("ok", request.Comment)
+	suite.ErrorIs(request.Reject("checker", ReviewInput{}, suite.Now),
+		ErrAdjustmentNotPending)
+
+	// maker can withdraw his own request
+	request = suite.Request
+	suite.Require().NoError(request.Reject("maker", ReviewInput{}, suite.Now))
+	suite.Equal(AdjustmentRejected, request.Status)
+
+	request = suite.Request
+	request.Status = AdjustmentExpired
+	suite.ErrorIs(request.Reject("checker", ReviewInput{}, suite.Now), ErrAdjustmentExpired)
+}
+
+func (suite *AdjustmentSuite) TestAdjustmentRequest_Operation() {
+	user := User{ID: 1, Amount: 100}
+	operation, err := suite.Request.Operation(&user, suite.Now)
+	suite.Require().NoError(err)
+	suite.Equal(Adjustment, operation.Type)
+	suite.Equal(-30.0, operation.Amount)
+	suite.Equal(70.0, user.Amount)
+	suite.NoError(operation.Validate())
+
+	// balance can't become negative
+	user.Amount = 10
+	_, err = suite.Request.Operation(&user, suite.Now)
+	suite.ErrorIs(err, ErrInsufficientFunds)
+	suite.Equal(10.0, user.Amount)
+
+	// only closed accounts can't be adjusted
+	operation.Initiator.Status = Frozen
+	suite.NoError(operation.CheckStatus())
+	operation.Initiator.Status = Closed
+	suite.ErrorIs(operation.CheckStatus(), ErrAccountClosed)
+}
+
+func (suite *AdjustmentSuite) TestOperationFilter_Validate() {
+	from := time.Now()
+	to := from.Add(time.Hour)
+	suite.NoError(OperationFilter{}.Validate())
+	suite.NoError(OperationFilter{Type: Deposit, From: &from, To: &to}.Validate())
+	suite.ErrorIs(OperationFilter{Type: "unknown"}.Validate(), ErrIncorrectOperationParams)
+	suite.ErrorIs(OperationFilter{From: &to, To: &from}.Validate(),
+		ErrIncorrectOperationParams)
+}
+
+func TestAdjustmentSuite(t *testing.T) {
+	suite.Run(t, new(AdjustmentSuite))
+}
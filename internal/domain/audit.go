@@ -0,0 +1,169 @@
+package domain
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// HTTPAction and GRPCAction mark AuditEntry of API call, other actions are admin
+	// changes.
+	HTTPAction = "http"
+	GRPCAction = "grpc"
+
+	AuditSuccess = "success"
+	AuditFailure = "failure"
+
+	// AnonymousActor is an actor of calls without caller's identity.
+	AnonymousActor = "anonymous"
+
+	maxPayloadLength = 4096
+	redactedValue    = "******"
+)
+
+var (
+	ErrBrokenAuditChain = errors.New("audit chain is broken")
+	ErrUnauthenticated  = errors.New("caller isn't authenticated")
+
+	sensitiveKeys = []string{"secret", "password", "token", "api_key", "authorization"}
+)
+
+// Caller describes who issued a call and where it came from. Actor is authenticated by
+// bearer token, ClaimedActor is sent by caller itself and isn't verified.
+type Caller struct {
+	Actor        string `json:"actor"`
+	ClaimedActor string `json:"claimed_actor,omitempty"`
+	SourceIP     string `json:"source_ip,omitempty"`
+	RequestID    string `json:"request_id,omitempty"`
+}
+
+// AuditEntry represents an append-only record of API call or admin action. Each entry
+// contains hash of the previous one, so changed or removed entries break the chain.
+type AuditEntry struct {
+	ID int64 `json:"id"`
+	Caller
+	Action    string    `json:"action"`
+	Endpoint  string    `json:"endpoint"`
+	Payload   string    `json:"payload,omitempty"`
+	Status    int       `json:"status,omitempty"`
+	Outcome   string    `json:"outcome"`
+	CreatedAt time.Time `json:"created_at"`
+	PrevHash  string    `json:"prev_hash"`
+	Hash      string    `json:"hash"`
+}
+
+// AuditFilter limits AuditEntry's list. Zero values aren't applied.
+type AuditFilter struct {
+	AfterID int64
+	Actor   string
+	Limit   int64
+}
+
+// AuditVerification represents result of audit chain verification.
+type AuditVerification struct {
+	Valid    bool   `json:"valid"`
+	Checked  int64  `json:"checked"`
+	BrokenID int64  `json:"broken_id,omitempty"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+// ComputeHash returns hex sha256 of AuditEntry's fields and previous hash. Time is
+// hashed in UTC with microseconds, because db keeps it so.
+func (entry AuditEntry) ComputeHash() string {
+	fields := []string{
+		entry.PrevHash,
+		entry.Actor,
+		entry.SourceIP,
+		entry.RequestID,
+		entry.Action,
+		entry.Endpoint,
+		entry.Payload,
+		strconv.Itoa(entry.Status),
+		entry.Outcome,
+		entry.CreatedAt.UTC().Truncate(time.Microsecond).Format(time.RFC3339Nano),
+	}
+	// claimed actor is hashed only if it's set, so entries without it keep their hashes
+	if entry.ClaimedActor != "" {
+		fields = append(fields, entry.ClaimedActor)
+	}
+	hash := sha256.New()
+	for _, field := range fields {
+		// length prefix keeps fields' boundaries
+		_, _ = fmt.Fprintf(hash, "%d:%s;", len(field), field)
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// Chain links AuditEntry to the previous hash and computes its own hash.
+func (entry *AuditEntry) Chain(prevHash string) {
+	entry.CreatedAt = entry.CreatedAt.UTC().Truncate(time.Microsecond)
+	entry.PrevHash = prevHash
+	entry.Hash = entry.ComputeHash()
+}
+
+// Verify checks that AuditEntry follows the entry with prevHash and wasn't changed.
+func (entry AuditEntry) Verify(prevHash string) error {
+	if entry.PrevHash != prevHash {
+		return fmt.Errorf("entry <%d> doesn't follow the previous one: <%w>",
+			entry.ID, ErrBrokenAuditChain)
+	}
+	if entry.ComputeHash() != entry.Hash {
+		return fmt.Errorf("entry <%d> was changed: <%w>", entry.ID, ErrBrokenAuditChain)
+	}
+	return nil
+}
+
+// SanitizePayload returns json payload with redacted secrets truncated to fixed length.
+func SanitizePayload(data []byte) string {
+	if len(data) == 0 {
+		return ""
+	}
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return fmt.Sprintf("<non-json payload of %d bytes>", len(data))
+	}
+	sanitized, err := json.Marshal(redact(value))
+	if err != nil {
+		return fmt.Sprintf("<unreadable payload of %d bytes>", len(data))
+	}
+	if len(sanitized) > maxPayloadLength {
+		return string(sanitized[:maxPayloadLength]) + "..."
+	}
+	return string(sanitized)
+}
+
+// redact replaces values of sensitive keys in decoded json.
+func redact(value interface{}) interface{} {
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		for key, nested := range typed {
+			if isSensitive(key) {
+				typed[key] = redactedValue
+				continue
+			}
+			typed[key] = redact(nested)
+		}
+	case []interface{}:
+		for i := range typed {
+			typed[i] = redact(typed[i])
+		}
+	}
+	return value
+}
+
+// isSensitive returns true if json key may contain secret.
+func isSensitive(key string) bool {
+	key = strings.ToLower(key)
+	for _, sensitive := range sensitiveKeys {
+		if strings.Contains(key, sensitive) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,76 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type AuditSuite struct {
+	suite.Suite
+	Entries []AuditEntry
+}
+
+func (suite *AuditSuite) SetupTest() {
+	suite.Entries = make([]AuditEntry, 3)
+	var prevHash string
+	for i := range suite.Entries {
+		suite.Entries[i] = AuditEntry{
+			ID:        int64(i + 1),
+			Caller:    Caller{Actor: "admin", SourceIP: "127.0.0.1"},
+			Action:    HTTPAction,
+			Endpoint:  "POST /operations/deposit",
+			Payload:   `{"amount":10}`,
+			Status:    201,
+			Outcome:   AuditSuccess,
+			CreatedAt: time.Now(),
+		}
+		suite.Entries[i].Chain(prevHash)
+		prevHash = suite.Entries[i].Hash
+	}
+}
+
+func (suite *AuditSuite) verify() error {
+	var prevHash string
+	for _, entry := range suite.Entries {
+		if err := entry.Verify(prevHash); err != nil {
+			return err
+		}
+		prevHash = entry.Hash
+	}
+	return nil
+}
+
+func (suite *AuditSuite) TestAuditEntry_Verify() {
+	suite.NoError(suite.verify())
+	// time read from db keeps hash
+	suite.Entries[0].CreatedAt = suite.Entries[0].CreatedAt.Local()
+	suite.NoError(suite.verify())
+
+	// changed entry
+	suite.Entries[1].Payload = `{"amount":1000}`
+	suite.ErrorIs(suite.verify(), ErrBrokenAuditChain)
+	suite.SetupTest()
+
+	// changed claimed actor
+	suite.Entries[1].ClaimedActor = "admin"
+	suite.ErrorIs(suite.verify(), ErrBrokenAuditChain)
+	suite.SetupTest()
+
+	// removed entry
+	suite.Entries = append(suite.Entries[:1], suite.Entries[2:]...)
+	suite.ErrorIs(suite.verify(), ErrBrokenAuditChain)
+}
+
+func (suite *AuditSuite) TestSanitizePayload() {
+	suite.Equal("", SanitizePayload(nil))
+	suite.Equal(`{"secret":"******","url":"http://a.b"}`,
+		SanitizePayload([]byte(`{"url": "http://a.b", "secret": "0123456789abcdef"}`)))
+	suite.Equal(`[{"api_key":"******"}]`, SanitizePayload([]byte(`[{"api_key": "key"}]`)))
+	suite.Equal("<non-json payload of 3 bytes>", SanitizePayload([]byte("abc")))
+}
+
+func TestAuditSuite(t *testing.T) {
+	suite.Run(t, new(AuditSuite))
+}
@@ -0,0 +1,71 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+)
+
+const (
+	AtomicMode  BatchMode = "atomic"
+	PartialMode BatchMode = "partial"
+
+	DepositItem  BatchItemType = "deposit"
+	WithdrawItem BatchItemType = "withdraw"
+	TransferItem BatchItemType = "transfer"
+)
+
+var ErrIncorrectBatchParams = errors.New("this batch is incorrect")
+
+// BatchMode describes how batch items are executed. Atomic batch is executed in
+// one transaction, items of partial batch are executed independently.
+type BatchMode string
+
+// BatchItemType describes type of BatchItem.
+type BatchItemType string
+
+// BatchItem represents a single operation of the batch.
+type BatchItem struct {
+	Type        BatchItemType `json:"type"`
+	InitiatorID int64         `json:"initiator_id"`
+	ReceiverID  int64         `json:"receiver_id,omitempty"`
+	Amount      float64       `json:"amount"`
+}
+
+// BatchInput represents user's input for batch operation. Mode is atomic by default.
+type BatchInput struct {
+	Mode  BatchMode   `json:"mode"`
+	Items []BatchItem `json:"items"`
+}
+
+// BatchResult represents result of the batch item with the same index.
+type BatchResult struct {
+	Index     int        `json:"index"`
+	Operation *Operation `json:"operation,omitempty"`
+	Error     string     `json:"error,omitempty"`
+}
+
+// Validate checks mode, size and items' types of BatchInput. Size isn't limited if
+// maxSize isn't positive.
+func (input *BatchInput) Validate(maxSize int) error {
+	if input.Mode == "" {
+		input.Mode = AtomicMode
+	}
+	if input.Mode != AtomicMode && input.Mode != PartialMode {
+		return fmt.Errorf("incorrect batch mode: <%w>", ErrIncorrectBatchParams)
+	}
+	if len(input.Items) == 0 {
+		return fmt.Errorf("batch can't be empty: <%w>", ErrIncorrectBatchParams)
+	}
+	if maxSize > 0 && len(input.Items) > maxSize {
+		return fmt.Errorf("batch can't contain more than %d items: <%w>", maxSize,
+			ErrIncorrectBatchParams)
+	}
+	for i, item := range input.Items {
+		switch item.Type {
+		case DepositItem, WithdrawItem, TransferItem:
+		default:
+			return fmt.Errorf("incorrect type of item %d: <%w>", i, ErrIncorrectBatchParams)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type BatchSuite struct {
+	suite.Suite
+	Input BatchInput
+}
+
+func (suite *BatchSuite) SetupTest() {
+	suite.Input = BatchInput{
+		Items: []BatchItem{
+			{Type: DepositItem, InitiatorID: 1, Amount: 10},
+			{Type: TransferItem, InitiatorID: 1, ReceiverID: 2, Amount: 5},
+		},
+	}
+}
+
+func (suite BatchSuite) TestBatchInput_Validate() {
+	suite.NoError(suite.Input.Validate(2))
+	suite.Equal(AtomicMode, suite.Input.Mode)
+
+	suite.ErrorIs(suite.Input.Validate(1), ErrIncorrectBatchParams)
+	// zero max size doesn't limit batch
+	suite.NoError(suite.Input.Validate(0))
+
+	input := suite.Input
+	input.Mode = "sometimes"
+	suite.ErrorIs(input.Validate(0), ErrIncorrectBatchParams)
+
+	input = suite.Input
+	input.Items = nil
+	suite.ErrorIs(input.Validate(0), ErrIncorrectBatchParams)
+
+	input = suite.Input
+	input.Items = []BatchItem{{Type: "refund", InitiatorID: 1, Amount: 1}}
+	suite.ErrorIs(input.Validate(0), ErrIncorrectBatchParams)
+}
+
+func TestBatchSuite(t *testing.T) {
+	suite.Run(t, new(BatchSuite))
+}
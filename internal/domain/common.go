@@ -1,5 +1,11 @@
 package domain
 
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
 const (
 	AmountMode SortingMode = "amount"
 	DateMode   SortingMode = "date"
@@ -8,9 +14,73 @@ const (
 // SortingMode represent string which describes Operation's order.
 type SortingMode string
 
+// errorCodes are machine-readable codes of known errors for clients.
+var errorCodes = []struct {
+	code string
+	err  error
+}{
+	{"no_such_user", ErrNoSuchUser},
+	{"user_exists", ErrUserExists},
+	{"zero_amount", ErrZeroAmount},
+	{"negative_amount", ErrNegativeAmount},
+	{"overflow", ErrOverflow},
+	{"insufficient_funds", ErrInsufficientFunds},
+	{"version_conflict", ErrVersionConflict},
+	{"limit_exceeded", ErrLimitExceeded},
+	{"account_frozen", ErrAccountFrozen},
+	{"account_closed", ErrAccountClosed},
+	{"non_zero_balance", ErrNonZeroBalance},
+	{"incorrect_status_change", ErrIncorrectStatusChange},
+	{"incorrect_user", ErrIncorrectUserParams},
+	{"incorrect_limits", ErrIncorrectLimitsParams},
+	{"incorrect_fee", ErrIncorrectFeeParams},
+	{"incorrect_webhook", ErrIncorrectWebhookParams},
+	{"incorrect_schedule", ErrIncorrectScheduleParams},
+	{"incorrect_operation", ErrIncorrectOperationParams},
+	{"incorrect_batch", ErrIncorrectBatchParams},
+	{"incorrect_adjustment", ErrIncorrectAdjustmentParams},
+	{"no_such_adjustment", ErrNoSuchAdjustment},
+	{"adjustment_not_pending", ErrAdjustmentNotPending},
+	{"adjustment_expired", ErrAdjustmentExpired},
+	{"self_approval", ErrSelfApproval},
+	{"unauthenticated", ErrUnauthenticated},
+	{"incorrect_idempotency_key", ErrIncorrectIdempotencyKey},
+	{"idempotency_key_reused", ErrIdempotencyKeyReused},
+	{"request_in_progress", ErrRequestInProgress},
+	{"exchange_unavailable", ErrExchangeUnavailable},
+	{"exchange_quota_exceeded", ErrExchangeQuotaExceeded},
+	{"unsupported_currency", ErrUnsupportedCurrency},
+	{"incorrect_conversion", ErrIncorrectConversionParams},
+}
+
+// ErrorCode returns code of known error or empty string.
+func ErrorCode(err error) string {
+	for _, errorCode := range errorCodes {
+		if errors.Is(err, errorCode.err) {
+			return errorCode.code
+		}
+	}
+	return ""
+}
+
+// CodeError returns known error by its code or nil.
+func CodeError(code string) error {
+	for _, errorCode := range errorCodes {
+		if errorCode.code == code {
+			return errorCode.err
+		}
+	}
+	return nil
+}
+
 // ErrorJSON represents service error as struct for convenient response representation.
 type ErrorJSON struct {
 	Message string `json:"error"`
+	// Code identifies known errors, it's empty for others.
+	Code string `json:"code,omitempty"`
+	// Limit and Remaining are set only if operation limit is exceeded.
+	Limit     string   `json:"limit,omitempty"`
+	Remaining *float64 `json:"remaining,omitempty"`
 }
 
 // OperationInput represents user's input for any operation except history.
@@ -25,4 +95,26 @@ type HistoryInput struct {
 	ID       int64       `json:"id"`
 	Quantity int64       `json:"quantity"`
 	Mode     SortingMode `json:"mode"`
+	OperationFilter
+}
+
+// OperationFilter restricts history by operation's type and time, zero values aren't
+// applied. From is inclusive and To is exclusive.
+type OperationFilter struct {
+	Type OperationType `json:"type,omitempty"`
+	From *time.Time    `json:"from,omitempty"`
+	To   *time.Time    `json:"to,omitempty"`
+}
+
+// Validate returns error if OperationFilter's type is unknown or its period is empty.
+func (filter OperationFilter) Validate() error {
+	if filter.Type != "" && !filter.Type.IsValid() {
+		return fmt.Errorf("unknown operation type <%s>: <%w>", filter.Type,
+			ErrIncorrectOperationParams)
+	}
+	if filter.From != nil && filter.To != nil && !filter.From.Before(*filter.To) {
+		return fmt.Errorf("period's start must be before its end: <%w>",
+			ErrIncorrectOperationParams)
+	}
+	return nil
 }
@@ -0,0 +1,63 @@
+package domain
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type CurrencySuite struct {
+	suite.Suite
+}
+
+func (suite CurrencySuite) TestLookupCurrency() {
+	currency, err := LookupCurrency("jpy")
+	suite.Require().NoError(err)
+	suite.Equal(Currency{Code: "JPY", Numeric: 392, Name: "Yen", MinorUnits: 0}, currency)
+	currency, err = LookupCurrency(DefaultCurrency)
+	suite.Require().NoError(err)
+	suite.Equal(643, currency.Numeric)
+
+	for _, code := range []string{"", "RUBLE", "BTC", "XAU"} {
+		_, err = LookupCurrency(code)
+		suite.ErrorIs(err, ErrUnsupportedCurrency)
+	}
+	// registry is searched by binary search
+	suite.True(sort.SliceIsSorted(iso4217, func(i, j int) bool {
+		return iso4217[i].Code < iso4217[j].Code
+	}))
+}
+
+func (suite CurrencySuite) TestCurrency_Round() {
+	usd := Currency{Code: "USD", MinorUnits: 2}
+	suite.Equal(1.00, usd.Round(1.005, HalfEven))
+	suite.Equal(1.02, usd.Round(1.015, HalfEven))
+	suite.Equal(1.01, usd.Round(1.005, HalfUp))
+	suite.Equal(1.00, usd.Round(1.005, HalfDown))
+	suite.Equal(1.01, usd.Round(1.006, HalfDown))
+	suite.Equal(1.99, usd.Round(1.999, Down))
+	suite.Equal(1.01, usd.Round(1.001, Up))
+	suite.Equal(1.5, usd.Round(1.5, Up))
+	// negative amounts are rounded symmetrically
+	suite.Equal(-1.02, usd.Round(-1.015, HalfEven))
+	suite.Equal(-1.99, usd.Round(-1.999, Down))
+	suite.Equal(-1.01, usd.Round(-1.001, Up))
+
+	jpy := Currency{Code: "JPY"}
+	suite.Equal(float64(2), jpy.Round(2.5, HalfEven))
+	suite.Equal(float64(3), jpy.Round(2.5, HalfUp))
+	kwd := Currency{Code: "KWD", MinorUnits: 3}
+	suite.Equal(0.124, kwd.Round(0.1235, HalfEven))
+	suite.Equal(0.124, kwd.Round(0.1245, HalfEven))
+}
+
+func (suite CurrencySuite) TestRoundingMode_IsValid() {
+	suite.True(HalfEven.IsValid())
+	suite.True(Up.IsValid())
+	suite.False(RoundingMode("half").IsValid())
+}
+
+func TestCurrencySuite(t *testing.T) {
+	suite.Run(t, new(CurrencySuite))
+}
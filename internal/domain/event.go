@@ -0,0 +1,99 @@
+package domain
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+const (
+	OperationCreated EventType = "OperationCreated"
+	BalanceChanged   EventType = "BalanceChanged"
+)
+
+// EventType describes type of Event.
+type EventType string
+
+// Event represents a notification about committed changes for downstream services.
+// Events of the same User are delivered in order of ID.
+type Event struct {
+	ID        int64           `json:"id"`
+	Type      EventType       `json:"type"`
+	UserID    int64           `json:"user_id"`
+	Payload   json.RawMessage `json:"payload"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// BalanceChange is the payload of BalanceChanged Event. Operation is seen from
+// the User's side and hides the other participant's balance.
+type BalanceChange struct {
+	UserID    int64     `json:"user_id"`
+	Amount    float64   `json:"amount"`
+	Delta     float64   `json:"delta"`
+	Operation Operation `json:"operation"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// Events returns OperationCreated Event and BalanceChanged Event for each participant.
+// Commission account's balance isn't known by Fee Operation, so its change isn't sent.
+func (operation Operation) Events() ([]Event, error) {
+	if err := operation.Validate(); err != nil {
+		return nil, fmt.Errorf("operation's validation is failed: <%w>", err)
+	}
+	created, err := newEvent(OperationCreated, operation.Initiator.ID,
+		operation.Timestamp, operation.Public())
+	if err != nil {
+		return nil, err
+	}
+	events := []Event{*created}
+	delta := operation.Amount
+	if operation.Type == Withdraw || operation.Type == TransferOut || operation.Type == Fee {
+		delta = -delta
+	}
+	changed, err := newEvent(BalanceChanged, operation.Initiator.ID, operation.Timestamp,
+		BalanceChange{
+			UserID:    operation.Initiator.ID,
+			Amount:    operation.Initiator.Amount,
+			Delta:     delta,
+			Operation: operation.Public(),
+			Timestamp: operation.Timestamp,
+		})
+	if err != nil {
+		return nil, err
+	}
+	events = append(events, *changed)
+	if operation.IsTransfer() {
+		reversed, err := operation.Reverse()
+		if err != nil {
+			return nil, err
+		}
+		changed, err = newEvent(BalanceChanged, reversed.Initiator.ID, reversed.Timestamp,
+			BalanceChange{
+				UserID:    reversed.Initiator.ID,
+				Amount:    reversed.Initiator.Amount,
+				Delta:     -delta,
+				Operation: reversed.Public(),
+				Timestamp: reversed.Timestamp,
+			})
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, *changed)
+	}
+	return events, nil
+}
+
+// newEvent marshals payload and creates Event.
+func newEvent(eventType EventType, userID int64, timestamp time.Time,
+	payload interface{}) (*Event, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("can't marshal %s payload: <%w>", eventType, err)
+	}
+	return &Event{
+		Type:      eventType,
+		UserID:    userID,
+		Payload:   data,
+		CreatedAt: timestamp,
+	}, nil
+}
@@ -0,0 +1,67 @@
+package domain
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type EventSuite struct {
+	suite.Suite
+	Operation Operation
+}
+
+func (suite *EventSuite) SetupTest() {
+	suite.Operation = Operation{
+		Initiator: &User{ID: 1, Amount: 90},
+		Type:      TransferOut,
+		Amount:    10,
+		Timestamp: time.Now(),
+		Receiver:  &User{ID: 2, Amount: 10},
+	}
+}
+
+func (suite EventSuite) TestOperation_Events() {
+	events, err := suite.Operation.Events()
+	suite.Require().NoError(err)
+	suite.Require().Len(events, 3)
+	suite.Equal(OperationCreated, events[0].Type)
+	// receiver's balance is hidden from consumers
+	var created struct {
+		Receiver map[string]interface{} `json:"receiver"`
+	}
+	suite.NoError(json.Unmarshal(events[0].Payload, &created))
+	suite.Equal(float64(2), created.Receiver["id"])
+	suite.NotContains(created.Receiver, "amount")
+
+	var change BalanceChange
+	suite.Equal(BalanceChanged, events[1].Type)
+	suite.NoError(json.Unmarshal(events[1].Payload, &change))
+	suite.Equal(int64(1), change.UserID)
+	suite.Equal(float64(90), change.Amount)
+	suite.Equal(float64(-10), change.Delta)
+
+	suite.Equal(BalanceChanged, events[2].Type)
+	suite.NoError(json.Unmarshal(events[2].Payload, &change))
+	suite.Equal(int64(2), change.UserID)
+	suite.Equal(TransferIn, change.Operation.Type)
+	suite.Equal(&User{ID: 1}, change.Operation.Receiver)
+	suite.Equal(float64(10), change.Delta)
+
+	// non transfer operation changes only initiator's balance
+	suite.Operation.Type = Deposit
+	suite.Operation.Receiver = nil
+	events, err = suite.Operation.Events()
+	suite.Require().NoError(err)
+	suite.Len(events, 2)
+
+	suite.Operation.Initiator = nil
+	_, err = suite.Operation.Events()
+	suite.ErrorIs(err, ErrIncorrectOperationParams)
+}
+
+func TestEventSuite(t *testing.T) {
+	suite.Run(t, new(EventSuite))
+}
@@ -0,0 +1,87 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	ErrExchangeUnavailable       = errors.New("exchange rates provider is unavailable")
+	ErrExchangeQuotaExceeded     = errors.New("exchange rates provider's quota is exceeded")
+	ErrUnsupportedCurrency       = errors.New("currency is unsupported")
+	ErrIncorrectConversionParams = errors.New("this conversion is incorrect")
+)
+
+// ConversionInput represents user's input for conversion between two currencies.
+type ConversionInput struct {
+	From   string  `json:"from"`
+	To     string  `json:"to"`
+	Amount float64 `json:"amount"`
+}
+
+// Validate checks ConversionInput and converts currencies to upper case.
+func (input *ConversionInput) Validate() error {
+	input.From = strings.ToUpper(input.From)
+	input.To = strings.ToUpper(input.To)
+	for _, code := range []string{input.From, input.To} {
+		if _, err := LookupCurrency(code); err != nil {
+			return fmt.Errorf("conversion currency error: <%w>", err)
+		}
+	}
+	if input.Amount <= 0 {
+		return fmt.Errorf("amount must be positive: <%w>", ErrIncorrectConversionParams)
+	}
+	return nil
+}
+
+// Conversion represents converted amount of money.
+type Conversion struct {
+	From   string  `json:"from"`
+	To     string  `json:"to"`
+	Amount float64 `json:"amount"`
+	Result float64 `json:"result"`
+}
+
+// BreakerState is a state of exchange rates provider's circuit breaker.
+type BreakerState string
+
+const (
+	// BreakerClosed passes requests to provider.
+	BreakerClosed BreakerState = "closed"
+	// BreakerOpen rejects requests without calling provider.
+	BreakerOpen BreakerState = "open"
+	// BreakerHalfOpen passes a few probe requests which close or open breaker again.
+	BreakerHalfOpen BreakerState = "half-open"
+)
+
+// ExchangeStatus represents circuit breaker and quota of exchange rates provider.
+type ExchangeStatus struct {
+	State BreakerState `json:"state"`
+	// Failures is a quantity of consecutive failed requests.
+	Failures int        `json:"failures"`
+	OpenedAt *time.Time `json:"opened_at,omitempty"`
+	// QuotaUsed is a quantity of requests sent in current month, zero QuotaLimit means
+	// unlimited requests.
+	QuotaUsed  int64 `json:"quota_used"`
+	QuotaLimit int64 `json:"quota_limit,omitempty"`
+}
+
+// Available returns true if requests are sent to provider.
+func (status ExchangeStatus) Available() bool {
+	return status.State != BreakerOpen &&
+		(status.QuotaLimit == 0 || status.QuotaUsed < status.QuotaLimit)
+}
+
+const (
+	HealthOK       = "ok"
+	HealthDegraded = "degraded"
+)
+
+// Health represents service's health. It's degraded if exchange rates provider is
+// unavailable, because only conversions fail.
+type Health struct {
+	Status   string          `json:"status"`
+	Exchange *ExchangeStatus `json:"exchange,omitempty"`
+}
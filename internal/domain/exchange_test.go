@@ -0,0 +1,37 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type ExchangeSuite struct {
+	suite.Suite
+}
+
+func (suite ExchangeSuite) TestConversionInput_Validate() {
+	input := ConversionInput{From: "usd", To: "Gbp", Amount: 10}
+	suite.NoError(input.Validate())
+	suite.Equal("USD", input.From)
+	suite.Equal("GBP", input.To)
+
+	for _, input := range []ConversionInput{
+		{From: "US", To: "GBP", Amount: 10},
+		{From: "USD", To: "", Amount: 10},
+		{From: "USD", To: "GB1", Amount: 10},
+		{From: "XYZ", To: "GBP", Amount: 10},
+	} {
+		suite.ErrorIs(input.Validate(), ErrUnsupportedCurrency)
+	}
+	for _, input := range []ConversionInput{
+		{From: "USD", To: "GBP"},
+		{From: "USD", To: "GBP", Amount: -1},
+	} {
+		suite.ErrorIs(input.Validate(), ErrIncorrectConversionParams)
+	}
+}
+
+func TestExchangeSuite(t *testing.T) {
+	suite.Run(t, new(ExchangeSuite))
+}
@@ -0,0 +1,115 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// ConversionFee is a type of FeeRule charged from operations whose amount is converted
+// to RUB in addition to the rule of their own type. Operations of this type aren't stored.
+const ConversionFee OperationType = "CONVERSION"
+
+var ErrIncorrectFeeParams = errors.New("this fee rule is incorrect")
+
+// FeeRule describes fee of operations with the same type and currency. Fee is Percent
+// of amount plus Fixed, but not less than Min and not more than Max if it's set.
+// Rule without Currency is applied to operations in RUB and to currencies without
+// their own rules. Fixed, Min and Max are in rule's Currency (RUB by default).
+type FeeRule struct {
+	Type     OperationType `json:"type"`
+	Currency string        `json:"currency,omitempty"`
+	Percent  float64       `json:"percent"`
+	Fixed    float64       `json:"fixed"`
+	Min      float64       `json:"min"`
+	Max      float64       `json:"max"`
+}
+
+// FeeSchedule contains fee rules and the commission account which receives fees.
+type FeeSchedule struct {
+	AccountID int64
+	Rules     []FeeRule
+}
+
+// Validate checks that rule is applied to withdrawals, outgoing transfers or conversions
+// and its values are consistent.
+func (rule FeeRule) Validate() error {
+	if rule.Type != Withdraw && rule.Type != TransferOut && rule.Type != ConversionFee {
+		return fmt.Errorf("fee can be charged only for %s, %s and %s: <%w>", Withdraw,
+			TransferOut, ConversionFee, ErrIncorrectFeeParams)
+	}
+	if rule.Percent < 0 || rule.Fixed < 0 || rule.Min < 0 || rule.Max < 0 {
+		return fmt.Errorf("fee values can't be negative: <%w>", ErrIncorrectFeeParams)
+	}
+	if rule.Max != 0 && rule.Max < rule.Min {
+		return fmt.Errorf("max fee can't be less than min one: <%w>", ErrIncorrectFeeParams)
+	}
+	return nil
+}
+
+// Fee returns fee in RUB of operation with amount in RUB converted from original amount
+// of currency user has chosen, empty currency means RUB. Fee of converted operation
+// includes ConversionFee one.
+func (schedule FeeSchedule) Fee(operationType OperationType, currency string, original,
+	amount float64) float64 {
+	fee := schedule.charge(operationType, currency, original, amount)
+	if len(currency) != 0 && !strings.EqualFold(currency, "RUB") {
+		fee += schedule.charge(ConversionFee, currency, original, amount)
+	}
+	// fee is charged in kopecks
+	return math.Round(fee*100) / 100
+}
+
+// charge returns fee in RUB by rule of operation's type and currency. Rule of currency
+// is computed from original amount and its fee is converted to RUB by operation's rate.
+func (schedule FeeSchedule) charge(operationType OperationType, currency string, original,
+	amount float64) float64 {
+	var rule *FeeRule
+	for i := range schedule.Rules {
+		if schedule.Rules[i].Type != operationType {
+			continue
+		}
+		if len(currency) != 0 && strings.EqualFold(schedule.Rules[i].Currency, currency) {
+			rule = &schedule.Rules[i]
+			break
+		}
+		if schedule.Rules[i].Currency == "" {
+			rule = &schedule.Rules[i]
+		}
+	}
+	if rule == nil {
+		return 0
+	}
+	if rule.Currency == "" || original == 0 {
+		return rule.fee(amount)
+	}
+	return rule.fee(original) * amount / original
+}
+
+// fee returns fee of amount in rule's currency limited by its caps.
+func (rule FeeRule) fee(amount float64) float64 {
+	fee := amount*rule.Percent/100 + rule.Fixed
+	if fee < rule.Min {
+		fee = rule.Min
+	}
+	if rule.Max != 0 && fee > rule.Max {
+		fee = rule.Max
+	}
+	return fee
+}
+
+// FeeOperation returns Fee Operation which credits commission account by operation's fee.
+// Initiator is shared with operation.
+func (schedule FeeSchedule) FeeOperation(operation Operation) *Operation {
+	if operation.Fee == 0 {
+		return nil
+	}
+	return &Operation{
+		Initiator: operation.Initiator,
+		Type:      Fee,
+		Amount:    operation.Fee,
+		Timestamp: operation.Timestamp,
+		Receiver:  &User{ID: schedule.AccountID},
+	}
+}
@@ -0,0 +1,70 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type FeeSuite struct {
+	suite.Suite
+	Schedule FeeSchedule
+}
+
+func (suite *FeeSuite) SetupTest() {
+	suite.Schedule = FeeSchedule{
+		AccountID: 1,
+		Rules: []FeeRule{
+			{Type: Withdraw, Percent: 1, Fixed: 10, Min: 15, Max: 100},
+			{Type: Withdraw, Currency: "USD", Percent: 2.5, Min: 1},
+			{Type: ConversionFee, Percent: 1},
+		},
+	}
+}
+
+func (suite FeeSuite) TestFeeSchedule_Fee() {
+	suite.Equal(float64(20), suite.Schedule.Fee(Withdraw, "", 1000, 1000))
+	// min and max caps
+	suite.Equal(float64(15), suite.Schedule.Fee(Withdraw, "", 100, 100))
+	suite.Equal(float64(100), suite.Schedule.Fee(Withdraw, "", 100000, 100000))
+	// currency rule is preferred, converted amounts are charged by conversion fee
+	suite.Equal(float64(350), suite.Schedule.Fee(Withdraw, "usd", 100, 10000))
+	suite.Equal(float64(30), suite.Schedule.Fee(Withdraw, "EUR", 10, 1000))
+	suite.Zero(suite.Schedule.Fee(TransferOut, "", 1000, 1000))
+	suite.Equal(float64(10), suite.Schedule.Fee(Deposit, "EUR", 10, 1000))
+	// caps of currency rule are in its currency: min 1 USD is 100 RUB
+	suite.Equal(float64(110), suite.Schedule.Fee(Withdraw, "USD", 10, 1000))
+}
+
+func (suite FeeSuite) TestFeeSchedule_FeeOperation() {
+	operation := Operation{
+		Initiator: &User{ID: 2, Amount: 80},
+		Type:      Withdraw,
+		Amount:    10,
+		Timestamp: time.Now(),
+	}
+	suite.Nil(suite.Schedule.FeeOperation(operation))
+
+	operation.Fee = 10
+	fee := suite.Schedule.FeeOperation(operation)
+	suite.Require().NotNil(fee)
+	suite.NoError(fee.Validate())
+	suite.Equal(Fee, fee.Type)
+	suite.Equal(float64(10), fee.Amount)
+	suite.Equal(int64(1), fee.Receiver.ID)
+}
+
+func (suite FeeSuite) TestFeeRule_Validate() {
+	for _, rule := range suite.Schedule.Rules {
+		suite.NoError(rule.Validate())
+	}
+	suite.ErrorIs(FeeRule{Type: Deposit}.Validate(), ErrIncorrectFeeParams)
+	suite.NoError(FeeRule{Type: ConversionFee, Currency: "USD", Fixed: 1}.Validate())
+	suite.ErrorIs(FeeRule{Type: Withdraw, Percent: -1}.Validate(), ErrIncorrectFeeParams)
+	suite.ErrorIs(FeeRule{Type: Withdraw, Min: 10, Max: 5}.Validate(), ErrIncorrectFeeParams)
+}
+
+func TestFeeSuite(t *testing.T) {
+	suite.Run(t, new(FeeSuite))
+}
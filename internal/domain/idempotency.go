@@ -0,0 +1,63 @@
+package domain
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// maxIdempotencyKeyLength limits length of client's keys.
+const maxIdempotencyKeyLength = 255
+
+var (
+	ErrIncorrectIdempotencyKey = errors.New("idempotency key is incorrect")
+	ErrIdempotencyKeyReused    = errors.New("idempotency key is used by another request")
+	ErrRequestInProgress       = errors.New("request with this idempotency key is in progress")
+)
+
+// IdempotencyRecord binds client's key to request and to its response. Response is
+// empty while request is processed.
+type IdempotencyRecord struct {
+	Key       string
+	Endpoint  string
+	Hash      string
+	Status    int
+	Response  []byte
+	CreatedAt time.Time
+}
+
+// NewIdempotencyRecord returns IdempotencyRecord of request identified by key.
+func NewIdempotencyRecord(key, endpoint string, body []byte,
+	ts time.Time) (*IdempotencyRecord, error) {
+	if len(key) == 0 || len(key) > maxIdempotencyKeyLength {
+		return nil, fmt.Errorf("key must contain from 1 to %d characters: <%w>",
+			maxIdempotencyKeyLength, ErrIncorrectIdempotencyKey)
+	}
+	hash := sha256.Sum256(append([]byte(endpoint+"\n"), body...))
+	return &IdempotencyRecord{
+		Key:       key,
+		Endpoint:  endpoint,
+		Hash:      hex.EncodeToString(hash[:]),
+		CreatedAt: ts,
+	}, nil
+}
+
+// IsCompleted returns true if IdempotencyRecord contains response.
+func (record IdempotencyRecord) IsCompleted() bool {
+	return record.Status != 0
+}
+
+// Replay checks that stored IdempotencyRecord belongs to the same request and returns
+// it if its response can be replayed.
+func (record IdempotencyRecord) Replay(request IdempotencyRecord) (*IdempotencyRecord,
+	error) {
+	if record.Endpoint != request.Endpoint || record.Hash != request.Hash {
+		return nil, ErrIdempotencyKeyReused
+	}
+	if !record.IsCompleted() {
+		return nil, ErrRequestInProgress
+	}
+	return &record, nil
+}
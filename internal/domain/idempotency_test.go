@@ -0,0 +1,66 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type IdempotencySuite struct {
+	suite.Suite
+	Record IdempotencyRecord
+}
+
+func (suite *IdempotencySuite) SetupTest() {
+	record, err := NewIdempotencyRecord("key", "POST /operations/deposit",
+		[]byte(`{"initiator_id":1,"amount":10}`), time.Now())
+	suite.Require().NoError(err)
+	suite.Record = *record
+}
+
+func (suite *IdempotencySuite) TestNewIdempotencyRecord() {
+	_, err := NewIdempotencyRecord("", "POST /operations/deposit", nil, time.Now())
+	suite.ErrorIs(err, ErrIncorrectIdempotencyKey)
+	_, err = NewIdempotencyRecord(strings.Repeat("k", 256), "POST /operations/deposit", nil,
+		time.Now())
+	suite.ErrorIs(err, ErrIncorrectIdempotencyKey)
+
+	// the same body of another endpoint is another request
+	record, err := NewIdempotencyRecord("key", "POST /operations/withdraw",
+		[]byte(`{"initiator_id":1,"amount":10}`), time.Now())
+	suite.Require().NoError(err)
+	suite.NotEqual(suite.Record.Hash, record.Hash)
+}
+
+func (suite *IdempotencySuite) TestIdempotencyRecord_Replay() {
+	stored := suite.Record
+	_, err := stored.Replay(suite.Record)
+	suite.ErrorIs(err, ErrRequestInProgress)
+
+	stored.Status, stored.Response = 201, []byte("{}")
+	replayed, err := stored.Replay(suite.Record)
+	suite.Require().NoError(err)
+	suite.Equal(201, replayed.Status)
+
+	request, err := NewIdempotencyRecord("key", suite.Record.Endpoint,
+		[]byte(`{"initiator_id":1,"amount":20}`), time.Now())
+	suite.Require().NoError(err)
+	_, err = stored.Replay(*request)
+	suite.ErrorIs(err, ErrIdempotencyKeyReused)
+}
+
+func (suite *IdempotencySuite) TestErrorCode() {
+	err := fmt.Errorf("grossbook withdraw error: <%w>", ErrInsufficientFunds)
+	suite.Equal("insufficient_funds", ErrorCode(err))
+	suite.Equal(ErrInsufficientFunds, CodeError(ErrorCode(err)))
+	suite.Equal("limit_exceeded", ErrorCode(LimitExceededError{Limit: MaxOperationLimit}))
+	suite.Empty(ErrorCode(fmt.Errorf("unknown")))
+	suite.Nil(CodeError("unknown"))
+}
+
+func TestIdempotencySuite(t *testing.T) {
+	suite.Run(t, new(IdempotencySuite))
+}
@@ -0,0 +1,175 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	MaxOperationLimit    = "max_operation"
+	DailyWithdrawLimit   = "daily_withdraw"
+	MonthlyWithdrawLimit = "monthly_withdraw"
+	DailyTransferLimit   = "daily_transfer"
+	MonthlyTransferLimit = "monthly_transfer"
+)
+
+var (
+	ErrLimitExceeded         = errors.New("operation limit is exceeded")
+	ErrIncorrectLimitsParams = errors.New("these limits are incorrect")
+)
+
+// Limits restricts amount of a single Operation and sums of User's withdrawals and
+// outgoing transfers per day and month. Unset limits aren't applied.
+type Limits struct {
+	MaxOperation    *float64 `json:"max_operation,omitempty"`
+	DailyWithdraw   *float64 `json:"daily_withdraw,omitempty"`
+	MonthlyWithdraw *float64 `json:"monthly_withdraw,omitempty"`
+	DailyTransfer   *float64 `json:"daily_transfer,omitempty"`
+	MonthlyTransfer *float64 `json:"monthly_transfer,omitempty"`
+}
+
+// UserLimits represents User's own limits and limits applied to him after merging
+// with global ones.
+type UserLimits struct {
+	UserID    int64  `json:"user_id"`
+	Limits    Limits `json:"limits"`
+	Effective Limits `json:"effective"`
+}
+
+// LimitUsage contains sums of User's withdrawals and outgoing transfers in the current
+// day and month.
+type LimitUsage struct {
+	DailyWithdraw   float64
+	MonthlyWithdraw float64
+	DailyTransfer   float64
+	MonthlyTransfer float64
+}
+
+// LimitExceededError reports exceeded limit and its remaining allowance.
+type LimitExceededError struct {
+	Limit     string
+	Remaining float64
+}
+
+func (limitError LimitExceededError) Error() string {
+	return fmt.Sprintf("%s: %s allows %f more", ErrLimitExceeded, limitError.Limit,
+		limitError.Remaining)
+}
+
+func (limitError LimitExceededError) Unwrap() error {
+	return ErrLimitExceeded
+}
+
+// Validate checks that all set limits aren't negative.
+func (limits Limits) Validate() error {
+	for name, limit := range limits.named() {
+		if limit != nil && *limit < 0 {
+			return fmt.Errorf("%s can't be negative: <%w>", name, ErrIncorrectLimitsParams)
+		}
+	}
+	return nil
+}
+
+// IsEmpty returns true if no limit is set.
+func (limits Limits) IsEmpty() bool {
+	for _, limit := range limits.named() {
+		if limit != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// Merge returns Limits where limits set in override replace the current ones.
+func (limits Limits) Merge(override Limits) Limits {
+	merged := limits
+	if override.MaxOperation != nil {
+		merged.MaxOperation = override.MaxOperation
+	}
+	if override.DailyWithdraw != nil {
+		merged.DailyWithdraw = override.DailyWithdraw
+	}
+	if override.MonthlyWithdraw != nil {
+		merged.MonthlyWithdraw = override.MonthlyWithdraw
+	}
+	if override.DailyTransfer != nil {
+		merged.DailyTransfer = override.DailyTransfer
+	}
+	if override.MonthlyTransfer != nil {
+		merged.MonthlyTransfer = override.MonthlyTransfer
+	}
+	return merged
+}
+
+// Check returns LimitExceededError if Operation exceeds any limit taking into account
+// initiator's usage. Periodical limits are applied only to withdrawals and outgoing
+// transfers.
+func (limits Limits) Check(operation Operation, usage LimitUsage) error {
+	if err := checkLimit(MaxOperationLimit, limits.MaxOperation, 0,
+		operation.Amount); err != nil {
+		return err
+	}
+	switch operation.Type {
+	case Withdraw:
+		if err := checkLimit(DailyWithdrawLimit, limits.DailyWithdraw,
+			usage.DailyWithdraw, operation.Amount); err != nil {
+			return err
+		}
+		return checkLimit(MonthlyWithdrawLimit, limits.MonthlyWithdraw,
+			usage.MonthlyWithdraw, operation.Amount)
+	case TransferOut:
+		if err := checkLimit(DailyTransferLimit, limits.DailyTransfer,
+			usage.DailyTransfer, operation.Amount); err != nil {
+			return err
+		}
+		return checkLimit(MonthlyTransferLimit, limits.MonthlyTransfer,
+			usage.MonthlyTransfer, operation.Amount)
+	}
+	return nil
+}
+
+// Add counts Operation in LimitUsage.
+func (usage *LimitUsage) Add(operation Operation) {
+	switch operation.Type {
+	case Withdraw:
+		usage.DailyWithdraw += operation.Amount
+		usage.MonthlyWithdraw += operation.Amount
+	case TransferOut:
+		usage.DailyTransfer += operation.Amount
+		usage.MonthlyTransfer += operation.Amount
+	}
+}
+
+// LimitPeriods returns starts of day and month which contain timestamp.
+func LimitPeriods(timestamp time.Time) (time.Time, time.Time) {
+	year, month, day := timestamp.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, timestamp.Location()),
+		time.Date(year, month, 1, 0, 0, 0, 0, timestamp.Location())
+}
+
+// named returns all limits by their names.
+func (limits Limits) named() map[string]*float64 {
+	return map[string]*float64{
+		MaxOperationLimit:    limits.MaxOperation,
+		DailyWithdrawLimit:   limits.DailyWithdraw,
+		MonthlyWithdrawLimit: limits.MonthlyWithdraw,
+		DailyTransferLimit:   limits.DailyTransfer,
+		MonthlyTransferLimit: limits.MonthlyTransfer,
+	}
+}
+
+// checkLimit returns LimitExceededError if amount is bigger than limit's remaining.
+func checkLimit(name string, limit *float64, used, amount float64) error {
+	if limit == nil {
+		return nil
+	}
+	remaining := *limit - used
+	if remaining < 0 {
+		remaining = 0
+	}
+	if amount > remaining {
+		return LimitExceededError{Limit: name, Remaining: remaining}
+	}
+	return nil
+}
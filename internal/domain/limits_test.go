@@ -0,0 +1,70 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type LimitsSuite struct {
+	suite.Suite
+	Limits    Limits
+	Operation Operation
+}
+
+func limit(value float64) *float64 {
+	return &value
+}
+
+func (suite *LimitsSuite) SetupTest() {
+	suite.Limits = Limits{
+		MaxOperation:    limit(1000),
+		DailyWithdraw:   limit(300),
+		MonthlyWithdraw: limit(2000),
+	}
+	suite.Operation = Operation{
+		Initiator: &User{ID: 1, Amount: 5000},
+		Type:      Withdraw,
+		Amount:    200,
+		Timestamp: time.Now(),
+	}
+}
+
+func (suite LimitsSuite) TestLimits_Check() {
+	suite.NoError(suite.Limits.Check(suite.Operation, LimitUsage{}))
+
+	usage := LimitUsage{}
+	usage.Add(suite.Operation)
+	err := suite.Limits.Check(suite.Operation, usage)
+	suite.ErrorIs(err, ErrLimitExceeded)
+	var limitError LimitExceededError
+	suite.Require().ErrorAs(err, &limitError)
+	suite.Equal(DailyWithdrawLimit, limitError.Limit)
+	suite.Equal(float64(100), limitError.Remaining)
+
+	// transfers aren't limited by withdraw limits
+	suite.Operation.Type = TransferOut
+	suite.Operation.Receiver = &User{ID: 2}
+	suite.NoError(suite.Limits.Check(suite.Operation, usage))
+
+	suite.Operation.Amount = 1001
+	suite.Require().ErrorAs(suite.Limits.Check(suite.Operation, usage), &limitError)
+	suite.Equal(MaxOperationLimit, limitError.Limit)
+}
+
+func (suite LimitsSuite) TestLimits_Merge() {
+	merged := suite.Limits.Merge(Limits{DailyWithdraw: limit(0), DailyTransfer: limit(50)})
+	suite.Equal(float64(1000), *merged.MaxOperation)
+	suite.Equal(float64(0), *merged.DailyWithdraw)
+	suite.Equal(float64(50), *merged.DailyTransfer)
+	suite.Nil(merged.MonthlyTransfer)
+	suite.False(merged.IsEmpty())
+	suite.True(Limits{}.IsEmpty())
+
+	suite.ErrorIs(Limits{MaxOperation: limit(-1)}.Validate(), ErrIncorrectLimitsParams)
+}
+
+func TestLimitsSuite(t *testing.T) {
+	suite.Run(t, new(LimitsSuite))
+}
@@ -11,6 +11,12 @@ const (
 	Withdraw    OperationType = "WITHDRAW"
 	TransferOut OperationType = "TRANSFER OUT"
 	TransferIn  OperationType = "TRANSFER IN"
+	Fee         OperationType = "FEE"
+	// Correction sets User's balance to the sum of his operations. Its Amount is signed
+	// and it isn't part of the sum itself.
+	Correction OperationType = "CORRECTION"
+	// Adjustment is an approved manual correction of User's balance. Its Amount is signed.
+	Adjustment OperationType = "ADJUSTMENT"
 )
 
 var (
@@ -24,13 +30,27 @@ type OperationType string
 // Operation represents a transaction event. It can be duplex and non-duplex.
 // Duplex Operation uses two User (Initiator and Receiver) to denote that both of
 // them participate in the operation. Non-duplex Operation denote that User uses
-// operations like deposit or withdraw.
+// operations like deposit or withdraw. Fee Operation is non-duplex too, but Receiver
+// is the commission account which is credited by it.
 type Operation struct {
 	Initiator *User         `json:"initiator"`
 	Type      OperationType `json:"type"`
 	Amount    float64       `json:"amount"`
 	Timestamp time.Time     `json:"timestamp"`
 	Receiver  *User         `json:"receiver,omitempty"`
+	// Fee is charged from Initiator in addition to Amount.
+	Fee float64 `json:"fee,omitempty"`
+	// Currency and OriginalAmount are set if Amount is converted to RUB from another
+	// currency.
+	Currency       string  `json:"currency,omitempty"`
+	OriginalAmount float64 `json:"original_amount,omitempty"`
+	// Limits are checked against initiator's usage when Operation is stored.
+	Limits *Limits `json:"-"`
+	// ExpectedVersion is Initiator's version client has seen, Operation isn't stored if
+	// it's changed. Zero means that Operation is applied to the current balance.
+	ExpectedVersion int64 `json:"-"`
+	// CreateInitiator means that unknown Initiator is created with Operation.
+	CreateInitiator bool `json:"-"`
 }
 
 // RepositoryOperation is restricted type of Operation for Repository aims.
@@ -40,6 +60,20 @@ type RepositoryOperation struct {
 	Amount      float64       `json:"amount"`
 	Timestamp   time.Time     `json:"timestamp"`
 	ReceiverID  int64         `json:"receiver_id,omitempty"`
+	Fee         float64       `json:"fee,omitempty"`
+	// Currency and OriginalAmount are set if Amount is converted to RUB.
+	Currency       string  `json:"currency,omitempty"`
+	OriginalAmount float64 `json:"original_amount,omitempty"`
+}
+
+// IsValid returns true if OperationType is known.
+func (operationType OperationType) IsValid() bool {
+	switch operationType {
+	case Deposit, Withdraw, TransferIn, TransferOut, Fee, Correction, Adjustment:
+		return true
+	default:
+		return false
+	}
 }
 
 // IsTransfer returns true if Operation type is Transfer and false otherwise.
@@ -50,17 +84,16 @@ func (operation Operation) IsTransfer() bool {
 // Validate is necessary in order to correlate field values and type value.
 func (operation Operation) Validate() error {
 	// check type
-	if operation.Type != Deposit && operation.Type != Withdraw &&
-		operation.Type != TransferIn && operation.Type != TransferOut {
+	if !operation.Type.IsValid() {
 		return fmt.Errorf("incorrect operation type: <%w>", ErrIncorrectOperationParams)
 	}
 	// check correlation of type and users' quantity
 	if operation.Initiator == nil {
 		return fmt.Errorf("initiator can't be nil: <%w>", ErrIncorrectOperationParams)
 	}
-	if operation.IsTransfer() {
+	if operation.IsTransfer() || operation.Type == Fee {
 		if operation.Receiver == nil {
-			return fmt.Errorf("receiver can't be nil in transfer operation: <%w>",
+			return fmt.Errorf("receiver can't be nil in transfer or fee operation: <%w>",
 				ErrIncorrectOperationParams)
 		}
 	} else {
@@ -81,8 +114,10 @@ func (operation Operation) Reverse() (*Operation, error) {
 		return nil, fmt.Errorf("can't reverse non duplex operation: <%w>", ErrNonTransferOperation)
 	}
 	reversed := Operation{
-		Amount:    operation.Amount,
-		Timestamp: operation.Timestamp,
+		Amount:         operation.Amount,
+		Timestamp:      operation.Timestamp,
+		Currency:       operation.Currency,
+		OriginalAmount: operation.OriginalAmount,
 	}
 	reversed.Initiator = operation.Receiver
 	reversed.Receiver = operation.Initiator
@@ -96,3 +131,44 @@ func (operation Operation) Reverse() (*Operation, error) {
 	}
 	return &reversed, nil
 }
+
+// Apply changes balances of initiator and receiver by Operation. Commission account
+// isn't changed by Fee Operation, it's credited without its balance.
+func (operation Operation) Apply(initiator, receiver *User) error {
+	switch operation.Type {
+	case Deposit:
+		return initiator.Deposit(operation.Amount)
+	case Withdraw, Fee:
+		return initiator.Withdraw(operation.Amount)
+	case TransferOut:
+		if err := initiator.Withdraw(operation.Amount); err != nil {
+			return err
+		}
+		return receiver.Deposit(operation.Amount)
+	case Adjustment:
+		if operation.Amount < 0 {
+			return initiator.Withdraw(-operation.Amount)
+		}
+		return initiator.Deposit(operation.Amount)
+	default:
+		return fmt.Errorf("can't apply %s operation: <%w>", operation.Type,
+			ErrIncorrectOperationParams)
+	}
+}
+
+// SetOriginal records that Amount is converted to RUB from amount of currency, empty
+// currency means that Amount isn't converted.
+func (operation *Operation) SetOriginal(currency string, amount float64) {
+	if len(currency) != 0 {
+		operation.Currency = currency
+		operation.OriginalAmount = amount
+	}
+}
+
+// Public returns Operation copy which hides Receiver's balance.
+func (operation Operation) Public() Operation {
+	if operation.Receiver != nil {
+		operation.Receiver = &User{ID: operation.Receiver.ID}
+	}
+	return operation
+}
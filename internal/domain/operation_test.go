@@ -62,6 +62,33 @@ func (suite OperationSuite) TestOperation_Validate() {
 	suite.NoError(suite.Operation.Validate())
 }
 
+func (suite OperationSuite) TestOperation_Public() {
+	suite.Operation.Initiator = &User{ID: 1, Amount: 1}
+	suite.Operation.Receiver = &User{ID: 2, Amount: 2}
+	public := suite.Operation.Public()
+	suite.Equal(suite.Operation.Initiator, public.Initiator)
+	suite.Equal(&User{ID: 2}, public.Receiver)
+	suite.Equal(float64(2), suite.Operation.Receiver.Amount)
+}
+
+func (suite OperationSuite) TestOperation_Apply() {
+	initiator, receiver := &User{ID: 1, Amount: 100}, &User{ID: 2}
+	suite.Operation = Operation{Type: TransferOut, Amount: 30}
+	suite.NoError(suite.Operation.Apply(initiator, receiver))
+	suite.Equal(float64(70), initiator.Amount)
+	suite.Equal(float64(30), receiver.Amount)
+
+	suite.Operation = Operation{Type: Fee, Amount: 1}
+	suite.NoError(suite.Operation.Apply(initiator, receiver))
+	suite.Equal(float64(69), initiator.Amount)
+	suite.Equal(float64(30), receiver.Amount)
+
+	suite.Operation = Operation{Type: Adjustment, Amount: -70}
+	suite.ErrorIs(suite.Operation.Apply(initiator, nil), ErrInsufficientFunds)
+	suite.Operation = Operation{Type: Correction, Amount: 1}
+	suite.ErrorIs(suite.Operation.Apply(initiator, nil), ErrIncorrectOperationParams)
+}
+
 func TestOperationSuite(t *testing.T) {
 	suite.Run(t, new(OperationSuite))
 }
@@ -0,0 +1,46 @@
+package domain
+
+import (
+	"math"
+	"time"
+)
+
+// BalanceTolerance is a half of kopeck. Smaller differences between balance and ledger
+// are rounding errors of float64 balances, so they aren't mismatches.
+const BalanceTolerance = 0.005
+
+// BalanceMismatch represents User whose balance differs from the sum of his operations.
+type BalanceMismatch struct {
+	UserID  int64   `json:"user_id"`
+	Balance float64 `json:"balance"`
+	Ledger  float64 `json:"ledger"`
+	// Difference is added to Balance to make it equal to Ledger.
+	Difference float64 `json:"difference"`
+	Operations int64   `json:"operations"`
+	Corrected  bool    `json:"corrected,omitempty"`
+}
+
+// ReconciliationReport represents result of balances verification.
+type ReconciliationReport struct {
+	StartedAt    time.Time         `json:"started_at"`
+	FinishedAt   time.Time         `json:"finished_at"`
+	UsersChecked int64             `json:"users_checked"`
+	Mismatches   []BalanceMismatch `json:"mismatches"`
+	Corrected    int               `json:"corrected"`
+	Errors       []string          `json:"errors,omitempty"`
+}
+
+// IsSignificant returns true if difference isn't a rounding error.
+func (mismatch BalanceMismatch) IsSignificant() bool {
+	return math.Abs(mismatch.Difference) >= BalanceTolerance
+}
+
+// Correction returns Correction Operation which sets User's balance to Ledger.
+func (mismatch BalanceMismatch) Correction(ts time.Time) Operation {
+	return Operation{
+		Initiator: &User{ID: mismatch.UserID, Amount: mismatch.Ledger},
+		Type:      Correction,
+		Amount:    mismatch.Difference,
+		Timestamp: ts,
+	}
+}
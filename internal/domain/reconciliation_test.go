@@ -0,0 +1,24 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type ReconciliationSuite struct {
+	suite.Suite
+}
+
+func (suite ReconciliationSuite) TestBalanceMismatch_IsSignificant() {
+	balance := 0.1
+	balance += 0.2
+	suite.False(BalanceMismatch{Balance: balance, Ledger: 0.3,
+		Difference: 0.3 - balance}.IsSignificant())
+	suite.True(BalanceMismatch{Balance: 0.29, Ledger: 0.3, Difference: 0.01}.IsSignificant())
+	suite.True(BalanceMismatch{Balance: 0.31, Ledger: 0.3, Difference: -0.01}.IsSignificant())
+}
+
+func TestReconciliationSuite(t *testing.T) {
+	suite.Run(t, new(ReconciliationSuite))
+}
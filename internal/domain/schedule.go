@@ -0,0 +1,104 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+const (
+	ExecutionSucceeded ExecutionStatus = "succeeded"
+	ExecutionFailed    ExecutionStatus = "failed"
+)
+
+var (
+	ErrIncorrectScheduleParams = errors.New("this schedule is incorrect")
+	ErrRunExecuted             = errors.New("schedule's run is already executed")
+)
+
+// ExecutionStatus describes state of ScheduleExecution.
+type ExecutionStatus string
+
+// Schedule represents a deferred transfer. It's one-off if RunAt is set and recurring
+// if Cron is set. NextRunAt is empty when Schedule won't run anymore.
+type Schedule struct {
+	ID          int64   `json:"id"`
+	InitiatorID int64   `json:"initiator_id"`
+	ReceiverID  int64   `json:"receiver_id"`
+	Amount      float64 `json:"amount"`
+	// Cron is a standard expression with minute, hour, day of month, month and
+	// day of week fields.
+	Cron      string     `json:"cron,omitempty"`
+	RunAt     *time.Time `json:"run_at,omitempty"`
+	NextRunAt *time.Time `json:"next_run_at,omitempty"`
+	Active    bool       `json:"active"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// ScheduleExecution represents a single run of Schedule.
+type ScheduleExecution struct {
+	ID          int64           `json:"id"`
+	ScheduleID  int64           `json:"schedule_id"`
+	ScheduledAt time.Time       `json:"scheduled_at"`
+	Status      ExecutionStatus `json:"status"`
+	Error       string          `json:"error,omitempty"`
+	ExecutedAt  *time.Time      `json:"executed_at,omitempty"`
+}
+
+// ScheduleRun is a due run of Schedule. Its Execution is stored and Schedule is moved
+// to Next run in the same transaction as run's transfer, so each run is executed once.
+type ScheduleRun struct {
+	Execution ScheduleExecution
+	// Next is the next run time, Schedule is finished if it's nil.
+	Next *time.Time
+}
+
+// Validate checks users, amount and timing of Schedule.
+func (schedule Schedule) Validate() error {
+	if schedule.InitiatorID <= 0 || schedule.ReceiverID <= 0 {
+		return fmt.Errorf("user ids must be positive: <%w>", ErrIncorrectScheduleParams)
+	}
+	if schedule.InitiatorID == schedule.ReceiverID {
+		return fmt.Errorf("can't transfer money for the same user: <%w>",
+			ErrIncorrectScheduleParams)
+	}
+	if schedule.Amount < eps {
+		return fmt.Errorf("amount must be positive: <%w>", ErrIncorrectScheduleParams)
+	}
+	if (schedule.Cron == "") == (schedule.RunAt == nil) {
+		return fmt.Errorf("either cron or run time must be set: <%w>",
+			ErrIncorrectScheduleParams)
+	}
+	if schedule.Cron != "" {
+		if _, err := cron.ParseStandard(schedule.Cron); err != nil {
+			return fmt.Errorf("cron expression is incorrect (%s): <%w>", err,
+				ErrIncorrectScheduleParams)
+		}
+	}
+	return nil
+}
+
+// NextRun returns the first run of Schedule after given time or nil if there is no one.
+func (schedule Schedule) NextRun(after time.Time) (*time.Time, error) {
+	if err := schedule.Validate(); err != nil {
+		return nil, err
+	}
+	if schedule.RunAt != nil {
+		if !schedule.RunAt.After(after) {
+			return nil, nil
+		}
+		runAt := *schedule.RunAt
+		return &runAt, nil
+	}
+	parsed, err := cron.ParseStandard(schedule.Cron)
+	if err != nil {
+		return nil, fmt.Errorf("can't parse cron: <%w>", err)
+	}
+	next := parsed.Next(after)
+	if next.IsZero() {
+		return nil, nil
+	}
+	return &next, nil
+}
@@ -0,0 +1,68 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type ScheduleSuite struct {
+	suite.Suite
+	Schedule Schedule
+}
+
+func (suite *ScheduleSuite) SetupTest() {
+	suite.Schedule = Schedule{
+		InitiatorID: 1,
+		ReceiverID:  2,
+		Amount:      100,
+		Cron:        "0 9 1 * *",
+	}
+}
+
+func (suite ScheduleSuite) TestSchedule_Validate() {
+	suite.NoError(suite.Schedule.Validate())
+
+	schedule := suite.Schedule
+	schedule.ReceiverID = schedule.InitiatorID
+	suite.ErrorIs(schedule.Validate(), ErrIncorrectScheduleParams)
+
+	schedule = suite.Schedule
+	schedule.Amount = 0
+	suite.ErrorIs(schedule.Validate(), ErrIncorrectScheduleParams)
+
+	schedule = suite.Schedule
+	schedule.Cron = "every month"
+	suite.ErrorIs(schedule.Validate(), ErrIncorrectScheduleParams)
+
+	// both timings can't be set
+	runAt := time.Now()
+	schedule = suite.Schedule
+	schedule.RunAt = &runAt
+	suite.ErrorIs(schedule.Validate(), ErrIncorrectScheduleParams)
+	schedule.Cron = ""
+	suite.NoError(schedule.Validate())
+}
+
+func (suite ScheduleSuite) TestSchedule_NextRun() {
+	after := time.Date(2022, 1, 14, 12, 0, 0, 0, time.UTC)
+	next, err := suite.Schedule.NextRun(after)
+	suite.Require().NoError(err)
+	suite.Equal(time.Date(2022, 2, 1, 9, 0, 0, 0, time.UTC), *next)
+
+	// one-off schedule runs only once
+	schedule := suite.Schedule
+	schedule.Cron = ""
+	schedule.RunAt = next
+	runAt, err := schedule.NextRun(after)
+	suite.Require().NoError(err)
+	suite.Equal(next, runAt)
+	runAt, err = schedule.NextRun(*next)
+	suite.NoError(err)
+	suite.Nil(runAt)
+}
+
+func TestScheduleSuite(t *testing.T) {
+	suite.Run(t, new(ScheduleSuite))
+}
@@ -0,0 +1,144 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// AccountStatus represents status of User's account.
+type AccountStatus string
+
+const (
+	Active AccountStatus = "active"
+	Frozen AccountStatus = "frozen"
+	Closed AccountStatus = "closed"
+)
+
+var (
+	ErrAccountFrozen         = errors.New("account is frozen")
+	ErrAccountClosed         = errors.New("account is closed")
+	ErrNonZeroBalance        = errors.New("account with money can't be closed")
+	ErrIncorrectStatusChange = errors.New("this status change is incorrect")
+)
+
+// StatusInput represents input for changing User's account status. BlockIncoming
+// forbids frozen account to receive money too.
+type StatusInput struct {
+	Status        AccountStatus `json:"status"`
+	Reason        string        `json:"reason"`
+	BlockIncoming bool          `json:"block_incoming,omitempty"`
+}
+
+// StatusChange represents a record of audit trail of User's account statuses.
+type StatusChange struct {
+	ID            int64         `json:"id"`
+	UserID        int64         `json:"user_id"`
+	From          AccountStatus `json:"from"`
+	To            AccountStatus `json:"to"`
+	Reason        string        `json:"reason"`
+	BlockIncoming bool          `json:"block_incoming,omitempty"`
+	ChangedAt     time.Time     `json:"changed_at"`
+}
+
+// IsValid returns true if AccountStatus is known.
+func (status AccountStatus) IsValid() bool {
+	switch status {
+	case Active, Frozen, Closed:
+		return true
+	}
+	return false
+}
+
+// AccountStatus returns User's status, users without status are active.
+func (user User) AccountStatus() AccountStatus {
+	if user.Status == "" {
+		return Active
+	}
+	return user.Status
+}
+
+// CanSend returns error if User's money can't be withdrawn or transferred.
+func (user User) CanSend() error {
+	switch user.AccountStatus() {
+	case Frozen:
+		return fmt.Errorf("user <%d> can't send money: <%w>", user.ID, ErrAccountFrozen)
+	case Closed:
+		return fmt.Errorf("user <%d> can't send money: <%w>", user.ID, ErrAccountClosed)
+	}
+	return nil
+}
+
+// CanReceive returns error if User can't receive money.
+func (user User) CanReceive() error {
+	switch user.AccountStatus() {
+	case Frozen:
+		if user.BlockIncoming {
+			return fmt.Errorf("user <%d> can't receive money: <%w>", user.ID, ErrAccountFrozen)
+		}
+	case Closed:
+		return fmt.Errorf("user <%d> can't receive money: <%w>", user.ID, ErrAccountClosed)
+	}
+	return nil
+}
+
+// ChangeStatus applies StatusInput to User and returns StatusChange for audit trail.
+// Closed account can't be reopened and only account without money can be closed.
+func (user *User) ChangeStatus(input StatusInput, ts time.Time) (*StatusChange, error) {
+	from := user.AccountStatus()
+	switch {
+	case !input.Status.IsValid():
+		return nil, fmt.Errorf("unknown status: <%w>", ErrIncorrectStatusChange)
+	case len(input.Reason) == 0:
+		return nil, fmt.Errorf("reason is required: <%w>", ErrIncorrectStatusChange)
+	case input.BlockIncoming && input.Status != Frozen:
+		return nil, fmt.Errorf("only frozen account blocks incoming money: <%w>",
+			ErrIncorrectStatusChange)
+	case from == Closed:
+		return nil, fmt.Errorf("can't change status: <%w>", ErrAccountClosed)
+	case from == input.Status && user.BlockIncoming == input.BlockIncoming:
+		return nil, fmt.Errorf("status is already %s: <%w>", from, ErrIncorrectStatusChange)
+	case input.Status == Closed && user.Amount != 0:
+		return nil, fmt.Errorf("can't close account: <%w>", ErrNonZeroBalance)
+	}
+	user.Status = input.Status
+	user.BlockIncoming = input.BlockIncoming
+	user.StatusReason = input.Reason
+	user.StatusChangedAt = &ts
+	return &StatusChange{
+		UserID:        user.ID,
+		From:          from,
+		To:            input.Status,
+		Reason:        input.Reason,
+		BlockIncoming: input.BlockIncoming,
+		ChangedAt:     ts,
+	}, nil
+}
+
+// CheckStatus returns error if statuses of Operation's users forbid it. Commission
+// account always receives fees.
+func (operation Operation) CheckStatus() error {
+	switch operation.Type {
+	case Deposit:
+		return operation.Initiator.CanReceive()
+	case Withdraw, TransferOut, Fee:
+		if err := operation.Initiator.CanSend(); err != nil {
+			return err
+		}
+		if operation.Type == TransferOut {
+			return operation.Receiver.CanReceive()
+		}
+	case TransferIn:
+		if err := operation.Initiator.CanReceive(); err != nil {
+			return err
+		}
+		return operation.Receiver.CanSend()
+	case Adjustment:
+		// frozen accounts are adjusted by operators, but closed ones can't be changed
+		if operation.Initiator.AccountStatus() == Closed {
+			return fmt.Errorf("user <%d> can't be adjusted: <%w>", operation.Initiator.ID,
+				ErrAccountClosed)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type StatusSuite struct {
+	suite.Suite
+	User User
+}
+
+func (suite *StatusSuite) SetupTest() {
+	suite.User = User{ID: 1}
+}
+
+func (suite *StatusSuite) TestUser_ChangeStatus() {
+	ts := time.Now()
+	// incorrect inputs
+	_, err := suite.User.ChangeStatus(StatusInput{Status: "unknown", Reason: "r"}, ts)
+	suite.ErrorIs(err, ErrIncorrectStatusChange)
+	_, err = suite.User.ChangeStatus(StatusInput{Status: Frozen}, ts)
+	suite.ErrorIs(err, ErrIncorrectStatusChange)
+	_, err = suite.User.ChangeStatus(StatusInput{Status: Active, Reason: "r",
+		BlockIncoming: true}, ts)
+	suite.ErrorIs(err, ErrIncorrectStatusChange)
+	_, err = suite.User.ChangeStatus(StatusInput{Status: Active, Reason: "r"}, ts)
+	suite.ErrorIs(err, ErrIncorrectStatusChange)
+
+	// freezing
+	change, err := suite.User.ChangeStatus(StatusInput{Status: Frozen, Reason: "fraud"}, ts)
+	suite.Require().NoError(err)
+	suite.Equal(StatusChange{UserID: 1, From: Active, To: Frozen, Reason: "fraud",
+		ChangedAt: ts}, *change)
+	suite.Equal(Frozen, suite.User.Status)
+	suite.Equal("fraud", suite.User.StatusReason)
+	// blocking incoming money of frozen account is a change too
+	_, err = suite.User.ChangeStatus(StatusInput{Status: Frozen, Reason: "fraud",
+		BlockIncoming: true}, ts)
+	suite.NoError(err)
+
+	// closure
+	suite.User.Amount = 1
+	_, err = suite.User.ChangeStatus(StatusInput{Status: Closed, Reason: "r"}, ts)
+	suite.ErrorIs(err, ErrNonZeroBalance)
+	suite.User.Amount = 0
+	_, err = suite.User.ChangeStatus(StatusInput{Status: Closed, Reason: "r"}, ts)
+	suite.NoError(err)
+	_, err = suite.User.ChangeStatus(StatusInput{Status: Active, Reason: "r"}, ts)
+	suite.ErrorIs(err, ErrAccountClosed)
+}
+
+func (suite *StatusSuite) TestOperation_CheckStatus() {
+	frozen := User{ID: 2, Status: Frozen}
+	blocked := User{ID: 3, Status: Frozen, BlockIncoming: true}
+	closed := User{ID: 4, Status: Closed}
+
+	suite.NoError(Operation{Type: Deposit, Initiator: &frozen}.CheckStatus())
+	suite.ErrorIs(Operation{Type: Deposit, Initiator: &blocked}.CheckStatus(),
+		ErrAccountFrozen)
+	suite.ErrorIs(Operation{Type: Deposit, Initiator: &closed}.CheckStatus(),
+		ErrAccountClosed)
+	suite.ErrorIs(Operation{Type: Withdraw, Initiator: &frozen}.CheckStatus(),
+		ErrAccountFrozen)
+	suite.NoError(Operation{Type: TransferOut, Initiator: &suite.User,
+		Receiver: &frozen}.CheckStatus())
+	suite.ErrorIs(Operation{Type: TransferOut, Initiator: &suite.User,
+		Receiver: &blocked}.CheckStatus(), ErrAccountFrozen)
+	suite.ErrorIs(Operation{Type: TransferIn, Initiator: &suite.User,
+		Receiver: &frozen}.CheckStatus(), ErrAccountFrozen)
+	// commission account receives fees whatever its status is
+	suite.NoError(Operation{Type: Fee, Initiator: &suite.User,
+		Receiver: &closed}.CheckStatus())
+}
+
+func TestStatusSuite(t *testing.T) {
+	suite.Run(t, new(StatusSuite))
+}
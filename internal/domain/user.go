@@ -4,6 +4,8 @@ import (
 	"errors"
 	"fmt"
 	"math"
+	"strings"
+	"time"
 )
 
 var (
@@ -13,12 +15,80 @@ var (
 	ErrNegativeAmount    = errors.New("doesn't work with negative amounts")
 	ErrOverflow          = errors.New("can't hold so big amount of money")
 	ErrInsufficientFunds = errors.New("user hasn't enough money")
+	ErrUserExists        = errors.New("user with this id or external reference exists")
+	ErrNoSuchUser        = errors.New("user with this id doesn't exist")
+	ErrVersionConflict   = errors.New("user's balance version differs from expected one")
+
+	ErrIncorrectUserParams = errors.New("these user parameters are incorrect")
 )
 
+// DefaultCurrency is a currency of accounts created without it.
+const DefaultCurrency = "RUB"
+
 // User represents user entity in our service.
 type User struct {
 	ID     int64   `json:"id"`
 	Amount float64 `json:"amount,omitempty"`
+	// Status is empty for users which aren't loaded from storage, they are active.
+	Status          AccountStatus `json:"status,omitempty"`
+	BlockIncoming   bool          `json:"block_incoming,omitempty"`
+	StatusReason    string        `json:"status_reason,omitempty"`
+	StatusChangedAt *time.Time    `json:"status_changed_at,omitempty"`
+	// ExternalRef, Currency and DisplayName are optional metadata of account.
+	ExternalRef string     `json:"external_ref,omitempty"`
+	Currency    string     `json:"currency,omitempty"`
+	DisplayName string     `json:"display_name,omitempty"`
+	CreatedAt   *time.Time `json:"created_at,omitempty"`
+	// Version is incremented by each change of balance.
+	Version int64 `json:"version,omitempty"`
+}
+
+// UserInput represents input for explicit account creation.
+type UserInput struct {
+	ID          int64  `json:"id"`
+	ExternalRef string `json:"external_ref,omitempty"`
+	Currency    string `json:"currency,omitempty"`
+	DisplayName string `json:"display_name,omitempty"`
+}
+
+// Validate checks UserInput and sets DefaultCurrency if currency is empty.
+func (input *UserInput) Validate() error {
+	if input.ID <= 0 {
+		return fmt.Errorf("id must be positive: <%w>", ErrIncorrectUserParams)
+	}
+	if len(input.Currency) == 0 {
+		input.Currency = DefaultCurrency
+	}
+	input.Currency = strings.ToUpper(input.Currency)
+	if _, err := LookupCurrency(input.Currency); err != nil {
+		return fmt.Errorf("currency must be ISO 4217 code: <%w>", ErrIncorrectUserParams)
+	}
+	if len(input.ExternalRef) > 255 || len(input.DisplayName) > 255 {
+		return fmt.Errorf("metadata is too long: <%w>", ErrIncorrectUserParams)
+	}
+	return nil
+}
+
+// User returns active User with zero balance created from UserInput.
+func (input UserInput) User(ts time.Time) User {
+	return User{
+		ID:          input.ID,
+		Status:      Active,
+		ExternalRef: input.ExternalRef,
+		Currency:    input.Currency,
+		DisplayName: input.DisplayName,
+		CreatedAt:   &ts,
+	}
+}
+
+// CheckVersion returns ErrVersionConflict if User's version isn't expected one. Zero
+// expected version matches any.
+func (user User) CheckVersion(expected int64) error {
+	if expected != 0 && user.Version != expected {
+		return fmt.Errorf("expected version <%d>, actual <%d>: <%w>", expected, user.Version,
+			ErrVersionConflict)
+	}
+	return nil
 }
 
 // Deposit increases User's amount.
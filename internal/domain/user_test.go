@@ -70,6 +70,14 @@ func (suite UserSuite) TestUser_Withdraw() {
 	suite.Equal(zeroValue, suite.User.Amount)
 }
 
+func (suite UserSuite) TestUser_CheckVersion() {
+	suite.User.Version = 2
+	// zero matches any version
+	suite.NoError(suite.User.CheckVersion(0))
+	suite.NoError(suite.User.CheckVersion(2))
+	suite.ErrorIs(suite.User.CheckVersion(1), ErrVersionConflict)
+}
+
 func TestUserSuite(t *testing.T) {
 	suite.Run(t, new(UserSuite))
 }
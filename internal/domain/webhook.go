@@ -0,0 +1,76 @@
+package domain
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+const (
+	DeliveryPending   DeliveryStatus = "pending"
+	DeliveryDelivered DeliveryStatus = "delivered"
+	DeliveryDead      DeliveryStatus = "dead"
+
+	minSecretLength = 16
+)
+
+var ErrIncorrectWebhookParams = errors.New("this webhook is incorrect")
+
+// DeliveryStatus describes state of WebhookDelivery.
+type DeliveryStatus string
+
+// Webhook represents partner's subscription on operations. Operations of all users
+// are sent if UserID is zero.
+type Webhook struct {
+	ID         int64           `json:"id"`
+	URL        string          `json:"url"`
+	EventTypes []OperationType `json:"event_types"`
+	UserID     int64           `json:"user_id,omitempty"`
+	Secret     string          `json:"secret,omitempty"`
+	CreatedAt  time.Time       `json:"created_at"`
+}
+
+// WebhookDelivery represents a single notification and its delivery state.
+type WebhookDelivery struct {
+	ID            int64           `json:"id"`
+	WebhookID     int64           `json:"webhook_id"`
+	EventType     OperationType   `json:"event_type"`
+	Payload       json.RawMessage `json:"payload"`
+	Status        DeliveryStatus  `json:"status"`
+	Attempts      int             `json:"attempts"`
+	NextAttemptAt time.Time       `json:"next_attempt_at"`
+	LastError     string          `json:"last_error,omitempty"`
+	ResponseCode  int             `json:"response_code,omitempty"`
+	CreatedAt     time.Time       `json:"created_at"`
+	DeliveredAt   *time.Time      `json:"delivered_at,omitempty"`
+	// Webhook is filled only for dispatching.
+	Webhook *Webhook `json:"-"`
+}
+
+// Validate checks url, event types and secret of Webhook.
+func (webhook Webhook) Validate() error {
+	parsed, err := url.Parse(webhook.URL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") ||
+		parsed.Host == "" {
+		return fmt.Errorf("url must be absolute http url: <%w>", ErrIncorrectWebhookParams)
+	}
+	if len(webhook.EventTypes) == 0 {
+		return fmt.Errorf("event types can't be empty: <%w>", ErrIncorrectWebhookParams)
+	}
+	for _, eventType := range webhook.EventTypes {
+		if !eventType.IsValid() {
+			return fmt.Errorf("incorrect event type %s: <%w>", eventType,
+				ErrIncorrectWebhookParams)
+		}
+	}
+	if len(webhook.Secret) < minSecretLength {
+		return fmt.Errorf("secret must contain at least %d symbols: <%w>",
+			minSecretLength, ErrIncorrectWebhookParams)
+	}
+	if webhook.UserID < 0 {
+		return fmt.Errorf("user id can't be negative: <%w>", ErrIncorrectWebhookParams)
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type WebhookSuite struct {
+	suite.Suite
+	Webhook Webhook
+}
+
+func (suite *WebhookSuite) SetupTest() {
+	suite.Webhook = Webhook{
+		URL:        "https://partner.example/hooks",
+		EventTypes: []OperationType{TransferIn, Withdraw},
+		Secret:     "0123456789abcdef",
+	}
+}
+
+func (suite WebhookSuite) TestWebhook_Validate() {
+	suite.NoError(suite.Webhook.Validate())
+
+	webhook := suite.Webhook
+	webhook.URL = "partner.example/hooks"
+	suite.ErrorIs(webhook.Validate(), ErrIncorrectWebhookParams)
+
+	webhook = suite.Webhook
+	webhook.EventTypes = nil
+	suite.ErrorIs(webhook.Validate(), ErrIncorrectWebhookParams)
+	webhook.EventTypes = []OperationType{"REFUND"}
+	suite.ErrorIs(webhook.Validate(), ErrIncorrectWebhookParams)
+
+	webhook = suite.Webhook
+	webhook.Secret = "short"
+	suite.ErrorIs(webhook.Validate(), ErrIncorrectWebhookParams)
+}
+
+func TestWebhookSuite(t *testing.T) {
+	suite.Run(t, new(WebhookSuite))
+}
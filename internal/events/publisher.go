@@ -0,0 +1,196 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+
+	"github.com/agandreev/avito-intern-assignment/internal/domain"
+)
+
+const (
+	eventIDHeader   = "X-Event-ID"
+	eventTypeHeader = "X-Event-Type"
+)
+
+// Publisher delivers domain.Event to downstream services.
+type Publisher interface {
+	Publish(ctx context.Context, event domain.Event) error
+}
+
+// WriterPublisher writes events to io.Writer as json lines.
+type WriterPublisher struct {
+	mu     sync.Mutex
+	writer io.Writer
+}
+
+// NewWriterPublisher sets writer and returns pointer.
+func NewWriterPublisher(writer io.Writer) *WriterPublisher {
+	return &WriterPublisher{writer: writer}
+}
+
+// NewStdoutPublisher returns WriterPublisher for stdout.
+func NewStdoutPublisher() *WriterPublisher {
+	return NewWriterPublisher(os.Stdout)
+}
+
+// Publish writes event as a single json line.
+func (publisher *WriterPublisher) Publish(_ context.Context, event domain.Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("can't marshal event: <%w>", err)
+	}
+	publisher.mu.Lock()
+	defer publisher.mu.Unlock()
+	if _, err = publisher.writer.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("can't write event: <%w>", err)
+	}
+	return nil
+}
+
+// FilePublisher appends events to file as json lines.
+type FilePublisher struct {
+	*WriterPublisher
+	file *os.File
+}
+
+// NewFilePublisher opens file for appending and returns pointer.
+func NewFilePublisher(path string) (*FilePublisher, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0660)
+	if err != nil {
+		return nil, fmt.Errorf("can't open events file: <%w>", err)
+	}
+	return &FilePublisher{
+		WriterPublisher: NewWriterPublisher(file),
+		file:            file,
+	}, nil
+}
+
+// Publish writes event and flushes it to disk, so delivered events can't be lost.
+func (publisher *FilePublisher) Publish(ctx context.Context, event domain.Event) error {
+	if err := publisher.WriterPublisher.Publish(ctx, event); err != nil {
+		return err
+	}
+	if err := publisher.file.Sync(); err != nil {
+		return fmt.Errorf("can't sync events file: <%w>", err)
+	}
+	return nil
+}
+
+// Close closes events file.
+func (publisher *FilePublisher) Close() error {
+	return publisher.file.Close()
+}
+
+// WebhookPublisher sends events by http POST requests.
+type WebhookPublisher struct {
+	client *http.Client
+	url    string
+}
+
+// NewWebhookPublisher sets client and url and returns pointer.
+func NewWebhookPublisher(client *http.Client, url string) *WebhookPublisher {
+	return &WebhookPublisher{
+		client: client,
+		url:    url,
+	}
+}
+
+// Publish sends event as json body. Only 2xx status codes are treated as delivered.
+func (publisher *WebhookPublisher) Publish(ctx context.Context, event domain.Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("can't marshal event: <%w>", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, publisher.url,
+		bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("can't create webhook request: <%w>", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	// receivers can deduplicate events by id
+	req.Header.Set(eventIDHeader, strconv.FormatInt(event.ID, 10))
+	req.Header.Set(eventTypeHeader, string(event.Type))
+	resp, err := publisher.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook request error: <%w>", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("unexpected status code received from webhook: %d",
+			resp.StatusCode)
+	}
+	return nil
+}
+
+// Producer describes Kafka-compatible producer which writes keyed messages to topic.
+type Producer interface {
+	Produce(ctx context.Context, topic string, key, value []byte) error
+}
+
+// ProducerPublisher publishes events to topic keyed by user id, so events of the
+// same user land to the same partition and keep their order.
+type ProducerPublisher struct {
+	producer Producer
+	topic    string
+}
+
+// NewProducerPublisher sets producer and topic and returns pointer.
+func NewProducerPublisher(producer Producer, topic string) *ProducerPublisher {
+	return &ProducerPublisher{
+		producer: producer,
+		topic:    topic,
+	}
+}
+
+// Publish produces event as json message.
+func (publisher *ProducerPublisher) Publish(ctx context.Context, event domain.Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("can't marshal event: <%w>", err)
+	}
+	key := []byte(strconv.FormatInt(event.UserID, 10))
+	if err = publisher.producer.Produce(ctx, publisher.topic, key, data); err != nil {
+		return fmt.Errorf("can't produce event: <%w>", err)
+	}
+	return nil
+}
+
+// Message represents a message stored by MemoryProducer.
+type Message struct {
+	Topic string
+	Key   []byte
+	Value []byte
+}
+
+// MemoryProducer is in-memory Producer for tests and local runs.
+type MemoryProducer struct {
+	mu       sync.Mutex
+	messages []Message
+}
+
+// Produce stores message.
+func (producer *MemoryProducer) Produce(_ context.Context, topic string, key, value []byte) error {
+	producer.mu.Lock()
+	defer producer.mu.Unlock()
+	producer.messages = append(producer.messages, Message{
+		Topic: topic,
+		Key:   key,
+		Value: value,
+	})
+	return nil
+}
+
+// Messages returns copy of stored messages.
+func (producer *MemoryProducer) Messages() []Message {
+	producer.mu.Lock()
+	defer producer.mu.Unlock()
+	return append([]Message(nil), producer.messages...)
+}
@@ -0,0 +1,69 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/agandreev/avito-intern-assignment/internal/domain"
+	"github.com/stretchr/testify/suite"
+)
+
+type PublisherSuite struct {
+	suite.Suite
+	Event domain.Event
+}
+
+func (suite *PublisherSuite) SetupTest() {
+	suite.Event = domain.Event{
+		ID:      7,
+		Type:    domain.OperationCreated,
+		UserID:  1,
+		Payload: json.RawMessage(`{"amount":1}`),
+	}
+}
+
+func (suite *PublisherSuite) TestWriterPublisher_Publish() {
+	var buffer bytes.Buffer
+	suite.NoError(NewWriterPublisher(&buffer).Publish(context.Background(), suite.Event))
+	var event domain.Event
+	suite.NoError(json.Unmarshal(buffer.Bytes(), &event))
+	suite.Equal(suite.Event.ID, event.ID)
+	suite.JSONEq(string(suite.Event.Payload), string(event.Payload))
+}
+
+func (suite *PublisherSuite) TestFilePublisher_Publish() {
+	path := filepath.Join(suite.T().TempDir(), "events.jsonl")
+	publisher, err := NewFilePublisher(path)
+	suite.Require().NoError(err)
+	suite.NoError(publisher.Publish(context.Background(), suite.Event))
+	suite.NoError(publisher.Publish(context.Background(), suite.Event))
+	suite.NoError(publisher.Close())
+	data, err := os.ReadFile(path)
+	suite.Require().NoError(err)
+	suite.Equal(2, bytes.Count(data, []byte("\n")))
+}
+
+func (suite *PublisherSuite) TestWebhookPublisher_Publish() {
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		suite.Equal("7", r.Header.Get(eventIDHeader))
+		suite.Equal(string(domain.OperationCreated), r.Header.Get(eventTypeHeader))
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+	publisher := NewWebhookPublisher(server.Client(), server.URL)
+
+	suite.NoError(publisher.Publish(context.Background(), suite.Event))
+	status = http.StatusInternalServerError
+	suite.Error(publisher.Publish(context.Background(), suite.Event))
+}
+
+func TestPublisherSuite(t *testing.T) {
+	suite.Run(t, new(PublisherSuite))
+}
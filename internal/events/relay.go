@@ -0,0 +1,67 @@
+package events
+
+import (
+	"context"
+	"time"
+
+	"github.com/agandreev/avito-intern-assignment/internal/domain"
+	"github.com/sirupsen/logrus"
+)
+
+// EventStorage describes outbox methods.
+type EventStorage interface {
+	RelayEvents(ctx context.Context, limit int,
+		deliver func(ctx context.Context, event domain.Event) error) (int, error)
+}
+
+// Relay periodically moves events from outbox to Publisher. Events are marked as
+// delivered only after successful publishing, so each event is delivered at least once.
+type Relay struct {
+	storage   EventStorage
+	publisher Publisher
+	interval  time.Duration
+	batchSize int
+	log       *logrus.Logger
+}
+
+// NewRelay sets Relay fields and returns pointer.
+func NewRelay(storage EventStorage, publisher Publisher, interval time.Duration,
+	batchSize int, log *logrus.Logger) *Relay {
+	return &Relay{
+		storage:   storage,
+		publisher: publisher,
+		interval:  interval,
+		batchSize: batchSize,
+		log:       log,
+	}
+}
+
+// Run relays events until ctx is done.
+func (relay *Relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(relay.interval)
+	defer ticker.Stop()
+	for {
+		// drain outbox while batches are full
+		for relay.RelayOnce(ctx) == relay.batchSize && ctx.Err() == nil {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RelayOnce relays a single batch and returns quantity of delivered events.
+func (relay *Relay) RelayOnce(ctx context.Context) int {
+	delivered, err := relay.storage.RelayEvents(ctx, relay.batchSize, relay.publisher.Publish)
+	if err != nil {
+		relay.log.Printf("RELAY ERROR: <%s>", err)
+		return 0
+	}
+	if delivered != 0 {
+		relay.log.Printf("RELAY: <%d> events were delivered", delivered)
+	}
+	return delivered
+}
@@ -0,0 +1,102 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/agandreev/avito-intern-assignment/internal/domain"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/suite"
+)
+
+// memoryOutbox imitates outbox table.
+type memoryOutbox struct {
+	events    []domain.Event
+	delivered int
+}
+
+func (outbox *memoryOutbox) RelayEvents(ctx context.Context, limit int,
+	deliver func(ctx context.Context, event domain.Event) error) (int, error) {
+	var delivered int
+	for delivered < limit && outbox.delivered < len(outbox.events) {
+		if err := deliver(ctx, outbox.events[outbox.delivered]); err != nil {
+			return delivered, err
+		}
+		outbox.delivered++
+		delivered++
+	}
+	return delivered, nil
+}
+
+// failingPublisher fails each second call.
+type failingPublisher struct {
+	Publisher
+	calls int
+}
+
+func (publisher *failingPublisher) Publish(ctx context.Context, event domain.Event) error {
+	publisher.calls++
+	if publisher.calls%2 == 0 {
+		return errors.New("broker is unavailable")
+	}
+	return publisher.Publisher.Publish(ctx, event)
+}
+
+type RelaySuite struct {
+	suite.Suite
+	Outbox   *memoryOutbox
+	Producer *MemoryProducer
+	log      *logrus.Logger
+}
+
+func (suite *RelaySuite) SetupTest() {
+	suite.Outbox = &memoryOutbox{}
+	for i := 1; i <= 5; i++ {
+		suite.Outbox.events = append(suite.Outbox.events, domain.Event{
+			ID:     int64(i),
+			Type:   domain.BalanceChanged,
+			UserID: int64(i % 2),
+		})
+	}
+	suite.Producer = &MemoryProducer{}
+	suite.log = logrus.New()
+	suite.log.SetOutput(io.Discard)
+}
+
+func (suite *RelaySuite) TestRelay_Run() {
+	relay := NewRelay(suite.Outbox, NewProducerPublisher(suite.Producer, "balance"),
+		time.Millisecond, 2, suite.log)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	relay.Run(ctx)
+
+	messages := suite.Producer.Messages()
+	suite.Require().Len(messages, 5)
+	for i, message := range messages {
+		suite.Equal("balance", message.Topic)
+		suite.Equal(strconv.Itoa((i+1)%2), string(message.Key))
+		suite.Contains(string(message.Value), `"id":`+strconv.Itoa(i+1))
+	}
+}
+
+func (suite *RelaySuite) TestRelay_AtLeastOnce() {
+	publisher := &failingPublisher{Publisher: NewProducerPublisher(suite.Producer, "balance")}
+	relay := NewRelay(suite.Outbox, publisher, time.Millisecond, 5, suite.log)
+
+	// first event is delivered, second one fails and stays in outbox
+	suite.Equal(0, relay.RelayOnce(context.Background()))
+	suite.Equal(1, suite.Outbox.delivered)
+	suite.Len(suite.Producer.Messages(), 1)
+	// failed event is retried
+	suite.Equal(0, relay.RelayOnce(context.Background()))
+	suite.Equal(2, suite.Outbox.delivered)
+	suite.Len(suite.Producer.Messages(), 2)
+}
+
+func TestRelaySuite(t *testing.T) {
+	suite.Run(t, new(RelaySuite))
+}
@@ -0,0 +1,255 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/agandreev/avito-intern-assignment/internal/domain"
+	"github.com/go-chi/chi/v5"
+)
+
+const statusParam = "status"
+
+// requestAdjustmentHandler
+// @Summary      requests adjustment of user's balance
+// @Description  creates pending adjustment of positive or negative amount with reason and ticket, it's applied only after approval by another operator
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id      path      int                     true  "User ID"
+// @Param        input   body      domain.AdjustmentInput  true  "Signed amount with reason and ticket"
+// @Param        Authorization    header  string  true   "Operator's bearer token"
+// @Param        Idempotency-Key  header  string  false  "Key of retried request"
+// @Success      201  {object}  domain.AdjustmentRequest
+// @Failure      400  {object}  domain.ErrorJSON
+// @Failure      401  {object}  domain.ErrorJSON
+// @Failure      409  {object}  domain.ErrorJSON
+// @Failure      422  {object}  domain.ErrorJSON
+// @Failure      500  {object}  domain.ErrorJSON
+// @Router       /admin/users/{id}/adjustments [post]
+func (handler *Handler) requestAdjustmentHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, idParam), 10, 64)
+	if err != nil {
+		processError(w, http.StatusBadRequest, err)
+		return
+	}
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		processError(w, http.StatusBadRequest, err)
+		return
+	}
+	defer r.Body.Close()
+	input := domain.AdjustmentInput{}
+	if err = json.Unmarshal(data, &input); err != nil {
+		processError(w, http.StatusBadRequest, err)
+		return
+	}
+	request, err := handler.GB.RequestAdjustment(r.Context(), id, input)
+	if err != nil {
+		handler.log.Printf("REQUEST ADJUSTMENT ERROR: <%s>", err)
+		processError(w, http.StatusBadRequest, err)
+		return
+	}
+	respBody, err := json.Marshal(request)
+	if err != nil {
+		processError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	if _, err = w.Write(respBody); err != nil {
+		processError(w, http.StatusInternalServerError, err)
+		return
+	}
+}
+
+// approveAdjustmentHandler
+// @Summary      approves adjustment
+// @Description  approves pending adjustment by operator who didn't create it and applies it as ADJUSTMENT operation
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id      path      int                 true   "Adjustment ID"
+// @Param        input   body      domain.ReviewInput  false  "Checker's comment"
+// @Param        Authorization  header  string  true   "Operator's bearer token"
+// @Success      200  {object}  domain.AdjustmentRequest
+// @Failure      400  {object}  domain.ErrorJSON
+// @Failure      401  {object}  domain.ErrorJSON
+// @Failure      403  {object}  domain.ErrorJSON
+// @Failure      404  {object}  domain.ErrorJSON
+// @Failure      409  {object}  domain.ErrorJSON
+// @Failure      500  {object}  domain.ErrorJSON
+// @Router       /admin/adjustments/{id}/approve [post]
+func (handler *Handler) approveAdjustmentHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, idParam), 10, 64)
+	if err != nil {
+		processError(w, http.StatusBadRequest, err)
+		return
+	}
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		processError(w, http.StatusBadRequest, err)
+		return
+	}
+	defer r.Body.Close()
+	input := domain.ReviewInput{}
+	if len(data) != 0 {
+		if err = json.Unmarshal(data, &input); err != nil {
+			processError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+	request, err := handler.GB.ApproveAdjustment(r.Context(), id, input)
+	if err != nil {
+		handler.log.Printf("APPROVE ADJUSTMENT ERROR: <%s>", err)
+		processError(w, http.StatusBadRequest, err)
+		return
+	}
+	respBody, err := json.Marshal(request)
+	if err != nil {
+		processError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	if _, err = w.Write(respBody); err != nil {
+		processError(w, http.StatusInternalServerError, err)
+		return
+	}
+}
+
+// rejectAdjustmentHandler
+// @Summary      rejects adjustment
+// @Description  rejects pending adjustment, its creator can reject it too
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id      path      int                 true   "Adjustment ID"
+// @Param        input   body      domain.ReviewInput  false  "Checker's comment"
+// @Param        Authorization  header  string  true   "Operator's bearer token"
+// @Success      200  {object}  domain.AdjustmentRequest
+// @Failure      400  {object}  domain.ErrorJSON
+// @Failure      401  {object}  domain.ErrorJSON
+// @Failure      404  {object}  domain.ErrorJSON
+// @Failure      409  {object}  domain.ErrorJSON
+// @Failure      500  {object}  domain.ErrorJSON
+// @Router       /admin/adjustments/{id}/reject [post]
+func (handler *Handler) rejectAdjustmentHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, idParam), 10, 64)
+	if err != nil {
+		processError(w, http.StatusBadRequest, err)
+		return
+	}
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		processError(w, http.StatusBadRequest, err)
+		return
+	}
+	defer r.Body.Close()
+	input := domain.ReviewInput{}
+	if len(data) != 0 {
+		if err = json.Unmarshal(data, &input); err != nil {
+			processError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+	request, err := handler.GB.RejectAdjustment(r.Context(), id, input)
+	if err != nil {
+		handler.log.Printf("REJECT ADJUSTMENT ERROR: <%s>", err)
+		processError(w, http.StatusBadRequest, err)
+		return
+	}
+	respBody, err := json.Marshal(request)
+	if err != nil {
+		processError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	if _, err = w.Write(respBody); err != nil {
+		processError(w, http.StatusInternalServerError, err)
+		return
+	}
+}
+
+// adjustmentHandler
+// @Summary      returns adjustment
+// @Description  returns adjustment with its creator, reviewer and status
+// @Tags         admin
+// @Produce      json
+// @Param        id   path      int  true  "Adjustment ID"
+// @Success      200  {object}  domain.AdjustmentRequest
+// @Failure      400  {object}  domain.ErrorJSON
+// @Failure      404  {object}  domain.ErrorJSON
+// @Failure      500  {object}  domain.ErrorJSON
+// @Router       /admin/adjustments/{id} [get]
+func (handler *Handler) adjustmentHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, idParam), 10, 64)
+	if err != nil {
+		processError(w, http.StatusBadRequest, err)
+		return
+	}
+	request, err := handler.GB.Adjustment(r.Context(), id)
+	if err != nil {
+		handler.log.Printf("ADJUSTMENT ERROR: <%s>", err)
+		processError(w, http.StatusBadRequest, err)
+		return
+	}
+	respBody, err := json.Marshal(request)
+	if err != nil {
+		processError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	if _, err = w.Write(respBody); err != nil {
+		processError(w, http.StatusInternalServerError, err)
+		return
+	}
+}
+
+// adjustmentsHandler
+// @Summary      returns adjustments
+// @Description  returns the latest adjustments, optionally by user and status
+// @Tags         admin
+// @Produce      json
+// @Param        user_id  query     int     false  "User ID"
+// @Param        status   query     string  false  "pending, approved, rejected or expired"
+// @Param        limit    query     int     false  "Adjustments quantity (100 by default)"
+// @Success      200  {object}  []domain.AdjustmentRequest
+// @Failure      400  {object}  domain.ErrorJSON
+// @Failure      500  {object}  domain.ErrorJSON
+// @Router       /admin/adjustments [get]
+func (handler *Handler) adjustmentsHandler(w http.ResponseWriter, r *http.Request) {
+	filter := domain.AdjustmentFilter{
+		Status: domain.AdjustmentStatus(r.URL.Query().Get(statusParam)),
+		Limit:  defaultLimit,
+	}
+	var err error
+	if value := r.URL.Query().Get(userIDParam); value != "" {
+		if filter.UserID, err = strconv.ParseInt(value, 10, 64); err != nil {
+			processError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+	if value := r.URL.Query().Get(limit); value != "" {
+		if filter.Limit, err = strconv.ParseInt(value, 10, 64); err != nil {
+			processError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+	requests, err := handler.GB.Adjustments(r.Context(), filter)
+	if err != nil {
+		handler.log.Printf("ADJUSTMENTS ERROR: <%s>", err)
+		processError(w, http.StatusBadRequest, err)
+		return
+	}
+	respBody, err := json.Marshal(requests)
+	if err != nil {
+		processError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	if _, err = w.Write(respBody); err != nil {
+		processError(w, http.StatusInternalServerError, err)
+		return
+	}
+}
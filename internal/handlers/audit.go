@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+
+	"github.com/agandreev/avito-intern-assignment/internal/domain"
+	"github.com/agandreev/avito-intern-assignment/internal/service"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+const (
+	// actorHeader is an actor claimed by caller. It isn't verified, so it's recorded apart
+	// from actor of bearer token.
+	actorHeader  = "X-Actor"
+	afterIDParam = "after_id"
+	actorParam   = "actor"
+)
+
+// callerMiddleware identifies caller of each API call and passes it to service, so
+// admin actions are recorded with it. Caller is anonymous unless bearer token
+// authenticates him.
+func (handler *Handler) callerMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		caller := domain.Caller{
+			Actor:        domain.AnonymousActor,
+			ClaimedActor: r.Header.Get(actorHeader),
+			SourceIP:     sourceIP(r),
+			RequestID:    middleware.GetReqID(r.Context()),
+		}
+		if operator, ok := handler.authenticate(r); ok {
+			caller.Actor = operator
+		}
+		next.ServeHTTP(w, r.WithContext(service.WithCaller(r.Context(), caller)))
+	})
+}
+
+// auditMiddleware records audited API calls (operations, balance reads and admin calls)
+// with their caller, sanitized payload and status.
+func (handler *Handler) auditMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		caller := service.CallerFrom(r.Context())
+		// body is read for audit and passed to handler again
+		var payload []byte
+		if r.Body != nil {
+			data, err := io.ReadAll(r.Body)
+			if err != nil {
+				processError(w, http.StatusBadRequest, err)
+				return
+			}
+			_ = r.Body.Close()
+			payload = data
+			r.Body = io.NopCloser(bytes.NewReader(data))
+		}
+		wrapped := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(wrapped, r)
+
+		status := wrapped.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		entry := domain.AuditEntry{
+			Caller:   caller,
+			Action:   domain.HTTPAction,
+			Endpoint: r.Method + " " + r.URL.RequestURI(),
+			Payload:  domain.SanitizePayload(payload),
+			Status:   status,
+			Outcome:  domain.AuditSuccess,
+		}
+		if status >= http.StatusBadRequest {
+			entry.Outcome = domain.AuditFailure
+		}
+		if err := handler.GB.RecordAudit(r.Context(), entry); err != nil {
+			handler.log.Printf("AUDIT ERROR: <%s>", err)
+		}
+	})
+}
+
+// sourceIP returns host of request's remote address.
+func sourceIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// auditHandler
+// @Summary      returns audit log
+// @Description  returns audit entries in chain's order after given id, optionally by actor
+// @Tags         admin
+// @Produce      json
+// @Param        after_id   query     int     false  "Entries after this id"
+// @Param        actor      query     string  false  "Caller's actor"
+// @Param        limit      query     int     false  "Entries quantity (100 by default)"
+// @Success      200  {object}  []domain.AuditEntry
+// @Failure      400  {object}  domain.ErrorJSON
+// @Failure      500  {object}  domain.ErrorJSON
+// @Router       /admin/audit [get]
+func (handler *Handler) auditHandler(w http.ResponseWriter, r *http.Request) {
+	filter := domain.AuditFilter{
+		Actor: r.URL.Query().Get(actorParam),
+		Limit: defaultLimit,
+	}
+	var err error
+	if value := r.URL.Query().Get(afterIDParam); value != "" {
+		if filter.AfterID, err = strconv.ParseInt(value, 10, 64); err != nil {
+			processError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+	if value := r.URL.Query().Get(limit); value != "" {
+		if filter.Limit, err = strconv.ParseInt(value, 10, 64); err != nil {
+			processError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+	entries, err := handler.GB.AuditEntries(r.Context(), filter)
+	if err != nil {
+		handler.log.Printf("AUDIT LOG ERROR: <%s>", err)
+		processError(w, http.StatusBadRequest, err)
+		return
+	}
+	respBody, err := json.Marshal(entries)
+	if err != nil {
+		processError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	if _, err = w.Write(respBody); err != nil {
+		processError(w, http.StatusInternalServerError, err)
+		return
+	}
+}
+
+// verifyAuditHandler
+// @Summary      verifies audit log
+// @Description  checks hash chain of the whole audit log and returns the first broken entry
+// @Tags         admin
+// @Produce      json
+// @Success      200  {object}  domain.AuditVerification
+// @Failure      500  {object}  domain.ErrorJSON
+// @Router       /admin/audit/verify [get]
+func (handler *Handler) verifyAuditHandler(w http.ResponseWriter, r *http.Request) {
+	verification, err := handler.GB.VerifyAudit(r.Context())
+	if err != nil {
+		handler.log.Printf("VERIFY AUDIT ERROR: <%s>", err)
+		processError(w, http.StatusInternalServerError, err)
+		return
+	}
+	respBody, err := json.Marshal(verification)
+	if err != nil {
+		processError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	if _, err = w.Write(respBody); err != nil {
+		processError(w, http.StatusInternalServerError, err)
+		return
+	}
+}
@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/agandreev/avito-intern-assignment/internal/domain"
+)
+
+const (
+	authorizationHeader = "Authorization"
+	bearerPrefix        = "Bearer "
+)
+
+// adminMiddleware rejects admin calls without operator's bearer token. Caller's actor
+// is taken from the token by callerMiddleware, so X-Actor header can't impersonate
+// another operator.
+func (handler *Handler) adminMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := handler.authenticate(r); !ok {
+			processError(w, http.StatusUnauthorized, domain.ErrUnauthenticated)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// authenticate returns operator of request's bearer token.
+func (handler *Handler) authenticate(r *http.Request) (string, bool) {
+	authorization := r.Header.Get(authorizationHeader)
+	if len(handler.config.AdminTokens) == 0 || !strings.HasPrefix(authorization, bearerPrefix) {
+		return "", false
+	}
+	token := strings.TrimPrefix(authorization, bearerPrefix)
+	// all tokens are compared in constant time
+	operator, ok := "", false
+	for known, knownOperator := range handler.config.AdminTokens {
+		if subtle.ConstantTimeCompare([]byte(known), []byte(token)) == 1 {
+			operator, ok = knownOperator, true
+		}
+	}
+	return operator, ok
+}
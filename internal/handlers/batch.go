@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/agandreev/avito-intern-assignment/internal/domain"
+)
+
+// batchHandler
+// @Summary      executes a batch of operations
+// @Description  executes deposit, withdraw and transfer items in one transaction (atomic mode) or one by one (partial mode), and returns result of each item
+// @Tags         operations
+// @Accept       json
+// @Produce      json
+// @Param        input   body      domain.BatchInput  true  "Batch mode and items"
+// @Param        Idempotency-Key  header  string  false  "Key of retried operation"
+// @Success      201  {object}  []domain.BatchResult
+// @Success      200  {object}  []domain.BatchResult
+// @Failure      400  {object}  domain.ErrorJSON
+// @Failure      403  {object}  domain.ErrorJSON
+// @Failure      409  {object}  domain.ErrorJSON
+// @Failure      422  {object}  domain.ErrorJSON
+// @Failure      500  {object}  domain.ErrorJSON
+// @Router       /operations/batch [post]
+func (handler *Handler) batchHandler(w http.ResponseWriter, r *http.Request) {
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		processError(w, http.StatusBadRequest, err)
+		return
+	}
+	defer r.Body.Close()
+	input := domain.BatchInput{}
+	if err = json.Unmarshal(data, &input); err != nil {
+		processError(w, http.StatusBadRequest, err)
+		return
+	}
+	results, err := handler.GB.ExecuteBatch(r.Context(), input)
+	if err != nil {
+		handler.log.Printf("BATCH ERROR: <%s>", err)
+		processError(w, http.StatusBadRequest, err)
+		return
+	}
+	respBody, err := json.Marshal(results)
+	if err != nil {
+		processError(w, http.StatusInternalServerError, err)
+		return
+	}
+	// partial batch can contain failed items, so nothing is guaranteed to be created
+	status := http.StatusCreated
+	if input.Mode == domain.PartialMode {
+		status = http.StatusOK
+	}
+	w.WriteHeader(status)
+	if _, err = w.Write(respBody); err != nil {
+		processError(w, http.StatusInternalServerError, err)
+		return
+	}
+}
@@ -2,14 +2,19 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"expvar"
 	"io"
 	"net/http"
+	"strconv"
 	"time"
 
 	// Register swagger staff
 	_ "github.com/agandreev/avito-intern-assignment/docs"
 	"github.com/agandreev/avito-intern-assignment/internal/domain"
+	"github.com/agandreev/avito-intern-assignment/internal/repository"
 	"github.com/agandreev/avito-intern-assignment/internal/service"
+	"github.com/agandreev/avito-intern-assignment/internal/stream"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/sirupsen/logrus"
@@ -18,19 +23,41 @@ import (
 
 const (
 	currency = "currency"
+	limit    = "limit"
+	idParam  = "id"
+
+	defaultLimit = 100
 )
 
 // Handler processes all http handlers and consists of service realization.
 type Handler struct {
-	GB  *service.GrossBook
-	log *logrus.Logger
+	GB     *service.GrossBook
+	Hub    *stream.Hub
+	log    *logrus.Logger
+	config Config
+}
+
+// Config contains Handler's settings.
+type Config struct {
+	// Timeout limits processing time of each request except streams.
+	Timeout time.Duration
+	// StreamHeartbeat is an interval of comments which keep idle streams alive.
+	StreamHeartbeat time.Duration
+	// StreamDuration limits stream's lifetime, clients reconnect after it.
+	StreamDuration time.Duration
+	// AdminTokens maps bearer tokens to operators, admin api rejects all calls without
+	// them.
+	AdminTokens map[string]string
 }
 
 // NewHandler sets all Handler's values and returns Handler's pointer.
-func NewHandler(gb *service.GrossBook, logger *logrus.Logger) *Handler {
+func NewHandler(gb *service.GrossBook, hub *stream.Hub, logger *logrus.Logger,
+	config Config) *Handler {
 	return &Handler{
-		GB:  gb,
-		log: logger,
+		GB:     gb,
+		Hub:    hub,
+		log:    logger,
+		config: config,
 	}
 }
 
@@ -40,19 +67,71 @@ func (handler *Handler) InitRoutes() *chi.Mux {
 	r.Use(middleware.RequestID)
 	r.Use(middleware.Recoverer)
 	r.Use(middleware.Logger)
-	r.Use(middleware.Timeout(10 * time.Second))
+	r.Use(handler.callerMiddleware)
 
-	r.Get("/swagger/*", httpSwagger.WrapHandler)
+	// streams are long-lived, so they aren't limited by timeout
+	r.Get("/users/{id}/stream", handler.streamHandler)
 
-	r.Route("/users", func(r chi.Router) {
-		r.Post("/balance", handler.balanceHandler)
-		r.Post("/history", handler.historyHandler)
-	})
+	r.Group(func(r chi.Router) {
+		r.Use(middleware.Timeout(handler.config.Timeout))
+
+		r.Get("/swagger/*", httpSwagger.WrapHandler)
+		// metrics of background jobs and exchange rates provider expose process' state
+		// (e.g. command line with secrets), so they're served to operators only
+		r.With(handler.adminMiddleware).Handle("/debug/vars", expvar.Handler())
+		r.Get(healthPath, handler.healthHandler)
+
+		r.Route("/users", func(r chi.Router) {
+			r.Post("/", handler.createUserHandler)
+			r.With(handler.auditMiddleware).Post("/balance", handler.balanceHandler)
+			r.Post("/history", handler.historyHandler)
+		})
+
+		r.Route("/operations", func(r chi.Router) {
+			r.Use(handler.auditMiddleware)
+			r.Use(handler.idempotencyMiddleware)
+			r.With(handler.versionMiddleware).Post("/deposit", handler.depositHandler)
+			r.With(handler.versionMiddleware).Post("/withdraw", handler.withdrawHandler)
+			r.With(handler.versionMiddleware).Post("/transfer", handler.transferHandler)
+			r.Post("/batch", handler.batchHandler)
+		})
 
-	r.Route("/operations", func(r chi.Router) {
-		r.Post("/deposit", handler.depositHandler)
-		r.Post("/withdraw", handler.withdrawHandler)
-		r.Post("/transfer", handler.transferHandler)
+		r.Get("/rates/convert", handler.convertHandler)
+
+		// webhooks receive operations of any user, so they're managed by operators
+		r.Route("/webhooks", func(r chi.Router) {
+			r.Use(handler.auditMiddleware)
+			r.Use(handler.adminMiddleware)
+			r.Post("/", handler.addWebhookHandler)
+			r.Get("/{id}/deliveries", handler.deliveriesHandler)
+		})
+
+		r.Route("/admin", func(r chi.Router) {
+			r.Use(handler.auditMiddleware)
+			r.Use(handler.adminMiddleware)
+			r.Get("/limits/{id}", handler.limitsHandler)
+			r.Put("/limits/{id}", handler.setLimitsHandler)
+			r.Delete("/limits/{id}", handler.deleteLimitsHandler)
+			r.Get("/users/{id}/status", handler.statusChangesHandler)
+			r.Put("/users/{id}/status", handler.changeStatusHandler)
+			r.With(handler.idempotencyMiddleware).Post("/users/{id}/adjustments",
+				handler.requestAdjustmentHandler)
+			r.Get("/adjustments", handler.adjustmentsHandler)
+			r.Get("/adjustments/{id}", handler.adjustmentHandler)
+			r.Post("/adjustments/{id}/approve", handler.approveAdjustmentHandler)
+			r.Post("/adjustments/{id}/reject", handler.rejectAdjustmentHandler)
+			r.Get("/audit", handler.auditHandler)
+			r.Get("/audit/verify", handler.verifyAuditHandler)
+		})
+
+		r.Route("/schedules", func(r chi.Router) {
+			r.Post("/", handler.addScheduleHandler)
+			r.Get("/", handler.userSchedulesHandler)
+			r.Get("/{id}", handler.scheduleHandler)
+			r.Put("/{id}", handler.updateScheduleHandler)
+			r.Delete("/{id}", handler.deleteScheduleHandler)
+			r.Get("/{id}/executions", handler.executionsHandler)
+		})
 	})
 
 	return r
@@ -60,12 +139,13 @@ func (handler *Handler) InitRoutes() *chi.Mux {
 
 // balanceHandler
 // @Summary      shows user's balance
-// @Description  returns user's money amount by given id
+// @Description  returns user's money amount by given id, its version is sent as ETag
 // @Tags         users
 // @Accept       json
 // @Produce      json
 // @Param        id   body      domain.User  true  "User ID (amount is redundant)"
 // @Success      200  {object}  domain.User
+// @Header       200  {string}  ETag  "Balance version"
 // @Failure      400  {object}  domain.ErrorJSON
 // @Failure      500  {object}  domain.ErrorJSON
 // @Router       /users/balance [post]
@@ -81,7 +161,7 @@ func (handler *Handler) balanceHandler(w http.ResponseWriter, r *http.Request) {
 		processError(w, http.StatusBadRequest, err)
 		return
 	}
-	balance, err := handler.GB.Balance(user.ID)
+	balance, err := handler.GB.Balance(r.Context(), user.ID)
 	if err != nil {
 		handler.log.Printf("BALANCE ERROR: <%s>", err)
 		processError(w, http.StatusBadRequest, err)
@@ -92,6 +172,7 @@ func (handler *Handler) balanceHandler(w http.ResponseWriter, r *http.Request) {
 		processError(w, http.StatusInternalServerError, err)
 		return
 	}
+	w.Header().Set(etagHeader, etag(balance.Version))
 	w.WriteHeader(http.StatusOK)
 	if _, err = w.Write(respBody); err != nil {
 		processError(w, http.StatusInternalServerError, err)
@@ -106,9 +187,17 @@ func (handler *Handler) balanceHandler(w http.ResponseWriter, r *http.Request) {
 // @Accept       json
 // @Produce      json
 // @Param        input   body      domain.OperationInput  true  "Operation parameters (receiver id is redundant)"
+// @Param        currency  query  string  false  "Deposit currency"
+// @Param        Idempotency-Key  header  string  false  "Key of retried operation"
+// @Param        If-Match  header  string  false  "Expected balance version of initiator"
 // @Success      201  {object}  domain.Operation
 // @Failure      400  {object}  domain.ErrorJSON
+// @Failure      403  {object}  domain.ErrorJSON
+// @Failure      409  {object}  domain.ErrorJSON
+// @Failure      412  {object}  domain.ErrorJSON
+// @Failure      422  {object}  domain.ErrorJSON
 // @Failure      500  {object}  domain.ErrorJSON
+// @Failure      503  {object}  domain.ErrorJSON
 // @Router       /operations/deposit [post]
 func (handler *Handler) depositHandler(w http.ResponseWriter, r *http.Request) {
 	data, err := io.ReadAll(r.Body)
@@ -122,8 +211,8 @@ func (handler *Handler) depositHandler(w http.ResponseWriter, r *http.Request) {
 		processError(w, http.StatusBadRequest, err)
 		return
 	}
-	operationInfo, err := handler.GB.DepositMoney(
-		input.InitiatorID, input.Amount)
+	operationInfo, err := handler.GB.DepositMoney(r.Context(),
+		input.InitiatorID, input.Amount, r.URL.Query().Get(currency))
 	if err != nil {
 		handler.log.Printf("DEPOSIT ERROR: <%s>", err)
 		processError(w, http.StatusBadRequest, err)
@@ -149,9 +238,16 @@ func (handler *Handler) depositHandler(w http.ResponseWriter, r *http.Request) {
 // @Produce      json
 // @Param        input   	body      domain.OperationInput true  	"Operation parameters (receiver id is redundant)"
 // @Param        currency   query     string  				false   "Withdraw currency"
+// @Param        Idempotency-Key  header  string  false  "Key of retried operation"
+// @Param        If-Match  header  string  false  "Expected balance version of initiator"
 // @Success      201  		{object}  domain.Operation
 // @Failure      400  		{object}  domain.ErrorJSON
+// @Failure      403  		{object}  domain.ErrorJSON
+// @Failure      409  		{object}  domain.ErrorJSON
+// @Failure      412  		{object}  domain.ErrorJSON
+// @Failure      422  		{object}  domain.ErrorJSON
 // @Failure      500  		{object}  domain.ErrorJSON
+// @Failure      503  		{object}  domain.ErrorJSON
 // @Router       /operations/withdraw [post]
 func (handler *Handler) withdrawHandler(w http.ResponseWriter, r *http.Request) {
 	var currencyValue string
@@ -171,7 +267,7 @@ func (handler *Handler) withdrawHandler(w http.ResponseWriter, r *http.Request)
 		processError(w, http.StatusBadRequest, err)
 		return
 	}
-	operationInfo, err := handler.GB.WithdrawMoney(
+	operationInfo, err := handler.GB.WithdrawMoney(r.Context(),
 		input.InitiatorID, input.Amount, currencyValue)
 	if err != nil {
 		handler.log.Printf("WITHDRAW ERROR: <%s>", err)
@@ -197,9 +293,17 @@ func (handler *Handler) withdrawHandler(w http.ResponseWriter, r *http.Request)
 // @Accept       json
 // @Produce      json
 // @Param        input   	body      domain.OperationInput true  	"Operation parameters"
+// @Param        currency   query     string  				false   "Transfer currency"
+// @Param        Idempotency-Key  header  string  false  "Key of retried operation"
+// @Param        If-Match  header  string  false  "Expected balance version of initiator"
 // @Success      201  		{object}  domain.Operation
 // @Failure      400  		{object}  domain.ErrorJSON
+// @Failure      403  		{object}  domain.ErrorJSON
+// @Failure      409  		{object}  domain.ErrorJSON
+// @Failure      412  		{object}  domain.ErrorJSON
+// @Failure      422  		{object}  domain.ErrorJSON
 // @Failure      500  		{object}  domain.ErrorJSON
+// @Failure      503  		{object}  domain.ErrorJSON
 // @Router       /operations/transfer [post]
 func (handler *Handler) transferHandler(w http.ResponseWriter, r *http.Request) {
 	data, err := io.ReadAll(r.Body)
@@ -213,8 +317,8 @@ func (handler *Handler) transferHandler(w http.ResponseWriter, r *http.Request)
 		processError(w, http.StatusBadRequest, err)
 		return
 	}
-	operationInfo, err := handler.GB.TransferMoney(
-		input.InitiatorID, input.ReceiverID, input.Amount)
+	operationInfo, err := handler.GB.TransferMoney(r.Context(),
+		input.InitiatorID, input.ReceiverID, input.Amount, r.URL.Query().Get(currency))
 	if err != nil {
 		handler.log.Printf("TRANSFER ERROR: <%s>", err)
 		processError(w, http.StatusBadRequest, err)
@@ -255,8 +359,8 @@ func (handler *Handler) historyHandler(w http.ResponseWriter, r *http.Request) {
 		processError(w, http.StatusBadRequest, err)
 		return
 	}
-	operationInfo, err := handler.GB.History(
-		input.ID, input.Quantity, input.Mode)
+	operationInfo, err := handler.GB.History(r.Context(),
+		input.ID, input.Quantity, input.Mode, input.OperationFilter)
 	if err != nil {
 		handler.log.Printf("HISTORY ERROR: <%s>", err)
 		processError(w, http.StatusBadRequest, err)
@@ -274,12 +378,69 @@ func (handler *Handler) historyHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// processError sends status code with error text.
+// processError sends status code with error text and code of known errors. Exceeded
+// limits are always reported as unprocessable entity with remaining allowance, operations
+// of frozen and closed accounts and self-approvals are forbidden, reviewed adjustments
+// conflict, changed balance versions fail preconditions, unavailable exchange rates
+// provider makes service unavailable. Unclassified errors given as bad requests are
+// reported as internal ones.
 func processError(w http.ResponseWriter, status int, err error) {
+	errorJSON := domain.ErrorJSON{Message: err.Error(), Code: domain.ErrorCode(err)}
+	var limitError domain.LimitExceededError
+	switch {
+	case errors.As(err, &limitError):
+		status = http.StatusUnprocessableEntity
+		errorJSON.Limit = limitError.Limit
+		errorJSON.Remaining = &limitError.Remaining
+	case errors.Is(err, domain.ErrAccountFrozen), errors.Is(err, domain.ErrAccountClosed),
+		errors.Is(err, domain.ErrSelfApproval):
+		status = http.StatusForbidden
+	case errors.Is(err, domain.ErrUnauthenticated):
+		status = http.StatusUnauthorized
+	case errors.Is(err, domain.ErrNoSuchAdjustment):
+		status = http.StatusNotFound
+	case errors.Is(err, domain.ErrAdjustmentNotPending),
+		errors.Is(err, domain.ErrAdjustmentExpired):
+		status = http.StatusConflict
+	case errors.Is(err, domain.ErrIdempotencyKeyReused):
+		status = http.StatusUnprocessableEntity
+	case errors.Is(err, domain.ErrRequestInProgress):
+		status = http.StatusConflict
+	case errors.Is(err, domain.ErrVersionConflict):
+		status = http.StatusPreconditionFailed
+	case errors.Is(err, domain.ErrExchangeUnavailable),
+		errors.Is(err, domain.ErrExchangeQuotaExceeded):
+		status = http.StatusServiceUnavailable
+	case errors.Is(err, repository.ErrStatusChanged):
+		status = http.StatusConflict
+	case status == http.StatusBadRequest && !isClientError(err):
+		// unclassified errors are server's ones, e.g. failed db queries, so they're
+		// retried by clients and aren't stored with idempotency keys
+		status = http.StatusInternalServerError
+	}
 	w.WriteHeader(status)
-	respBody, err := json.Marshal(domain.ErrorJSON{Message: err.Error()})
+	respBody, err := json.Marshal(errorJSON)
 	if err != nil {
 		return
 	}
 	_, _ = w.Write(respBody)
 }
+
+// isClientError returns true if err is caused by request: known domain errors, lack of
+// requested entities and malformed input.
+func isClientError(err error) bool {
+	var (
+		limitError  domain.LimitExceededError
+		syntaxError *json.SyntaxError
+		typeError   *json.UnmarshalTypeError
+		numError    *strconv.NumError
+		timeError   *time.ParseError
+	)
+	return domain.ErrorCode(err) != "" || errors.As(err, &limitError) ||
+		errors.Is(err, repository.ErrNoOperations) ||
+		errors.Is(err, repository.ErrNoSuchSchedule) ||
+		errors.Is(err, repository.ErrNoSuchWebhook) ||
+		errors.Is(err, repository.ErrIncorrectLimit) ||
+		errors.As(err, &syntaxError) || errors.As(err, &typeError) ||
+		errors.As(err, &numError) || errors.As(err, &timeError)
+}
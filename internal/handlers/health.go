@@ -0,0 +1,30 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// healthPath is polled by orchestrator, so its calls aren't audited.
+const healthPath = "/health"
+
+// healthHandler
+// @Summary      shows service's health
+// @Description  returns status which is degraded while exchange rates provider is unavailable, and provider's circuit breaker and quota
+// @Tags         health
+// @Produce      json
+// @Success      200  {object}  domain.Health
+// @Failure      500  {object}  domain.ErrorJSON
+// @Router       /health [get]
+func (handler *Handler) healthHandler(w http.ResponseWriter, r *http.Request) {
+	respBody, err := json.Marshal(handler.GB.Health())
+	if err != nil {
+		processError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	if _, err = w.Write(respBody); err != nil {
+		processError(w, http.StatusInternalServerError, err)
+		return
+	}
+}
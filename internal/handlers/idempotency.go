@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/agandreev/avito-intern-assignment/internal/domain"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+const (
+	idempotencyKeyHeader = "Idempotency-Key"
+	// replayedHeader marks responses which are sent again for retried requests.
+	replayedHeader = "Idempotent-Replayed"
+)
+
+// idempotencyMiddleware processes requests with Idempotency-Key header only once. The
+// first response is stored and sent to all retries of the same request, server errors
+// aren't stored, so such requests can be retried.
+func (handler *Handler) idempotencyMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(idempotencyKeyHeader)
+		if key == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			processError(w, http.StatusBadRequest, err)
+			return
+		}
+		_ = r.Body.Close()
+		r.Body = io.NopCloser(bytes.NewReader(data))
+		record, err := domain.NewIdempotencyRecord(key, r.Method+" "+r.URL.RequestURI(),
+			data, time.Now())
+		if err != nil {
+			processError(w, http.StatusBadRequest, err)
+			return
+		}
+		stored, err := handler.GB.ClaimIdempotencyKey(r.Context(), *record)
+		if err != nil {
+			handler.log.Printf("IDEMPOTENCY ERROR: <%s>", err)
+			processError(w, http.StatusInternalServerError, err)
+			return
+		}
+		if stored != nil {
+			w.Header().Set(replayedHeader, "true")
+			w.WriteHeader(stored.Status)
+			_, _ = w.Write(stored.Response)
+			return
+		}
+
+		// key is released if handler panics, so request can be retried
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				_ = handler.GB.ReleaseIdempotencyKey(r.Context(), key)
+				panic(recovered)
+			}
+		}()
+		// response is copied to be stored
+		var response bytes.Buffer
+		wrapped := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		wrapped.Tee(&response)
+		next.ServeHTTP(wrapped, r)
+
+		status := wrapped.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		if status >= http.StatusInternalServerError {
+			err = handler.GB.ReleaseIdempotencyKey(r.Context(), key)
+		} else {
+			err = handler.GB.CompleteIdempotencyKey(r.Context(), key, status, response.Bytes())
+		}
+		if err != nil {
+			handler.log.Printf("IDEMPOTENCY ERROR: <%s>", err)
+		}
+	})
+}
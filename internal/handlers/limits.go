@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/agandreev/avito-intern-assignment/internal/domain"
+	"github.com/go-chi/chi/v5"
+)
+
+// limitsHandler
+// @Summary      returns user's limits
+// @Description  returns user's own limits and limits applied after merging with global ones
+// @Tags         admin
+// @Produce      json
+// @Param        id   path      int  true  "User ID"
+// @Success      200  {object}  domain.UserLimits
+// @Failure      400  {object}  domain.ErrorJSON
+// @Failure      500  {object}  domain.ErrorJSON
+// @Router       /admin/limits/{id} [get]
+func (handler *Handler) limitsHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, idParam), 10, 64)
+	if err != nil {
+		processError(w, http.StatusBadRequest, err)
+		return
+	}
+	limits, err := handler.GB.UserLimits(r.Context(), id)
+	if err != nil {
+		handler.log.Printf("LIMITS ERROR: <%s>", err)
+		processError(w, http.StatusBadRequest, err)
+		return
+	}
+	respBody, err := json.Marshal(limits)
+	if err != nil {
+		processError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	if _, err = w.Write(respBody); err != nil {
+		processError(w, http.StatusInternalServerError, err)
+		return
+	}
+}
+
+// setLimitsHandler
+// @Summary      overrides user's limits
+// @Description  replaces user's own limits, unset limits are taken from global ones
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id      path      int            true  "User ID"
+// @Param        input   body      domain.Limits  true  "User's own limits"
+// @Success      200  {object}  domain.UserLimits
+// @Failure      400  {object}  domain.ErrorJSON
+// @Failure      500  {object}  domain.ErrorJSON
+// @Router       /admin/limits/{id} [put]
+func (handler *Handler) setLimitsHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, idParam), 10, 64)
+	if err != nil {
+		processError(w, http.StatusBadRequest, err)
+		return
+	}
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		processError(w, http.StatusBadRequest, err)
+		return
+	}
+	defer r.Body.Close()
+	input := domain.Limits{}
+	if err = json.Unmarshal(data, &input); err != nil {
+		processError(w, http.StatusBadRequest, err)
+		return
+	}
+	limits, err := handler.GB.SetUserLimits(r.Context(), id, input)
+	if err != nil {
+		handler.log.Printf("SET LIMITS ERROR: <%s>", err)
+		processError(w, http.StatusBadRequest, err)
+		return
+	}
+	respBody, err := json.Marshal(limits)
+	if err != nil {
+		processError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	if _, err = w.Write(respBody); err != nil {
+		processError(w, http.StatusInternalServerError, err)
+		return
+	}
+}
+
+// deleteLimitsHandler
+// @Summary      removes user's limits
+// @Description  removes user's own limits, so only global ones are applied
+// @Tags         admin
+// @Param        id   path      int  true  "User ID"
+// @Success      204
+// @Failure      400  {object}  domain.ErrorJSON
+// @Router       /admin/limits/{id} [delete]
+func (handler *Handler) deleteLimitsHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, idParam), 10, 64)
+	if err != nil {
+		processError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err = handler.GB.DeleteUserLimits(r.Context(), id); err != nil {
+		handler.log.Printf("DELETE LIMITS ERROR: <%s>", err)
+		processError(w, http.StatusBadRequest, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/agandreev/avito-intern-assignment/internal/domain"
+)
+
+const (
+	fromParam   = "from"
+	toParam     = "to"
+	amountParam = "amount"
+)
+
+// convertHandler
+// @Summary      converts money between currencies
+// @Description  converts amount of any supported currency to another one by current rates without any operation
+// @Tags         rates
+// @Produce      json
+// @Param        from    query     string  true  "Source currency"
+// @Param        to      query     string  true  "Target currency"
+// @Param        amount  query     number  true  "Amount in source currency"
+// @Success      200  {object}  domain.Conversion
+// @Failure      400  {object}  domain.ErrorJSON
+// @Failure      500  {object}  domain.ErrorJSON
+// @Failure      503  {object}  domain.ErrorJSON
+// @Router       /rates/convert [get]
+func (handler *Handler) convertHandler(w http.ResponseWriter, r *http.Request) {
+	input := domain.ConversionInput{
+		From: r.URL.Query().Get(fromParam),
+		To:   r.URL.Query().Get(toParam),
+	}
+	var err error
+	if value := r.URL.Query().Get(amountParam); value != "" {
+		if input.Amount, err = strconv.ParseFloat(value, 64); err != nil {
+			processError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+	conversion, err := handler.GB.Convert(r.Context(), input)
+	if err != nil {
+		handler.log.Printf("CONVERT ERROR: <%s>", err)
+		processError(w, http.StatusBadRequest, err)
+		return
+	}
+	respBody, err := json.Marshal(conversion)
+	if err != nil {
+		processError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	if _, err = w.Write(respBody); err != nil {
+		processError(w, http.StatusInternalServerError, err)
+		return
+	}
+}
@@ -0,0 +1,234 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/agandreev/avito-intern-assignment/internal/domain"
+	"github.com/go-chi/chi/v5"
+)
+
+const userIDParam = "user_id"
+
+// addScheduleHandler
+// @Summary      schedules transfer
+// @Description  creates one-off (by run_at) or recurring (by cron) transfer between users
+// @Tags         schedules
+// @Accept       json
+// @Produce      json
+// @Param        input   body      domain.Schedule  true  "Users, amount, cron or run_at, and activity"
+// @Success      201  {object}  domain.Schedule
+// @Failure      400  {object}  domain.ErrorJSON
+// @Failure      500  {object}  domain.ErrorJSON
+// @Router       /schedules [post]
+func (handler *Handler) addScheduleHandler(w http.ResponseWriter, r *http.Request) {
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		processError(w, http.StatusBadRequest, err)
+		return
+	}
+	defer r.Body.Close()
+	// schedules are active unless the opposite is set
+	input := domain.Schedule{Active: true}
+	if err = json.Unmarshal(data, &input); err != nil {
+		processError(w, http.StatusBadRequest, err)
+		return
+	}
+	schedule, err := handler.GB.AddSchedule(r.Context(), input)
+	if err != nil {
+		handler.log.Printf("ADD SCHEDULE ERROR: <%s>", err)
+		processError(w, http.StatusBadRequest, err)
+		return
+	}
+	respBody, err := json.Marshal(schedule)
+	if err != nil {
+		processError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	if _, err = w.Write(respBody); err != nil {
+		processError(w, http.StatusInternalServerError, err)
+		return
+	}
+}
+
+// userSchedulesHandler
+// @Summary      returns user's schedules
+// @Description  returns all transfers scheduled by user
+// @Tags         schedules
+// @Produce      json
+// @Param        user_id   query     int  true  "Initiator ID"
+// @Success      200  {object}  []domain.Schedule
+// @Failure      400  {object}  domain.ErrorJSON
+// @Failure      500  {object}  domain.ErrorJSON
+// @Router       /schedules [get]
+func (handler *Handler) userSchedulesHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.ParseInt(r.URL.Query().Get(userIDParam), 10, 64)
+	if err != nil {
+		processError(w, http.StatusBadRequest, err)
+		return
+	}
+	schedules, err := handler.GB.UserSchedules(r.Context(), userID)
+	if err != nil {
+		handler.log.Printf("SCHEDULES ERROR: <%s>", err)
+		processError(w, http.StatusBadRequest, err)
+		return
+	}
+	respBody, err := json.Marshal(schedules)
+	if err != nil {
+		processError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	if _, err = w.Write(respBody); err != nil {
+		processError(w, http.StatusInternalServerError, err)
+		return
+	}
+}
+
+// scheduleHandler
+// @Summary      returns schedule
+// @Description  returns scheduled transfer with its next run
+// @Tags         schedules
+// @Produce      json
+// @Param        id   path      int  true  "Schedule ID"
+// @Success      200  {object}  domain.Schedule
+// @Failure      400  {object}  domain.ErrorJSON
+// @Failure      500  {object}  domain.ErrorJSON
+// @Router       /schedules/{id} [get]
+func (handler *Handler) scheduleHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, idParam), 10, 64)
+	if err != nil {
+		processError(w, http.StatusBadRequest, err)
+		return
+	}
+	schedule, err := handler.GB.Schedule(r.Context(), id)
+	if err != nil {
+		handler.log.Printf("SCHEDULE ERROR: <%s>", err)
+		processError(w, http.StatusBadRequest, err)
+		return
+	}
+	respBody, err := json.Marshal(schedule)
+	if err != nil {
+		processError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	if _, err = w.Write(respBody); err != nil {
+		processError(w, http.StatusInternalServerError, err)
+		return
+	}
+}
+
+// updateScheduleHandler
+// @Summary      changes schedule
+// @Description  replaces amount, cron or run_at, and activity of scheduled transfer
+// @Tags         schedules
+// @Accept       json
+// @Produce      json
+// @Param        id      path      int              true  "Schedule ID"
+// @Param        input   body      domain.Schedule  true  "Amount, cron or run_at, and activity (users are redundant)"
+// @Success      200  {object}  domain.Schedule
+// @Failure      400  {object}  domain.ErrorJSON
+// @Failure      500  {object}  domain.ErrorJSON
+// @Router       /schedules/{id} [put]
+func (handler *Handler) updateScheduleHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, idParam), 10, 64)
+	if err != nil {
+		processError(w, http.StatusBadRequest, err)
+		return
+	}
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		processError(w, http.StatusBadRequest, err)
+		return
+	}
+	defer r.Body.Close()
+	input := domain.Schedule{Active: true}
+	if err = json.Unmarshal(data, &input); err != nil {
+		processError(w, http.StatusBadRequest, err)
+		return
+	}
+	schedule, err := handler.GB.UpdateSchedule(r.Context(), id, input)
+	if err != nil {
+		handler.log.Printf("UPDATE SCHEDULE ERROR: <%s>", err)
+		processError(w, http.StatusBadRequest, err)
+		return
+	}
+	respBody, err := json.Marshal(schedule)
+	if err != nil {
+		processError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	if _, err = w.Write(respBody); err != nil {
+		processError(w, http.StatusInternalServerError, err)
+		return
+	}
+}
+
+// deleteScheduleHandler
+// @Summary      removes schedule
+// @Description  removes scheduled transfer with its executions
+// @Tags         schedules
+// @Param        id   path      int  true  "Schedule ID"
+// @Success      204
+// @Failure      400  {object}  domain.ErrorJSON
+// @Router       /schedules/{id} [delete]
+func (handler *Handler) deleteScheduleHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, idParam), 10, 64)
+	if err != nil {
+		processError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err = handler.GB.DeleteSchedule(r.Context(), id); err != nil {
+		handler.log.Printf("DELETE SCHEDULE ERROR: <%s>", err)
+		processError(w, http.StatusBadRequest, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// executionsHandler
+// @Summary      returns schedule's executions
+// @Description  returns the latest runs of scheduled transfer with their results
+// @Tags         schedules
+// @Produce      json
+// @Param        id      path      int  true   "Schedule ID"
+// @Param        limit   query     int  false  "Executions quantity (100 by default)"
+// @Success      200  {object}  []domain.ScheduleExecution
+// @Failure      400  {object}  domain.ErrorJSON
+// @Failure      500  {object}  domain.ErrorJSON
+// @Router       /schedules/{id}/executions [get]
+func (handler *Handler) executionsHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, idParam), 10, 64)
+	if err != nil {
+		processError(w, http.StatusBadRequest, err)
+		return
+	}
+	limitValue := int64(defaultLimit)
+	if value := r.URL.Query().Get(limit); value != "" {
+		if limitValue, err = strconv.ParseInt(value, 10, 64); err != nil {
+			processError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+	executions, err := handler.GB.ScheduleExecutions(r.Context(), id, limitValue)
+	if err != nil {
+		handler.log.Printf("SCHEDULE EXECUTIONS ERROR: <%s>", err)
+		processError(w, http.StatusBadRequest, err)
+		return
+	}
+	respBody, err := json.Marshal(executions)
+	if err != nil {
+		processError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	if _, err = w.Write(respBody); err != nil {
+		processError(w, http.StatusInternalServerError, err)
+		return
+	}
+}
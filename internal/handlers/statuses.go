@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/agandreev/avito-intern-assignment/internal/domain"
+	"github.com/go-chi/chi/v5"
+)
+
+// changeStatusHandler
+// @Summary      changes user's account status
+// @Description  freezes, unfreezes or closes user's account and records the change in audit trail
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id      path      int                 true  "User ID"
+// @Param        input   body      domain.StatusInput  true  "New status with reason"
+// @Success      200  {object}  domain.User
+// @Failure      400  {object}  domain.ErrorJSON
+// @Failure      403  {object}  domain.ErrorJSON
+// @Failure      500  {object}  domain.ErrorJSON
+// @Router       /admin/users/{id}/status [put]
+func (handler *Handler) changeStatusHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, idParam), 10, 64)
+	if err != nil {
+		processError(w, http.StatusBadRequest, err)
+		return
+	}
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		processError(w, http.StatusBadRequest, err)
+		return
+	}
+	defer r.Body.Close()
+	input := domain.StatusInput{}
+	if err = json.Unmarshal(data, &input); err != nil {
+		processError(w, http.StatusBadRequest, err)
+		return
+	}
+	user, err := handler.GB.ChangeStatus(r.Context(), id, input)
+	if err != nil {
+		handler.log.Printf("CHANGE STATUS ERROR: <%s>", err)
+		processError(w, http.StatusBadRequest, err)
+		return
+	}
+	respBody, err := json.Marshal(user)
+	if err != nil {
+		processError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	if _, err = w.Write(respBody); err != nil {
+		processError(w, http.StatusInternalServerError, err)
+		return
+	}
+}
+
+// statusChangesHandler
+// @Summary      returns user's status changes
+// @Description  returns the latest records of user's account status audit trail
+// @Tags         admin
+// @Produce      json
+// @Param        id      path      int  true   "User ID"
+// @Param        limit   query     int  false  "Changes quantity (100 by default)"
+// @Success      200  {object}  []domain.StatusChange
+// @Failure      400  {object}  domain.ErrorJSON
+// @Failure      500  {object}  domain.ErrorJSON
+// @Router       /admin/users/{id}/status [get]
+func (handler *Handler) statusChangesHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, idParam), 10, 64)
+	if err != nil {
+		processError(w, http.StatusBadRequest, err)
+		return
+	}
+	limitValue := int64(defaultLimit)
+	if value := r.URL.Query().Get(limit); value != "" {
+		if limitValue, err = strconv.ParseInt(value, 10, 64); err != nil {
+			processError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+	changes, err := handler.GB.StatusChanges(r.Context(), id, limitValue)
+	if err != nil {
+		handler.log.Printf("STATUS CHANGES ERROR: <%s>", err)
+		processError(w, http.StatusBadRequest, err)
+		return
+	}
+	respBody, err := json.Marshal(changes)
+	if err != nil {
+		processError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	if _, err = w.Write(respBody); err != nil {
+		processError(w, http.StatusInternalServerError, err)
+		return
+	}
+}
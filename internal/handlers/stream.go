@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/agandreev/avito-intern-assignment/internal/domain"
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	lastEventIDHeader = "Last-Event-ID"
+	lastEventID       = "last_event_id"
+
+	balanceEvent = "balance"
+	// retryDelay is reconnection delay for clients in milliseconds
+	retryDelay = 1000
+	// resumeLimit limits quantity of missed events sent on reconnection
+	resumeLimit = 1000
+)
+
+var errStreamingUnsupported = errors.New("streaming is unsupported")
+
+// streamHandler
+// @Summary      streams user's balance
+// @Description  sends current balance and then BalanceChanged event after each committed operation as server-sent events, missed events are resent after Last-Event-ID
+// @Tags         users
+// @Produce      text/event-stream
+// @Param        id              path      int     true   "User ID"
+// @Param        Last-Event-ID   header    int     false  "The last received event id"
+// @Param        last_event_id   query     int     false  "The last received event id"
+// @Success      200  {object}  domain.BalanceChange
+// @Failure      400  {object}  domain.ErrorJSON
+// @Failure      500  {object}  domain.ErrorJSON
+// @Router       /users/{id}/stream [get]
+func (handler *Handler) streamHandler(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		processError(w, http.StatusInternalServerError, errStreamingUnsupported)
+		return
+	}
+	id, err := strconv.ParseInt(chi.URLParam(r, idParam), 10, 64)
+	if err != nil {
+		processError(w, http.StatusBadRequest, err)
+		return
+	}
+	lastID, err := parseLastEventID(r)
+	if err != nil {
+		processError(w, http.StatusBadRequest, err)
+		return
+	}
+	// subscribe before loading, so no event is lost between them
+	subscription := handler.Hub.Subscribe(id)
+	defer subscription.Close()
+	user, err := handler.GB.Balance(r.Context(), id)
+	if err != nil {
+		handler.log.Printf("STREAM ERROR: <%s>", err)
+		processError(w, http.StatusBadRequest, err)
+		return
+	}
+	var missed []domain.Event
+	if lastID != 0 {
+		if missed, err = handler.GB.BalanceEvents(r.Context(), id, lastID,
+			resumeLimit); err != nil {
+			handler.log.Printf("STREAM ERROR: <%s>", err)
+			processError(w, http.StatusInternalServerError, err)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	current, err := json.Marshal(user)
+	if err != nil {
+		return
+	}
+	if _, err = fmt.Fprintf(w, "retry: %d\nevent: %s\ndata: %s\n\n",
+		retryDelay, balanceEvent, current); err != nil {
+		return
+	}
+	for _, event := range missed {
+		if err = writeEvent(w, event); err != nil {
+			return
+		}
+		lastID = event.ID
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(handler.config.StreamHeartbeat)
+	defer heartbeat.Stop()
+	deadline := time.NewTimer(handler.config.StreamDuration)
+	defer deadline.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-deadline.C:
+			return
+		case <-heartbeat.C:
+			_, err = fmt.Fprint(w, ": heartbeat\n\n")
+		case event, ok := <-subscription.C:
+			// closed subscription means slow client, it will resume after reconnection
+			if !ok {
+				return
+			}
+			if event.ID <= lastID {
+				continue
+			}
+			err = writeEvent(w, event)
+			lastID = event.ID
+		}
+		if err != nil {
+			return
+		}
+		flusher.Flush()
+	}
+}
+
+// parseLastEventID reads the last received event id from header or query.
+func parseLastEventID(r *http.Request) (int64, error) {
+	value := r.Header.Get(lastEventIDHeader)
+	if value == "" {
+		value = r.URL.Query().Get(lastEventID)
+	}
+	if value == "" {
+		return 0, nil
+	}
+	id, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || id < 0 {
+		return 0, fmt.Errorf("incorrect last event id: %s", value)
+	}
+	return id, nil
+}
+
+// writeEvent writes domain.Event as server-sent event.
+func writeEvent(w http.ResponseWriter, event domain.Event) error {
+	_, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type,
+		event.Payload)
+	return err
+}
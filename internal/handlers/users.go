@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/agandreev/avito-intern-assignment/internal/domain"
+)
+
+// createUserHandler
+// @Summary      creates user
+// @Description  creates user's account with zero balance and optional metadata
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param        input   body      domain.UserInput  true  "User ID and metadata"
+// @Success      201  {object}  domain.User
+// @Failure      400  {object}  domain.ErrorJSON
+// @Failure      409  {object}  domain.ErrorJSON
+// @Failure      500  {object}  domain.ErrorJSON
+// @Router       /users [post]
+func (handler *Handler) createUserHandler(w http.ResponseWriter, r *http.Request) {
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		processError(w, http.StatusBadRequest, err)
+		return
+	}
+	defer r.Body.Close()
+	input := domain.UserInput{}
+	if err = json.Unmarshal(data, &input); err != nil {
+		processError(w, http.StatusBadRequest, err)
+		return
+	}
+	user, err := handler.GB.CreateUser(r.Context(), input)
+	if err != nil {
+		handler.log.Printf("CREATE USER ERROR: <%s>", err)
+		status := http.StatusBadRequest
+		if errors.Is(err, domain.ErrUserExists) {
+			status = http.StatusConflict
+		}
+		processError(w, status, err)
+		return
+	}
+	respBody, err := json.Marshal(user)
+	if err != nil {
+		processError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	if _, err = w.Write(respBody); err != nil {
+		processError(w, http.StatusInternalServerError, err)
+		return
+	}
+}
@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/agandreev/avito-intern-assignment/internal/domain"
+	"github.com/agandreev/avito-intern-assignment/internal/service"
+)
+
+const (
+	etagHeader    = "ETag"
+	ifMatchHeader = "If-Match"
+)
+
+// versionMiddleware passes version from If-Match header to operation, so it's applied
+// only if initiator's balance isn't changed since it was read.
+func (handler *Handler) versionMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		value := r.Header.Get(ifMatchHeader)
+		if value == "" || value == "*" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		version, err := parseETag(value)
+		if err != nil {
+			processError(w, http.StatusBadRequest, err)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(service.WithExpectedVersion(r.Context(), version)))
+	})
+}
+
+// etag returns strong entity tag of user's balance version.
+func etag(version int64) string {
+	return strconv.Quote(strconv.FormatInt(version, 10))
+}
+
+// parseETag returns version from entity tag, weak and unquoted tags are accepted too.
+func parseETag(value string) (int64, error) {
+	value = strings.Trim(strings.TrimPrefix(strings.TrimSpace(value), "W/"), `"`)
+	version, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || version <= 0 {
+		return 0, fmt.Errorf("%s must be balance version: <%w>", ifMatchHeader,
+			domain.ErrIncorrectOperationParams)
+	}
+	return version, nil
+}
@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/agandreev/avito-intern-assignment/internal/domain"
+	"github.com/go-chi/chi/v5"
+)
+
+// addWebhookHandler
+// @Summary      subscribes on operations
+// @Description  creates webhook which receives signed operations of chosen types
+// @Tags         webhooks
+// @Accept       json
+// @Produce      json
+// @Param        input          body    domain.Webhook  true  "Webhook url, event types, secret and optional user id"
+// @Param        Authorization  header  string          true  "Operator's bearer token"
+// @Success      201  {object}  domain.Webhook
+// @Failure      400  {object}  domain.ErrorJSON
+// @Failure      401  {object}  domain.ErrorJSON
+// @Failure      500  {object}  domain.ErrorJSON
+// @Router       /webhooks [post]
+func (handler *Handler) addWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		processError(w, http.StatusBadRequest, err)
+		return
+	}
+	defer r.Body.Close()
+	input := domain.Webhook{}
+	if err = json.Unmarshal(data, &input); err != nil {
+		processError(w, http.StatusBadRequest, err)
+		return
+	}
+	webhook, err := handler.GB.AddWebhook(r.Context(), input)
+	if err != nil {
+		handler.log.Printf("ADD WEBHOOK ERROR: <%s>", err)
+		processError(w, http.StatusBadRequest, err)
+		return
+	}
+	// secret is never returned back
+	webhook.Secret = ""
+	respBody, err := json.Marshal(webhook)
+	if err != nil {
+		processError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	if _, err = w.Write(respBody); err != nil {
+		processError(w, http.StatusInternalServerError, err)
+		return
+	}
+}
+
+// deliveriesHandler
+// @Summary      returns webhook's deliveries
+// @Description  returns the latest deliveries of webhook with their status and attempts
+// @Tags         webhooks
+// @Produce      json
+// @Param        id             path    int     true   "Webhook ID"
+// @Param        limit          query   int     false  "Deliveries quantity (100 by default)"
+// @Param        Authorization  header  string  true   "Operator's bearer token"
+// @Success      200  {object}  []domain.WebhookDelivery
+// @Failure      400  {object}  domain.ErrorJSON
+// @Failure      401  {object}  domain.ErrorJSON
+// @Failure      500  {object}  domain.ErrorJSON
+// @Router       /webhooks/{id}/deliveries [get]
+func (handler *Handler) deliveriesHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, idParam), 10, 64)
+	if err != nil {
+		processError(w, http.StatusBadRequest, err)
+		return
+	}
+	limitValue := int64(defaultLimit)
+	if value := r.URL.Query().Get(limit); value != "" {
+		if limitValue, err = strconv.ParseInt(value, 10, 64); err != nil {
+			processError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+	deliveries, err := handler.GB.WebhookDeliveries(r.Context(), id, limitValue)
+	if err != nil {
+		handler.log.Printf("WEBHOOK DELIVERIES ERROR: <%s>", err)
+		processError(w, http.StatusBadRequest, err)
+		return
+	}
+	respBody, err := json.Marshal(deliveries)
+	if err != nil {
+		processError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	if _, err = w.Write(respBody); err != nil {
+		processError(w, http.StatusInternalServerError, err)
+		return
+	}
+}
@@ -0,0 +1,138 @@
+package reconciler
+
+import (
+	"context"
+	"encoding/json"
+	"expvar"
+	"sync"
+	"time"
+
+	"github.com/agandreev/avito-intern-assignment/internal/domain"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	// metrics are served by expvar handler
+	runs        = expvar.NewInt("reconciliation_runs")
+	failures    = expvar.NewInt("reconciliation_failures")
+	mismatches  = expvar.NewInt("reconciliation_mismatches")
+	corrections = expvar.NewInt("reconciliation_corrections")
+	lastReport  = &reportVar{}
+)
+
+func init() {
+	expvar.Publish("reconciliation_last_report", lastReport)
+}
+
+// LedgerStorage describes balances verification methods.
+type LedgerStorage interface {
+	BalanceMismatches(ctx context.Context) (int64, []domain.BalanceMismatch, error)
+	CorrectBalance(ctx context.Context, userID int64) (*domain.BalanceMismatch, error)
+}
+
+// Config contains reconciliation settings.
+type Config struct {
+	// Interval of periodic reconciliation, zero disables it.
+	Interval time.Duration
+	// Correct enables correction of mismatched balances, otherwise they're only reported.
+	Correct bool
+}
+
+// Reconciler verifies users' balances against their operations.
+type Reconciler struct {
+	storage LedgerStorage
+	config  Config
+	log     *logrus.Logger
+}
+
+// NewReconciler sets Reconciler fields and returns pointer.
+func NewReconciler(storage LedgerStorage, config Config, log *logrus.Logger) *Reconciler {
+	return &Reconciler{
+		storage: storage,
+		config:  config,
+		log:     log,
+	}
+}
+
+// Run reconciles balances by interval until ctx is done.
+func (reconciler *Reconciler) Run(ctx context.Context) {
+	if reconciler.config.Interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(reconciler.config.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if _, err := reconciler.RunOnce(ctx); err != nil {
+			reconciler.log.Printf("RECONCILIATION ERROR: <%s>", err)
+		}
+	}
+}
+
+// RunOnce finds mismatched balances, corrects them if it's enabled and returns report.
+// Failed corrections are reported and don't stop the others.
+func (reconciler *Reconciler) RunOnce(ctx context.Context) (*domain.ReconciliationReport,
+	error) {
+	report := &domain.ReconciliationReport{StartedAt: time.Now()}
+	reconciler.log.Printf("RECONCILIATION: processing...")
+	runs.Add(1)
+	checked, found, err := reconciler.storage.BalanceMismatches(ctx)
+	if err != nil {
+		failures.Add(1)
+		return nil, err
+	}
+	report.UsersChecked = checked
+	report.Mismatches = found
+	for i, mismatch := range report.Mismatches {
+		reconciler.log.Printf("RECONCILIATION: user <%d> has <%f> instead of <%f>",
+			mismatch.UserID, mismatch.Balance, mismatch.Ledger)
+		if !reconciler.config.Correct {
+			continue
+		}
+		corrected, err := reconciler.storage.CorrectBalance(ctx, mismatch.UserID)
+		if err != nil {
+			report.Errors = append(report.Errors, err.Error())
+			continue
+		}
+		// balance may be corrected by another replica
+		if corrected != nil {
+			report.Mismatches[i] = *corrected
+			report.Corrected++
+		}
+	}
+	report.FinishedAt = time.Now()
+	mismatches.Set(int64(len(report.Mismatches)))
+	corrections.Add(int64(report.Corrected))
+	lastReport.set(*report)
+	reconciler.log.Printf("RECONCILIATION: <%d> users were checked, <%d> mismatches, "+
+		"<%d> corrected", report.UsersChecked, len(report.Mismatches), report.Corrected)
+	return report, nil
+}
+
+// reportVar publishes the last domain.ReconciliationReport as expvar.Var.
+type reportVar struct {
+	mu     sync.RWMutex
+	report *domain.ReconciliationReport
+}
+
+// set replaces the last report.
+func (last *reportVar) set(report domain.ReconciliationReport) {
+	last.mu.Lock()
+	defer last.mu.Unlock()
+	last.report = &report
+}
+
+// String returns json of the last report or null.
+func (last *reportVar) String() string {
+	last.mu.RLock()
+	defer last.mu.RUnlock()
+	data, err := json.Marshal(last.report)
+	if err != nil {
+		return "null"
+	}
+	return string(data)
+}
@@ -0,0 +1,136 @@
+package reconciler
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/agandreev/avito-intern-assignment/internal/domain"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/suite"
+)
+
+var errLocked = errors.New("user is locked")
+
+// memoryLedger imitates users' balances and sums of their operations.
+type memoryLedger struct {
+	// checks counts BalanceMismatches calls
+	checks   int
+	balances map[int64]float64
+	ledgers  map[int64]float64
+	// broken users can't be corrected
+	broken map[int64]bool
+}
+
+func (storage *memoryLedger) BalanceMismatches(_ context.Context) (int64,
+	[]domain.BalanceMismatch, error) {
+	storage.checks++
+	mismatches := make([]domain.BalanceMismatch, 0)
+	for id := int64(1); id <= int64(len(storage.balances)); id++ {
+		// mismatches are filtered as by db
+		mismatch := domain.BalanceMismatch{
+			UserID:     id,
+			Balance:    storage.balances[id],
+			Ledger:     storage.ledgers[id],
+			Difference: storage.ledgers[id] - storage.balances[id],
+		}
+		if mismatch.IsSignificant() {
+			mismatches = append(mismatches, mismatch)
+		}
+	}
+	return int64(len(storage.balances)), mismatches, nil
+}
+
+func (storage *memoryLedger) CorrectBalance(_ context.Context, userID int64) (
+	*domain.BalanceMismatch, error) {
+	if storage.broken[userID] {
+		return nil, errLocked
+	}
+	mismatch := domain.BalanceMismatch{
+		UserID:     userID,
+		Balance:    storage.balances[userID],
+		Ledger:     storage.ledgers[userID],
+		Difference: storage.ledgers[userID] - storage.balances[userID],
+		Corrected:  true,
+	}
+	storage.balances[userID] = storage.ledgers[userID]
+	return &mismatch, nil
+}
+
+type ReconcilerSuite struct {
+	suite.Suite
+	Storage *memoryLedger
+	logger  *logrus.Logger
+}
+
+func (suite *ReconcilerSuite) SetupTest() {
+	suite.logger = logrus.New()
+	suite.logger.SetOutput(io.Discard)
+	suite.Storage = &memoryLedger{
+		balances: map[int64]float64{1: 100, 2: 50, 3: 10},
+		ledgers:  map[int64]float64{1: 100, 2: 70, 3: 5},
+		broken:   map[int64]bool{3: true},
+	}
+}
+
+func (suite *ReconcilerSuite) TestReconciler_Report() {
+	reconciler := NewReconciler(suite.Storage, Config{}, suite.logger)
+	report, err := reconciler.RunOnce(context.Background())
+	suite.Require().NoError(err)
+	suite.Equal(int64(3), report.UsersChecked)
+	suite.Require().Len(report.Mismatches, 2)
+	suite.Equal(float64(20), report.Mismatches[0].Difference)
+	suite.Equal(0, report.Corrected)
+	// balances aren't changed without correction
+	suite.Equal(float64(50), suite.Storage.balances[2])
+	suite.Contains(lastReport.String(), `"users_checked":3`)
+}
+
+func (suite *ReconcilerSuite) TestReconciler_Correct() {
+	reconciler := NewReconciler(suite.Storage, Config{Correct: true}, suite.logger)
+	report, err := reconciler.RunOnce(context.Background())
+	suite.Require().NoError(err)
+	suite.Equal(1, report.Corrected)
+	suite.True(report.Mismatches[0].Corrected)
+	suite.Equal(float64(70), suite.Storage.balances[2])
+	// failed correction is reported
+	suite.False(report.Mismatches[1].Corrected)
+	suite.Len(report.Errors, 1)
+}
+
+func (suite *ReconcilerSuite) TestReconciler_RoundingErrors() {
+	// float64 balance of two deposits differs from exact sum of operations
+	user := domain.User{ID: 1}
+	suite.Require().NoError(user.Deposit(0.1))
+	suite.Require().NoError(user.Deposit(0.2))
+	suite.NotEqual(0.3, user.Amount)
+	suite.Storage = &memoryLedger{
+		balances: map[int64]float64{1: user.Amount},
+		ledgers:  map[int64]float64{1: 0.3},
+	}
+	reconciler := NewReconciler(suite.Storage, Config{Correct: true}, suite.logger)
+	report, err := reconciler.RunOnce(context.Background())
+	suite.Require().NoError(err)
+	suite.Empty(report.Mismatches)
+	suite.Zero(report.Corrected)
+}
+
+func (suite *ReconcilerSuite) TestReconciler_Run() {
+	reconciler := NewReconciler(suite.Storage, Config{Interval: time.Millisecond},
+		suite.logger)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	reconciler.Run(ctx)
+	suite.Greater(suite.Storage.checks, 1)
+
+	// zero interval disables periodic job
+	suite.Storage.checks = 0
+	NewReconciler(suite.Storage, Config{}, suite.logger).Run(context.Background())
+	suite.Zero(suite.Storage.checks)
+}
+
+func TestReconcilerSuite(t *testing.T) {
+	suite.Run(t, new(ReconcilerSuite))
+}
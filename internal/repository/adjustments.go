@@ -0,0 +1,171 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/agandreev/avito-intern-assignment/internal/domain"
+	"github.com/jackc/pgx/v4"
+)
+
+const (
+	adjustmentColumns = "id, user_id, amount, reason, ticket, status, created_by, " +
+		"created_at, expires_at, reviewed_by, reviewed_at, comment"
+	insertAdjustmentSQL = "INSERT INTO adjustments(user_id, amount, reason, ticket, status, " +
+		"created_by, created_at, expires_at) VALUES($1, $2, $3, $4, $5, $6, $7, $8) " +
+		"RETURNING id"
+	selectAdjustmentSQL  = "SELECT " + adjustmentColumns + " FROM adjustments WHERE id=$1"
+	selectAdjustmentsSQL = "SELECT " + adjustmentColumns + " FROM adjustments " +
+		"WHERE ($1=0 OR user_id=$1) AND ($2='' OR status=$2) ORDER BY id DESC LIMIT $3"
+	// expired adjustments are reviewed at the moment of expiry
+	expireAdjustmentsSQL = "UPDATE adjustments SET status=$1, reviewed_at=expires_at " +
+		"WHERE status=$2 AND expires_at<=$3"
+	// adjustment is reviewed only once and only before expiry
+	reviewAdjustmentSQL = "UPDATE adjustments SET status=$1, reviewed_by=$2, " +
+		"reviewed_at=$3, comment=$4 WHERE id=$5 AND status=$6 AND expires_at>$3"
+)
+
+// AddAdjustment stores pending domain.AdjustmentRequest and returns it with id.
+func (storage *GrossBookStorage) AddAdjustment(ctx context.Context,
+	request domain.AdjustmentRequest) (*domain.AdjustmentRequest, error) {
+	if storage.pool == nil {
+		return nil, ErrNotConnected
+	}
+	if err := storage.pool.QueryRow(ctx, insertAdjustmentSQL, request.UserID,
+		request.Amount, request.Reason, request.Ticket, request.Status, request.CreatedBy,
+		request.CreatedAt, request.ExpiresAt).Scan(&request.ID); err != nil {
+		return nil, fmt.Errorf("can't add adjustment: <%w>", err)
+	}
+	return &request, nil
+}
+
+// Adjustment returns domain.AdjustmentRequest by id.
+func (storage *GrossBookStorage) Adjustment(ctx context.Context, id int64) (
+	*domain.AdjustmentRequest, error) {
+	if storage.pool == nil {
+		return nil, ErrNotConnected
+	}
+	request, err := scanAdjustment(storage.pool.QueryRow(ctx, selectAdjustmentSQL, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrNoSuchAdjustment
+	}
+	return request, err
+}
+
+// Adjustments returns the latest domain.AdjustmentRequest list filtered by
+// domain.AdjustmentFilter.
+func (storage *GrossBookStorage) Adjustments(ctx context.Context,
+	filter domain.AdjustmentFilter) ([]domain.AdjustmentRequest, error) {
+	if storage.pool == nil {
+		return nil, ErrNotConnected
+	}
+	if filter.Limit <= 0 {
+		return nil, ErrIncorrectLimit
+	}
+	rows, err := storage.pool.Query(ctx, selectAdjustmentsSQL, filter.UserID,
+		string(filter.Status), filter.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("can't read adjustments from db <%w>", err)
+	}
+	defer rows.Close()
+	requests := make([]domain.AdjustmentRequest, 0)
+	for rows.Next() {
+		request, err := scanAdjustment(rows)
+		if err != nil {
+			return nil, err
+		}
+		requests = append(requests, *request)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("can't read adjustments from db <%w>", err)
+	}
+	return requests, nil
+}
+
+// ExpireAdjustments marks pending adjustments which expired before now and returns their
+// quantity.
+func (storage *GrossBookStorage) ExpireAdjustments(ctx context.Context, now time.Time) (
+	int64, error) {
+	if storage.pool == nil {
+		return 0, ErrNotConnected
+	}
+	tag, err := storage.pool.Exec(ctx, expireAdjustmentsSQL, domain.AdjustmentExpired,
+		domain.AdjustmentPending, now)
+	if err != nil {
+		return 0, fmt.Errorf("can't expire adjustments: <%w>", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+// ReviewAdjustment saves checker's decision of pending domain.AdjustmentRequest and adds
+// its operation if it's approved in one transaction. It returns
+// domain.ErrAdjustmentNotPending if adjustment was reviewed or expired concurrently, so
+// it's applied once.
+func (storage *GrossBookStorage) ReviewAdjustment(ctx context.Context,
+	request domain.AdjustmentRequest, operation *domain.Operation) ([]domain.Event, error) {
+	if storage.pool == nil {
+		return nil, ErrNotConnected
+	}
+	if request.ReviewedAt == nil {
+		return nil, fmt.Errorf("adjustment <%d> isn't reviewed: <%w>", request.ID,
+			domain.ErrIncorrectAdjustmentParams)
+	}
+	if operation != nil {
+		if err := operation.Validate(); err != nil {
+			return nil, fmt.Errorf("can't add adjustment operation: <%w>", err)
+		}
+	}
+	var events []domain.Event
+	if err := storage.transaction(ctx, "adjustment", pgx.TxOptions{}, func(tx pgx.Tx) error {
+		events = nil
+		tag, err := tx.Exec(ctx, reviewAdjustmentSQL, request.Status, request.ReviewedBy,
+			*request.ReviewedAt, request.Comment, request.ID, domain.AdjustmentPending)
+		if err != nil {
+			return fmt.Errorf("can't review adjustment: <%w>", err)
+		}
+		if tag.RowsAffected() == 0 {
+			return fmt.Errorf("adjustment <%d> was reviewed concurrently: <%w>", request.ID,
+				domain.ErrAdjustmentNotPending)
+		}
+		if operation == nil {
+			return nil
+		}
+		operations := []domain.Operation{*operation}
+		if err = checkUsers(ctx, tx, map[int64]struct{}{operation.Initiator.ID: {}},
+			operations); err != nil {
+			return fmt.Errorf("error while adding adjustment: <%w>", err)
+		}
+		batch := &operationBatch{}
+		if err = batch.queueOperation(*operation); err != nil {
+			return fmt.Errorf("can't prepare adjustment: <%w>", err)
+		}
+		if err = batch.send(ctx, tx); err != nil {
+			return fmt.Errorf("can't execute adjustment: <%w>", err)
+		}
+		events = batch.events
+		if storage.Config.Notify {
+			return notifyEvents(ctx, tx, batch.events)
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// scanAdjustment reads domain.AdjustmentRequest from row.
+func scanAdjustment(row pgx.Row) (*domain.AdjustmentRequest, error) {
+	var request domain.AdjustmentRequest
+	if err := row.Scan(&request.ID, &request.UserID, &request.Amount, &request.Reason,
+		&request.Ticket, &request.Status, &request.CreatedBy, &request.CreatedAt,
+		&request.ExpiresAt, &request.ReviewedBy, &request.ReviewedAt,
+		&request.Comment); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("can't read adjustment from db <%w>", err)
+	}
+	return &request, nil
+}
@@ -0,0 +1,179 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/agandreev/avito-intern-assignment/internal/domain"
+	"github.com/jackc/pgx/v4"
+)
+
+const (
+	// auditLockKey is a key of postgres advisory lock which serializes chain's appends.
+	auditLockKey = 20220115
+	// maxAuditGroup limits entries appended by one transaction.
+	maxAuditGroup = 100
+
+	auditColumns = "id, actor, claimed_actor, source_ip, request_id, action, endpoint, " +
+		"payload, status, outcome, created_at, prev_hash, hash"
+	lastAuditHashSQL = "SELECT hash FROM audit_log ORDER BY id DESC LIMIT 1"
+	insertAuditSQL   = "INSERT INTO audit_log(actor, claimed_actor, source_ip, request_id, " +
+		"action, endpoint, payload, status, outcome, created_at, prev_hash, hash) " +
+		"VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12) RETURNING id"
+	selectAuditSQL = "SELECT " + auditColumns + " FROM audit_log " +
+		"WHERE id>$1 AND ($2='' OR actor=$2) ORDER BY id LIMIT $3"
+)
+
+// AddAuditEntry chains domain.AuditEntry to the last stored one and appends it.
+// Appends are serialized, so the chain has no forks. Concurrent appends are grouped and
+// each group is appended by one transaction.
+func (storage *GrossBookStorage) AddAuditEntry(ctx context.Context,
+	entry domain.AuditEntry) (*domain.AuditEntry, error) {
+	if storage.pool == nil {
+		return nil, ErrNotConnected
+	}
+	request := &auditAppend{entry: entry, done: make(chan bool, 1)}
+	// the rest of appends wait for their group's leader
+	if !storage.audit.join(request) && !<-request.done {
+		return request.result()
+	}
+	group := storage.audit.group()
+	err := storage.appendAuditEntries(ctx, group)
+	for _, appended := range group {
+		appended.err = err
+		if appended != request {
+			appended.done <- false
+		}
+	}
+	storage.audit.handOver()
+	return request.result()
+}
+
+// appendAuditEntries chains group of appends to the last stored entry and inserts them
+// by one batch while chain's appends are locked.
+func (storage *GrossBookStorage) appendAuditEntries(ctx context.Context,
+	group []*auditAppend) error {
+	return storage.transaction(ctx, "audit", pgx.TxOptions{}, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, "SELECT pg_advisory_xact_lock($1)",
+			auditLockKey); err != nil {
+			return fmt.Errorf("can't take audit lock: <%w>", err)
+		}
+		var prevHash string
+		if err := tx.QueryRow(ctx, lastAuditHashSQL).Scan(&prevHash); err != nil &&
+			!errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("can't read audit chain: <%w>", err)
+		}
+		batch := &pgx.Batch{}
+		for _, appended := range group {
+			entry := &appended.entry
+			entry.Chain(prevHash)
+			prevHash = entry.Hash
+			batch.Queue(insertAuditSQL, entry.Actor, entry.ClaimedActor, entry.SourceIP,
+				entry.RequestID, entry.Action, entry.Endpoint, entry.Payload, entry.Status, entry.Outcome,
+				entry.CreatedAt, entry.PrevHash, entry.Hash)
+		}
+		results := tx.SendBatch(ctx, batch)
+		for _, appended := range group {
+			if err := results.QueryRow().Scan(&appended.entry.ID); err != nil {
+				_ = results.Close()
+				return fmt.Errorf("can't add audit entry: <%w>", err)
+			}
+		}
+		return results.Close()
+	})
+}
+
+// auditAppender groups concurrent appends, so chain's lock is taken once per group.
+// Append which finds no running group leads the next one: it appends pending entries
+// and passes leadership to the first entry added meanwhile.
+type auditAppender struct {
+	mu      sync.Mutex
+	pending []*auditAppend
+	leading bool
+}
+
+// auditAppend is domain.AuditEntry waiting for its group.
+type auditAppend struct {
+	entry domain.AuditEntry
+	err   error
+	// done receives true if append leads the next group and false if it's appended
+	done chan bool
+}
+
+// join adds request to pending appends and returns true if it leads the next group.
+// Pending appends always have a leader, so it's the first of them.
+func (appender *auditAppender) join(request *auditAppend) bool {
+	appender.mu.Lock()
+	defer appender.mu.Unlock()
+	appender.pending = append(appender.pending, request)
+	if appender.leading {
+		return false
+	}
+	appender.leading = true
+	return true
+}
+
+// group takes up to maxAuditGroup pending appends starting from the leader's one.
+func (appender *auditAppender) group() []*auditAppend {
+	appender.mu.Lock()
+	defer appender.mu.Unlock()
+	size := len(appender.pending)
+	if size > maxAuditGroup {
+		size = maxAuditGroup
+	}
+	group := appender.pending[:size:size]
+	appender.pending = append([]*auditAppend(nil), appender.pending[size:]...)
+	return group
+}
+
+// handOver passes leadership to the first pending append.
+func (appender *auditAppender) handOver() {
+	appender.mu.Lock()
+	defer appender.mu.Unlock()
+	if len(appender.pending) == 0 {
+		appender.leading = false
+		return
+	}
+	appender.pending[0].done <- true
+}
+
+// result returns appended entry or error of its group.
+func (request *auditAppend) result() (*domain.AuditEntry, error) {
+	if request.err != nil {
+		return nil, request.err
+	}
+	return &request.entry, nil
+}
+
+// AuditEntries returns domain.AuditEntry list in chain's order.
+func (storage *GrossBookStorage) AuditEntries(ctx context.Context,
+	filter domain.AuditFilter) ([]domain.AuditEntry, error) {
+	if storage.pool == nil {
+		return nil, ErrNotConnected
+	}
+	if filter.Limit <= 0 {
+		return nil, ErrIncorrectLimit
+	}
+	rows, err := storage.pool.Query(ctx, selectAuditSQL, filter.AfterID, filter.Actor,
+		filter.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("can't read audit log from db <%w>", err)
+	}
+	defer rows.Close()
+	entries := make([]domain.AuditEntry, 0)
+	for rows.Next() {
+		var entry domain.AuditEntry
+		if err = rows.Scan(&entry.ID, &entry.Actor, &entry.ClaimedActor, &entry.SourceIP,
+			&entry.RequestID, &entry.Action, &entry.Endpoint, &entry.Payload, &entry.Status, &entry.Outcome,
+			&entry.CreatedAt, &entry.PrevHash, &entry.Hash); err != nil {
+			return nil, fmt.Errorf("can't scan audit entry <%w>", err)
+		}
+		entries = append(entries, entry)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("can't read audit log from db <%w>", err)
+	}
+	return entries, nil
+}
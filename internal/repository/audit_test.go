@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/agandreev/avito-intern-assignment/internal/domain"
+	"github.com/stretchr/testify/suite"
+)
+
+type AuditSuite struct {
+	suite.Suite
+}
+
+func (suite *AuditSuite) newAppend(actor string) *auditAppend {
+	return &auditAppend{entry: domain.AuditEntry{Caller: domain.Caller{Actor: actor}},
+		done: make(chan bool, 1)}
+}
+
+func (suite *AuditSuite) TestAuditAppender() {
+	var appender auditAppender
+	leader, follower := suite.newAppend("leader"), suite.newAppend("follower")
+	suite.True(appender.join(leader))
+	suite.False(appender.join(follower))
+	// leader appends the whole group and releases leadership
+	suite.Equal([]*auditAppend{leader, follower}, appender.group())
+	appender.handOver()
+	suite.Empty(follower.done)
+	suite.True(appender.join(suite.newAppend("next")))
+
+	// append added during group's transaction leads the next group
+	appender = auditAppender{}
+	suite.True(appender.join(leader))
+	suite.Len(appender.group(), 1)
+	suite.False(appender.join(follower))
+	appender.handOver()
+	suite.True(<-follower.done)
+	suite.Equal([]*auditAppend{follower}, appender.group())
+}
+
+func (suite *AuditSuite) TestAuditAppender_LimitGroup() {
+	var appender auditAppender
+	for i := 0; i < maxAuditGroup+1; i++ {
+		appender.join(suite.newAppend("caller"))
+	}
+	suite.Len(appender.group(), maxAuditGroup)
+	suite.Len(appender.pending, 1)
+}
+
+func TestAuditSuite(t *testing.T) {
+	suite.Run(t, new(AuditSuite))
+}
@@ -0,0 +1,270 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/agandreev/avito-intern-assignment/internal/domain"
+	"github.com/jackc/pgx/v4"
+)
+
+// users are locked in the same order by all transactions to avoid deadlocks, so their
+// statuses and balances can't be changed before commit
+const lockUserBalancesSQL = "SELECT user_id, status, block_incoming, amount, version " +
+	"FROM users WHERE user_id = ANY($1) ORDER BY user_id FOR UPDATE"
+
+// AddOperations adds all domain.Operation to the storage in one transaction and updates
+// domain.User from them. All statements are sent to db by one batch. It returns stored
+// events of operations.
+func (storage *GrossBookStorage) AddOperations(ctx context.Context,
+	operations []domain.Operation) ([]domain.Event, error) {
+	return storage.addOperations(ctx, operations, nil)
+}
+
+// AddScheduledOperations adds operations of domain.ScheduleRun as AddOperations with
+// run's execution in the same transaction. It returns domain.ErrRunExecuted without
+// adding operations if the run was already executed.
+func (storage *GrossBookStorage) AddScheduledOperations(ctx context.Context,
+	operations []domain.Operation, run *domain.ScheduleRun) ([]domain.Event, error) {
+	return storage.addOperations(ctx, operations, run)
+}
+
+// addOperations adds operations and starts run if it's set in one transaction.
+func (storage *GrossBookStorage) addOperations(ctx context.Context,
+	operations []domain.Operation, run *domain.ScheduleRun) ([]domain.Event, error) {
+	if storage.pool == nil {
+		return nil, ErrNotConnected
+	}
+	// check that operations are correct
+	ids := make(map[int64]struct{})
+	for _, operation := range operations {
+		if err := operation.Validate(); err != nil {
+			return nil, fmt.Errorf("can't add operation: <%w>", err)
+		}
+		ids[operation.Initiator.ID] = struct{}{}
+		if operation.Receiver != nil {
+			ids[operation.Receiver.ID] = struct{}{}
+		}
+	}
+	// add operations and update users in one transaction
+	var batch *operationBatch
+	if err := storage.transaction(ctx, "operation", pgx.TxOptions{}, func(tx pgx.Tx) error {
+		if run != nil {
+			if err := startRun(ctx, tx, run); err != nil {
+				return err
+			}
+		}
+		// create unknown users which are allowed to be created by operations
+		for _, operation := range operations {
+			if !operation.CreateInitiator {
+				continue
+			}
+			if _, err := tx.Exec(ctx, addUserSQL, operation.Initiator.ID,
+				InitialAmountValue, operation.Timestamp); err != nil {
+				return fmt.Errorf("can't add user: <%w>", err)
+			}
+		}
+		// check if users are existed and their statuses permit operations
+		if err := checkUsers(ctx, tx, ids, operations); err != nil {
+			return fmt.Errorf("error while adding operation: <%w>", err)
+		}
+		// check limits by operations stored before
+		if err := checkLimits(ctx, tx, operations); err != nil {
+			return fmt.Errorf("error while adding operation: <%w>", err)
+		}
+		// try to execute queries
+		batch = &operationBatch{}
+		for _, operation := range operations {
+			if err := batch.queueOperation(operation); err != nil {
+				return fmt.Errorf("can't prepare operation: <%w>", err)
+			}
+		}
+		if err := batch.send(ctx, tx); err != nil {
+			return fmt.Errorf("can't execute transaction: <%w>", err)
+		}
+		if storage.Config.Notify {
+			return notifyEvents(ctx, tx, batch.events)
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return batch.events, nil
+}
+
+// checkUsers returns ErrNoSuchUser if any of ids doesn't exist and locks users to check
+// operations against their current statuses. Operations are applied again to the current
+// balances and their snapshots are changed by results, so concurrent operations don't
+// conflict. domain.ErrVersionConflict is returned only if initiator's version isn't the
+// one operation expects.
+func checkUsers(ctx context.Context, tx pgx.Tx, ids map[int64]struct{},
+	operations []domain.Operation) error {
+	values := make([]int64, 0, len(ids))
+	for id := range ids {
+		values = append(values, id)
+	}
+	rows, err := tx.Query(ctx, lockUserBalancesSQL, values)
+	if err != nil {
+		return fmt.Errorf("can't read from db <%w>", err)
+	}
+	defer rows.Close()
+	users := make(map[int64]*domain.User, len(values))
+	for rows.Next() {
+		var user domain.User
+		if err = rows.Scan(&user.ID, &user.Status, &user.BlockIncoming, &user.Amount,
+			&user.Version); err != nil {
+			return fmt.Errorf("can't scan user <%w>", err)
+		}
+		users[user.ID] = &user
+	}
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("can't read from db <%w>", err)
+	}
+	if len(users) != len(values) {
+		return ErrNoSuchUser
+	}
+	for _, operation := range operations {
+		initiator := users[operation.Initiator.ID]
+		if err = initiator.CheckVersion(operation.ExpectedVersion); err != nil {
+			return err
+		}
+		// operation's snapshots may be read before status or balance change
+		current := operation
+		current.Initiator = initiator
+		if operation.Receiver != nil {
+			current.Receiver = users[operation.Receiver.ID]
+		}
+		if err = current.CheckStatus(); err != nil {
+			return err
+		}
+		if err = current.Apply(current.Initiator, current.Receiver); err != nil {
+			return err
+		}
+		operation.Initiator.Amount = initiator.Amount
+		operation.Initiator.Version = initiator.Version
+		if operation.IsTransfer() {
+			operation.Receiver.Amount = current.Receiver.Amount
+			operation.Receiver.Version = current.Receiver.Version
+		}
+	}
+	return nil
+}
+
+// notifyEvents sends events' notifications which postgres delivers only after commit.
+func notifyEvents(ctx context.Context, tx pgx.Tx, events []domain.Event) error {
+	batch := &pgx.Batch{}
+	for _, event := range events {
+		data, err := json.Marshal(event)
+		if err != nil {
+			return fmt.Errorf("can't marshal event: <%w>", err)
+		}
+		batch.Queue(notifySQL, eventsChannel, string(data))
+	}
+	results := tx.SendBatch(ctx, batch)
+	for range events {
+		if _, err := results.Exec(); err != nil {
+			_ = results.Close()
+			return fmt.Errorf("can't notify about event <%w>", err)
+		}
+	}
+	return results.Close()
+}
+
+// operationBatch queues statements of operations and reads their results in order.
+type operationBatch struct {
+	batch   pgx.Batch
+	readers []func(results pgx.BatchResults) error
+	events  []domain.Event
+}
+
+// exec queues statement without returned rows.
+func (batch *operationBatch) exec(sql string, args ...interface{}) {
+	batch.batch.Queue(sql, args...)
+	batch.readers = append(batch.readers, func(results pgx.BatchResults) error {
+		_, err := results.Exec()
+		return err
+	})
+}
+
+// updateUser queues update of user's balance and reads its new version. User has to be
+// locked by checkUsers, so balance is computed from the current one.
+func (batch *operationBatch) updateUser(user *domain.User) {
+	batch.batch.Queue(updateUserSQL, user.Amount, user.ID)
+	batch.readers = append(batch.readers, func(results pgx.BatchResults) error {
+		return results.QueryRow().Scan(&user.Version)
+	})
+}
+
+// queueOperation queues users' updates, operation's rows, its events and webhook deliveries.
+func (batch *operationBatch) queueOperation(operation domain.Operation) error {
+	// update initiator and receiver if it's existed, initiator of fee shares its snapshot
+	// with the charged operation, so fee is debited by the operation's update
+	if operation.Type == domain.Fee {
+		batch.exec(creditUserSQL, operation.Amount, operation.Receiver.ID)
+	} else {
+		batch.updateUser(operation.Initiator)
+		if operation.IsTransfer() {
+			batch.updateUser(operation.Receiver)
+		}
+	}
+	// add operation info (transfer is stored as transfer-out and transfer-in)
+	sides := []domain.Operation{operation}
+	if operation.IsTransfer() {
+		reversed, err := operation.Reverse()
+		if err != nil {
+			return fmt.Errorf("can't add reversed transaction: <%w>", err)
+		}
+		sides = append(sides, *reversed)
+	}
+	for _, side := range sides {
+		if side.Receiver != nil {
+			batch.exec(insertTransferOperationSQL, side.Initiator.ID, side.Type, side.Amount,
+				side.Timestamp, side.Receiver.ID, side.Fee, side.Currency, side.OriginalAmount)
+		} else {
+			batch.exec(insertNonTransferOperationSQL, side.Initiator.ID, side.Type,
+				side.Amount, side.Timestamp, side.Fee, side.Currency, side.OriginalAmount)
+		}
+	}
+	// add events for downstream services
+	events, err := operation.Events()
+	if err != nil {
+		return fmt.Errorf("can't create events: <%w>", err)
+	}
+	for _, event := range events {
+		batch.queueEvent(event)
+	}
+	// add notifications for partners' webhooks
+	for _, side := range sides {
+		payload, err := json.Marshal(side.Public())
+		if err != nil {
+			return fmt.Errorf("can't marshal operation: <%w>", err)
+		}
+		batch.exec(insertDeliveriesSQL, string(side.Type), string(payload),
+			domain.DeliveryPending, side.Timestamp, side.Initiator.ID)
+	}
+	return nil
+}
+
+// queueEvent queues outbox insertion and reads event's id.
+func (batch *operationBatch) queueEvent(event domain.Event) {
+	i := len(batch.events)
+	batch.events = append(batch.events, event)
+	batch.batch.Queue(insertEventSQL, event.Type, event.UserID, string(event.Payload),
+		event.CreatedAt)
+	batch.readers = append(batch.readers, func(results pgx.BatchResults) error {
+		return results.QueryRow().Scan(&batch.events[i].ID)
+	})
+}
+
+// send executes all queued statements in tx.
+func (batch *operationBatch) send(ctx context.Context, tx pgx.Tx) error {
+	results := tx.SendBatch(ctx, &batch.batch)
+	for _, read := range batch.readers {
+		if err := read(results); err != nil {
+			_ = results.Close()
+			return fmt.Errorf("can't execute statement: <%w>", err)
+		}
+	}
+	return results.Close()
+}
@@ -5,33 +5,59 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
-	"log"
+	"net"
+	"net/url"
 	"sort"
+	"time"
 
 	"github.com/agandreev/avito-intern-assignment/internal/domain"
 	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/sirupsen/logrus"
 )
 
 const (
 	insertTransferOperationSQL = "INSERT INTO operations(initiator_id, type, amount, " +
-		"time, receiver_id) " +
+		"time, receiver_id, fee, currency, original_amount) " +
 		"VALUES(" +
 		"(SELECT id from users WHERE user_id=$1), " +
 		"$2, $3, $4, " +
-		"(SELECT id from users WHERE user_id=$5))"
+		"(SELECT id from users WHERE user_id=$5), $6, $7, $8)"
 	insertNonTransferOperationSQL = "INSERT INTO operations(initiator_id, type, amount, " +
-		"time, receiver_id) " +
+		"time, receiver_id, fee, currency, original_amount) " +
 		"VALUES(" +
 		"(SELECT id from users WHERE user_id=$1), " +
 		"$2, $3, $4, " +
-		"NULL)"
+		"NULL, $5, $6, $7)"
+	// commission account's history contains fees credited to it
+	selectOperationsSQL = "SELECT id, initiator_id, type, amount, time, receiver_id, fee, " +
+		"currency, original_amount " +
+		"FROM operations WHERE (initiator_id=(SELECT id FROM users WHERE user_id=$1) " +
+		"OR (type=$3 AND receiver_id=(SELECT id FROM users WHERE user_id=$1))) " +
+		"AND ($4='' OR type=$4) AND ($5::timestamp IS NULL OR time>=$5) " +
+		"AND ($6::timestamp IS NULL OR time<$6) " +
+		"ORDER BY time DESC LIMIT $2"
+	selectUserSQL = "SELECT user_id, amount, status, block_incoming, status_reason, " +
+		"status_changed_at, external_ref, currency, display_name, created_at, version " +
+		"FROM users WHERE user_id=$1"
+	addUserSQL = "INSERT INTO users(user_id, amount, created_at) VALUES($1, $2, $3) " +
+		"ON CONFLICT (user_id) DO NOTHING"
+	// conflict target is omitted, so both user id and external reference are unique
+	createUserSQL = "INSERT INTO users(user_id, amount, status, external_ref, currency, " +
+		"display_name, created_at) VALUES($1, $2, $3, $4, $5, $6, $7) " +
+		"ON CONFLICT DO NOTHING RETURNING user_id"
+	updateUserSQL = "UPDATE users SET amount=$1, version=version+1 WHERE user_id=$2 " +
+		"RETURNING version"
+	// commission account is credited by delta, because it's changed concurrently
+	creditUserSQL = "UPDATE users SET amount=amount+$1, version=version+1 WHERE user_id=$2"
 )
 
 var (
 	ErrNotConnected = errors.New("there is no db connection")
-	ErrNoSuchUser   = errors.New("user with this id doesn't exist")
+	ErrNoSuchUser   = domain.ErrNoSuchUser
 	ErrNoOperations = errors.New("this user hasn't any operations")
+	// ErrIncorrectLimit is returned for non-positive quantity of requested rows.
+	ErrIncorrectLimit = errors.New("incorrect limit value")
 
 	InitialAmountValue = 0
 )
@@ -40,12 +66,15 @@ var (
 type GrossBookStorage struct {
 	pool   *pgxpool.Pool
 	Config ConnectionConfig
+	log    *logrus.Logger
+	audit  auditAppender
 }
 
 // NewGrossBookStorage create an entity and returns pointer.
-func NewGrossBookStorage(config ConnectionConfig) *GrossBookStorage {
+func NewGrossBookStorage(config ConnectionConfig, log *logrus.Logger) *GrossBookStorage {
 	return &GrossBookStorage{
 		Config: config,
+		log:    log,
 	}
 }
 
@@ -54,15 +83,41 @@ type ConnectionConfig struct {
 	Username string
 	Password string
 	NameDB   string
+	Host     string
 	Port     string
+	SSLMode  string
+	MaxConns int32
+	// Notify enables postgres notifications about committed events.
+	Notify bool
+	// MaxRetries limits retries of transactions failed by transient errors, zero
+	// disables retries.
+	MaxRetries int
+	// RetryBackoff is a delay before the first retry, it's doubled for each next one.
+	RetryBackoff time.Duration
+}
+
+// DSN returns connection string built from ConnectionConfig.
+func (config ConnectionConfig) DSN() string {
+	dsn := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(config.Username, config.Password),
+		Host:     net.JoinHostPort(config.Host, config.Port),
+		Path:     config.NameDB,
+		RawQuery: url.Values{"sslmode": []string{config.SSLMode}}.Encode(),
+	}
+	return dsn.String()
 }
 
 // Connect creates connection and returns error otherwise.
-func (storage *GrossBookStorage) Connect() error {
-	dsn := fmt.Sprintf("postgres://%s:%s@localhost:%s/%s"+
-		"?sslmode=disable", storage.Config.Username, storage.Config.Password,
-		storage.Config.Port, storage.Config.NameDB)
-	pool, err := pgxpool.Connect(context.Background(), dsn)
+func (storage *GrossBookStorage) Connect(ctx context.Context) error {
+	poolConfig, err := pgxpool.ParseConfig(storage.Config.DSN())
+	if err != nil {
+		return fmt.Errorf("can't parse db config <%w>", err)
+	}
+	if storage.Config.MaxConns > 0 {
+		poolConfig.MaxConns = storage.Config.MaxConns
+	}
+	pool, err := pgxpool.ConnectConfig(ctx, poolConfig)
 	if err != nil {
 		return fmt.Errorf("can't connect db <%w>", err)
 	}
@@ -71,12 +126,12 @@ func (storage *GrossBookStorage) Connect() error {
 }
 
 // User return domain.User by id.
-func (storage *GrossBookStorage) User(id int64) (*domain.User, error) {
-	row := storage.pool.QueryRow(context.Background(),
-		"SELECT * FROM users WHERE user_id=$1", id)
+func (storage *GrossBookStorage) User(ctx context.Context, id int64) (*domain.User, error) {
+	row := storage.pool.QueryRow(ctx, selectUserSQL, id)
 	var user domain.User
-	var dbNumber int
-	if err := row.Scan(&dbNumber, &user.ID, &user.Amount); err != nil {
+	if err := row.Scan(&user.ID, &user.Amount, &user.Status, &user.BlockIncoming,
+		&user.StatusReason, &user.StatusChangedAt, &user.ExternalRef, &user.Currency,
+		&user.DisplayName, &user.CreatedAt, &user.Version); err != nil {
 		if err == pgx.ErrNoRows {
 			return nil, ErrNoSuchUser
 		}
@@ -86,68 +141,51 @@ func (storage *GrossBookStorage) User(id int64) (*domain.User, error) {
 }
 
 // AddUser initialize domain.User by id with initial amount value.
-func (storage *GrossBookStorage) AddUser(id int64) error {
-	if _, err := storage.pool.Exec(context.Background(),
-		"INSERT INTO users(user_id, amount) VALUES($1, $2)",
-		id, InitialAmountValue); err != nil {
+// It does nothing if domain.User already exists, so concurrent calls are safe.
+func (storage *GrossBookStorage) AddUser(ctx context.Context, id int64) error {
+	if _, err := storage.pool.Exec(ctx, addUserSQL, id, InitialAmountValue,
+		time.Now()); err != nil {
 		return fmt.Errorf("can't add to db <%w>", err)
 	}
 	return nil
 }
 
-// AddOperation adds domain.Operation to the storage and updates domain.User from it.
-func (storage *GrossBookStorage) AddOperation(ctx context.Context, operation domain.Operation) error {
-	if storage.pool == nil {
-		return ErrNotConnected
-	}
-	// start transaction to add operations and update users
-	tx, err := storage.pool.Begin(ctx)
-	if err != nil {
-		log.Fatal(err)
-	}
-	defer func() {
-		if err != nil {
-			tx.Rollback(ctx)
-		}
-	}()
-	// check that operation is correct
-	if err = operation.Validate(); err != nil {
-		return fmt.Errorf("can't add operation: <%w>", err)
-	}
-	// check if users are existed
-	if _, err = storage.User(operation.Initiator.ID); err != nil {
-		return fmt.Errorf("error while adding operation "+
-			"(can't get initiator): <%w>", err)
-	}
-	if operation.IsTransfer() {
-		if _, err = storage.User(operation.Receiver.ID); err != nil {
-			return fmt.Errorf("error while adding operation "+
-				"(can't get receiver): <%w>", err)
+// CreateUser adds domain.User with its metadata. It returns domain.ErrUserExists if
+// user's id or external reference is taken.
+func (storage *GrossBookStorage) CreateUser(ctx context.Context, user domain.User) error {
+	var id int64
+	if err := storage.pool.QueryRow(ctx, createUserSQL, user.ID, InitialAmountValue,
+		user.AccountStatus(), user.ExternalRef, user.Currency, user.DisplayName,
+		user.CreatedAt).Scan(&id); err != nil {
+		if err == pgx.ErrNoRows {
+			return domain.ErrUserExists
 		}
-	}
-	// try to execute queries
-	if err = processOperation(ctx, tx, operation); err != nil {
-		return fmt.Errorf("can't execute transaction: <%w>", err)
-	}
-	if err = tx.Commit(ctx); err != nil {
-		return fmt.Errorf("can't commit operation transaction: <%w>", err)
+		return fmt.Errorf("can't add to db <%w>", err)
 	}
 	return nil
 }
 
-// Operations returns domain.Operation's slice by domain.User's id,
-// sorted as domain.SortingMode and limited as offset
-func (storage *GrossBookStorage) Operations(id int64, offset int64,
-	mode domain.SortingMode) ([]domain.RepositoryOperation, error) {
+// AddOperation adds domain.Operation to the storage and updates domain.User from it.
+// It returns stored events of the operation.
+func (storage *GrossBookStorage) AddOperation(ctx context.Context, operation domain.Operation) (
+	[]domain.Event, error) {
+	return storage.AddOperations(ctx, []domain.Operation{operation})
+}
+
+// Operations returns domain.Operation's slice by domain.User's id and
+// domain.OperationFilter, sorted as domain.SortingMode and limited as offset
+func (storage *GrossBookStorage) Operations(ctx context.Context, id int64, offset int64,
+	mode domain.SortingMode, filter domain.OperationFilter) ([]domain.RepositoryOperation,
+	error) {
 	if offset <= 0 {
-		return nil, fmt.Errorf("incorrect offset value")
+		return nil, fmt.Errorf("incorrect offset value: <%w>", ErrIncorrectLimit)
 	}
-	rows, err := storage.pool.Query(context.Background(),
-		"SELECT * FROM operations WHERE initiator_id="+
-			"(SELECT id FROM users WHERE user_id=$1) ORDER BY time DESC LIMIT $2", id, offset)
+	rows, err := storage.pool.Query(ctx, selectOperationsSQL, id, offset, domain.Fee,
+		string(filter.Type), filter.From, filter.To)
 	if err != nil {
 		return nil, fmt.Errorf("can't get operations: <%w>", err)
 	}
+	defer rows.Close()
 	var operationQuantity int64
 	operations := make([]domain.RepositoryOperation, 0)
 	var dbNumber int
@@ -155,7 +193,8 @@ func (storage *GrossBookStorage) Operations(id int64, offset int64,
 	for rows.Next() && operationQuantity < offset {
 		var operation domain.RepositoryOperation
 		if err := rows.Scan(&dbNumber, &operation.InitiatorID, &operation.Type,
-			&operation.Amount, &operation.Timestamp, &optionalID); err != nil {
+			&operation.Amount, &operation.Timestamp, &optionalID, &operation.Fee,
+			&operation.Currency, &operation.OriginalAmount); err != nil {
 			if err == pgx.ErrNoRows {
 				return nil, ErrNoOperations
 			}
@@ -167,12 +206,15 @@ func (storage *GrossBookStorage) Operations(id int64, offset int64,
 		operationQuantity++
 		operations = append(operations, operation)
 	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("can't read operations: <%w>", err)
+	}
 	sortOperations(operations, mode)
 	return operations, nil
 }
 
 // Shutdown closes connection. It blocks while all current queries are processing.
-func (storage GrossBookStorage) Shutdown() {
+func (storage *GrossBookStorage) Shutdown() {
 	if storage.pool != nil {
 		storage.pool.Close()
 	}
@@ -191,69 +233,3 @@ func sortOperations(operations []domain.RepositoryOperation, mode domain.Sorting
 		}
 	})
 }
-
-// processOperation executes pgx.Tx by domain.Operation.
-func processOperation(ctx context.Context, tx pgx.Tx, operation domain.Operation) error {
-	// update initiator
-	if err := updateUser(ctx, tx, *operation.Initiator); err != nil {
-		return fmt.Errorf("transaction initiator error: <%w>", err)
-	}
-	// update receiver if it's existed
-	if operation.IsTransfer() {
-		if err := updateUser(ctx, tx, *operation.Receiver); err != nil {
-			return fmt.Errorf("transaction receiver error: <%w>", err)
-		}
-	}
-	// add operation info to db
-	if err := addOperation(ctx, tx, operation); err != nil {
-		return fmt.Errorf("can't add operation to db: <%w>", err)
-	}
-	return nil
-}
-
-// updateUser updates domain.User's balance in db.
-func updateUser(ctx context.Context, tx pgx.Tx, user domain.User) error {
-	if _, err := tx.Exec(ctx, "UPDATE users SET amount=$1 WHERE user_id=$2",
-		user.Amount, user.ID); err != nil {
-		return fmt.Errorf("can't execute user updation: <%w>", err)
-	}
-	return nil
-}
-
-// addOperation adds domain.Operation's info to db.
-func addOperation(ctx context.Context, tx pgx.Tx, operation domain.Operation) error {
-	// add non-duplex transaction
-	if !operation.IsTransfer() {
-		if _, err := tx.Exec(ctx,
-			insertNonTransferOperationSQL,
-			operation.Initiator.ID, operation.Type, operation.Amount,
-			operation.Timestamp); err != nil {
-			return fmt.Errorf("can't add operation to db <%w>", err)
-		}
-	} else {
-		// add duplex transaction (transfer-out)
-		if err := addDuplexOperation(ctx, tx, operation); err != nil {
-			return fmt.Errorf("origin operation error: <%w>", err)
-		}
-		// add reversed duplex transaction (transfer-in)
-		reversed, err := operation.Reverse()
-		if err != nil {
-			return fmt.Errorf("can't add reversed transaction: <%w>", err)
-		}
-		if err = addDuplexOperation(ctx, tx, *reversed); err != nil {
-			return fmt.Errorf("origin operation error: <%w>", err)
-		}
-	}
-	return nil
-}
-
-// addDuplexOperation adds domain.Operation's info with receiver id to db.
-func addDuplexOperation(ctx context.Context, tx pgx.Tx, operation domain.Operation) error {
-	if _, err := tx.Exec(ctx,
-		insertTransferOperationSQL,
-		operation.Initiator.ID, operation.Type, operation.Amount,
-		operation.Timestamp, operation.Receiver.ID); err != nil {
-		return fmt.Errorf("can't add operation to db <%w>", err)
-	}
-	return nil
-}
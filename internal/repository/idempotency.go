@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/agandreev/avito-intern-assignment/internal/domain"
+	"github.com/jackc/pgx/v4"
+)
+
+const (
+	// expired key is claimed again by the new request
+	claimIdempotencyKeySQL = "INSERT INTO idempotency_keys(key, endpoint, request_hash, " +
+		"created_at) VALUES($1, $2, $3, $4) " +
+		"ON CONFLICT (key) DO UPDATE SET endpoint=EXCLUDED.endpoint, " +
+		"request_hash=EXCLUDED.request_hash, status=NULL, response=NULL, " +
+		"created_at=EXCLUDED.created_at WHERE idempotency_keys.created_at < $5 " +
+		"RETURNING key"
+	selectIdempotencyKeySQL = "SELECT key, endpoint, request_hash, status, response, " +
+		"created_at FROM idempotency_keys WHERE key=$1"
+	completeIdempotencyKeySQL = "UPDATE idempotency_keys SET status=$2, response=$3 " +
+		"WHERE key=$1 AND status IS NULL"
+	releaseIdempotencyKeySQL = "DELETE FROM idempotency_keys WHERE key=$1 AND status IS NULL"
+)
+
+// ClaimIdempotencyKey stores domain.IdempotencyRecord without response if its key is
+// free or expired before expiredBefore and returns nil. Otherwise, it returns the stored
+// record.
+func (storage *GrossBookStorage) ClaimIdempotencyKey(ctx context.Context,
+	record domain.IdempotencyRecord, expiredBefore time.Time) (*domain.IdempotencyRecord,
+	error) {
+	if storage.pool == nil {
+		return nil, ErrNotConnected
+	}
+	var key string
+	err := storage.pool.QueryRow(ctx, claimIdempotencyKeySQL, record.Key, record.Endpoint,
+		record.Hash, record.CreatedAt, expiredBefore).Scan(&key)
+	if err == nil {
+		return nil, nil
+	}
+	if err != pgx.ErrNoRows {
+		return nil, fmt.Errorf("can't claim idempotency key: <%w>", err)
+	}
+	var stored domain.IdempotencyRecord
+	var status sql.NullInt32
+	if err = storage.pool.QueryRow(ctx, selectIdempotencyKeySQL, record.Key).Scan(
+		&stored.Key, &stored.Endpoint, &stored.Hash, &status, &stored.Response,
+		&stored.CreatedAt); err != nil {
+		// key is released concurrently, so its request is still in progress
+		if err == pgx.ErrNoRows {
+			return nil, domain.ErrRequestInProgress
+		}
+		return nil, fmt.Errorf("can't read idempotency key: <%w>", err)
+	}
+	stored.Status = int(status.Int32)
+	return &stored, nil
+}
+
+// CompleteIdempotencyKey stores response of claimed key.
+func (storage *GrossBookStorage) CompleteIdempotencyKey(ctx context.Context, key string,
+	status int, response []byte) error {
+	if storage.pool == nil {
+		return ErrNotConnected
+	}
+	if _, err := storage.pool.Exec(ctx, completeIdempotencyKeySQL, key, status,
+		response); err != nil {
+		return fmt.Errorf("can't complete idempotency key: <%w>", err)
+	}
+	return nil
+}
+
+// ReleaseIdempotencyKey removes claimed key without response, so request can be retried.
+func (storage *GrossBookStorage) ReleaseIdempotencyKey(ctx context.Context,
+	key string) error {
+	if storage.pool == nil {
+		return ErrNotConnected
+	}
+	if _, err := storage.pool.Exec(ctx, releaseIdempotencyKeySQL, key); err != nil {
+		return fmt.Errorf("can't release idempotency key: <%w>", err)
+	}
+	return nil
+}
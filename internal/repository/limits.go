@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/agandreev/avito-intern-assignment/internal/domain"
+	"github.com/jackc/pgx/v4"
+)
+
+const (
+	selectLimitsSQL = "SELECT max_operation, daily_withdraw, monthly_withdraw, " +
+		"daily_transfer, monthly_transfer FROM user_limits WHERE user_id=$1"
+	upsertLimitsSQL = "INSERT INTO user_limits(user_id, max_operation, daily_withdraw, " +
+		"monthly_withdraw, daily_transfer, monthly_transfer, updated_at) " +
+		"VALUES($1, $2, $3, $4, $5, $6, $7) ON CONFLICT (user_id) DO UPDATE SET " +
+		"max_operation=$2, daily_withdraw=$3, monthly_withdraw=$4, daily_transfer=$5, " +
+		"monthly_transfer=$6, updated_at=$7"
+	deleteLimitsSQL = "DELETE FROM user_limits WHERE user_id=$1"
+	// users are locked in the same order by all transactions to avoid deadlocks
+	lockUsersSQL = "SELECT user_id FROM users WHERE user_id = ANY($1) " +
+		"ORDER BY user_id FOR UPDATE"
+	selectUsageSQL = "SELECT " +
+		"COALESCE(SUM(o.amount) FILTER (WHERE o.type=$2 AND o.time>=$4), 0), " +
+		"COALESCE(SUM(o.amount) FILTER (WHERE o.type=$2), 0), " +
+		"COALESCE(SUM(o.amount) FILTER (WHERE o.type=$3 AND o.time>=$4), 0), " +
+		"COALESCE(SUM(o.amount) FILTER (WHERE o.type=$3), 0) " +
+		"FROM operations AS o JOIN users AS u ON u.id=o.initiator_id " +
+		"WHERE u.user_id=$1 AND o.time>=$5"
+)
+
+// UserLimits returns domain.Limits which override global ones for user.
+func (storage *GrossBookStorage) UserLimits(ctx context.Context, userID int64) (
+	*domain.Limits, error) {
+	if storage.pool == nil {
+		return nil, ErrNotConnected
+	}
+	var limits domain.Limits
+	if err := storage.pool.QueryRow(ctx, selectLimitsSQL, userID).Scan(&limits.MaxOperation,
+		&limits.DailyWithdraw, &limits.MonthlyWithdraw, &limits.DailyTransfer,
+		&limits.MonthlyTransfer); err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("can't read limits from db <%w>", err)
+	}
+	return &limits, nil
+}
+
+// SetUserLimits replaces domain.Limits which override global ones for user.
+func (storage *GrossBookStorage) SetUserLimits(ctx context.Context, userID int64,
+	limits domain.Limits) error {
+	if storage.pool == nil {
+		return ErrNotConnected
+	}
+	if _, err := storage.pool.Exec(ctx, upsertLimitsSQL, userID, limits.MaxOperation,
+		limits.DailyWithdraw, limits.MonthlyWithdraw, limits.DailyTransfer,
+		limits.MonthlyTransfer, time.Now()); err != nil {
+		return fmt.Errorf("can't set limits: <%w>", err)
+	}
+	return nil
+}
+
+// DeleteUserLimits removes user's own limits, so global ones are applied.
+func (storage *GrossBookStorage) DeleteUserLimits(ctx context.Context, userID int64) error {
+	if storage.pool == nil {
+		return ErrNotConnected
+	}
+	if _, err := storage.pool.Exec(ctx, deleteLimitsSQL, userID); err != nil {
+		return fmt.Errorf("can't delete limits: <%w>", err)
+	}
+	return nil
+}
+
+// checkLimits locks initiators of limited operations and checks operations against
+// their usage, so concurrent operations can't exceed limits together.
+func checkLimits(ctx context.Context, tx pgx.Tx, operations []domain.Operation) error {
+	ids := make([]int64, 0)
+	usages := make(map[int64]*domain.LimitUsage)
+	for _, operation := range operations {
+		if operation.Limits == nil || operation.Limits.IsEmpty() {
+			continue
+		}
+		if _, ok := usages[operation.Initiator.ID]; !ok {
+			ids = append(ids, operation.Initiator.ID)
+			usages[operation.Initiator.ID] = nil
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	rows, err := tx.Query(ctx, lockUsersSQL, ids)
+	if err != nil {
+		return fmt.Errorf("can't lock users: <%w>", err)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("can't lock users: <%w>", err)
+	}
+	for _, operation := range operations {
+		if operation.Limits == nil || operation.Limits.IsEmpty() {
+			continue
+		}
+		usage := usages[operation.Initiator.ID]
+		if usage == nil {
+			if usage, err = limitUsage(ctx, tx, operation.Initiator.ID,
+				operation.Timestamp); err != nil {
+				return err
+			}
+			usages[operation.Initiator.ID] = usage
+		}
+		if err = operation.Limits.Check(operation, *usage); err != nil {
+			return err
+		}
+		// the next operations of batch are checked with the current one
+		usage.Add(operation)
+	}
+	return nil
+}
+
+// limitUsage returns user's domain.LimitUsage in periods of timestamp.
+func limitUsage(ctx context.Context, tx pgx.Tx, userID int64, timestamp time.Time) (
+	*domain.LimitUsage, error) {
+	day, month := domain.LimitPeriods(timestamp)
+	var usage domain.LimitUsage
+	if err := tx.QueryRow(ctx, selectUsageSQL, userID, domain.Withdraw, domain.TransferOut,
+		day, month).Scan(&usage.DailyWithdraw, &usage.MonthlyWithdraw, &usage.DailyTransfer,
+		&usage.MonthlyTransfer); err != nil {
+		return nil, fmt.Errorf("can't read limit usage from db <%w>", err)
+	}
+	return &usage, nil
+}
@@ -0,0 +1,231 @@
+package repository
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+)
+
+const (
+	migrationsDir = "migrations"
+	upSuffix      = ".up.sql"
+	downSuffix    = ".down.sql"
+
+	// migrationLockKey is a key of postgres advisory lock held while migrating.
+	migrationLockKey = 20220114
+
+	createSchemaVersionSQL = "CREATE TABLE IF NOT EXISTS schema_version(" +
+		"version INT PRIMARY KEY, " +
+		"name VARCHAR(255) NOT NULL, " +
+		"applied_at TIMESTAMP NOT NULL)"
+)
+
+var (
+	ErrUnknownVersion  = errors.New("there is no migration with this version")
+	ErrBrokenMigration = errors.New("migration files are inconsistent")
+
+	//go:embed migrations/*.sql
+	migrationFiles embed.FS
+)
+
+// Migration represents a single versioned schema change.
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Migrations returns all embedded migrations sorted by version.
+func Migrations() ([]Migration, error) {
+	return loadMigrations(migrationFiles, migrationsDir)
+}
+
+// LatestVersion returns the version of the last embedded migration.
+func LatestVersion() (int, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return 0, err
+	}
+	if len(migrations) == 0 {
+		return 0, nil
+	}
+	return migrations[len(migrations)-1].Version, nil
+}
+
+// loadMigrations parses files named as <version>_<name>.up.sql and <version>_<name>.down.sql.
+func loadMigrations(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("can't read migrations: <%w>", err)
+	}
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		fileName := entry.Name()
+		var suffix string
+		switch {
+		case strings.HasSuffix(fileName, upSuffix):
+			suffix = upSuffix
+		case strings.HasSuffix(fileName, downSuffix):
+			suffix = downSuffix
+		default:
+			return nil, fmt.Errorf("unexpected file %s: <%w>", fileName, ErrBrokenMigration)
+		}
+		parts := strings.SplitN(strings.TrimSuffix(fileName, suffix), "_", 2)
+		version, err := strconv.Atoi(parts[0])
+		if err != nil || len(parts) != 2 || version <= 0 {
+			return nil, fmt.Errorf("incorrect file name %s: <%w>", fileName, ErrBrokenMigration)
+		}
+		data, err := fs.ReadFile(fsys, path.Join(dir, fileName))
+		if err != nil {
+			return nil, fmt.Errorf("can't read migration %s: <%w>", fileName, err)
+		}
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: parts[1]}
+			byVersion[version] = migration
+		}
+		if migration.Name != parts[1] {
+			return nil, fmt.Errorf("different names for version %d: <%w>",
+				version, ErrBrokenMigration)
+		}
+		if suffix == upSuffix {
+			migration.Up = string(data)
+		} else {
+			migration.Down = string(data)
+		}
+	}
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %d hasn't both directions: <%w>",
+				migration.Version, ErrBrokenMigration)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	// versions must go without gaps
+	for i, migration := range migrations {
+		if migration.Version != i+1 {
+			return nil, fmt.Errorf("version %d is missed: <%w>", i+1, ErrBrokenMigration)
+		}
+	}
+	return migrations, nil
+}
+
+// SchemaVersion returns the version of the last applied migration, 0 for empty db.
+func (storage *GrossBookStorage) SchemaVersion(ctx context.Context) (int, error) {
+	if storage.pool == nil {
+		return 0, ErrNotConnected
+	}
+	if _, err := storage.pool.Exec(ctx, createSchemaVersionSQL); err != nil {
+		return 0, fmt.Errorf("can't create schema version table: <%w>", err)
+	}
+	return schemaVersion(ctx, storage.pool)
+}
+
+// Migrate applies up or down migrations until the schema reaches given version.
+// Concurrent runs are serialized by postgres advisory lock.
+func (storage *GrossBookStorage) Migrate(ctx context.Context, version int) error {
+	if storage.pool == nil {
+		return ErrNotConnected
+	}
+	migrations, err := Migrations()
+	if err != nil {
+		return err
+	}
+	if version < 0 || version > len(migrations) {
+		return fmt.Errorf("can't migrate to %d: <%w>", version, ErrUnknownVersion)
+	}
+	conn, err := storage.pool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("can't acquire connection: <%w>", err)
+	}
+	defer conn.Release()
+	// lock is bound to session, so all migrations are executed by the same connection
+	if _, err = conn.Exec(ctx, "SELECT pg_advisory_lock($1)", migrationLockKey); err != nil {
+		return fmt.Errorf("can't take migration lock: <%w>", err)
+	}
+	defer func() {
+		_, _ = conn.Exec(context.Background(), "SELECT pg_advisory_unlock($1)",
+			migrationLockKey)
+	}()
+	if _, err = conn.Exec(ctx, createSchemaVersionSQL); err != nil {
+		return fmt.Errorf("can't create schema version table: <%w>", err)
+	}
+	current, err := schemaVersion(ctx, conn)
+	if err != nil {
+		return err
+	}
+	// apply up migrations in ascending order
+	for _, migration := range migrations {
+		if migration.Version <= current || migration.Version > version {
+			continue
+		}
+		if err = applyMigration(ctx, conn.Conn(), migration.Up,
+			"INSERT INTO schema_version(version, name, applied_at) VALUES($1, $2, $3)",
+			migration.Version, migration.Name, time.Now()); err != nil {
+			return fmt.Errorf("can't apply migration %d: <%w>", migration.Version, err)
+		}
+	}
+	// apply down migrations in descending order
+	for i := len(migrations) - 1; i >= 0; i-- {
+		migration := migrations[i]
+		if migration.Version > current || migration.Version <= version {
+			continue
+		}
+		if err = applyMigration(ctx, conn.Conn(), migration.Down,
+			"DELETE FROM schema_version WHERE version=$1",
+			migration.Version); err != nil {
+			return fmt.Errorf("can't revert migration %d: <%w>", migration.Version, err)
+		}
+	}
+	return nil
+}
+
+// querier is a common part of pgxpool.Pool and pgxpool.Conn.
+type querier interface {
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+}
+
+// schemaVersion reads the maximal applied version.
+func schemaVersion(ctx context.Context, db querier) (int, error) {
+	var version int
+	if err := db.QueryRow(ctx,
+		"SELECT COALESCE(MAX(version), 0) FROM schema_version").Scan(&version); err != nil {
+		return 0, fmt.Errorf("can't read schema version: <%w>", err)
+	}
+	return version, nil
+}
+
+// applyMigration executes migration's sql and bookkeeping query in one transaction.
+func applyMigration(ctx context.Context, conn *pgx.Conn, migrationSQL string,
+	versionSQL string, args ...interface{}) (err error) {
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("can't begin transaction: <%w>", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback(ctx)
+		}
+	}()
+	if _, err = tx.Exec(ctx, migrationSQL); err != nil {
+		return fmt.Errorf("can't execute migration: <%w>", err)
+	}
+	if _, err = tx.Exec(ctx, versionSQL, args...); err != nil {
+		return fmt.Errorf("can't update schema version: <%w>", err)
+	}
+	return tx.Commit(ctx)
+}
@@ -1,11 +1,11 @@
-CREATE TABLE users
+CREATE TABLE IF NOT EXISTS users
 (
     id      SERIAL PRIMARY KEY,
     user_id INT,
     amount  NUMERIC
 );
 
-CREATE TABLE operations
+CREATE TABLE IF NOT EXISTS operations
 (
     id           SERIAL PRIMARY KEY,
     initiator_id INT,
@@ -15,4 +15,4 @@ CREATE TABLE operations
     receiver_id  INT,
     FOREIGN KEY (initiator_id) REFERENCES users(id),
     FOREIGN KEY (receiver_id) REFERENCES users(id)
-);
\ No newline at end of file
+);
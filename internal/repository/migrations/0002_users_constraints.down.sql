@@ -0,0 +1,19 @@
+DROP INDEX IF EXISTS operations_receiver_id_idx;
+DROP INDEX IF EXISTS operations_initiator_id_time_idx;
+
+ALTER TABLE operations
+    ALTER COLUMN receiver_id TYPE INT,
+    ALTER COLUMN initiator_id TYPE INT,
+    ALTER COLUMN id TYPE INT;
+
+ALTER TABLE users
+    DROP CONSTRAINT users_amount_check,
+    DROP CONSTRAINT users_user_id_key,
+    ALTER COLUMN amount DROP NOT NULL,
+    ALTER COLUMN amount DROP DEFAULT,
+    ALTER COLUMN user_id DROP NOT NULL,
+    ALTER COLUMN user_id TYPE INT,
+    ALTER COLUMN id TYPE INT;
+
+ALTER SEQUENCE operations_id_seq AS INT;
+ALTER SEQUENCE users_id_seq AS INT;
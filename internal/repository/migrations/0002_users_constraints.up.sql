@@ -0,0 +1,40 @@
+-- merge users duplicated by concurrent first deposits into the oldest row
+UPDATE operations
+SET initiator_id = duplicates.keep_id
+FROM (SELECT id, MIN(id) OVER (PARTITION BY user_id) AS keep_id FROM users) AS duplicates
+WHERE operations.initiator_id = duplicates.id
+  AND duplicates.id <> duplicates.keep_id;
+
+UPDATE operations
+SET receiver_id = duplicates.keep_id
+FROM (SELECT id, MIN(id) OVER (PARTITION BY user_id) AS keep_id FROM users) AS duplicates
+WHERE operations.receiver_id = duplicates.id
+  AND duplicates.id <> duplicates.keep_id;
+
+DELETE
+FROM users
+    USING users AS kept
+WHERE users.user_id = kept.user_id
+  AND users.id > kept.id;
+
+-- ids are int64 in the service
+ALTER SEQUENCE users_id_seq AS BIGINT;
+ALTER SEQUENCE operations_id_seq AS BIGINT;
+
+ALTER TABLE users
+    ALTER COLUMN id TYPE BIGINT,
+    ALTER COLUMN user_id TYPE BIGINT,
+    ALTER COLUMN user_id SET NOT NULL,
+    ALTER COLUMN amount SET DEFAULT 0,
+    ALTER COLUMN amount SET NOT NULL,
+    ADD CONSTRAINT users_user_id_key UNIQUE (user_id),
+    ADD CONSTRAINT users_amount_check CHECK (amount >= 0);
+
+ALTER TABLE operations
+    ALTER COLUMN id TYPE BIGINT,
+    ALTER COLUMN initiator_id TYPE BIGINT,
+    ALTER COLUMN receiver_id TYPE BIGINT;
+
+-- history is read by initiator ordered by time
+CREATE INDEX operations_initiator_id_time_idx ON operations (initiator_id, time DESC);
+CREATE INDEX operations_receiver_id_idx ON operations (receiver_id);
@@ -0,0 +1,12 @@
+CREATE TABLE outbox
+(
+    id           BIGSERIAL PRIMARY KEY,
+    type         VARCHAR(50) NOT NULL,
+    user_id      BIGINT      NOT NULL,
+    payload      JSONB       NOT NULL,
+    created_at   TIMESTAMP   NOT NULL,
+    delivered_at TIMESTAMP
+);
+
+-- relay reads only undelivered events in order
+CREATE INDEX outbox_undelivered_idx ON outbox (id) WHERE delivered_at IS NULL;
@@ -0,0 +1,28 @@
+CREATE TABLE webhooks
+(
+    id          BIGSERIAL PRIMARY KEY,
+    url         TEXT          NOT NULL,
+    event_types VARCHAR(20)[] NOT NULL,
+    user_id     BIGINT,
+    secret      TEXT          NOT NULL,
+    created_at  TIMESTAMP     NOT NULL
+);
+
+CREATE TABLE webhook_deliveries
+(
+    id              BIGSERIAL PRIMARY KEY,
+    webhook_id      BIGINT      NOT NULL REFERENCES webhooks (id) ON DELETE CASCADE,
+    event_type      VARCHAR(20) NOT NULL,
+    payload         JSONB       NOT NULL,
+    status          VARCHAR(20) NOT NULL,
+    attempts        INT         NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP   NOT NULL,
+    last_error      TEXT        NOT NULL DEFAULT '',
+    response_code   INT         NOT NULL DEFAULT 0,
+    created_at      TIMESTAMP   NOT NULL,
+    delivered_at    TIMESTAMP
+);
+
+-- dispatcher reads only pending deliveries which are due
+CREATE INDEX webhook_deliveries_due_idx ON webhook_deliveries (next_attempt_at) WHERE status = 'pending';
+CREATE INDEX webhook_deliveries_webhook_id_idx ON webhook_deliveries (webhook_id, id DESC);
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS outbox_user_id_idx;
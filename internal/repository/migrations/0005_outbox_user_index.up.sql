@@ -0,0 +1,2 @@
+-- balance streams resume from the last seen event of user
+CREATE INDEX outbox_user_id_idx ON outbox (user_id, id);
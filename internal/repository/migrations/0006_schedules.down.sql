@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS schedule_executions;
+
+DROP TABLE IF EXISTS schedules;
@@ -0,0 +1,29 @@
+CREATE TABLE schedules
+(
+    id           BIGSERIAL PRIMARY KEY,
+    initiator_id BIGINT    NOT NULL,
+    receiver_id  BIGINT    NOT NULL,
+    amount       NUMERIC   NOT NULL CHECK (amount > 0),
+    cron         TEXT      NOT NULL DEFAULT '',
+    run_at       TIMESTAMP,
+    next_run_at  TIMESTAMP,
+    locked_until TIMESTAMP,
+    active       BOOLEAN   NOT NULL DEFAULT TRUE,
+    created_at   TIMESTAMP NOT NULL
+);
+
+-- every run of schedule is executed once, so executions are unique by run time
+CREATE TABLE schedule_executions
+(
+    id           BIGSERIAL PRIMARY KEY,
+    schedule_id  BIGINT      NOT NULL REFERENCES schedules (id) ON DELETE CASCADE,
+    scheduled_at TIMESTAMP   NOT NULL,
+    status       VARCHAR(20) NOT NULL,
+    error        TEXT        NOT NULL DEFAULT '',
+    executed_at  TIMESTAMP,
+    UNIQUE (schedule_id, scheduled_at)
+);
+
+-- scheduler reads only active schedules which are due
+CREATE INDEX schedules_due_idx ON schedules (next_run_at) WHERE active;
+CREATE INDEX schedules_initiator_id_idx ON schedules (initiator_id, id);
@@ -0,0 +1,11 @@
+-- limits which override global ones, NULL columns aren't overridden
+CREATE TABLE user_limits
+(
+    user_id          BIGINT PRIMARY KEY,
+    max_operation    NUMERIC CHECK (max_operation >= 0),
+    daily_withdraw   NUMERIC CHECK (daily_withdraw >= 0),
+    monthly_withdraw NUMERIC CHECK (monthly_withdraw >= 0),
+    daily_transfer   NUMERIC CHECK (daily_transfer >= 0),
+    monthly_transfer NUMERIC CHECK (monthly_transfer >= 0),
+    updated_at       TIMESTAMP NOT NULL
+);
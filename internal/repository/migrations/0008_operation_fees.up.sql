@@ -0,0 +1 @@
+ALTER TABLE operations ADD COLUMN IF NOT EXISTS fee NUMERIC NOT NULL DEFAULT 0;
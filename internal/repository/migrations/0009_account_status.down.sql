@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS account_status_changes;
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS status_changed_at,
+    DROP COLUMN IF EXISTS status_reason,
+    DROP COLUMN IF EXISTS block_incoming,
+    DROP COLUMN IF EXISTS status;
@@ -0,0 +1,21 @@
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS status            VARCHAR(20) NOT NULL DEFAULT 'active',
+    ADD COLUMN IF NOT EXISTS block_incoming    BOOLEAN     NOT NULL DEFAULT FALSE,
+    ADD COLUMN IF NOT EXISTS status_reason     TEXT        NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS status_changed_at TIMESTAMP;
+
+-- audit trail of status changes, records are never updated
+CREATE TABLE IF NOT EXISTS account_status_changes
+(
+    id             BIGSERIAL PRIMARY KEY,
+    user_id        BIGINT      NOT NULL,
+    from_status    VARCHAR(20) NOT NULL,
+    to_status      VARCHAR(20) NOT NULL,
+    reason         TEXT        NOT NULL,
+    block_incoming BOOLEAN     NOT NULL DEFAULT FALSE,
+    changed_at     TIMESTAMP   NOT NULL,
+    FOREIGN KEY (user_id) REFERENCES users (user_id)
+);
+
+CREATE INDEX IF NOT EXISTS account_status_changes_user_id_idx
+    ON account_status_changes (user_id, id DESC);
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS users_external_ref_key;
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS created_at,
+    DROP COLUMN IF EXISTS display_name,
+    DROP COLUMN IF EXISTS currency,
+    DROP COLUMN IF EXISTS external_ref;
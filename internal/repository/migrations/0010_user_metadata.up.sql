@@ -0,0 +1,9 @@
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS external_ref VARCHAR(255) NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS currency     VARCHAR(3)   NOT NULL DEFAULT 'RUB',
+    ADD COLUMN IF NOT EXISTS display_name VARCHAR(255) NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS created_at   TIMESTAMP;
+
+-- external reference is optional, but it identifies only one user
+CREATE UNIQUE INDEX IF NOT EXISTS users_external_ref_key
+    ON users (external_ref) WHERE external_ref <> '';
@@ -0,0 +1,37 @@
+CREATE TABLE IF NOT EXISTS audit_log
+(
+    id         BIGSERIAL PRIMARY KEY,
+    actor      TEXT      NOT NULL,
+    source_ip  TEXT      NOT NULL DEFAULT '',
+    request_id TEXT      NOT NULL DEFAULT '',
+    action     TEXT      NOT NULL,
+    endpoint   TEXT      NOT NULL,
+    payload    TEXT      NOT NULL DEFAULT '',
+    status     INT       NOT NULL DEFAULT 0,
+    outcome    TEXT      NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    prev_hash  TEXT      NOT NULL,
+    hash       TEXT      NOT NULL UNIQUE
+);
+
+CREATE INDEX IF NOT EXISTS audit_log_actor_idx ON audit_log (actor, id);
+
+-- audit log is append-only, changes are rejected even for service's db user
+CREATE OR REPLACE FUNCTION audit_log_append_only() RETURNS TRIGGER AS
+$$
+BEGIN
+    RAISE EXCEPTION 'audit log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_log_no_changes
+    BEFORE UPDATE OR DELETE
+    ON audit_log
+    FOR EACH ROW
+EXECUTE PROCEDURE audit_log_append_only();
+
+CREATE TRIGGER audit_log_no_truncate
+    BEFORE TRUNCATE
+    ON audit_log
+    FOR EACH STATEMENT
+EXECUTE PROCEDURE audit_log_append_only();
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS idempotency_keys
+(
+    key          TEXT PRIMARY KEY,
+    endpoint     TEXT      NOT NULL,
+    request_hash TEXT      NOT NULL,
+    -- status and response are empty while request is processed
+    status       INT,
+    response     BYTEA,
+    created_at   TIMESTAMP NOT NULL
+);
@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS adjustments
+(
+    id          BIGSERIAL PRIMARY KEY,
+    user_id     BIGINT      NOT NULL,
+    amount      NUMERIC     NOT NULL CHECK (amount <> 0),
+    reason      TEXT        NOT NULL,
+    ticket      TEXT        NOT NULL,
+    status      VARCHAR(20) NOT NULL,
+    created_by  TEXT        NOT NULL,
+    created_at  TIMESTAMP   NOT NULL,
+    expires_at  TIMESTAMP   NOT NULL,
+    reviewed_by TEXT        NOT NULL DEFAULT '',
+    reviewed_at TIMESTAMP,
+    comment     TEXT        NOT NULL DEFAULT '',
+    -- adjustment is applied only if another operator approved it
+    CHECK (status <> 'approved' OR reviewed_by NOT IN ('', created_by))
+);
+
+CREATE INDEX IF NOT EXISTS adjustments_user_id_idx ON adjustments (user_id, id);
+CREATE INDEX IF NOT EXISTS adjustments_pending_idx ON adjustments (expires_at)
+    WHERE status = 'pending';
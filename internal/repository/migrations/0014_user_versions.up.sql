@@ -0,0 +1,2 @@
+-- version is incremented by each balance change, so clients can detect concurrent ones
+ALTER TABLE users ADD COLUMN IF NOT EXISTS version BIGINT NOT NULL DEFAULT 1;
@@ -0,0 +1,3 @@
+ALTER TABLE operations
+    DROP COLUMN IF EXISTS original_amount,
+    DROP COLUMN IF EXISTS currency;
@@ -0,0 +1,4 @@
+-- currency is empty and original amount is zero for operations in RUB
+ALTER TABLE operations
+    ADD COLUMN IF NOT EXISTS currency        VARCHAR(3) NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS original_amount NUMERIC    NOT NULL DEFAULT 0;
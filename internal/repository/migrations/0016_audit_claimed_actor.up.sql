@@ -0,0 +1,3 @@
+-- actor sent by caller itself isn't verified, so it's kept apart from authenticated one
+ALTER TABLE audit_log
+    ADD COLUMN IF NOT EXISTS claimed_actor TEXT NOT NULL DEFAULT '';
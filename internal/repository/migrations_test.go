@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type MigrationsSuite struct {
+	suite.Suite
+}
+
+func (suite *MigrationsSuite) TestMigrations_Embedded() {
+	migrations, err := Migrations()
+	suite.Require().NoError(err)
+	suite.Require().NotEmpty(migrations)
+	suite.Equal("init", migrations[0].Name)
+	for i, migration := range migrations {
+		suite.Equal(i+1, migration.Version)
+		suite.NotEmpty(migration.Up)
+		suite.NotEmpty(migration.Down)
+	}
+}
+
+func (suite *MigrationsSuite) TestLoadMigrations_Broken() {
+	file := &fstest.MapFile{Data: []byte("SELECT 1;")}
+	// lack of down migration
+	_, err := loadMigrations(fstest.MapFS{"m/0001_a.up.sql": file}, "m")
+	suite.ErrorIs(err, ErrBrokenMigration)
+	// gap between versions
+	_, err = loadMigrations(fstest.MapFS{
+		"m/0001_a.up.sql": file, "m/0001_a.down.sql": file,
+		"m/0003_c.up.sql": file, "m/0003_c.down.sql": file,
+	}, "m")
+	suite.ErrorIs(err, ErrBrokenMigration)
+	// incorrect name
+	_, err = loadMigrations(fstest.MapFS{"m/first.up.sql": file}, "m")
+	suite.ErrorIs(err, ErrBrokenMigration)
+	// unexpected file
+	_, err = loadMigrations(fstest.MapFS{"m/README.md": file}, "m")
+	suite.ErrorIs(err, ErrBrokenMigration)
+}
+
+func TestMigrationsSuite(t *testing.T) {
+	suite.Run(t, new(MigrationsSuite))
+}
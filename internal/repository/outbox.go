@@ -0,0 +1,136 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/agandreev/avito-intern-assignment/internal/domain"
+	"github.com/jackc/pgx/v4"
+)
+
+const (
+	eventsChannel = "outbox_events"
+
+	insertEventSQL = "INSERT INTO outbox(type, user_id, payload, created_at) " +
+		"VALUES($1, $2, $3, $4) RETURNING id"
+	selectUserEventsSQL = "SELECT id, type, user_id, payload, created_at FROM outbox " +
+		"WHERE user_id=$1 AND type=$2 AND id>$3 ORDER BY id LIMIT $4"
+	notifySQL = "SELECT pg_notify($1, $2)"
+	listenSQL = "LISTEN " + eventsChannel
+	// rows are locked without skipping, so concurrent relays can't break the order
+	selectUndeliveredSQL = "SELECT id, type, user_id, payload, created_at FROM outbox " +
+		"WHERE delivered_at IS NULL ORDER BY id LIMIT $1 FOR UPDATE"
+	markDeliveredSQL = "UPDATE outbox SET delivered_at=$1 WHERE id=$2"
+)
+
+// RelayEvents passes up to limit undelivered domain.Event to deliver in order and
+// marks delivered ones. It stops on the first delivery error, so the rest of events
+// are going to be delivered by the next call. It returns quantity of delivered events.
+// Its transaction isn't retried, because events are delivered outside of it.
+func (storage *GrossBookStorage) RelayEvents(ctx context.Context, limit int,
+	deliver func(ctx context.Context, event domain.Event) error) (delivered int, err error) {
+	if storage.pool == nil {
+		return 0, ErrNotConnected
+	}
+	tx, err := storage.pool.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("can't begin relay transaction: <%w>", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback(ctx)
+		}
+	}()
+	events, err := undeliveredEvents(ctx, tx, limit)
+	if err != nil {
+		return 0, err
+	}
+	var deliveryErr error
+	for _, event := range events {
+		if deliveryErr = deliver(ctx, event); deliveryErr != nil {
+			break
+		}
+		if _, err = tx.Exec(ctx, markDeliveredSQL, time.Now(), event.ID); err != nil {
+			return 0, fmt.Errorf("can't mark event as delivered: <%w>", err)
+		}
+		delivered++
+	}
+	// commit delivered part even if delivery is failed
+	if err = tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("can't commit relay transaction: <%w>", err)
+	}
+	if deliveryErr != nil {
+		return delivered, fmt.Errorf("can't deliver event: <%w>", deliveryErr)
+	}
+	return delivered, nil
+}
+
+// undeliveredEvents reads and locks the oldest undelivered events.
+func undeliveredEvents(ctx context.Context, tx pgx.Tx, limit int) ([]domain.Event, error) {
+	rows, err := tx.Query(ctx, selectUndeliveredSQL, limit)
+	if err != nil {
+		return nil, fmt.Errorf("can't get events: <%w>", err)
+	}
+	return scanEvents(rows)
+}
+
+// UserEvents returns up to limit domain.Event of given type and user stored after afterID.
+func (storage *GrossBookStorage) UserEvents(ctx context.Context, userID, afterID int64,
+	eventType domain.EventType, limit int64) ([]domain.Event, error) {
+	if storage.pool == nil {
+		return nil, ErrNotConnected
+	}
+	rows, err := storage.pool.Query(ctx, selectUserEventsSQL, userID, eventType, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("can't get events: <%w>", err)
+	}
+	return scanEvents(rows)
+}
+
+// ListenEvents passes events committed by any replica to handle until ctx is done.
+// Events are sent only by storages with enabled ConnectionConfig.Notify.
+func (storage *GrossBookStorage) ListenEvents(ctx context.Context,
+	handle func(event domain.Event)) error {
+	if storage.pool == nil {
+		return ErrNotConnected
+	}
+	conn, err := storage.pool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("can't acquire connection: <%w>", err)
+	}
+	defer conn.Release()
+	if _, err = conn.Exec(ctx, listenSQL); err != nil {
+		return fmt.Errorf("can't listen events: <%w>", err)
+	}
+	for {
+		notification, err := conn.Conn().WaitForNotification(ctx)
+		if err != nil {
+			return fmt.Errorf("can't receive event: <%w>", err)
+		}
+		var event domain.Event
+		if err = json.Unmarshal([]byte(notification.Payload), &event); err != nil {
+			return fmt.Errorf("can't unmarshal event: <%w>", err)
+		}
+		handle(event)
+	}
+}
+
+// scanEvents reads all domain.Event from rows and closes them.
+func scanEvents(rows pgx.Rows) ([]domain.Event, error) {
+	defer rows.Close()
+	events := make([]domain.Event, 0)
+	for rows.Next() {
+		var event domain.Event
+		if err := rows.Scan(&event.ID, &event.Type, &event.UserID, &event.Payload,
+			&event.CreatedAt); err != nil {
+			return nil, fmt.Errorf("can't read event from db <%w>", err)
+		}
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("can't read events: <%w>", err)
+	}
+	return events, nil
+}
@@ -0,0 +1,134 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/agandreev/avito-intern-assignment/internal/domain"
+	"github.com/jackc/pgx/v4"
+)
+
+const (
+	// ledgerSQL sums users' operations: deposits and incoming transfers increase balance,
+	// withdrawals, outgoing transfers and fees decrease it, fees credit commission account,
+	// adjustments are signed. Corrections aren't summed, because they only fix balances.
+	ledgerSQL = "SELECT u.user_id, u.amount, COALESCE(SUM(l.delta), 0), " +
+		"COALESCE(SUM(l.delta), 0) - u.amount, COUNT(l.delta) " +
+		"FROM users AS u LEFT JOIN (" +
+		"SELECT initiator_id AS id, " +
+		"CASE WHEN type IN ($1, $2, $6) THEN amount ELSE -amount END AS delta " +
+		"FROM operations WHERE type IN ($1, $2, $3, $4, $5, $6) " +
+		"UNION ALL " +
+		"SELECT receiver_id, amount FROM operations WHERE type=$5" +
+		") AS l ON l.id=u.id "
+	// balances are written from float64, so differences less than tolerance are skipped
+	selectMismatchesSQL = ledgerSQL +
+		"GROUP BY u.id, u.user_id, u.amount " +
+		"HAVING ABS(COALESCE(SUM(l.delta), 0) - u.amount) >= $7 ORDER BY u.user_id"
+	selectUserLedgerSQL = ledgerSQL + "WHERE u.user_id=$7 GROUP BY u.id, u.user_id, u.amount"
+	countUsersSQL       = "SELECT COUNT(*) FROM users"
+	lockUserSQL         = "SELECT user_id FROM users WHERE user_id=$1 FOR UPDATE"
+)
+
+// ledgerArgs returns operation types of ledgerSQL.
+func ledgerArgs(args ...interface{}) []interface{} {
+	return append([]interface{}{domain.Deposit, domain.TransferIn, domain.Withdraw,
+		domain.TransferOut, domain.Fee, domain.Adjustment}, args...)
+}
+
+// BalanceMismatches returns quantity of checked users and domain.BalanceMismatch of users
+// whose balance differs from their operations. Both are read from the same snapshot.
+func (storage *GrossBookStorage) BalanceMismatches(ctx context.Context) (
+	checked int64, mismatches []domain.BalanceMismatch, err error) {
+	if storage.pool == nil {
+		return 0, nil, ErrNotConnected
+	}
+	err = storage.transaction(ctx, "reconciliation", pgx.TxOptions{
+		IsoLevel:   pgx.RepeatableRead,
+		AccessMode: pgx.ReadOnly,
+	}, func(tx pgx.Tx) error {
+		if err := tx.QueryRow(ctx, countUsersSQL).Scan(&checked); err != nil {
+			return fmt.Errorf("can't count users: <%w>", err)
+		}
+		rows, err := tx.Query(ctx, selectMismatchesSQL, ledgerArgs(domain.BalanceTolerance)...)
+		if err != nil {
+			return fmt.Errorf("can't read ledger from db <%w>", err)
+		}
+		defer rows.Close()
+		mismatches = make([]domain.BalanceMismatch, 0)
+		for rows.Next() {
+			mismatch, err := scanMismatch(rows)
+			if err != nil {
+				return err
+			}
+			mismatches = append(mismatches, *mismatch)
+		}
+		if err = rows.Err(); err != nil {
+			return fmt.Errorf("can't read ledger from db <%w>", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, nil, err
+	}
+	return checked, mismatches, nil
+}
+
+// CorrectBalance locks user and sets his balance to the sum of his operations by
+// domain.Correction operation. It returns nil if balance is already correct.
+func (storage *GrossBookStorage) CorrectBalance(ctx context.Context, userID int64) (
+	*domain.BalanceMismatch, error) {
+	if storage.pool == nil {
+		return nil, ErrNotConnected
+	}
+	var corrected *domain.BalanceMismatch
+	if err := storage.transaction(ctx, "correction", pgx.TxOptions{}, func(tx pgx.Tx) error {
+		corrected = nil
+		// concurrent operations change balance and ledger after correction
+		if err := tx.QueryRow(ctx, lockUserSQL, userID).Scan(&userID); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ErrNoSuchUser
+			}
+			return fmt.Errorf("can't lock user: <%w>", err)
+		}
+		mismatch, err := scanMismatch(tx.QueryRow(ctx, selectUserLedgerSQL,
+			ledgerArgs(userID)...))
+		if err != nil {
+			return err
+		}
+		if !mismatch.IsSignificant() {
+			return nil
+		}
+		batch := &operationBatch{}
+		if err = batch.queueOperation(mismatch.Correction(time.Now())); err != nil {
+			return fmt.Errorf("can't prepare correction: <%w>", err)
+		}
+		if err = batch.send(ctx, tx); err != nil {
+			return fmt.Errorf("can't execute correction: <%w>", err)
+		}
+		if storage.Config.Notify {
+			if err = notifyEvents(ctx, tx, batch.events); err != nil {
+				return err
+			}
+		}
+		mismatch.Corrected = true
+		corrected = mismatch
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return corrected, nil
+}
+
+// scanMismatch reads domain.BalanceMismatch from ledgerSQL's row.
+func scanMismatch(row pgx.Row) (*domain.BalanceMismatch, error) {
+	var mismatch domain.BalanceMismatch
+	// difference is computed by db, because float subtraction isn't exact
+	if err := row.Scan(&mismatch.UserID, &mismatch.Balance, &mismatch.Ledger,
+		&mismatch.Difference, &mismatch.Operations); err != nil {
+		return nil, fmt.Errorf("can't scan ledger <%w>", err)
+	}
+	return &mismatch, nil
+}
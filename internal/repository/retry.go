@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+)
+
+const (
+	serializationFailureCode = "40001"
+	deadlockDetectedCode     = "40P01"
+	// connectionExceptionClass contains codes of lost and rejected connections
+	connectionExceptionClass = "08"
+)
+
+var (
+	// metrics are served by expvar handler
+	txRetries  = expvar.NewInt("db_transaction_retries")
+	txFailures = expvar.NewInt("db_transaction_retry_failures")
+
+	ErrUnknownCommit = errors.New("transaction may be committed")
+)
+
+// transaction runs fn in transaction and commits it. Transaction failed by serialization
+// failure, deadlock or connection error is rolled back and run again with growing
+// jittered backoff up to Config.MaxRetries times. So fn must do all its work by tx and
+// must not have other side effects. Commit which result is unknown isn't retried.
+func (storage *GrossBookStorage) transaction(ctx context.Context, name string,
+	options pgx.TxOptions, fn func(tx pgx.Tx) error) error {
+	backoff := storage.Config.RetryBackoff
+	for attempt := 0; ; attempt++ {
+		err := storage.runTransaction(ctx, name, options, fn)
+		if err == nil || !isRetryable(err) || ctx.Err() != nil {
+			return err
+		}
+		if attempt >= storage.Config.MaxRetries {
+			if attempt > 0 {
+				txFailures.Add(1)
+			}
+			return err
+		}
+		txRetries.Add(1)
+		delay := jitter(backoff)
+		if storage.log != nil {
+			storage.log.Warnf("DB: <%s> transaction is retried in <%s>, attempt <%d>: <%s>",
+				name, delay, attempt+1, err)
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+		backoff *= 2
+	}
+}
+
+// runTransaction runs fn in transaction once, it's rolled back if fn fails.
+func (storage *GrossBookStorage) runTransaction(ctx context.Context, name string,
+	options pgx.TxOptions, fn func(tx pgx.Tx) error) (err error) {
+	tx, err := storage.pool.BeginTx(ctx, options)
+	if err != nil {
+		return fmt.Errorf("can't begin %s transaction: <%w>", name, err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback(ctx)
+		}
+	}()
+	if err = fn(tx); err != nil {
+		return err
+	}
+	if err = tx.Commit(ctx); err != nil {
+		// commit rejected by db is rolled back, but lost one may be applied
+		var pgErr *pgconn.PgError
+		if !errors.As(err, &pgErr) {
+			return fmt.Errorf("can't commit %s transaction <%s>: <%w>", name, err,
+				ErrUnknownCommit)
+		}
+		return fmt.Errorf("can't commit %s transaction: <%w>", name, err)
+	}
+	return nil
+}
+
+// isRetryable returns true for serialization failures, deadlocks and connection errors.
+func isRetryable(err error) bool {
+	if errors.Is(err, ErrUnknownCommit) {
+		return false
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code == serializationFailureCode || pgErr.Code == deadlockDetectedCode ||
+			strings.HasPrefix(pgErr.Code, connectionExceptionClass)
+	}
+	if pgconn.SafeToRetry(err) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED)
+}
+
+// jitter returns random delay from half to one and a half of backoff.
+func jitter(backoff time.Duration) time.Duration {
+	if backoff <= 0 {
+		return 0
+	}
+	return backoff/2 + time.Duration(rand.Int63n(int64(backoff)))
+}
@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgconn"
+	"github.com/stretchr/testify/suite"
+)
+
+type RetrySuite struct {
+	suite.Suite
+}
+
+func (suite *RetrySuite) TestIsRetryable() {
+	wrap := func(err error) error {
+		return fmt.Errorf("can't execute transaction: <%w>", err)
+	}
+	suite.True(isRetryable(wrap(&pgconn.PgError{Code: serializationFailureCode})))
+	suite.True(isRetryable(wrap(&pgconn.PgError{Code: deadlockDetectedCode})))
+	suite.True(isRetryable(wrap(&pgconn.PgError{Code: "08006"})))
+	suite.True(isRetryable(wrap(io.ErrUnexpectedEOF)))
+	suite.True(isRetryable(wrap(syscall.ECONNRESET)))
+	// constraint violations and domain errors aren't transient
+	suite.False(isRetryable(wrap(&pgconn.PgError{Code: "23505"})))
+	suite.False(isRetryable(wrap(ErrStatusChanged)))
+	suite.False(isRetryable(errors.New("unknown")))
+	// lost commit may be applied, so it's never retried
+	suite.False(isRetryable(fmt.Errorf("can't commit <%s>: <%w>", io.EOF, ErrUnknownCommit)))
+}
+
+func (suite *RetrySuite) TestJitter() {
+	suite.Zero(jitter(0))
+	for i := 0; i < 100; i++ {
+		delay := jitter(100 * time.Millisecond)
+		suite.GreaterOrEqual(delay, 50*time.Millisecond)
+		suite.Less(delay, 150*time.Millisecond)
+	}
+}
+
+func TestRetrySuite(t *testing.T) {
+	suite.Run(t, new(RetrySuite))
+}
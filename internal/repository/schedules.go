@@ -0,0 +1,227 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/agandreev/avito-intern-assignment/internal/domain"
+	"github.com/jackc/pgx/v4"
+)
+
+const (
+	scheduleColumns = "id, initiator_id, receiver_id, amount, cron, run_at, next_run_at, " +
+		"active, created_at"
+	insertScheduleSQL = "INSERT INTO schedules(initiator_id, receiver_id, amount, cron, " +
+		"run_at, next_run_at, active, created_at) " +
+		"VALUES($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id"
+	selectScheduleSQL      = "SELECT " + scheduleColumns + " FROM schedules WHERE id=$1"
+	selectUserSchedulesSQL = "SELECT " + scheduleColumns + " FROM schedules " +
+		"WHERE initiator_id=$1 ORDER BY id"
+	updateScheduleSQL = "UPDATE schedules SET amount=$1, cron=$2, run_at=$3, " +
+		"next_run_at=$4, active=$5 WHERE id=$6"
+	deleteScheduleSQL = "DELETE FROM schedules WHERE id=$1"
+	// due schedules are leased, so other replicas skip them
+	claimSchedulesSQL = "UPDATE schedules SET locked_until=$1 WHERE id IN (" +
+		"SELECT id FROM schedules WHERE active AND next_run_at<=$2 " +
+		"AND (locked_until IS NULL OR locked_until<$2) " +
+		"ORDER BY next_run_at LIMIT $3 FOR UPDATE SKIP LOCKED) " +
+		"RETURNING " + scheduleColumns
+	// schedule is moved only from the claimed run, so concurrent changes aren't lost
+	advanceScheduleSQL = "UPDATE schedules SET next_run_at=$1, active=$2, " +
+		"locked_until=NULL WHERE id=$3 AND next_run_at=$4"
+	insertExecutionSQL = "INSERT INTO schedule_executions(schedule_id, scheduled_at, " +
+		"status, error, executed_at) VALUES($1, $2, $3, $4, $5) " +
+		"ON CONFLICT (schedule_id, scheduled_at) DO NOTHING RETURNING id"
+	selectExecutionsSQL = "SELECT id, schedule_id, scheduled_at, status, error, executed_at " +
+		"FROM schedule_executions WHERE schedule_id=$1 ORDER BY id DESC LIMIT $2"
+)
+
+var ErrNoSuchSchedule = errors.New("schedule with this id doesn't exist")
+
+// AddSchedule stores domain.Schedule and returns it with id.
+func (storage *GrossBookStorage) AddSchedule(ctx context.Context, schedule domain.Schedule) (
+	*domain.Schedule, error) {
+	if storage.pool == nil {
+		return nil, ErrNotConnected
+	}
+	if err := storage.pool.QueryRow(ctx, insertScheduleSQL, schedule.InitiatorID,
+		schedule.ReceiverID, schedule.Amount, schedule.Cron, schedule.RunAt,
+		schedule.NextRunAt, schedule.Active, schedule.CreatedAt).Scan(&schedule.ID); err != nil {
+		return nil, fmt.Errorf("can't add schedule to db <%w>", err)
+	}
+	return &schedule, nil
+}
+
+// Schedule returns domain.Schedule by id.
+func (storage *GrossBookStorage) Schedule(ctx context.Context, id int64) (
+	*domain.Schedule, error) {
+	if storage.pool == nil {
+		return nil, ErrNotConnected
+	}
+	schedule, err := scanSchedule(storage.pool.QueryRow(ctx, selectScheduleSQL, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNoSuchSchedule
+		}
+		return nil, err
+	}
+	return schedule, nil
+}
+
+// UserSchedules returns all domain.Schedule initiated by user.
+func (storage *GrossBookStorage) UserSchedules(ctx context.Context, userID int64) (
+	[]domain.Schedule, error) {
+	if storage.pool == nil {
+		return nil, ErrNotConnected
+	}
+	rows, err := storage.pool.Query(ctx, selectUserSchedulesSQL, userID)
+	if err != nil {
+		return nil, fmt.Errorf("can't get schedules: <%w>", err)
+	}
+	return scanSchedules(rows)
+}
+
+// UpdateSchedule saves amount, timing and activity of domain.Schedule.
+func (storage *GrossBookStorage) UpdateSchedule(ctx context.Context,
+	schedule domain.Schedule) error {
+	if storage.pool == nil {
+		return ErrNotConnected
+	}
+	tag, err := storage.pool.Exec(ctx, updateScheduleSQL, schedule.Amount, schedule.Cron,
+		schedule.RunAt, schedule.NextRunAt, schedule.Active, schedule.ID)
+	if err != nil {
+		return fmt.Errorf("can't update schedule: <%w>", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNoSuchSchedule
+	}
+	return nil
+}
+
+// DeleteSchedule removes domain.Schedule with its executions.
+func (storage *GrossBookStorage) DeleteSchedule(ctx context.Context, id int64) error {
+	if storage.pool == nil {
+		return ErrNotConnected
+	}
+	tag, err := storage.pool.Exec(ctx, deleteScheduleSQL, id)
+	if err != nil {
+		return fmt.Errorf("can't delete schedule: <%w>", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNoSuchSchedule
+	}
+	return nil
+}
+
+// ScheduleExecutions returns the latest domain.ScheduleExecution of schedule limited
+// by limit.
+func (storage *GrossBookStorage) ScheduleExecutions(ctx context.Context, scheduleID,
+	limit int64) ([]domain.ScheduleExecution, error) {
+	if storage.pool == nil {
+		return nil, ErrNotConnected
+	}
+	if limit <= 0 {
+		return nil, ErrIncorrectLimit
+	}
+	rows, err := storage.pool.Query(ctx, selectExecutionsSQL, scheduleID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("can't get executions: <%w>", err)
+	}
+	defer rows.Close()
+	executions := make([]domain.ScheduleExecution, 0)
+	for rows.Next() {
+		var execution domain.ScheduleExecution
+		if err = rows.Scan(&execution.ID, &execution.ScheduleID, &execution.ScheduledAt,
+			&execution.Status, &execution.Error, &execution.ExecutedAt); err != nil {
+			return nil, fmt.Errorf("can't read execution from db <%w>", err)
+		}
+		executions = append(executions, execution)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("can't read executions: <%w>", err)
+	}
+	return executions, nil
+}
+
+// ClaimSchedules returns up to limit due active schedules and leases them, so they
+// aren't claimed twice while being executed.
+func (storage *GrossBookStorage) ClaimSchedules(ctx context.Context, limit int,
+	lease time.Duration) ([]domain.Schedule, error) {
+	if storage.pool == nil {
+		return nil, ErrNotConnected
+	}
+	now := time.Now()
+	rows, err := storage.pool.Query(ctx, claimSchedulesSQL, now.Add(lease), now, limit)
+	if err != nil {
+		return nil, fmt.Errorf("can't claim schedules: <%w>", err)
+	}
+	return scanSchedules(rows)
+}
+
+// RecordRun stores domain.ScheduleRun whose transfer failed and moves schedule to the
+// next run in one transaction. It returns domain.ErrRunExecuted if the run was already
+// executed or schedule was changed after claiming.
+func (storage *GrossBookStorage) RecordRun(ctx context.Context, run *domain.ScheduleRun) error {
+	if storage.pool == nil {
+		return ErrNotConnected
+	}
+	return storage.transaction(ctx, "execution", pgx.TxOptions{}, func(tx pgx.Tx) error {
+		return startRun(ctx, tx, run)
+	})
+}
+
+// startRun moves claimed schedule to the next run and inserts run's execution. It
+// returns domain.ErrRunExecuted if the run was already executed or schedule was changed
+// after claiming, so transaction has to be rolled back.
+func startRun(ctx context.Context, tx pgx.Tx, run *domain.ScheduleRun) error {
+	execution := &run.Execution
+	tag, err := tx.Exec(ctx, advanceScheduleSQL, run.Next, run.Next != nil,
+		execution.ScheduleID, execution.ScheduledAt)
+	if err != nil {
+		return fmt.Errorf("can't advance schedule: <%w>", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("schedule <%d> was changed: <%w>", execution.ScheduleID,
+			domain.ErrRunExecuted)
+	}
+	if err = tx.QueryRow(ctx, insertExecutionSQL, execution.ScheduleID,
+		execution.ScheduledAt, execution.Status, execution.Error,
+		execution.ExecutedAt).Scan(&execution.ID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("schedule <%d> run at <%s>: <%w>", execution.ScheduleID,
+				execution.ScheduledAt, domain.ErrRunExecuted)
+		}
+		return fmt.Errorf("can't add execution: <%w>", err)
+	}
+	return nil
+}
+
+// scanSchedules reads all domain.Schedule from rows and closes them.
+func scanSchedules(rows pgx.Rows) ([]domain.Schedule, error) {
+	defer rows.Close()
+	schedules := make([]domain.Schedule, 0)
+	for rows.Next() {
+		schedule, err := scanSchedule(rows)
+		if err != nil {
+			return nil, err
+		}
+		schedules = append(schedules, *schedule)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("can't read schedules: <%w>", err)
+	}
+	return schedules, nil
+}
+
+// scanSchedule reads domain.Schedule from row.
+func scanSchedule(row pgx.Row) (*domain.Schedule, error) {
+	var schedule domain.Schedule
+	if err := row.Scan(&schedule.ID, &schedule.InitiatorID, &schedule.ReceiverID,
+		&schedule.Amount, &schedule.Cron, &schedule.RunAt, &schedule.NextRunAt,
+		&schedule.Active, &schedule.CreatedAt); err != nil {
+		return nil, fmt.Errorf("can't read schedule from db <%w>", err)
+	}
+	return &schedule, nil
+}
@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/agandreev/avito-intern-assignment/internal/domain"
+	"github.com/jackc/pgx/v4"
+)
+
+const (
+	// status is changed only from the read one and closed account must be empty
+	updateStatusSQL = "UPDATE users SET status=$1, block_incoming=$2, status_reason=$3, " +
+		"status_changed_at=$4 WHERE user_id=$5 AND status=$6 AND (NOT $7 OR amount=0)"
+	insertStatusChangeSQL = "INSERT INTO account_status_changes(user_id, from_status, " +
+		"to_status, reason, block_incoming, changed_at) VALUES($1, $2, $3, $4, $5, $6) " +
+		"RETURNING id"
+	selectStatusChangesSQL = "SELECT id, user_id, from_status, to_status, reason, " +
+		"block_incoming, changed_at FROM account_status_changes WHERE user_id=$1 " +
+		"ORDER BY id DESC LIMIT $2"
+)
+
+var ErrStatusChanged = errors.New("user's status or balance was changed concurrently")
+
+// ChangeStatus updates user's status and adds domain.StatusChange to audit trail in one
+// transaction. It returns ErrStatusChanged if status isn't StatusChange.From anymore.
+func (storage *GrossBookStorage) ChangeStatus(ctx context.Context,
+	change domain.StatusChange) (*domain.StatusChange, error) {
+	if storage.pool == nil {
+		return nil, ErrNotConnected
+	}
+	if err := storage.transaction(ctx, "status", pgx.TxOptions{}, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx, updateStatusSQL, change.To, change.BlockIncoming,
+			change.Reason, change.ChangedAt, change.UserID, change.From,
+			change.To == domain.Closed)
+		if err != nil {
+			return fmt.Errorf("can't update status: <%w>", err)
+		}
+		if tag.RowsAffected() == 0 {
+			return ErrStatusChanged
+		}
+		if err = tx.QueryRow(ctx, insertStatusChangeSQL, change.UserID, change.From,
+			change.To, change.Reason, change.BlockIncoming,
+			change.ChangedAt).Scan(&change.ID); err != nil {
+			return fmt.Errorf("can't add status change: <%w>", err)
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return &change, nil
+}
+
+// StatusChanges returns the latest domain.StatusChange of user.
+func (storage *GrossBookStorage) StatusChanges(ctx context.Context, userID, limit int64) (
+	[]domain.StatusChange, error) {
+	if storage.pool == nil {
+		return nil, ErrNotConnected
+	}
+	if limit <= 0 {
+		return nil, ErrIncorrectLimit
+	}
+	rows, err := storage.pool.Query(ctx, selectStatusChangesSQL, userID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("can't read status changes from db <%w>", err)
+	}
+	defer rows.Close()
+	changes := make([]domain.StatusChange, 0)
+	for rows.Next() {
+		var change domain.StatusChange
+		if err = rows.Scan(&change.ID, &change.UserID, &change.From, &change.To,
+			&change.Reason, &change.BlockIncoming, &change.ChangedAt); err != nil {
+			return nil, fmt.Errorf("can't scan status change <%w>", err)
+		}
+		changes = append(changes, change)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("can't read status changes from db <%w>", err)
+	}
+	return changes, nil
+}
@@ -0,0 +1,168 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/agandreev/avito-intern-assignment/internal/domain"
+	"github.com/jackc/pgx/v4"
+)
+
+const (
+	insertWebhookSQL = "INSERT INTO webhooks(url, event_types, user_id, secret, created_at) " +
+		"VALUES($1, $2, $3, $4, $5) RETURNING id"
+	selectWebhookSQL = "SELECT id, url, event_types, user_id, secret, created_at " +
+		"FROM webhooks WHERE id=$1"
+	// deliveries are created for all webhooks subscribed on operation's type and user
+	insertDeliveriesSQL = "INSERT INTO webhook_deliveries(webhook_id, event_type, payload, " +
+		"status, next_attempt_at, created_at) " +
+		"SELECT id, $1, $2, $3, $4, $4 FROM webhooks " +
+		"WHERE $1 = ANY(event_types) AND (user_id IS NULL OR user_id=$5)"
+	selectDeliveriesSQL = "SELECT id, webhook_id, event_type, payload, status, attempts, " +
+		"next_attempt_at, last_error, response_code, created_at, delivered_at " +
+		"FROM webhook_deliveries WHERE webhook_id=$1 ORDER BY id DESC LIMIT $2"
+	// due deliveries are leased by moving next attempt, so other replicas skip them
+	claimDeliveriesSQL = "UPDATE webhook_deliveries AS d SET next_attempt_at=$1 " +
+		"FROM webhooks AS w WHERE w.id=d.webhook_id AND d.id IN (" +
+		"SELECT id FROM webhook_deliveries WHERE status=$2 AND next_attempt_at<=$3 " +
+		"ORDER BY next_attempt_at LIMIT $4 FOR UPDATE SKIP LOCKED) " +
+		"RETURNING d.id, d.webhook_id, d.event_type, d.payload, d.status, d.attempts, " +
+		"d.next_attempt_at, d.last_error, d.response_code, d.created_at, d.delivered_at, " +
+		"w.url, w.secret"
+	updateDeliverySQL = "UPDATE webhook_deliveries SET status=$1, attempts=$2, " +
+		"next_attempt_at=$3, last_error=$4, response_code=$5, delivered_at=$6 WHERE id=$7"
+)
+
+var ErrNoSuchWebhook = errors.New("webhook with this id doesn't exist")
+
+// AddWebhook stores domain.Webhook and returns it with id.
+func (storage *GrossBookStorage) AddWebhook(ctx context.Context, webhook domain.Webhook) (
+	*domain.Webhook, error) {
+	if storage.pool == nil {
+		return nil, ErrNotConnected
+	}
+	var userID sql.NullInt64
+	if webhook.UserID != 0 {
+		userID = sql.NullInt64{Int64: webhook.UserID, Valid: true}
+	}
+	eventTypes := make([]string, 0, len(webhook.EventTypes))
+	for _, eventType := range webhook.EventTypes {
+		eventTypes = append(eventTypes, string(eventType))
+	}
+	if err := storage.pool.QueryRow(ctx, insertWebhookSQL, webhook.URL, eventTypes,
+		userID, webhook.Secret, webhook.CreatedAt).Scan(&webhook.ID); err != nil {
+		return nil, fmt.Errorf("can't add webhook to db <%w>", err)
+	}
+	return &webhook, nil
+}
+
+// Webhook returns domain.Webhook by id.
+func (storage *GrossBookStorage) Webhook(ctx context.Context, id int64) (*domain.Webhook, error) {
+	if storage.pool == nil {
+		return nil, ErrNotConnected
+	}
+	var webhook domain.Webhook
+	var eventTypes []string
+	var userID sql.NullInt64
+	if err := storage.pool.QueryRow(ctx, selectWebhookSQL, id).Scan(&webhook.ID,
+		&webhook.URL, &eventTypes, &userID, &webhook.Secret, &webhook.CreatedAt); err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrNoSuchWebhook
+		}
+		return nil, fmt.Errorf("can't read from db <%w>", err)
+	}
+	for _, eventType := range eventTypes {
+		webhook.EventTypes = append(webhook.EventTypes, domain.OperationType(eventType))
+	}
+	webhook.UserID = userID.Int64
+	return &webhook, nil
+}
+
+// WebhookDeliveries returns the latest domain.WebhookDelivery of webhook limited by limit.
+func (storage *GrossBookStorage) WebhookDeliveries(ctx context.Context, webhookID,
+	limit int64) ([]domain.WebhookDelivery, error) {
+	if storage.pool == nil {
+		return nil, ErrNotConnected
+	}
+	if limit <= 0 {
+		return nil, ErrIncorrectLimit
+	}
+	rows, err := storage.pool.Query(ctx, selectDeliveriesSQL, webhookID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("can't get deliveries: <%w>", err)
+	}
+	defer rows.Close()
+	deliveries := make([]domain.WebhookDelivery, 0)
+	for rows.Next() {
+		delivery, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, *delivery)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("can't read deliveries: <%w>", err)
+	}
+	return deliveries, nil
+}
+
+// ClaimDeliveries returns up to limit due pending deliveries with their webhooks and
+// postpones them by lease, so they aren't claimed twice while being sent.
+func (storage *GrossBookStorage) ClaimDeliveries(ctx context.Context, limit int,
+	lease time.Duration) ([]domain.WebhookDelivery, error) {
+	if storage.pool == nil {
+		return nil, ErrNotConnected
+	}
+	now := time.Now()
+	rows, err := storage.pool.Query(ctx, claimDeliveriesSQL, now.Add(lease),
+		domain.DeliveryPending, now, limit)
+	if err != nil {
+		return nil, fmt.Errorf("can't claim deliveries: <%w>", err)
+	}
+	defer rows.Close()
+	deliveries := make([]domain.WebhookDelivery, 0)
+	for rows.Next() {
+		webhook := &domain.Webhook{}
+		delivery, err := scanDelivery(rows, &webhook.URL, &webhook.Secret)
+		if err != nil {
+			return nil, err
+		}
+		webhook.ID = delivery.WebhookID
+		delivery.Webhook = webhook
+		deliveries = append(deliveries, *delivery)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("can't read deliveries: <%w>", err)
+	}
+	return deliveries, nil
+}
+
+// UpdateDelivery saves state of domain.WebhookDelivery after an attempt.
+func (storage *GrossBookStorage) UpdateDelivery(ctx context.Context,
+	delivery domain.WebhookDelivery) error {
+	if storage.pool == nil {
+		return ErrNotConnected
+	}
+	if _, err := storage.pool.Exec(ctx, updateDeliverySQL, delivery.Status,
+		delivery.Attempts, delivery.NextAttemptAt, delivery.LastError,
+		delivery.ResponseCode, delivery.DeliveredAt, delivery.ID); err != nil {
+		return fmt.Errorf("can't update delivery: <%w>", err)
+	}
+	return nil
+}
+
+// scanDelivery reads domain.WebhookDelivery and extra columns from row.
+func scanDelivery(row pgx.Row, extra ...interface{}) (*domain.WebhookDelivery, error) {
+	var delivery domain.WebhookDelivery
+	dest := append([]interface{}{&delivery.ID, &delivery.WebhookID, &delivery.EventType,
+		&delivery.Payload, &delivery.Status, &delivery.Attempts, &delivery.NextAttemptAt,
+		&delivery.LastError, &delivery.ResponseCode, &delivery.CreatedAt,
+		&delivery.DeliveredAt}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return nil, fmt.Errorf("can't read delivery from db <%w>", err)
+	}
+	return &delivery, nil
+}
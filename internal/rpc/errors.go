@@ -0,0 +1,50 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+
+	"github.com/agandreev/avito-intern-assignment/internal/domain"
+	"github.com/agandreev/avito-intern-assignment/internal/repository"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// statusError converts service error to grpc status with the same message.
+func statusError(err error) error {
+	return status.Error(errorCode(err), err.Error())
+}
+
+// errorCode returns grpc code of domain and repository errors. Errors without known
+// reason are reported as codes.Unknown.
+func errorCode(err error) codes.Code {
+	switch {
+	case errors.Is(err, repository.ErrNoSuchUser), errors.Is(err, repository.ErrNoOperations):
+		return codes.NotFound
+	case errors.Is(err, domain.ErrUserExists):
+		return codes.AlreadyExists
+	case errors.Is(err, domain.ErrZeroAmount), errors.Is(err, domain.ErrNegativeAmount),
+		errors.Is(err, domain.ErrIncorrectOperationParams),
+		errors.Is(err, domain.ErrIncorrectUserParams),
+		errors.Is(err, domain.ErrUnsupportedCurrency):
+		return codes.InvalidArgument
+	case errors.Is(err, domain.ErrInsufficientFunds), errors.Is(err, domain.ErrOverflow),
+		errors.Is(err, repository.ErrStatusChanged):
+		return codes.FailedPrecondition
+	case errors.Is(err, domain.ErrVersionConflict):
+		return codes.Aborted
+	case errors.Is(err, domain.ErrLimitExceeded), errors.Is(err, domain.ErrExchangeQuotaExceeded):
+		return codes.ResourceExhausted
+	case errors.Is(err, domain.ErrAccountFrozen), errors.Is(err, domain.ErrAccountClosed):
+		return codes.PermissionDenied
+	case errors.Is(err, repository.ErrNotConnected),
+		errors.Is(err, domain.ErrExchangeUnavailable):
+		return codes.Unavailable
+	case errors.Is(err, context.DeadlineExceeded):
+		return codes.DeadlineExceeded
+	case errors.Is(err, context.Canceled):
+		return codes.Canceled
+	default:
+		return codes.Unknown
+	}
+}
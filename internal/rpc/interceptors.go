@@ -0,0 +1,159 @@
+package rpc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/agandreev/avito-intern-assignment/internal/domain"
+	"github.com/agandreev/avito-intern-assignment/internal/service"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// metadata keys are lowercase versions of http headers
+	requestIDKey     = "x-request-id"
+	actorKey         = "x-actor"
+	authorizationKey = "authorization"
+	bearerPrefix     = "Bearer "
+)
+
+// requestIDKeyType is a context key of request's id.
+type requestIDKeyType struct{}
+
+// requestIDInterceptor takes request's id from metadata or generates it and sends it
+// back in header.
+func requestIDInterceptor(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler) (interface{}, error) {
+	requestID := metadataValue(ctx, requestIDKey)
+	if requestID == "" {
+		requestID = newRequestID()
+	}
+	_ = grpc.SetHeader(ctx, metadata.Pairs(requestIDKey, requestID))
+	return handler(context.WithValue(ctx, requestIDKeyType{}, requestID), req)
+}
+
+// authInterceptor identifies caller by bearer token and passes him to service. If
+// tokens are empty, any caller is accepted as anonymous. Actor from metadata isn't
+// verified, so it's passed as claimed one as in http api.
+func authInterceptor(tokens map[string]string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler) (interface{}, error) {
+		caller := domain.Caller{
+			Actor:        domain.AnonymousActor,
+			ClaimedActor: metadataValue(ctx, actorKey),
+			SourceIP:     sourceIP(ctx),
+		}
+		caller.RequestID, _ = ctx.Value(requestIDKeyType{}).(string)
+		if len(tokens) != 0 {
+			actor, ok := authenticate(tokens, metadataValue(ctx, authorizationKey))
+			if !ok {
+				return nil, status.Error(codes.Unauthenticated, "invalid bearer token")
+			}
+			caller.Actor = actor
+		}
+		return handler(service.WithCaller(ctx, caller), req)
+	}
+}
+
+// loggingInterceptor logs method, status code and duration of each call.
+func loggingInterceptor(logger *logrus.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		caller := service.CallerFrom(ctx)
+		logger.Printf("GRPC: <%s> from <%s> by <%s> [%s] returned <%s> in %s",
+			info.FullMethod, caller.SourceIP, caller.Actor, caller.RequestID,
+			status.Code(err), time.Since(start))
+		return resp, err
+	}
+}
+
+// auditInterceptor records every call with its caller, sanitized request and code.
+func auditInterceptor(gb *service.GrossBook, logger *logrus.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler) (interface{}, error) {
+		resp, err := handler(ctx, req)
+		var payload []byte
+		if message, ok := req.(proto.Message); ok {
+			payload, _ = protojson.Marshal(message)
+		}
+		code := status.Code(err)
+		entry := domain.AuditEntry{
+			Caller:   service.CallerFrom(ctx),
+			Action:   domain.GRPCAction,
+			Endpoint: info.FullMethod,
+			Payload:  domain.SanitizePayload(payload),
+			Status:   int(code),
+			Outcome:  domain.AuditSuccess,
+		}
+		if code != codes.OK {
+			entry.Outcome = domain.AuditFailure
+		}
+		if auditErr := gb.RecordAudit(ctx, entry); auditErr != nil {
+			logger.Printf("GRPC AUDIT ERROR: <%s>", auditErr)
+		}
+		return resp, err
+	}
+}
+
+// authenticate returns actor of bearer token from authorization value.
+func authenticate(tokens map[string]string, authorization string) (string, bool) {
+	if !strings.HasPrefix(authorization, bearerPrefix) {
+		return "", false
+	}
+	token := strings.TrimPrefix(authorization, bearerPrefix)
+	// all tokens are compared in constant time
+	actor, ok := "", false
+	for known, knownActor := range tokens {
+		if subtle.ConstantTimeCompare([]byte(known), []byte(token)) == 1 {
+			actor, ok = knownActor, true
+		}
+	}
+	return actor, ok
+}
+
+// metadataValue returns the first value of incoming metadata by key.
+func metadataValue(ctx context.Context, key string) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	values := md.Get(key)
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+// sourceIP returns host of caller's address.
+func sourceIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
+
+// newRequestID returns random hex id.
+func newRequestID() string {
+	data := make([]byte, 8)
+	_, _ = rand.Read(data)
+	return hex.EncodeToString(data)
+}
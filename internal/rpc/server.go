@@ -0,0 +1,151 @@
+package rpc
+
+import (
+	"context"
+
+	"github.com/agandreev/avito-intern-assignment/internal/domain"
+	"github.com/agandreev/avito-intern-assignment/internal/service"
+	"github.com/agandreev/avito-intern-assignment/pkg/grossbookpb"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Config contains grpc server's settings.
+type Config struct {
+	// Tokens maps bearer tokens to actors, empty Tokens disable authentication.
+	Tokens map[string]string
+}
+
+// Server implements grossbookpb.GrossBookServer by service.GrossBook.
+type Server struct {
+	grossbookpb.UnimplementedGrossBookServer
+	GB  *service.GrossBook
+	log *logrus.Logger
+}
+
+// NewServer creates grpc.Server with interceptors and registers Server in it.
+func NewServer(gb *service.GrossBook, logger *logrus.Logger, config Config) *grpc.Server {
+	grpcServer := grpc.NewServer(grpc.ChainUnaryInterceptor(
+		requestIDInterceptor,
+		authInterceptor(config.Tokens),
+		loggingInterceptor(logger),
+		auditInterceptor(gb, logger),
+	))
+	grossbookpb.RegisterGrossBookServer(grpcServer, &Server{GB: gb, log: logger})
+	return grpcServer
+}
+
+// Balance returns user's balance.
+func (server *Server) Balance(ctx context.Context, request *grossbookpb.BalanceRequest) (
+	*grossbookpb.User, error) {
+	user, err := server.GB.Balance(ctx, request.GetId())
+	if err != nil {
+		server.log.Printf("GRPC BALANCE ERROR: <%s>", err)
+		return nil, statusError(err)
+	}
+	return &grossbookpb.User{
+		Id:       user.ID,
+		Amount:   user.Amount,
+		Status:   string(user.AccountStatus()),
+		Currency: user.Currency,
+	}, nil
+}
+
+// History returns user's operations.
+func (server *Server) History(ctx context.Context, request *grossbookpb.HistoryRequest) (
+	*grossbookpb.HistoryResponse, error) {
+	if request.GetQuantity() <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "quantity must be positive")
+	}
+	operations, err := server.GB.History(ctx, request.GetId(), request.GetQuantity(),
+		sortingMode(request.GetMode()), domain.OperationFilter{})
+	if err != nil {
+		server.log.Printf("GRPC HISTORY ERROR: <%s>", err)
+		return nil, statusError(err)
+	}
+	response := &grossbookpb.HistoryResponse{
+		Operations: make([]*grossbookpb.Operation, 0, len(operations)),
+	}
+	for _, operation := range operations {
+		response.Operations = append(response.Operations, &grossbookpb.Operation{
+			InitiatorId:    operation.InitiatorID,
+			Type:           string(operation.Type),
+			Amount:         operation.Amount,
+			Timestamp:      timestamppb.New(operation.Timestamp),
+			ReceiverId:     operation.ReceiverID,
+			Fee:            operation.Fee,
+			Currency:       operation.Currency,
+			OriginalAmount: operation.OriginalAmount,
+		})
+	}
+	return response, nil
+}
+
+// Deposit increases user's balance.
+func (server *Server) Deposit(ctx context.Context, request *grossbookpb.DepositRequest) (
+	*grossbookpb.Operation, error) {
+	operation, err := server.GB.DepositMoney(ctx, request.GetInitiatorId(),
+		request.GetAmount(), request.GetCurrency())
+	if err != nil {
+		server.log.Printf("GRPC DEPOSIT ERROR: <%s>", err)
+		return nil, statusError(err)
+	}
+	return operationMessage(operation), nil
+}
+
+// Withdraw decreases user's balance.
+func (server *Server) Withdraw(ctx context.Context, request *grossbookpb.WithdrawRequest) (
+	*grossbookpb.Operation, error) {
+	operation, err := server.GB.WithdrawMoney(ctx, request.GetInitiatorId(),
+		request.GetAmount(), request.GetCurrency())
+	if err != nil {
+		server.log.Printf("GRPC WITHDRAW ERROR: <%s>", err)
+		return nil, statusError(err)
+	}
+	return operationMessage(operation), nil
+}
+
+// Transfer transfers money from initiator to receiver.
+func (server *Server) Transfer(ctx context.Context, request *grossbookpb.TransferRequest) (
+	*grossbookpb.Operation, error) {
+	operation, err := server.GB.TransferMoney(ctx, request.GetInitiatorId(),
+		request.GetReceiverId(), request.GetAmount(), request.GetCurrency())
+	if err != nil {
+		server.log.Printf("GRPC TRANSFER ERROR: <%s>", err)
+		return nil, statusError(err)
+	}
+	return operationMessage(operation), nil
+}
+
+// operationMessage converts domain.Operation to grossbookpb.Operation.
+func operationMessage(operation *domain.Operation) *grossbookpb.Operation {
+	message := &grossbookpb.Operation{
+		InitiatorId:    operation.Initiator.ID,
+		Type:           string(operation.Type),
+		Amount:         operation.Amount,
+		Timestamp:      timestamppb.New(operation.Timestamp),
+		Fee:            operation.Fee,
+		Balance:        operation.Initiator.Amount,
+		Currency:       operation.Currency,
+		OriginalAmount: operation.OriginalAmount,
+	}
+	if operation.Receiver != nil {
+		message.ReceiverId = operation.Receiver.ID
+	}
+	return message
+}
+
+// sortingMode converts grossbookpb.SortingMode to domain.SortingMode.
+func sortingMode(mode grossbookpb.SortingMode) domain.SortingMode {
+	switch mode {
+	case grossbookpb.SortingMode_SORTING_MODE_AMOUNT:
+		return domain.AmountMode
+	case grossbookpb.SortingMode_SORTING_MODE_DATE:
+		return domain.DateMode
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,231 @@
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/agandreev/avito-intern-assignment/internal/domain"
+	"github.com/agandreev/avito-intern-assignment/internal/repository"
+	"github.com/agandreev/avito-intern-assignment/internal/service"
+	"github.com/agandreev/avito-intern-assignment/pkg/grossbookpb"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// memoryRepository stores users' balances, unused methods panic.
+type memoryRepository struct {
+	service.GrossBookRepository
+	mu      sync.Mutex
+	amounts map[int64]float64
+	entries []domain.AuditEntry
+}
+
+func (storage *memoryRepository) User(_ context.Context, id int64) (*domain.User, error) {
+	storage.mu.Lock()
+	defer storage.mu.Unlock()
+	amount, ok := storage.amounts[id]
+	if !ok {
+		return nil, repository.ErrNoSuchUser
+	}
+	return &domain.User{ID: id, Amount: amount, Status: domain.Active}, nil
+}
+
+func (storage *memoryRepository) UserLimits(context.Context, int64) (*domain.Limits, error) {
+	return &domain.Limits{}, nil
+}
+
+func (storage *memoryRepository) AddOperation(ctx context.Context,
+	operation domain.Operation) ([]domain.Event, error) {
+	return storage.AddOperations(ctx, []domain.Operation{operation})
+}
+
+func (storage *memoryRepository) AddOperations(_ context.Context,
+	operations []domain.Operation) ([]domain.Event, error) {
+	storage.mu.Lock()
+	defer storage.mu.Unlock()
+	for _, operation := range operations {
+		storage.amounts[operation.Initiator.ID] = operation.Initiator.Amount
+		if operation.IsTransfer() {
+			storage.amounts[operation.Receiver.ID] = operation.Receiver.Amount
+		}
+	}
+	return nil, nil
+}
+
+func (storage *memoryRepository) AddAuditEntry(_ context.Context,
+	entry domain.AuditEntry) (*domain.AuditEntry, error) {
+	storage.mu.Lock()
+	defer storage.mu.Unlock()
+	storage.entries = append(storage.entries, entry)
+	return &entry, nil
+}
+
+// rubConverter converts money to RUB by a fixed rate.
+type rubConverter float64
+
+func (rate rubConverter) Convert(_ context.Context, _, _ string, amount float64) (
+	float64, error) {
+	return amount * float64(rate), nil
+}
+
+type ServerSuite struct {
+	suite.Suite
+	storage  *memoryRepository
+	server   *grpc.Server
+	conn     *grpc.ClientConn
+	client   grossbookpb.GrossBookClient
+	listener *bufconn.Listener
+}
+
+func (suite *ServerSuite) SetupTest() {
+	suite.storage = &memoryRepository{amounts: map[int64]float64{1: 100, 2: 0}}
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	gb := service.NewGrossBook(suite.storage, rubConverter(90), logger)
+	suite.server = NewServer(gb, logger, Config{Tokens: map[string]string{"secret": "billing"}})
+	suite.listener = bufconn.Listen(1 << 20)
+	go func() {
+		_ = suite.server.Serve(suite.listener)
+	}()
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return suite.listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	suite.Require().NoError(err)
+	suite.conn = conn
+	suite.client = grossbookpb.NewGrossBookClient(conn)
+}
+
+func (suite *ServerSuite) TearDownTest() {
+	_ = suite.conn.Close()
+	suite.server.Stop()
+}
+
+// authorized returns context with billing's token.
+func (suite *ServerSuite) authorized() context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(),
+		authorizationKey, bearerPrefix+"secret")
+}
+
+func (suite *ServerSuite) TestServer_Auth() {
+	_, err := suite.client.Balance(context.Background(), &grossbookpb.BalanceRequest{Id: 1})
+	suite.Equal(codes.Unauthenticated, status.Code(err))
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(),
+		authorizationKey, bearerPrefix+"wrong")
+	_, err = suite.client.Balance(ctx, &grossbookpb.BalanceRequest{Id: 1})
+	suite.Equal(codes.Unauthenticated, status.Code(err))
+
+	user, err := suite.client.Balance(suite.authorized(), &grossbookpb.BalanceRequest{Id: 1})
+	suite.Require().NoError(err)
+	suite.Equal(100.0, user.GetAmount())
+	suite.Equal(string(domain.Active), user.GetStatus())
+}
+
+func (suite *ServerSuite) TestServer_Operations() {
+	operation, err := suite.client.Transfer(suite.authorized(),
+		&grossbookpb.TransferRequest{InitiatorId: 1, ReceiverId: 2, Amount: 40})
+	suite.Require().NoError(err)
+	suite.Equal(string(domain.TransferOut), operation.GetType())
+	suite.Equal(int64(2), operation.GetReceiverId())
+	suite.Equal(60.0, operation.GetBalance())
+
+	// foreign amounts are converted to RUB
+	operation, err = suite.client.Deposit(suite.authorized(),
+		&grossbookpb.DepositRequest{InitiatorId: 2, Amount: 1, Currency: "usd"})
+	suite.Require().NoError(err)
+	suite.Equal(90.0, operation.GetAmount())
+	suite.Equal("USD", operation.GetCurrency())
+	suite.Equal(1.0, operation.GetOriginalAmount())
+	operation, err = suite.client.Transfer(suite.authorized(),
+		&grossbookpb.TransferRequest{InitiatorId: 2, ReceiverId: 1, Amount: 1,
+			Currency: "USD"})
+	suite.Require().NoError(err)
+	suite.Equal(40.0, operation.GetBalance())
+	suite.Equal("USD", operation.GetCurrency())
+
+	_, err = suite.client.Withdraw(suite.authorized(),
+		&grossbookpb.WithdrawRequest{InitiatorId: 2, Amount: 50})
+	suite.Equal(codes.FailedPrecondition, status.Code(err))
+
+	_, err = suite.client.Deposit(suite.authorized(),
+		&grossbookpb.DepositRequest{InitiatorId: 3, Amount: 50})
+	suite.Equal(codes.NotFound, status.Code(err))
+
+	_, err = suite.client.Deposit(suite.authorized(),
+		&grossbookpb.DepositRequest{InitiatorId: 1, Amount: -5})
+	suite.Equal(codes.InvalidArgument, status.Code(err))
+}
+
+func (suite *ServerSuite) TestServer_RequestIDAndAudit() {
+	var header metadata.MD
+	ctx := metadata.AppendToOutgoingContext(suite.authorized(), requestIDKey, "req-1",
+		actorKey, "payouts")
+	_, err := suite.client.Deposit(ctx, &grossbookpb.DepositRequest{InitiatorId: 1,
+		Amount: 10}, grpc.Header(&header))
+	suite.Require().NoError(err)
+	suite.Equal([]string{"req-1"}, header.Get(requestIDKey))
+
+	// id is generated if caller hasn't sent it
+	_, err = suite.client.Balance(suite.authorized(), &grossbookpb.BalanceRequest{Id: 5},
+		grpc.Header(&header))
+	suite.Equal(codes.NotFound, status.Code(err))
+	suite.Len(header.Get(requestIDKey), 1)
+	suite.NotEqual("req-1", header.Get(requestIDKey)[0])
+
+	suite.storage.mu.Lock()
+	defer suite.storage.mu.Unlock()
+	suite.Require().Len(suite.storage.entries, 2)
+	deposit := suite.storage.entries[0]
+	suite.Equal(domain.GRPCAction, deposit.Action)
+	suite.Equal(grossbookpb.GrossBook_Deposit_FullMethodName, deposit.Endpoint)
+	// actor of token is audited, actor of metadata is only claimed
+	suite.Equal("billing", deposit.Actor)
+	suite.Equal("payouts", deposit.ClaimedActor)
+	suite.Equal("req-1", deposit.RequestID)
+	suite.Equal(domain.AuditSuccess, deposit.Outcome)
+	suite.Contains(deposit.Payload, "initiatorId")
+	suite.Equal(domain.AuditFailure, suite.storage.entries[1].Outcome)
+	suite.Equal(int(codes.NotFound), suite.storage.entries[1].Status)
+}
+
+func (suite *ServerSuite) TestErrorCode() {
+	tests := []struct {
+		err  error
+		code codes.Code
+	}{
+		{fmt.Errorf("wrapped: <%w>", domain.ErrUserExists), codes.AlreadyExists},
+		{fmt.Errorf("wrapped: <%w>", domain.ErrIncorrectUserParams), codes.InvalidArgument},
+		{fmt.Errorf("wrapped: <%w>", repository.ErrStatusChanged), codes.FailedPrecondition},
+		{fmt.Errorf("wrapped: <%w>", domain.ErrVersionConflict), codes.Aborted},
+		{fmt.Errorf("wrapped: <%w>", domain.ErrUnsupportedCurrency), codes.InvalidArgument},
+		{fmt.Errorf("wrapped: <%w>", domain.LimitExceededError{Limit: "daily_withdraw"}),
+			codes.ResourceExhausted},
+		{fmt.Errorf("wrapped: <%w>", domain.ErrAccountFrozen), codes.PermissionDenied},
+		{fmt.Errorf("wrapped: <%w>", domain.ErrAccountClosed), codes.PermissionDenied},
+		{repository.ErrNotConnected, codes.Unavailable},
+		{fmt.Errorf("wrapped: <%w>", domain.ErrExchangeUnavailable), codes.Unavailable},
+		{fmt.Errorf("wrapped: <%w>", domain.ErrExchangeQuotaExceeded),
+			codes.ResourceExhausted},
+		{context.DeadlineExceeded, codes.DeadlineExceeded},
+		{fmt.Errorf("unknown"), codes.Unknown},
+	}
+	for _, test := range tests {
+		suite.Equal(test.code, errorCode(test.err), test.err.Error())
+	}
+}
+
+func TestServerSuite(t *testing.T) {
+	suite.Run(t, new(ServerSuite))
+}
@@ -0,0 +1,132 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/agandreev/avito-intern-assignment/internal/domain"
+	"github.com/sirupsen/logrus"
+)
+
+// ScheduleStorage describes scheduled transfers storage methods.
+type ScheduleStorage interface {
+	ClaimSchedules(ctx context.Context, limit int, lease time.Duration) (
+		[]domain.Schedule, error)
+	RecordRun(ctx context.Context, run *domain.ScheduleRun) error
+}
+
+// Transferrer executes scheduled transfers and stores their runs in the same transaction.
+type Transferrer interface {
+	TransferScheduled(ctx context.Context, schedule domain.Schedule,
+		run domain.ScheduleRun) (*domain.Operation, error)
+}
+
+// Config contains polling settings.
+type Config struct {
+	Interval  time.Duration
+	BatchSize int
+	// Lease is a time during which claimed schedules aren't claimed by other replicas.
+	Lease time.Duration
+}
+
+// Scheduler executes due scheduled transfers. Each run is executed once: execution is
+// stored and schedule is moved to the next run in the transfer's transaction, so a run
+// which was interrupted is rolled back and claimed again after the lease.
+type Scheduler struct {
+	storage     ScheduleStorage
+	transferrer Transferrer
+	config      Config
+	log         *logrus.Logger
+}
+
+// NewScheduler sets Scheduler fields and returns pointer.
+func NewScheduler(storage ScheduleStorage, transferrer Transferrer, config Config,
+	log *logrus.Logger) *Scheduler {
+	return &Scheduler{
+		storage:     storage,
+		transferrer: transferrer,
+		config:      config,
+		log:         log,
+	}
+}
+
+// Run executes schedules until ctx is done.
+func (scheduler *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(scheduler.config.Interval)
+	defer ticker.Stop()
+	for {
+		// drain due schedules while batches are full
+		for scheduler.RunOnce(ctx) == scheduler.config.BatchSize && ctx.Err() == nil {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce executes a single batch of due schedules and returns its size.
+func (scheduler *Scheduler) RunOnce(ctx context.Context) int {
+	schedules, err := scheduler.storage.ClaimSchedules(ctx, scheduler.config.BatchSize,
+		scheduler.config.Lease)
+	if err != nil {
+		scheduler.log.Printf("SCHEDULER ERROR: <%s>", err)
+		return 0
+	}
+	for _, schedule := range schedules {
+		if err = scheduler.execute(ctx, schedule); err != nil {
+			scheduler.log.Printf("SCHEDULER ERROR: schedule <%d>: <%s>", schedule.ID, err)
+		}
+	}
+	return len(schedules)
+}
+
+// execute transfers money of schedule's due run and saves the result.
+func (scheduler *Scheduler) execute(ctx context.Context, schedule domain.Schedule) error {
+	// missed runs aren't repeated, the next run is planned after now
+	after := time.Now()
+	if schedule.NextRunAt.After(after) {
+		after = *schedule.NextRunAt
+	}
+	next, err := schedule.NextRun(after)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	run := domain.ScheduleRun{
+		Execution: domain.ScheduleExecution{
+			ScheduleID:  schedule.ID,
+			ScheduledAt: *schedule.NextRunAt,
+			Status:      domain.ExecutionSucceeded,
+			ExecutedAt:  &now,
+		},
+		Next: next,
+	}
+	scheduler.log.Printf("SCHEDULER: schedule <%d> run at <%s> processing...",
+		schedule.ID, run.Execution.ScheduledAt)
+	_, err = scheduler.transferrer.TransferScheduled(ctx, schedule, run)
+	switch {
+	case err == nil:
+	case errors.Is(err, domain.ErrRunExecuted):
+		// run was executed by another replica
+		return nil
+	case ctx.Err() != nil:
+		// interrupted run is claimed again after the lease
+		return err
+	default:
+		run.Execution.Status = domain.ExecutionFailed
+		run.Execution.Error = err.Error()
+		if err = scheduler.storage.RecordRun(ctx, &run); err != nil {
+			if errors.Is(err, domain.ErrRunExecuted) {
+				return nil
+			}
+			return err
+		}
+	}
+	scheduler.log.Printf("SCHEDULER: schedule <%d> run at <%s> is %s",
+		schedule.ID, run.Execution.ScheduledAt, run.Execution.Status)
+	return nil
+}
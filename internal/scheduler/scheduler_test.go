@@ -0,0 +1,158 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/agandreev/avito-intern-assignment/internal/domain"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/suite"
+)
+
+var errInsufficientFunds = errors.New("user hasn't enough money")
+
+// memorySchedules imitates schedules and schedule_executions tables.
+type memorySchedules struct {
+	schedules  map[int64]domain.Schedule
+	executions []domain.ScheduleExecution
+}
+
+func (storage *memorySchedules) ClaimSchedules(_ context.Context, limit int,
+	_ time.Duration) ([]domain.Schedule, error) {
+	claimed := make([]domain.Schedule, 0)
+	for _, schedule := range storage.schedules {
+		if len(claimed) == limit {
+			break
+		}
+		if schedule.Active && !schedule.NextRunAt.After(time.Now()) {
+			claimed = append(claimed, schedule)
+		}
+	}
+	return claimed, nil
+}
+
+func (storage *memorySchedules) RecordRun(_ context.Context, run *domain.ScheduleRun) error {
+	return storage.startRun(run)
+}
+
+// startRun stores run's execution and moves schedule to the next run.
+func (storage *memorySchedules) startRun(run *domain.ScheduleRun) error {
+	for _, execution := range storage.executions {
+		if execution.ScheduleID == run.Execution.ScheduleID &&
+			execution.ScheduledAt.Equal(run.Execution.ScheduledAt) {
+			return domain.ErrRunExecuted
+		}
+	}
+	run.Execution.ID = int64(len(storage.executions) + 1)
+	storage.executions = append(storage.executions, run.Execution)
+	schedule := storage.schedules[run.Execution.ScheduleID]
+	schedule.NextRunAt = run.Next
+	schedule.Active = run.Next != nil
+	storage.schedules[schedule.ID] = schedule
+	return nil
+}
+
+// poorTransferrer fails transfers of amounts bigger than balance and stores runs of
+// successful ones.
+type poorTransferrer struct {
+	storage   *memorySchedules
+	balance   float64
+	transfers int
+}
+
+func (transferrer *poorTransferrer) TransferScheduled(ctx context.Context,
+	schedule domain.Schedule, run domain.ScheduleRun) (*domain.Operation, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if schedule.Amount > transferrer.balance {
+		return nil, errInsufficientFunds
+	}
+	if err := transferrer.storage.startRun(&run); err != nil {
+		return nil, err
+	}
+	transferrer.transfers++
+	return &domain.Operation{
+		Initiator: &domain.User{ID: schedule.InitiatorID},
+		Type:      domain.TransferOut,
+		Amount:    schedule.Amount,
+		Receiver:  &domain.User{ID: schedule.ReceiverID},
+	}, nil
+}
+
+type SchedulerSuite struct {
+	suite.Suite
+	Storage     *memorySchedules
+	Transferrer *poorTransferrer
+	Scheduler   *Scheduler
+}
+
+func (suite *SchedulerSuite) SetupTest() {
+	due := time.Now().Add(-time.Hour)
+	suite.Storage = &memorySchedules{schedules: map[int64]domain.Schedule{
+		1: {ID: 1, InitiatorID: 1, ReceiverID: 2, Amount: 10, Cron: "0 9 1 * *",
+			NextRunAt: &due, Active: true},
+		2: {ID: 2, InitiatorID: 1, ReceiverID: 3, Amount: 1000, RunAt: &due,
+			NextRunAt: &due, Active: true},
+	}}
+	suite.Transferrer = &poorTransferrer{storage: suite.Storage, balance: 100}
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	suite.Scheduler = NewScheduler(suite.Storage, suite.Transferrer, Config{
+		Interval:  time.Millisecond,
+		BatchSize: 10,
+		Lease:     time.Minute,
+	}, logger)
+}
+
+func (suite *SchedulerSuite) TestScheduler_RunOnce() {
+	suite.Equal(2, suite.Scheduler.RunOnce(context.Background()))
+	suite.Equal(1, suite.Transferrer.transfers)
+	suite.Require().Len(suite.Storage.executions, 2)
+	for _, execution := range suite.Storage.executions {
+		suite.NotNil(execution.ExecutedAt)
+		if execution.ScheduleID == 1 {
+			suite.Equal(domain.ExecutionSucceeded, execution.Status)
+		} else {
+			suite.Equal(domain.ExecutionFailed, execution.Status)
+			suite.Equal(errInsufficientFunds.Error(), execution.Error)
+		}
+	}
+	// recurring schedule is moved to the future, one-off schedule is finished
+	suite.True(suite.Storage.schedules[1].NextRunAt.After(time.Now()))
+	suite.False(suite.Storage.schedules[2].Active)
+	suite.Nil(suite.Storage.schedules[2].NextRunAt)
+
+	suite.Zero(suite.Scheduler.RunOnce(context.Background()))
+	suite.Equal(1, suite.Transferrer.transfers)
+}
+
+func (suite *SchedulerSuite) TestScheduler_RunOnceExecutedRun() {
+	// run which was executed by another replica isn't repeated
+	suite.Storage.executions = append(suite.Storage.executions, domain.ScheduleExecution{
+		ID:          1,
+		ScheduleID:  1,
+		ScheduledAt: *suite.Storage.schedules[1].NextRunAt,
+		Status:      domain.ExecutionSucceeded,
+	})
+	suite.Scheduler.RunOnce(context.Background())
+	suite.Zero(suite.Transferrer.transfers)
+	suite.Len(suite.Storage.executions, 2)
+}
+
+func (suite *SchedulerSuite) TestScheduler_RunOnceInterrupted() {
+	// interrupted runs aren't recorded, so they're claimed again
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	suite.Equal(2, suite.Scheduler.RunOnce(ctx))
+	suite.Empty(suite.Storage.executions)
+	suite.Equal(2, suite.Scheduler.RunOnce(context.Background()))
+	suite.Len(suite.Storage.executions, 2)
+}
+
+func TestSchedulerSuite(t *testing.T) {
+	suite.Run(t, new(SchedulerSuite))
+}
@@ -0,0 +1,138 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/agandreev/avito-intern-assignment/internal/domain"
+)
+
+// defaultAdjustmentTTL is used if GrossBook.AdjustmentTTL isn't set.
+const defaultAdjustmentTTL = 72 * time.Hour
+
+// RequestAdjustment creates pending domain.AdjustmentRequest of ctx's caller. It changes
+// nothing until another operator approves it.
+func (grossBook GrossBook) RequestAdjustment(ctx context.Context, userID int64,
+	input domain.AdjustmentInput) (request *domain.AdjustmentRequest, err error) {
+	grossBook.log.Printf("REQUEST ADJUSTMENT: <%f>RUB of <%d> processing...", input.Amount,
+		userID)
+	defer func() {
+		grossBook.audit(ctx, requestAdjustmentAction, userTarget(userID), input, err)
+	}()
+	if _, err = grossBook.Users.User(ctx, userID); err != nil {
+		return nil, fmt.Errorf("can't request adjustment: <%w>", err)
+	}
+	ttl := grossBook.AdjustmentTTL
+	if ttl <= 0 {
+		ttl = defaultAdjustmentTTL
+	}
+	request, err = input.Request(userID, CallerFrom(ctx).Actor, time.Now(), ttl)
+	if err != nil {
+		return nil, fmt.Errorf("can't request adjustment: <%w>", err)
+	}
+	if request, err = grossBook.Users.AddAdjustment(ctx, *request); err != nil {
+		return nil, fmt.Errorf("can't request adjustment: <%w>", err)
+	}
+	grossBook.log.Printf("REQUEST ADJUSTMENT: <%d> of <%d> was processed successful",
+		request.ID, userID)
+	return request, nil
+}
+
+// ApproveAdjustment approves pending domain.AdjustmentRequest by ctx's caller, who must
+// differ from its creator, and applies it as domain.Adjustment operation. Adjustments
+// aren't limited and aren't charged.
+func (grossBook *GrossBook) ApproveAdjustment(ctx context.Context, id int64,
+	input domain.ReviewInput) (request *domain.AdjustmentRequest, err error) {
+	grossBook.log.Printf("APPROVE ADJUSTMENT: <%d> processing...", id)
+	defer func() {
+		grossBook.audit(ctx, approveAdjustmentAction, adjustmentTarget(id), input, err)
+	}()
+	now := time.Now()
+	if request, err = grossBook.adjustment(ctx, id, now); err != nil {
+		return nil, fmt.Errorf("can't approve adjustment: <%w>", err)
+	}
+	if err = request.Approve(CallerFrom(ctx).Actor, input, now); err != nil {
+		return nil, fmt.Errorf("can't approve adjustment: <%w>", err)
+	}
+	// operation is computed again from user's fresh balance after conflicts
+	var events []domain.Event
+	if err = grossBook.retryConflicts(ctx, func() error {
+		user, err := grossBook.Users.User(ctx, request.UserID)
+		if err != nil {
+			return err
+		}
+		operation, err := request.Operation(user, now)
+		if err != nil {
+			return err
+		}
+		if err = operation.CheckStatus(); err != nil {
+			return err
+		}
+		events, err = grossBook.Users.ReviewAdjustment(ctx, *request, operation)
+		return err
+	}); err != nil {
+		return nil, fmt.Errorf("can't approve adjustment: <%w>", err)
+	}
+	grossBook.notify(events)
+	grossBook.log.Printf("APPROVE ADJUSTMENT: <%d> of <%d> was processed successful", id,
+		request.UserID)
+	return request, nil
+}
+
+// RejectAdjustment rejects pending domain.AdjustmentRequest by ctx's caller.
+func (grossBook GrossBook) RejectAdjustment(ctx context.Context, id int64,
+	input domain.ReviewInput) (request *domain.AdjustmentRequest, err error) {
+	grossBook.log.Printf("REJECT ADJUSTMENT: <%d> processing...", id)
+	defer func() {
+		grossBook.audit(ctx, rejectAdjustmentAction, adjustmentTarget(id), input, err)
+	}()
+	now := time.Now()
+	if request, err = grossBook.adjustment(ctx, id, now); err != nil {
+		return nil, fmt.Errorf("can't reject adjustment: <%w>", err)
+	}
+	if err = request.Reject(CallerFrom(ctx).Actor, input, now); err != nil {
+		return nil, fmt.Errorf("can't reject adjustment: <%w>", err)
+	}
+	if _, err = grossBook.Users.ReviewAdjustment(ctx, *request, nil); err != nil {
+		return nil, fmt.Errorf("can't reject adjustment: <%w>", err)
+	}
+	grossBook.log.Printf("REJECT ADJUSTMENT: <%d> was processed successful", id)
+	return request, nil
+}
+
+// Adjustment returns domain.AdjustmentRequest with its current status.
+func (grossBook GrossBook) Adjustment(ctx context.Context, id int64) (
+	*domain.AdjustmentRequest, error) {
+	request, err := grossBook.adjustment(ctx, id, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("can't load adjustment: <%w>", err)
+	}
+	return request, nil
+}
+
+// Adjustments returns history of adjustments with their creators and reviewers.
+func (grossBook GrossBook) Adjustments(ctx context.Context,
+	filter domain.AdjustmentFilter) ([]domain.AdjustmentRequest, error) {
+	if filter.Status != "" && !filter.Status.IsValid() {
+		return nil, fmt.Errorf("unknown status <%s>: <%w>", filter.Status,
+			domain.ErrIncorrectAdjustmentParams)
+	}
+	if _, err := grossBook.Users.ExpireAdjustments(ctx, time.Now()); err != nil {
+		return nil, fmt.Errorf("can't load adjustments: <%w>", err)
+	}
+	requests, err := grossBook.Users.Adjustments(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("can't load adjustments: <%w>", err)
+	}
+	return requests, nil
+}
+
+// adjustment expires outdated adjustments and returns domain.AdjustmentRequest by id.
+func (grossBook GrossBook) adjustment(ctx context.Context, id int64, now time.Time) (
+	*domain.AdjustmentRequest, error) {
+	if _, err := grossBook.Users.ExpireAdjustments(ctx, now); err != nil {
+		return nil, err
+	}
+	return grossBook.Users.Adjustment(ctx, id)
+}
@@ -0,0 +1,194 @@
+package service
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/agandreev/avito-intern-assignment/internal/domain"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/suite"
+)
+
+// adjustmentRepository keeps adjustments in memory and applies approved ones.
+type adjustmentRepository struct {
+	auditRepository
+	requests []domain.AdjustmentRequest
+	// conflicts is a quantity of the next reviews failed by concurrent deposits
+	conflicts int
+}
+
+func (repository *adjustmentRepository) AddAdjustment(_ context.Context,
+	request domain.AdjustmentRequest) (*domain.AdjustmentRequest, error) {
+	request.ID = int64(len(repository.requests) + 1)
+	repository.requests = append(repository.requests, request)
+	return &request, nil
+}
+
+func (repository *adjustmentRepository) Adjustment(_ context.Context, id int64) (
+	*domain.AdjustmentRequest, error) {
+	if id < 1 || id > int64(len(repository.requests)) {
+		return nil, domain.ErrNoSuchAdjustment
+	}
+	request := repository.requests[id-1]
+	return &request, nil
+}
+
+func (repository *adjustmentRepository) ExpireAdjustments(_ context.Context,
+	now time.Time) (int64, error) {
+	var expired int64
+	for i, request := range repository.requests {
+		if request.Status == domain.AdjustmentPending && !now.Before(request.ExpiresAt) {
+			repository.requests[i].Status = domain.AdjustmentExpired
+			expired++
+		}
+	}
+	return expired, nil
+}
+
+func (repository *adjustmentRepository) ReviewAdjustment(ctx context.Context,
+	request domain.AdjustmentRequest, operation *domain.Operation) ([]domain.Event, error) {
+	if repository.conflicts > 0 && operation != nil {
+		repository.conflicts--
+		user := repository.users[operation.Initiator.ID]
+		user.Amount += 10
+		repository.users[user.ID] = user
+		return nil, domain.ErrVersionConflict
+	}
+	repository.requests[request.ID-1] = request
+	if operation == nil {
+		return nil, nil
+	}
+	return repository.AddOperation(ctx, *operation)
+}
+
+type AdjustmentsSuite struct {
+	suite.Suite
+	Repository *adjustmentRepository
+	GrossBook  *GrossBook
+	Maker      context.Context
+	Checker    context.Context
+}
+
+func (suite *AdjustmentsSuite) SetupTest() {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	suite.Repository = &adjustmentRepository{auditRepository: auditRepository{
+		statusRepository: statusRepository{users: map[int64]domain.User{
+			1: {ID: 1, Amount: 100},
+			2: {ID: 2, Amount: 100, Status: domain.Closed},
+		}},
+	}}
+	suite.GrossBook = NewGrossBook(suite.Repository, blockingConverter{}, logger)
+	suite.Maker = WithCaller(context.Background(), domain.Caller{Actor: "maker"})
+	suite.Checker = WithCaller(context.Background(), domain.Caller{Actor: "checker"})
+}
+
+func (suite *AdjustmentsSuite) TestGrossBook_ApproveAdjustment() {
+	request, err := suite.GrossBook.RequestAdjustment(suite.Maker, 1,
+		domain.AdjustmentInput{Amount: -30, Reason: "duplicated deposit", Ticket: "SUP-42"})
+	suite.Require().NoError(err)
+	suite.Equal("maker", request.CreatedBy)
+	suite.Equal(request.CreatedAt.Add(defaultAdjustmentTTL), request.ExpiresAt)
+	// nothing is applied before approval
+	suite.Empty(suite.Repository.operations)
+
+	_, err = suite.GrossBook.ApproveAdjustment(suite.Maker, request.ID, domain.ReviewInput{})
+	suite.ErrorIs(err, domain.ErrSelfApproval)
+	_, err = suite.GrossBook.ApproveAdjustment(context.Background(), request.ID,
+		domain.ReviewInput{})
+	suite.ErrorIs(err, domain.ErrIncorrectAdjustmentParams)
+
+	request, err = suite.GrossBook.ApproveAdjustment(suite.Checker, request.ID,
+		domain.ReviewInput{Comment: "checked"})
+	suite.Require().NoError(err)
+	suite.Equal(domain.AdjustmentApproved, request.Status)
+	suite.Equal("checker", request.ReviewedBy)
+	suite.Require().Len(suite.Repository.operations, 1)
+	operation := suite.Repository.operations[0]
+	suite.Equal(domain.Adjustment, operation.Type)
+	suite.Equal(-30.0, operation.Amount)
+	suite.Equal(70.0, operation.Initiator.Amount)
+
+	// adjustment is applied once
+	_, err = suite.GrossBook.ApproveAdjustment(suite.Checker, request.ID, domain.ReviewInput{})
+	suite.ErrorIs(err, domain.ErrAdjustmentNotPending)
+	suite.Len(suite.Repository.operations, 1)
+
+	// creator and reviewer of each step are in audit log
+	actions := make([]string, 0)
+	for _, entry := range suite.Repository.entries {
+		actions = append(actions, entry.Actor+" "+entry.Action)
+	}
+	suite.Equal([]string{
+		"maker " + requestAdjustmentAction,
+		"maker " + approveAdjustmentAction,
+		domain.AnonymousActor + " " + approveAdjustmentAction,
+		"checker " + approveAdjustmentAction,
+		"checker " + approveAdjustmentAction,
+	}, actions)
+}
+
+func (suite *AdjustmentsSuite) TestGrossBook_ApproveAdjustmentConflict() {
+	request, err := suite.GrossBook.RequestAdjustment(suite.Maker, 1,
+		domain.AdjustmentInput{Amount: -30, Reason: "duplicated deposit", Ticket: "SUP-42"})
+	suite.Require().NoError(err)
+	suite.Repository.conflicts = 1
+
+	// adjustment is computed again from the balance changed concurrently
+	_, err = suite.GrossBook.ApproveAdjustment(suite.Checker, request.ID, domain.ReviewInput{})
+	suite.Require().NoError(err)
+	suite.Require().Len(suite.Repository.operations, 1)
+	suite.Equal(80.0, suite.Repository.operations[0].Initiator.Amount)
+}
+
+func (suite *AdjustmentsSuite) TestGrossBook_RejectAdjustment() {
+	_, err := suite.GrossBook.RequestAdjustment(suite.Maker, 1,
+		domain.AdjustmentInput{Amount: 10, Reason: "bonus"})
+	suite.ErrorIs(err, domain.ErrIncorrectAdjustmentParams)
+	_, err = suite.GrossBook.RequestAdjustment(context.Background(), 1,
+		domain.AdjustmentInput{Amount: 10, Reason: "bonus", Ticket: "SUP-1"})
+	suite.ErrorIs(err, domain.ErrIncorrectAdjustmentParams)
+
+	request, err := suite.GrossBook.RequestAdjustment(suite.Maker, 1,
+		domain.AdjustmentInput{Amount: 10, Reason: "bonus", Ticket: "SUP-1"})
+	suite.Require().NoError(err)
+	request, err = suite.GrossBook.RejectAdjustment(suite.Checker, request.ID,
+		domain.ReviewInput{Comment: "no bonuses"})
+	suite.Require().NoError(err)
+	suite.Equal(domain.AdjustmentRejected, request.Status)
+	_, err = suite.GrossBook.ApproveAdjustment(suite.Checker, request.ID, domain.ReviewInput{})
+	suite.ErrorIs(err, domain.ErrAdjustmentNotPending)
+	suite.Empty(suite.Repository.operations)
+
+	// closed accounts can't be adjusted
+	request, err = suite.GrossBook.RequestAdjustment(suite.Maker, 2,
+		domain.AdjustmentInput{Amount: 10, Reason: "bonus", Ticket: "SUP-2"})
+	suite.Require().NoError(err)
+	_, err = suite.GrossBook.ApproveAdjustment(suite.Checker, request.ID, domain.ReviewInput{})
+	suite.ErrorIs(err, domain.ErrAccountClosed)
+	suite.Empty(suite.Repository.operations)
+}
+
+func (suite *AdjustmentsSuite) TestGrossBook_ExpireAdjustment() {
+	suite.GrossBook.AdjustmentTTL = time.Nanosecond
+	request, err := suite.GrossBook.RequestAdjustment(suite.Maker, 1,
+		domain.AdjustmentInput{Amount: 10, Reason: "bonus", Ticket: "SUP-1"})
+	suite.Require().NoError(err)
+	time.Sleep(time.Millisecond)
+	_, err = suite.GrossBook.ApproveAdjustment(suite.Checker, request.ID, domain.ReviewInput{})
+	suite.ErrorIs(err, domain.ErrAdjustmentExpired)
+	request, err = suite.GrossBook.Adjustment(context.Background(), request.ID)
+	suite.Require().NoError(err)
+	suite.Equal(domain.AdjustmentExpired, request.Status)
+	suite.Empty(suite.Repository.operations)
+
+	_, err = suite.GrossBook.Adjustments(context.Background(),
+		domain.AdjustmentFilter{Status: "unknown", Limit: 1})
+	suite.ErrorIs(err, domain.ErrIncorrectAdjustmentParams)
+}
+
+func TestAdjustmentsSuite(t *testing.T) {
+	suite.Run(t, new(AdjustmentsSuite))
+}
@@ -0,0 +1,136 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/agandreev/avito-intern-assignment/internal/domain"
+)
+
+const (
+	// auditTimeout limits audit writes which aren't bound to canceled requests.
+	auditTimeout = 5 * time.Second
+	// auditPageSize is a quantity of entries verified at once.
+	auditPageSize = 1000
+
+	changeStatusAction      = "change_status"
+	setLimitsAction         = "set_limits"
+	deleteLimitsAction      = "delete_limits"
+	requestAdjustmentAction = "request_adjustment"
+	approveAdjustmentAction = "approve_adjustment"
+	rejectAdjustmentAction  = "reject_adjustment"
+)
+
+// callerKey is a context key of domain.Caller.
+type callerKey struct{}
+
+// WithCaller returns context with domain.Caller which is written to audit log.
+func WithCaller(ctx context.Context, caller domain.Caller) context.Context {
+	return context.WithValue(ctx, callerKey{}, caller)
+}
+
+// CallerFrom returns domain.Caller of context, unknown callers are anonymous.
+func CallerFrom(ctx context.Context) domain.Caller {
+	caller, ok := ctx.Value(callerKey{}).(domain.Caller)
+	if !ok || caller.Actor == "" {
+		caller.Actor = domain.AnonymousActor
+	}
+	return caller
+}
+
+// RecordAudit appends domain.AuditEntry to audit chain. It isn't canceled with ctx, so
+// calls are recorded even after timeouts.
+func (grossBook GrossBook) RecordAudit(ctx context.Context, entry domain.AuditEntry) error {
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = time.Now()
+	}
+	auditCtx, cancel := context.WithTimeout(context.Background(), auditTimeout)
+	defer cancel()
+	if _, err := grossBook.Users.AddAuditEntry(auditCtx, entry); err != nil {
+		return fmt.Errorf("can't record audit: <%w>", err)
+	}
+	return nil
+}
+
+// AuditEntries returns domain.AuditEntry list filtered by domain.AuditFilter.
+func (grossBook GrossBook) AuditEntries(ctx context.Context, filter domain.AuditFilter) (
+	[]domain.AuditEntry, error) {
+	entries, err := grossBook.Users.AuditEntries(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("can't load audit log: <%w>", err)
+	}
+	return entries, nil
+}
+
+// VerifyAudit checks the whole audit chain from the first entry.
+func (grossBook GrossBook) VerifyAudit(ctx context.Context) (*domain.AuditVerification,
+	error) {
+	grossBook.log.Printf("VERIFY AUDIT: processing...")
+	verification := &domain.AuditVerification{Valid: true}
+	var afterID int64
+	var prevHash string
+	for {
+		entries, err := grossBook.Users.AuditEntries(ctx, domain.AuditFilter{
+			AfterID: afterID,
+			Limit:   auditPageSize,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("can't verify audit log: <%w>", err)
+		}
+		if len(entries) == 0 {
+			break
+		}
+		for _, entry := range entries {
+			if err = entry.Verify(prevHash); err != nil {
+				verification.Valid = false
+				verification.BrokenID = entry.ID
+				verification.Reason = err.Error()
+				grossBook.log.Printf("VERIFY AUDIT: chain is broken at <%d>", entry.ID)
+				return verification, nil
+			}
+			verification.Checked++
+			prevHash = entry.Hash
+		}
+		afterID = entries[len(entries)-1].ID
+	}
+	grossBook.log.Printf("VERIFY AUDIT: <%d> entries were verified successful",
+		verification.Checked)
+	return verification, nil
+}
+
+// userTarget returns audit target of admin action with domain.User.
+func userTarget(id int64) string {
+	return fmt.Sprintf("users/%d", id)
+}
+
+// adjustmentTarget returns audit target of admin action with domain.AdjustmentRequest.
+func adjustmentTarget(id int64) string {
+	return fmt.Sprintf("adjustments/%d", id)
+}
+
+// audit records admin action of ctx's caller. Failures are logged, because the action
+// is already done.
+func (grossBook GrossBook) audit(ctx context.Context, action, target string,
+	payload interface{}, actionErr error) {
+	var data []byte
+	if payload != nil {
+		// unmarshalable payload is recorded as empty one
+		data, _ = json.Marshal(payload)
+	}
+	entry := domain.AuditEntry{
+		Caller:   CallerFrom(ctx),
+		Action:   action,
+		Endpoint: target,
+		Payload:  domain.SanitizePayload(data),
+		Outcome:  domain.AuditSuccess,
+	}
+	if actionErr != nil {
+		entry.Outcome = fmt.Sprintf("%s: %s", domain.AuditFailure, actionErr)
+	}
+	if err := grossBook.RecordAudit(ctx, entry); err != nil {
+		grossBook.log.Printf("AUDIT ERROR: %s of <%s> isn't recorded <%s>", action, target,
+			err)
+	}
+}
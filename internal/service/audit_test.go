@@ -0,0 +1,97 @@
+package service
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/agandreev/avito-intern-assignment/internal/domain"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/suite"
+)
+
+// auditRepository keeps audit chain in memory.
+type auditRepository struct {
+	statusRepository
+	entries []domain.AuditEntry
+}
+
+func (repository *auditRepository) AddAuditEntry(_ context.Context,
+	entry domain.AuditEntry) (*domain.AuditEntry, error) {
+	var prevHash string
+	if len(repository.entries) != 0 {
+		prevHash = repository.entries[len(repository.entries)-1].Hash
+	}
+	entry.ID = int64(len(repository.entries) + 1)
+	entry.Chain(prevHash)
+	repository.entries = append(repository.entries, entry)
+	return &entry, nil
+}
+
+func (repository *auditRepository) AuditEntries(_ context.Context,
+	filter domain.AuditFilter) ([]domain.AuditEntry, error) {
+	entries := make([]domain.AuditEntry, 0)
+	for _, entry := range repository.entries {
+		if entry.ID > filter.AfterID && int64(len(entries)) < filter.Limit {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+type AuditSuite struct {
+	suite.Suite
+	Repository *auditRepository
+	GrossBook  *GrossBook
+}
+
+func (suite *AuditSuite) SetupTest() {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	suite.Repository = &auditRepository{statusRepository: statusRepository{
+		users: map[int64]domain.User{1: {ID: 1, Amount: 100}},
+	}}
+	suite.GrossBook = NewGrossBook(suite.Repository, blockingConverter{}, logger)
+}
+
+func (suite *AuditSuite) TestGrossBook_AuditAdminAction() {
+	ctx := WithCaller(context.Background(), domain.Caller{Actor: "admin", RequestID: "r1"})
+	_, err := suite.GrossBook.ChangeStatus(ctx, 1,
+		domain.StatusInput{Status: domain.Frozen, Reason: "fraud"})
+	suite.Require().NoError(err)
+	_, err = suite.GrossBook.ChangeStatus(context.Background(), 1,
+		domain.StatusInput{Status: domain.Closed, Reason: "request"})
+	suite.Require().ErrorIs(err, domain.ErrNonZeroBalance)
+
+	suite.Require().Len(suite.Repository.entries, 2)
+	entry := suite.Repository.entries[0]
+	suite.Equal("admin", entry.Actor)
+	suite.Equal("r1", entry.RequestID)
+	suite.Equal(changeStatusAction, entry.Action)
+	suite.Equal("users/1", entry.Endpoint)
+	suite.Equal(domain.AuditSuccess, entry.Outcome)
+	// failed actions are recorded too
+	suite.Equal(domain.AnonymousActor, suite.Repository.entries[1].Actor)
+	suite.Contains(suite.Repository.entries[1].Outcome, domain.AuditFailure)
+}
+
+func (suite *AuditSuite) TestGrossBook_VerifyAudit() {
+	for i := 0; i < 3; i++ {
+		suite.Require().NoError(suite.GrossBook.RecordAudit(context.Background(),
+			domain.AuditEntry{Caller: domain.Caller{Actor: "admin"}, Action: domain.HTTPAction}))
+	}
+	verification, err := suite.GrossBook.VerifyAudit(context.Background())
+	suite.Require().NoError(err)
+	suite.Equal(domain.AuditVerification{Valid: true, Checked: 3}, *verification)
+
+	suite.Repository.entries[1].Actor = "somebody"
+	verification, err = suite.GrossBook.VerifyAudit(context.Background())
+	suite.Require().NoError(err)
+	suite.False(verification.Valid)
+	suite.Equal(int64(2), verification.BrokenID)
+	suite.Equal(int64(1), verification.Checked)
+}
+
+func TestAuditSuite(t *testing.T) {
+	suite.Run(t, new(AuditSuite))
+}
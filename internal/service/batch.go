@@ -0,0 +1,169 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/agandreev/avito-intern-assignment/internal/domain"
+	"github.com/agandreev/avito-intern-assignment/internal/repository"
+)
+
+// ExecuteBatch executes all items of domain.BatchInput. Atomic batch is stored in one
+// transaction and fails entirely, partial batch returns the result of each item.
+func (grossBook *GrossBook) ExecuteBatch(ctx context.Context, input domain.BatchInput) (
+	[]domain.BatchResult, error) {
+	if err := input.Validate(grossBook.MaxBatchSize); err != nil {
+		return nil, fmt.Errorf("grossbook batch error: <%w>", err)
+	}
+	grossBook.log.Printf("BATCH: <%d> %s items processing...", len(input.Items), input.Mode)
+	var (
+		results []domain.BatchResult
+		err     error
+	)
+	if input.Mode == domain.AtomicMode {
+		err = grossBook.retryConflicts(ctx, func() (err error) {
+			results, err = grossBook.executeAtomic(ctx, input.Items)
+			return err
+		})
+	} else {
+		results = grossBook.executePartial(ctx, input.Items)
+	}
+	if err != nil {
+		return nil, err
+	}
+	grossBook.log.Printf("BATCH: <%d> %s items was processed successful",
+		len(input.Items), input.Mode)
+	return results, nil
+}
+
+// executePartial executes each item as a separate operation.
+func (grossBook *GrossBook) executePartial(ctx context.Context,
+	items []domain.BatchItem) []domain.BatchResult {
+	results := make([]domain.BatchResult, len(items))
+	for i, item := range items {
+		var (
+			operation *domain.Operation
+			err       error
+		)
+		switch item.Type {
+		case domain.DepositItem:
+			operation, err = grossBook.DepositMoney(ctx, item.InitiatorID, item.Amount, "")
+		case domain.WithdrawItem:
+			operation, err = grossBook.WithdrawMoney(ctx, item.InitiatorID, item.Amount, "")
+		case domain.TransferItem:
+			operation, err = grossBook.TransferMoney(ctx, item.InitiatorID, item.ReceiverID,
+				item.Amount, "")
+		}
+		results[i] = domain.BatchResult{Index: i, Operation: operation}
+		if err != nil {
+			results[i].Error = err.Error()
+		}
+	}
+	return results
+}
+
+// executeAtomic applies items one by one to users' balances and stores all operations
+// at once. Unknown deposits' receivers are created in the same transaction.
+func (grossBook *GrossBook) executeAtomic(ctx context.Context,
+	items []domain.BatchItem) ([]domain.BatchResult, error) {
+	users := make(map[int64]*domain.User)
+	created := make(map[int64]bool)
+	// user returns cached user, deposit's receivers may be created if they aren't existed
+	user := func(id int64, create bool) (*domain.User, error) {
+		if cached, ok := users[id]; ok {
+			return cached, nil
+		}
+		loaded, err := grossBook.Users.User(ctx, id)
+		if errors.Is(err, repository.ErrNoSuchUser) && create && grossBook.AutoCreateUsers {
+			loaded, err = &domain.User{ID: id, Status: domain.Active}, nil
+			created[id] = true
+		}
+		if err != nil {
+			return nil, err
+		}
+		users[id] = loaded
+		return loaded, nil
+	}
+	operations := make([]domain.Operation, len(items))
+	// stored operations contain fees in addition to items' operations
+	stored := make([]domain.Operation, 0, len(items))
+	for i, item := range items {
+		operation, err := grossBook.batchOperation(item, user)
+		if err == nil {
+			err = grossBook.limitOperation(ctx, operation)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("grossbook batch item %d error: <%w>", i, err)
+		}
+		operation.CreateInitiator = created[operation.Initiator.ID]
+		operations[i] = *operation
+		stored = append(stored, grossBook.withFee(*operation)...)
+	}
+	// update db
+	events, err := grossBook.Users.AddOperations(ctx, stored)
+	if err != nil {
+		return nil, fmt.Errorf("grossbook batch update error: <%w>", err)
+	}
+	grossBook.notify(events)
+	results := make([]domain.BatchResult, len(operations))
+	for i := range operations {
+		// hide second side amount for safety
+		operation := operations[i].Public()
+		results[i] = domain.BatchResult{Index: i, Operation: &operation}
+	}
+	return results, nil
+}
+
+// batchOperation changes balances of item's users and returns domain.Operation with
+// their snapshots.
+func (grossBook *GrossBook) batchOperation(item domain.BatchItem,
+	user func(id int64, create bool) (*domain.User, error)) (*domain.Operation, error) {
+	initiator, err := user(item.InitiatorID, item.Type == domain.DepositItem)
+	if err != nil {
+		return nil, fmt.Errorf("can't get initiator: <%w>", err)
+	}
+	operation := domain.Operation{
+		Amount:    item.Amount,
+		Timestamp: time.Now(),
+	}
+	switch item.Type {
+	case domain.DepositItem:
+		operation.Type = domain.Deposit
+		err = initiator.Deposit(item.Amount)
+	case domain.WithdrawItem:
+		operation.Type = domain.Withdraw
+		operation.Fee = grossBook.Fees.Fee(domain.Withdraw, "", item.Amount, item.Amount)
+		err = initiator.Withdraw(item.Amount + operation.Fee)
+	case domain.TransferItem:
+		operation.Type = domain.TransferOut
+		if item.InitiatorID == item.ReceiverID {
+			return nil, fmt.Errorf("can't transfer money for the same user: <%w>",
+				domain.ErrIncorrectOperationParams)
+		}
+		receiver, err := user(item.ReceiverID, false)
+		if err != nil {
+			return nil, fmt.Errorf("can't get receiver: <%w>", err)
+		}
+		operation.Fee = grossBook.Fees.Fee(domain.TransferOut, "", item.Amount,
+			item.Amount)
+		if err = initiator.Withdraw(item.Amount + operation.Fee); err != nil {
+			return nil, err
+		}
+		if err = receiver.Deposit(item.Amount); err != nil {
+			return nil, err
+		}
+		snapshot := *receiver
+		operation.Receiver = &snapshot
+	}
+	if err != nil {
+		return nil, err
+	}
+	snapshot := *initiator
+	operation.Initiator = &snapshot
+	if err = operation.CheckStatus(); err != nil {
+		return nil, err
+	}
+	return &operation, nil
+}
@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/agandreev/avito-intern-assignment/internal/domain"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/suite"
+)
+
+// recordingRepository stores added operations instead of db.
+type recordingRepository struct {
+	stubRepository
+	operations []domain.Operation
+}
+
+func (repository *recordingRepository) AddOperation(ctx context.Context,
+	operation domain.Operation) ([]domain.Event, error) {
+	return repository.AddOperations(ctx, []domain.Operation{operation})
+}
+
+func (repository *recordingRepository) AddOperations(_ context.Context,
+	operations []domain.Operation) ([]domain.Event, error) {
+	repository.operations = append(repository.operations, operations...)
+	return nil, nil
+}
+
+type BatchSuite struct {
+	suite.Suite
+	Repository *recordingRepository
+	GrossBook  *GrossBook
+}
+
+func (suite *BatchSuite) SetupTest() {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	suite.Repository = &recordingRepository{}
+	suite.GrossBook = NewGrossBook(suite.Repository, blockingConverter{}, logger)
+}
+
+func (suite *BatchSuite) TestGrossBook_ExecuteAtomicBatch() {
+	results, err := suite.GrossBook.ExecuteBatch(context.Background(), domain.BatchInput{
+		Items: []domain.BatchItem{
+			{Type: domain.DepositItem, InitiatorID: 1, Amount: 10},
+			{Type: domain.TransferItem, InitiatorID: 1, ReceiverID: 2, Amount: 50},
+		},
+	})
+	suite.Require().NoError(err)
+	suite.Require().Len(results, 2)
+	// balances are changed one after another
+	operations := suite.Repository.operations
+	suite.Require().Len(operations, 2)
+	suite.Equal(float64(110), operations[0].Initiator.Amount)
+	suite.Equal(float64(60), operations[1].Initiator.Amount)
+	suite.Equal(float64(150), operations[1].Receiver.Amount)
+	suite.Equal(&domain.User{ID: 2}, results[1].Operation.Receiver)
+
+	// nothing is stored if any item fails
+	suite.Repository.operations = nil
+	_, err = suite.GrossBook.ExecuteBatch(context.Background(), domain.BatchInput{
+		Mode: domain.AtomicMode,
+		Items: []domain.BatchItem{
+			{Type: domain.WithdrawItem, InitiatorID: 1, Amount: 60},
+			{Type: domain.WithdrawItem, InitiatorID: 1, Amount: 60},
+		},
+	})
+	suite.ErrorIs(err, domain.ErrInsufficientFunds)
+	suite.Empty(suite.Repository.operations)
+}
+
+func (suite *BatchSuite) TestGrossBook_ExecutePartialBatch() {
+	results, err := suite.GrossBook.ExecuteBatch(context.Background(), domain.BatchInput{
+		Mode: domain.PartialMode,
+		Items: []domain.BatchItem{
+			{Type: domain.WithdrawItem, InitiatorID: 1, Amount: 500},
+			{Type: domain.WithdrawItem, InitiatorID: 1, Amount: 5},
+		},
+	})
+	suite.Require().NoError(err)
+	suite.Require().Len(results, 2)
+	suite.NotEmpty(results[0].Error)
+	suite.Nil(results[0].Operation)
+	suite.Empty(results[1].Error)
+	suite.Equal(1, results[1].Index)
+	suite.Len(suite.Repository.operations, 1)
+}
+
+func (suite *BatchSuite) TestGrossBook_LimitBatch() {
+	suite.GrossBook.MaxBatchSize = 1
+	_, err := suite.GrossBook.ExecuteBatch(context.Background(), domain.BatchInput{
+		Items: []domain.BatchItem{
+			{Type: domain.DepositItem, InitiatorID: 1, Amount: 10},
+			{Type: domain.DepositItem, InitiatorID: 2, Amount: 10},
+		},
+	})
+	suite.ErrorIs(err, domain.ErrIncorrectBatchParams)
+	suite.Empty(suite.Repository.operations)
+}
+
+func TestBatchSuite(t *testing.T) {
+	suite.Run(t, new(BatchSuite))
+}
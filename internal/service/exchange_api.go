@@ -1,17 +1,22 @@
 package service
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"sort"
 	"strings"
+	"sync"
 	"time"
+
+	"github.com/agandreev/avito-intern-assignment/internal/domain"
 )
 
 const (
-	timeout = 10 * time.Second
+	timeout    = 10 * time.Second
+	symbolsTTL = 24 * time.Hour
 
 	apiURL  = "http://api.exchangeratesapi.io/v1/"
 	latest  = "latest"
@@ -25,20 +30,66 @@ const (
 	eur = "EUR"
 )
 
-// ExchangeAPI implements Converter applying exchangerateapi v1.
+// ExchangeAPI implements Converter applying exchangerateapi v1. Its requests are guarded
+// by circuit breaker and monthly quota.
 type ExchangeAPI struct {
-	client *http.Client
-	apiKey string
+	client   *http.Client
+	guard    *exchangeGuard
+	symbols  *symbolsCache
+	rounding domain.RoundingMode
+	apiKey   string
+	apiURL   string
+}
+
+// ExchangeConfig contains exchangerateapi settings. Empty fields are replaced by defaults.
+type ExchangeConfig struct {
+	APIKey  string
+	URL     string
+	Timeout time.Duration
+	Breaker BreakerConfig
+	// Rounding is applied to converted amounts, half-even by default.
+	Rounding domain.RoundingMode
+	// SymbolsTTL is a time during which provider's currencies are cached, a day by default.
+	SymbolsTTL time.Duration
+}
+
+// symbolsCache keeps provider's supported currencies, because they're rarely changed.
+type symbolsCache struct {
+	mu         sync.Mutex
+	ttl        time.Duration
+	currencies SupportedCurrencies
+	expiresAt  time.Time
 }
 
 // NewExchangeAPI sets timeout and returns pointer.
-func NewExchangeAPI(apiKey string) *ExchangeAPI {
+func NewExchangeAPI(config ExchangeConfig) *ExchangeAPI {
+	if config.URL == "" {
+		config.URL = apiURL
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = timeout
+	}
+	if !config.Rounding.IsValid() {
+		config.Rounding = domain.HalfEven
+	}
+	if config.SymbolsTTL <= 0 {
+		config.SymbolsTTL = symbolsTTL
+	}
 	return &ExchangeAPI{
-		client: &http.Client{Timeout: timeout},
-		apiKey: apiKey,
+		client:   &http.Client{Timeout: config.Timeout},
+		guard:    newExchangeGuard(config.Breaker),
+		symbols:  &symbolsCache{ttl: config.SymbolsTTL},
+		rounding: config.Rounding,
+		apiKey:   config.APIKey,
+		apiURL:   config.URL,
 	}
 }
 
+// Status returns circuit breaker's state and used quota.
+func (exchange ExchangeAPI) Status() domain.ExchangeStatus {
+	return exchange.guard.Status()
+}
+
 // SupportedCurrencies represents slice of available currencies.
 type SupportedCurrencies []string
 
@@ -52,7 +103,8 @@ func (currencies SupportedCurrencies) Contains(currency string) bool {
 func (currencies SupportedCurrencies) ContainsAll(checkingCurrencies ...string) error {
 	for _, currency := range checkingCurrencies {
 		if !currencies.Contains(currency) {
-			return fmt.Errorf("%s is unsupported", currency)
+			return fmt.Errorf("%s is unsupported: <%w>", currency,
+				domain.ErrUnsupportedCurrency)
 		}
 	}
 	return nil
@@ -86,7 +138,8 @@ type SymbolsResponse struct {
 	Symbols map[string]string `json:"symbols"`
 }
 
-// Currencies returns SupportedCurrencies for each request.
+// Currencies returns SupportedCurrencies for each request. Currencies which aren't in
+// ISO 4217 registry are skipped.
 func (symbolsResponse SymbolsResponse) Currencies() (SupportedCurrencies, error) {
 	if !symbolsResponse.Success {
 		return nil, symbolsResponse.Error
@@ -96,7 +149,9 @@ func (symbolsResponse SymbolsResponse) Currencies() (SupportedCurrencies, error)
 	}
 	currencies := make(SupportedCurrencies, 0)
 	for currency := range symbolsResponse.Symbols {
-		currencies = append(currencies, currency)
+		if _, err := domain.LookupCurrency(currency); err == nil {
+			currencies = append(currencies, currency)
+		}
 	}
 	sort.Strings(currencies)
 	return currencies, nil
@@ -117,9 +172,10 @@ func (badRequestError BadRequestError) Error() string {
 }
 
 // SupportedSymbols return array of supported currencies.
-func (exchange ExchangeAPI) SupportedSymbols() (
+func (exchange ExchangeAPI) SupportedSymbols(ctx context.Context) (
 	SupportedCurrencies, error) {
-	req, err := http.NewRequest("GET", apiURL+symbols, nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		exchange.apiURL+symbols, nil)
 	if err != nil {
 		return nil, fmt.Errorf("exchange convert error: <%w>", err)
 	}
@@ -127,10 +183,11 @@ func (exchange ExchangeAPI) SupportedSymbols() (
 	q.Add(accessKeyTag, exchange.apiKey)
 	req.URL.RawQuery = q.Encode()
 
-	resp, err := exchange.client.Do(req)
+	resp, err := exchange.guard.do(exchange.client, req)
 	if err != nil {
 		return nil, fmt.Errorf("exchange request error: <%w>", err)
 	}
+	defer resp.Body.Close()
 	data, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return nil, fmt.Errorf("exchange read body error: <%w>", err)
@@ -153,64 +210,138 @@ func (exchange ExchangeAPI) SupportedSymbols() (
 		resp.StatusCode)
 }
 
-// Convert converts any currency to rubbles.
-func (exchange ExchangeAPI) Convert(from string, amount float64) (
+// supportedCurrencies returns cached SupportedSymbols and refreshes them after ttl.
+func (exchange ExchangeAPI) supportedCurrencies(ctx context.Context) (
+	SupportedCurrencies, error) {
+	cache := exchange.symbols
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	if cache.currencies != nil && time.Now().Before(cache.expiresAt) {
+		return cache.currencies, nil
+	}
+	currencies, err := exchange.SupportedSymbols(ctx)
+	if err != nil {
+		return nil, err
+	}
+	cache.currencies = currencies
+	cache.expiresAt = time.Now().Add(cache.ttl)
+	return currencies, nil
+}
+
+// Convert converts amount of money between any supported currencies through EUR rates.
+func (exchange ExchangeAPI) Convert(ctx context.Context, from, to string, amount float64) (
 	float64, error) {
-	// for each request to avoid mutexes
-	supportedCurrencies, err := exchange.SupportedSymbols()
+	converted, err := exchange.ConvertAll(ctx, to, map[string]float64{from: amount})
+	if err != nil {
+		return 0, err
+	}
+	return converted[from], nil
+}
+
+// ConvertAll converts amounts of several currencies to one currency. Rates of all of
+// them are received by one request, amounts are rounded to target's minor units.
+func (exchange ExchangeAPI) ConvertAll(ctx context.Context, to string,
+	amounts map[string]float64) (map[string]float64, error) {
+	currencies := []string{to}
+	for from := range amounts {
+		currencies = append(currencies, from)
+	}
+	// registry check doesn't need provider
+	for _, currency := range currencies {
+		if _, err := domain.LookupCurrency(currency); err != nil {
+			return nil, fmt.Errorf("can't convert: <%w>", err)
+		}
+	}
+	target, _ := domain.LookupCurrency(to)
+	conversionResponse, err := exchange.latestRates(ctx, currencies)
+	if err != nil {
+		return nil, err
+	}
+	converted := make(map[string]float64, len(amounts))
+	for from, amount := range amounts {
+		convertedAmount, err := conversionResponse.Amount(amount, from, to)
+		if err != nil {
+			return nil, fmt.Errorf("exchange calculation error: <%w>", err)
+		}
+		converted[from] = target.Round(convertedAmount, exchange.rounding)
+	}
+	return converted, nil
+}
+
+// latestRates returns rates of currencies with EUR base.
+func (exchange ExchangeAPI) latestRates(ctx context.Context, currencies []string) (
+	*ConversionResponse, error) {
+	supportedCurrencies, err := exchange.supportedCurrencies(ctx)
 	if err != nil {
-		return 0, fmt.Errorf("can't get supported symbols: <%w>", err)
+		return nil, fmt.Errorf("can't get supported symbols: <%w>", err)
 	}
-	if err = supportedCurrencies.ContainsAll(rub, eur, from); err != nil {
-		return 0, fmt.Errorf("can't convert: <%w>", err)
+	if err = supportedCurrencies.ContainsAll(append(currencies, eur)...); err != nil {
+		return nil, fmt.Errorf("can't convert: <%w>", err)
 	}
 	// request creation
-	req, err := http.NewRequest("GET", apiURL+latest, nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		exchange.apiURL+latest, nil)
 	if err != nil {
-		return 0, fmt.Errorf("exchange convert error: <%w>", err)
+		return nil, fmt.Errorf("exchange convert error: <%w>", err)
 	}
-	symbols := []string{rub, from}
 	q := req.URL.Query()
 	q.Add(accessKeyTag, exchange.apiKey)
 	q.Add(baseTag, eur)
-	q.Add(symbolsTag, strings.Join(symbols, ","))
+	q.Add(symbolsTag, strings.Join(uniqueCurrencies(currencies), ","))
 	req.URL.RawQuery = q.Encode()
 
 	// sending request
-	resp, err := exchange.client.Do(req)
+	resp, err := exchange.guard.do(exchange.client, req)
 	if err != nil {
-		return 0, fmt.Errorf("exchange request error: <%w>", err)
+		return nil, fmt.Errorf("exchange request error: <%w>", err)
 	}
+	defer resp.Body.Close()
 	data, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return 0, fmt.Errorf("exchange read body error: <%w>", err)
+		return nil, fmt.Errorf("exchange read body error: <%w>", err)
 	}
 	// process status codes
 	switch resp.StatusCode {
 	case http.StatusOK:
 		var conversionResponse ConversionResponse
 		if err = json.Unmarshal(data, &conversionResponse); err != nil {
-			return 0, fmt.Errorf("exchange unmarshal conversion error: <%w>", err)
+			return nil, fmt.Errorf("exchange unmarshal conversion error: <%w>", err)
 		}
-		convertedAmount, err := conversionResponse.Amount(amount, from)
-		if err != nil {
-			return 0, fmt.Errorf("exchange calculation error: <%w>", err)
+		if !conversionResponse.Success {
+			return nil, conversionResponse.Error
 		}
-		return convertedAmount, nil
+		if err = conversionResponse.Validate(currencies...); err != nil {
+			return nil, fmt.Errorf("exchange calculation error: <%w>", err)
+		}
+		return &conversionResponse, nil
 	case http.StatusBadRequest:
 		var badRequestResponse BadRequestResponse
 		if err = json.Unmarshal(data, &badRequestResponse); err != nil {
-			return 0, fmt.Errorf("exchange unmarshal bad request error: <%w>", err)
+			return nil, fmt.Errorf("exchange unmarshal bad request error: <%w>", err)
 		}
-		return 0, badRequestResponse.BadRequestError
+		return nil, badRequestResponse.BadRequestError
 	default:
-		return 0, fmt.Errorf("unexpected status code received from exchager: %d",
+		return nil, fmt.Errorf("unexpected status code received from exchager: %d",
 			resp.StatusCode)
 	}
 }
 
+// uniqueCurrencies returns sorted currencies without duplicates.
+func uniqueCurrencies(currencies []string) []string {
+	unique := append([]string(nil), currencies...)
+	sort.Strings(unique)
+	n := 0
+	for i := range unique {
+		if i == 0 || unique[i] != unique[n-1] {
+			unique[n] = unique[i]
+			n++
+		}
+	}
+	return unique[:n]
+}
+
 // Validate is necessary to prevent an error from the API side.
-func (conversion ConversionResponse) Validate(currency string) error {
+func (conversion ConversionResponse) Validate(currencies ...string) error {
 	// check if body exists
 	if !conversion.Success {
 		return fmt.Errorf("conversion response body is empty")
@@ -221,7 +352,7 @@ func (conversion ConversionResponse) Validate(currency string) error {
 			conversion.Base)
 	}
 	// check rates map
-	if err := conversion.checkRatesLen(currency); err != nil {
+	if err := conversion.checkRates(currencies...); err != nil {
 		return fmt.Errorf("something wrong with rates map in response: <%w>", err)
 	}
 	// check rate's values
@@ -234,40 +365,35 @@ func (conversion ConversionResponse) Validate(currency string) error {
 	return nil
 }
 
-// Amount counts final value by formula.
-func (conversion ConversionResponse) Amount(amount float64, currency string) (float64, error) {
+// Amount counts final value by formula, from and to are triangulated through base.
+func (conversion ConversionResponse) Amount(amount float64, from, to string) (
+	float64, error) {
 	if !conversion.Success {
 		return 0, conversion.Error
 	}
-	if err := conversion.Validate(currency); err != nil {
+	if err := conversion.Validate(from, to); err != nil {
 		return 0, fmt.Errorf("validation is failed: <%w>", err)
 	}
-	return amount / conversion.Rates[currency] * conversion.Rates[rub], nil
+	return amount / conversion.rate(from) * conversion.rate(to), nil
 }
 
-// checkRatesLen is a part of validation.
-func (conversion ConversionResponse) checkRatesLen(currency string) error {
-	switch len(conversion.Rates) {
-	case 0:
+// rate returns rate of currency, base's rate can be omitted in response.
+func (conversion ConversionResponse) rate(currency string) float64 {
+	if rate, ok := conversion.Rates[currency]; ok || currency != conversion.Base {
+		return rate
+	}
+	return 1
+}
+
+// checkRates is a part of validation.
+func (conversion ConversionResponse) checkRates(currencies ...string) error {
+	if len(conversion.Rates) == 0 {
 		return fmt.Errorf("returned empty rates map")
-	case 1:
-		// case, when users input == base or input currency == output currency
-		if currency != conversion.Base && currency != rub {
-			return fmt.Errorf("not enough rates returned")
-		}
-		if _, ok := conversion.Rates[rub]; !ok {
-			return fmt.Errorf("there is no RUB is response")
-		}
-	case 2:
-		// case with 3 currencies, including RUB and EURO
-		if _, ok := conversion.Rates[currency]; !ok {
+	}
+	for _, currency := range currencies {
+		if _, ok := conversion.Rates[currency]; !ok && currency != conversion.Base {
 			return fmt.Errorf("lack of currency in map: %s", currency)
 		}
-		if _, ok := conversion.Rates[rub]; !ok {
-			return fmt.Errorf("there is no RUB is response")
-		}
-	default:
-		return fmt.Errorf("too much currencies")
 	}
 	return nil
 }
@@ -0,0 +1,201 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/agandreev/avito-intern-assignment/internal/domain"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/suite"
+)
+
+type ExchangeAPISuite struct {
+	suite.Suite
+	Server   *httptest.Server
+	Exchange *ExchangeAPI
+	aborted  chan struct{}
+}
+
+func (suite *ExchangeAPISuite) SetupTest() {
+	suite.aborted = make(chan struct{}, 1)
+	// server hangs until client goes away
+	suite.Server = httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			<-r.Context().Done()
+			suite.aborted <- struct{}{}
+		}))
+	suite.Exchange = NewExchangeAPI(ExchangeConfig{
+		APIKey: "key",
+		URL:    suite.Server.URL + "/",
+	})
+}
+
+func (suite *ExchangeAPISuite) TearDownTest() {
+	suite.Server.Close()
+}
+
+func (suite *ExchangeAPISuite) TestExchangeAPI_CancelConvert() {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err := suite.Exchange.Convert(ctx, "USD", rub, 1)
+	suite.ErrorIs(err, context.DeadlineExceeded)
+	select {
+	case <-suite.aborted:
+	case <-time.After(time.Second):
+		suite.Fail("upstream request wasn't aborted")
+	}
+}
+
+func (suite *ExchangeAPISuite) TestGrossBook_Health() {
+	gb := NewGrossBook(nil, suite.Exchange, logrus.New())
+	health := gb.Health()
+	suite.Equal(domain.HealthOK, health.Status)
+	suite.Require().NotNil(health.Exchange)
+	suite.Equal(domain.BreakerClosed, health.Exchange.State)
+
+	suite.Exchange.guard.open()
+	suite.Equal(domain.HealthDegraded, gb.Health().Status)
+	_, err := suite.Exchange.Convert(context.Background(), "USD", rub, 1)
+	suite.ErrorIs(err, domain.ErrExchangeUnavailable)
+}
+
+func TestExchangeAPISuite(t *testing.T) {
+	suite.Run(t, new(ExchangeAPISuite))
+}
+
+type RatesSuite struct {
+	suite.Suite
+	Server   *httptest.Server
+	Exchange *ExchangeAPI
+	requests []url.Values
+	// symbolsRequests counts requests of supported currencies
+	symbolsRequests int
+}
+
+func (suite *RatesSuite) SetupTest() {
+	suite.requests = nil
+	suite.symbolsRequests = 0
+	// provider omits base currency and returns only requested symbols
+	rates := map[string]float64{"RUB": 100, "USD": 1.25, "GBP": 0.5}
+	suite.Server = httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			var response interface{}
+			switch strings.TrimPrefix(r.URL.Path, "/") {
+			case symbols:
+				suite.symbolsRequests++
+				// BTC isn't ISO 4217 currency
+				response = SymbolsResponse{
+					Success: true,
+					Symbols: map[string]string{"EUR": "Euro", "RUB": "Ruble",
+						"USD": "Dollar", "GBP": "Pound", "BTC": "Bitcoin"},
+				}
+			case latest:
+				suite.requests = append(suite.requests, r.URL.Query())
+				info := ConversionResponseInfo{Base: eur, Rates: map[string]float64{}}
+				for _, symbol := range strings.Split(r.URL.Query().Get(symbolsTag), ",") {
+					if rate, ok := rates[symbol]; ok {
+						info.Rates[symbol] = rate
+					}
+				}
+				response = ConversionResponse{Success: true, ConversionResponseInfo: info}
+			}
+			_ = json.NewEncoder(w).Encode(response)
+		}))
+	suite.Exchange = NewExchangeAPI(ExchangeConfig{
+		APIKey: "key",
+		URL:    suite.Server.URL + "/",
+	})
+}
+
+func (suite *RatesSuite) TearDownTest() {
+	suite.Server.Close()
+}
+
+func (suite *RatesSuite) TestExchangeAPI_Convert() {
+	ctx := context.Background()
+	amount, err := suite.Exchange.Convert(ctx, "USD", "GBP", 10)
+	suite.Require().NoError(err)
+	suite.InDelta(4, amount, 1e-9)
+	suite.Require().Len(suite.requests, 1)
+	suite.Equal(eur, suite.requests[0].Get(baseTag))
+	suite.Equal("GBP,USD", suite.requests[0].Get(symbolsTag))
+
+	// base currency is triangulated without its rate
+	amount, err = suite.Exchange.Convert(ctx, eur, rub, 2)
+	suite.Require().NoError(err)
+	suite.InDelta(200, amount, 1e-9)
+	amount, err = suite.Exchange.Convert(ctx, rub, eur, 50)
+	suite.Require().NoError(err)
+	suite.InDelta(0.5, amount, 1e-9)
+
+	_, err = suite.Exchange.Convert(ctx, "USD", "JPY", 10)
+	suite.ErrorIs(err, domain.ErrUnsupportedCurrency)
+	suite.Len(suite.requests, 3)
+	// provider's currencies are cached
+	suite.Equal(1, suite.symbolsRequests)
+}
+
+func (suite *RatesSuite) TestExchangeAPI_ConvertOffline() {
+	// unknown code is rejected by registry without provider
+	_, err := suite.Exchange.Convert(context.Background(), "XYZ", rub, 10)
+	suite.ErrorIs(err, domain.ErrUnsupportedCurrency)
+	suite.Zero(suite.symbolsRequests)
+	suite.Empty(suite.requests)
+}
+
+func (suite *RatesSuite) TestExchangeAPI_Rounding() {
+	ctx := context.Background()
+	// 0.0123 USD is 0.984 RUB which is rounded to kopecks
+	amount, err := suite.Exchange.Convert(ctx, "USD", rub, 0.0123)
+	suite.Require().NoError(err)
+	suite.Equal(0.98, amount)
+
+	suite.Exchange.rounding = domain.Up
+	amount, err = suite.Exchange.Convert(ctx, "USD", rub, 0.0123)
+	suite.Require().NoError(err)
+	suite.Equal(0.99, amount)
+}
+
+func (suite *RatesSuite) TestExchangeAPI_SupportedSymbols() {
+	currencies, err := suite.Exchange.SupportedSymbols(context.Background())
+	suite.Require().NoError(err)
+	suite.Equal(SupportedCurrencies{"EUR", "GBP", "RUB", "USD"}, currencies)
+}
+
+func (suite *RatesSuite) TestExchangeAPI_ConvertAll() {
+	converted, err := suite.Exchange.ConvertAll(context.Background(), rub,
+		map[string]float64{"USD": 10, "GBP": 1, eur: 1, rub: 5})
+	suite.Require().NoError(err)
+	suite.Len(suite.requests, 1)
+	suite.InDeltaMapValues(map[string]float64{"USD": 800, "GBP": 200, eur: 100, rub: 5},
+		converted, 1e-9)
+}
+
+func (suite *RatesSuite) TestGrossBook_Convert() {
+	gb := NewGrossBook(nil, suite.Exchange, logrus.New())
+	conversion, err := gb.Convert(context.Background(),
+		domain.ConversionInput{From: "usd", To: "rub", Amount: 10})
+	suite.Require().NoError(err)
+	suite.Equal("USD", conversion.From)
+	suite.Equal("RUB", conversion.To)
+	suite.InDelta(800, conversion.Result, 1e-9)
+
+	_, err = gb.Convert(context.Background(),
+		domain.ConversionInput{From: "USD", To: "RUB"})
+	suite.ErrorIs(err, domain.ErrIncorrectConversionParams)
+	gb.Exchange = nil
+	_, err = gb.Convert(context.Background(),
+		domain.ConversionInput{From: "USD", To: "RUB", Amount: 10})
+	suite.ErrorIs(err, domain.ErrExchangeUnavailable)
+}
+
+func TestRatesSuite(t *testing.T) {
+	suite.Run(t, new(RatesSuite))
+}
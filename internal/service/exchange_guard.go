@@ -0,0 +1,205 @@
+package service
+
+import (
+	"expvar"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/agandreev/avito-intern-assignment/internal/domain"
+)
+
+const (
+	defaultFailureThreshold = 5
+	defaultOpenTimeout      = 30 * time.Second
+	defaultHalfOpenRequests = 1
+
+	quotaPeriodLayout = "2006-01"
+)
+
+// requestResult is a result of provider's request for circuit breaker.
+type requestResult int
+
+const (
+	requestCanceled requestResult = iota
+	requestSucceeded
+	requestFailed
+)
+
+var (
+	// metrics are served by expvar handler
+	exchangeRequests   = expvar.NewInt("exchange_requests")
+	exchangeFailures   = expvar.NewInt("exchange_failures")
+	exchangeRejections = expvar.NewInt("exchange_rejections")
+	exchangeQuotaUsed  = expvar.NewInt("exchange_quota_used")
+	exchangeState      = expvar.NewString("exchange_breaker_state")
+)
+
+// BreakerConfig contains circuit breaker and quota settings of exchange rates provider.
+// Zero fields are replaced by defaults, zero MonthlyQuota means unlimited requests.
+type BreakerConfig struct {
+	// FailureThreshold is a quantity of consecutive failures which opens breaker.
+	FailureThreshold int
+	// OpenTimeout is a time after which open breaker passes probe requests.
+	OpenTimeout time.Duration
+	// HalfOpenRequests is a quantity of successful probes which closes breaker.
+	HalfOpenRequests int
+	MonthlyQuota     int64
+}
+
+// exchangeGuard sends provider's requests, fails fast while provider is unavailable and
+// doesn't exceed provider's monthly quota. Network errors, timeouts, server errors and
+// throttling are failures, requests canceled by caller aren't counted.
+type exchangeGuard struct {
+	config BreakerConfig
+	now    func() time.Time
+
+	mu       sync.Mutex
+	state    domain.BreakerState
+	failures int
+	openedAt time.Time
+	// probes are half-open requests in flight, successes are finished ones
+	probes    int
+	successes int
+	// used is a quantity of requests sent in period
+	period string
+	used   int64
+}
+
+// newExchangeGuard sets defaults and returns closed exchangeGuard.
+func newExchangeGuard(config BreakerConfig) *exchangeGuard {
+	if config.FailureThreshold <= 0 {
+		config.FailureThreshold = defaultFailureThreshold
+	}
+	if config.OpenTimeout <= 0 {
+		config.OpenTimeout = defaultOpenTimeout
+	}
+	if config.HalfOpenRequests <= 0 {
+		config.HalfOpenRequests = defaultHalfOpenRequests
+	}
+	exchangeState.Set(string(domain.BreakerClosed))
+	return &exchangeGuard{
+		config: config,
+		now:    time.Now,
+		state:  domain.BreakerClosed,
+	}
+}
+
+// do sends request by client if breaker and quota permit it.
+func (guard *exchangeGuard) do(client *http.Client, request *http.Request) (*http.Response,
+	error) {
+	if err := guard.acquire(); err != nil {
+		exchangeRejections.Add(1)
+		return nil, err
+	}
+	exchangeRequests.Add(1)
+	response, err := client.Do(request)
+	switch {
+	// request's context is caller's one, client's timeout isn't a cancellation
+	case err != nil && request.Context().Err() != nil:
+		guard.release(requestCanceled)
+	case err != nil || response.StatusCode >= http.StatusInternalServerError ||
+		response.StatusCode == http.StatusTooManyRequests:
+		guard.release(requestFailed)
+	default:
+		guard.release(requestSucceeded)
+	}
+	return response, err
+}
+
+// Status returns domain.ExchangeStatus.
+func (guard *exchangeGuard) Status() domain.ExchangeStatus {
+	guard.mu.Lock()
+	defer guard.mu.Unlock()
+	status := domain.ExchangeStatus{
+		State:      guard.state,
+		Failures:   guard.failures,
+		QuotaLimit: guard.config.MonthlyQuota,
+	}
+	if guard.period == guard.now().UTC().Format(quotaPeriodLayout) {
+		status.QuotaUsed = guard.used
+	}
+	if guard.state != domain.BreakerClosed {
+		openedAt := guard.openedAt
+		status.OpenedAt = &openedAt
+	}
+	return status
+}
+
+// acquire reserves request in quota and in half-open breaker's probes.
+func (guard *exchangeGuard) acquire() error {
+	guard.mu.Lock()
+	defer guard.mu.Unlock()
+	now := guard.now()
+	if guard.state == domain.BreakerOpen {
+		if now.Sub(guard.openedAt) < guard.config.OpenTimeout {
+			return fmt.Errorf("circuit breaker is open since %s: <%w>",
+				guard.openedAt.Format(time.RFC3339), domain.ErrExchangeUnavailable)
+		}
+		guard.setState(domain.BreakerHalfOpen)
+		guard.probes, guard.successes = 0, 0
+	}
+	if guard.state == domain.BreakerHalfOpen &&
+		guard.probes+guard.successes >= guard.config.HalfOpenRequests {
+		return fmt.Errorf("circuit breaker is half-open: <%w>", domain.ErrExchangeUnavailable)
+	}
+	if period := now.UTC().Format(quotaPeriodLayout); period != guard.period {
+		guard.period, guard.used = period, 0
+	}
+	if guard.config.MonthlyQuota > 0 && guard.used >= guard.config.MonthlyQuota {
+		return fmt.Errorf("%d requests are sent in %s: <%w>", guard.used, guard.period,
+			domain.ErrExchangeQuotaExceeded)
+	}
+	guard.used++
+	exchangeQuotaUsed.Set(guard.used)
+	if guard.state == domain.BreakerHalfOpen {
+		guard.probes++
+	}
+	return nil
+}
+
+// release records result of acquired request.
+func (guard *exchangeGuard) release(result requestResult) {
+	guard.mu.Lock()
+	defer guard.mu.Unlock()
+	if result == requestFailed {
+		exchangeFailures.Add(1)
+	}
+	switch guard.state {
+	case domain.BreakerHalfOpen:
+		guard.probes--
+		switch result {
+		case requestFailed:
+			guard.open()
+		case requestSucceeded:
+			guard.successes++
+			if guard.successes >= guard.config.HalfOpenRequests {
+				guard.failures = 0
+				guard.setState(domain.BreakerClosed)
+			}
+		}
+	case domain.BreakerClosed:
+		switch result {
+		case requestFailed:
+			guard.failures++
+			if guard.failures >= guard.config.FailureThreshold {
+				guard.open()
+			}
+		case requestSucceeded:
+			guard.failures = 0
+		}
+	}
+}
+
+// open opens breaker from now.
+func (guard *exchangeGuard) open() {
+	guard.openedAt = guard.now()
+	guard.setState(domain.BreakerOpen)
+}
+
+// setState changes breaker's state and its metric.
+func (guard *exchangeGuard) setState(state domain.BreakerState) {
+	guard.state = state
+	exchangeState.Set(string(state))
+}
@@ -0,0 +1,124 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/agandreev/avito-intern-assignment/internal/domain"
+	"github.com/stretchr/testify/suite"
+)
+
+type ExchangeGuardSuite struct {
+	suite.Suite
+	Server *httptest.Server
+	Client *http.Client
+	Guard  *exchangeGuard
+	Now    time.Time
+	mu     sync.Mutex
+	status int
+	calls  int
+}
+
+func (suite *ExchangeGuardSuite) SetupTest() {
+	suite.status, suite.calls = http.StatusOK, 0
+	suite.Server = httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			suite.mu.Lock()
+			defer suite.mu.Unlock()
+			suite.calls++
+			w.WriteHeader(suite.status)
+		}))
+	suite.Client = suite.Server.Client()
+	suite.Now = time.Date(2022, 1, 31, 12, 0, 0, 0, time.UTC)
+	suite.Guard = newExchangeGuard(BreakerConfig{
+		FailureThreshold: 2,
+		OpenTimeout:      time.Minute,
+		HalfOpenRequests: 1,
+		MonthlyQuota:     10,
+	})
+	suite.Guard.now = func() time.Time { return suite.Now }
+}
+
+func (suite *ExchangeGuardSuite) TearDownTest() {
+	suite.Server.Close()
+}
+
+func (suite *ExchangeGuardSuite) send(status int) error {
+	suite.mu.Lock()
+	suite.status = status
+	suite.mu.Unlock()
+	request, err := http.NewRequest(http.MethodGet, suite.Server.URL, nil)
+	suite.Require().NoError(err)
+	response, err := suite.Guard.do(suite.Client, request)
+	if err != nil {
+		return err
+	}
+	return response.Body.Close()
+}
+
+func (suite *ExchangeGuardSuite) TestExchangeGuard_Breaker() {
+	// client errors aren't provider's failures
+	suite.NoError(suite.send(http.StatusBadRequest))
+	suite.NoError(suite.send(http.StatusInternalServerError))
+	suite.NoError(suite.send(http.StatusOK))
+	suite.NoError(suite.send(http.StatusTooManyRequests))
+	suite.Equal(domain.BreakerClosed, suite.Guard.Status().State)
+	suite.NoError(suite.send(http.StatusBadGateway))
+	status := suite.Guard.Status()
+	suite.Equal(domain.BreakerOpen, status.State)
+	suite.False(status.Available())
+
+	// open breaker fails fast
+	suite.ErrorIs(suite.send(http.StatusOK), domain.ErrExchangeUnavailable)
+	suite.Equal(5, suite.calls)
+
+	// failed probe opens breaker again
+	suite.Now = suite.Now.Add(time.Minute)
+	suite.NoError(suite.send(http.StatusServiceUnavailable))
+	suite.ErrorIs(suite.send(http.StatusOK), domain.ErrExchangeUnavailable)
+
+	// successful probe closes breaker
+	suite.Now = suite.Now.Add(time.Minute)
+	suite.NoError(suite.send(http.StatusOK))
+	status = suite.Guard.Status()
+	suite.Equal(domain.BreakerClosed, status.State)
+	suite.Zero(status.Failures)
+	suite.Equal(7, suite.calls)
+}
+
+func (suite *ExchangeGuardSuite) TestExchangeGuard_Quota() {
+	for i := 0; i < 10; i++ {
+		suite.Require().NoError(suite.send(http.StatusOK))
+	}
+	suite.ErrorIs(suite.send(http.StatusOK), domain.ErrExchangeQuotaExceeded)
+	status := suite.Guard.Status()
+	suite.Equal(int64(10), status.QuotaUsed)
+	suite.False(status.Available())
+
+	// quota is renewed each month
+	suite.Now = suite.Now.Add(24 * time.Hour)
+	suite.Zero(suite.Guard.Status().QuotaUsed)
+	suite.NoError(suite.send(http.StatusOK))
+	suite.Equal(11, suite.calls)
+}
+
+func (suite *ExchangeGuardSuite) TestExchangeGuard_Canceled() {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for i := 0; i < 3; i++ {
+		request, err := http.NewRequestWithContext(ctx, http.MethodGet, suite.Server.URL, nil)
+		suite.Require().NoError(err)
+		_, err = suite.Guard.do(suite.Client, request)
+		suite.ErrorIs(err, context.Canceled)
+	}
+	// canceled requests aren't failures
+	suite.Equal(domain.BreakerClosed, suite.Guard.Status().State)
+}
+
+func TestExchangeGuardSuite(t *testing.T) {
+	suite.Run(t, new(ExchangeGuardSuite))
+}
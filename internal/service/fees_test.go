@@ -0,0 +1,77 @@
+package service
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/agandreev/avito-intern-assignment/internal/domain"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/suite"
+)
+
+type FeesSuite struct {
+	suite.Suite
+	Repository *recordingRepository
+	GrossBook  *GrossBook
+}
+
+func (suite *FeesSuite) SetupTest() {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	suite.Repository = &recordingRepository{}
+	suite.GrossBook = NewGrossBook(suite.Repository, blockingConverter{}, logger)
+	suite.GrossBook.Fees = domain.FeeSchedule{
+		AccountID: 99,
+		Rules:     []domain.FeeRule{{Type: domain.Withdraw, Percent: 1, Fixed: 1}},
+	}
+}
+
+func (suite *FeesSuite) TestGrossBook_ChargeFee() {
+	operation, err := suite.GrossBook.WithdrawMoney(context.Background(), 1, 50, "")
+	suite.Require().NoError(err)
+	suite.Equal(1.5, operation.Fee)
+	suite.Equal(48.5, operation.Initiator.Amount)
+
+	// fee is stored with operation
+	operations := suite.Repository.operations
+	suite.Require().Len(operations, 2)
+	suite.Equal(domain.Fee, operations[1].Type)
+	suite.Equal(1.5, operations[1].Amount)
+	suite.Equal(int64(99), operations[1].Receiver.ID)
+
+	// fee can't be paid without enough money
+	_, err = suite.GrossBook.WithdrawMoney(context.Background(), 1, 99.5, "")
+	suite.ErrorIs(err, domain.ErrInsufficientFunds)
+
+	// transfers are free
+	suite.Repository.operations = nil
+	operation, err = suite.GrossBook.TransferMoney(context.Background(), 1, 2, 50, "")
+	suite.Require().NoError(err)
+	suite.Zero(operation.Fee)
+	suite.Len(suite.Repository.operations, 1)
+}
+
+func (suite *FeesSuite) TestGrossBook_ChargeConversionFee() {
+	suite.GrossBook.Exchange = rateConverter{"RUB": 1, "USD": 100}
+	suite.GrossBook.Fees.Rules = append(suite.GrossBook.Fees.Rules,
+		domain.FeeRule{Type: domain.ConversionFee, Percent: 1})
+
+	// conversion fee is paid from deposited money
+	operation, err := suite.GrossBook.DepositMoney(context.Background(), 1, 1, "USD")
+	suite.Require().NoError(err)
+	suite.Equal(1.0, operation.Fee)
+	suite.Equal(199.0, operation.Initiator.Amount)
+	operations := suite.Repository.operations
+	suite.Require().Len(operations, 2)
+	suite.Equal(domain.Fee, operations[1].Type)
+
+	// converted withdrawal is charged by both rules
+	operation, err = suite.GrossBook.WithdrawMoney(context.Background(), 1, 0.5, "USD")
+	suite.Require().NoError(err)
+	suite.Equal(2.0, operation.Fee)
+}
+
+func TestFeesSuite(t *testing.T) {
+	suite.Run(t, new(FeesSuite))
+}
@@ -2,7 +2,9 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/agandreev/avito-intern-assignment/internal/domain"
@@ -14,31 +16,128 @@ import (
 type GrossBookRepository interface {
 	UserRepository
 	OperationRepository
+	WebhookRepository
+	EventRepository
+	ScheduleRepository
+	LimitRepository
+	StatusRepository
+	AuditRepository
+	IdempotencyRepository
+	AdjustmentRepository
 	Shutdown()
 }
 
 // UserRepository describes UserStorage methods.
 type UserRepository interface {
-	AddUser(id int64) error
-	User(id int64) (*domain.User, error)
+	AddUser(ctx context.Context, id int64) error
+	CreateUser(ctx context.Context, user domain.User) error
+	User(ctx context.Context, id int64) (*domain.User, error)
 }
 
 // OperationRepository describes UserStorage methods.
 type OperationRepository interface {
-	AddOperation(ctx context.Context, operation domain.Operation) error
-	Operations(id, offset int64, mode domain.SortingMode) ([]domain.RepositoryOperation, error)
+	AddOperation(ctx context.Context, operation domain.Operation) ([]domain.Event, error)
+	AddOperations(ctx context.Context, operations []domain.Operation) ([]domain.Event, error)
+	Operations(ctx context.Context, id, offset int64, mode domain.SortingMode,
+		filter domain.OperationFilter) ([]domain.RepositoryOperation, error)
 }
 
-// Converter converts amount of money from one currency to RUB.
+// WebhookRepository describes webhook subscriptions storage methods.
+type WebhookRepository interface {
+	AddWebhook(ctx context.Context, webhook domain.Webhook) (*domain.Webhook, error)
+	Webhook(ctx context.Context, id int64) (*domain.Webhook, error)
+	WebhookDeliveries(ctx context.Context, webhookID, limit int64) (
+		[]domain.WebhookDelivery, error)
+}
+
+// EventRepository describes stored events methods.
+type EventRepository interface {
+	UserEvents(ctx context.Context, userID, afterID int64, eventType domain.EventType,
+		limit int64) ([]domain.Event, error)
+}
+
+// ScheduleRepository describes scheduled transfers storage methods.
+type ScheduleRepository interface {
+	AddSchedule(ctx context.Context, schedule domain.Schedule) (*domain.Schedule, error)
+	Schedule(ctx context.Context, id int64) (*domain.Schedule, error)
+	UserSchedules(ctx context.Context, userID int64) ([]domain.Schedule, error)
+	UpdateSchedule(ctx context.Context, schedule domain.Schedule) error
+	DeleteSchedule(ctx context.Context, id int64) error
+	ScheduleExecutions(ctx context.Context, scheduleID, limit int64) (
+		[]domain.ScheduleExecution, error)
+	AddScheduledOperations(ctx context.Context, operations []domain.Operation,
+		run *domain.ScheduleRun) ([]domain.Event, error)
+}
+
+// LimitRepository describes users' own limits storage methods.
+type LimitRepository interface {
+	UserLimits(ctx context.Context, userID int64) (*domain.Limits, error)
+	SetUserLimits(ctx context.Context, userID int64, limits domain.Limits) error
+	DeleteUserLimits(ctx context.Context, userID int64) error
+}
+
+// StatusRepository describes accounts' statuses audit trail methods.
+type StatusRepository interface {
+	ChangeStatus(ctx context.Context, change domain.StatusChange) (*domain.StatusChange, error)
+	StatusChanges(ctx context.Context, userID, limit int64) ([]domain.StatusChange, error)
+}
+
+// AuditRepository describes append-only audit log methods.
+type AuditRepository interface {
+	AddAuditEntry(ctx context.Context, entry domain.AuditEntry) (*domain.AuditEntry, error)
+	AuditEntries(ctx context.Context, filter domain.AuditFilter) ([]domain.AuditEntry, error)
+}
+
+// IdempotencyRepository describes storage of idempotency keys and their responses.
+type IdempotencyRepository interface {
+	ClaimIdempotencyKey(ctx context.Context, record domain.IdempotencyRecord,
+		expiredBefore time.Time) (*domain.IdempotencyRecord, error)
+	CompleteIdempotencyKey(ctx context.Context, key string, status int, response []byte) error
+	ReleaseIdempotencyKey(ctx context.Context, key string) error
+}
+
+// AdjustmentRepository describes storage of manual adjustments and their reviews.
+type AdjustmentRepository interface {
+	AddAdjustment(ctx context.Context, request domain.AdjustmentRequest) (
+		*domain.AdjustmentRequest, error)
+	Adjustment(ctx context.Context, id int64) (*domain.AdjustmentRequest, error)
+	Adjustments(ctx context.Context, filter domain.AdjustmentFilter) (
+		[]domain.AdjustmentRequest, error)
+	ExpireAdjustments(ctx context.Context, now time.Time) (int64, error)
+	ReviewAdjustment(ctx context.Context, request domain.AdjustmentRequest,
+		operation *domain.Operation) ([]domain.Event, error)
+}
+
+// Notifier receives events of committed operations.
+type Notifier interface {
+	Notify(events ...domain.Event)
+}
+
+// Converter converts amount of money from one currency to another.
 type Converter interface {
-	Convert(from string, amount float64) (float64, error)
+	Convert(ctx context.Context, from, to string, amount float64) (float64, error)
 }
 
 // GrossBook represents this service logic.
 type GrossBook struct {
 	Users    GrossBookRepository
 	Exchange Converter
-	log      *logrus.Logger
+	// Notifier is optional and receives events after each committed operation.
+	Notifier Notifier
+	// MaxBatchSize limits quantity of batch items, zero means no limit.
+	MaxBatchSize int
+	// Limits are global limits which can be overridden for each user.
+	Limits domain.Limits
+	// Fees are charged from withdrawals, outgoing transfers and converted amounts.
+	Fees domain.FeeSchedule
+	// AutoCreateUsers allows deposits to create unknown users.
+	AutoCreateUsers bool
+	// IdempotencyTTL is a time after which idempotency keys can be reused, zero means
+	// that keys never expire.
+	IdempotencyTTL time.Duration
+	// AdjustmentTTL is a time in which adjustment must be approved, three days by default.
+	AdjustmentTTL time.Duration
+	log           *logrus.Logger
 }
 
 // NewGrossBook sets GrossBook fields and returns pointer.
@@ -52,112 +151,216 @@ func NewGrossBook(users GrossBookRepository, exchange Converter,
 }
 
 // DepositMoney increases user balance by id and updates db.
-func (grossBook *GrossBook) DepositMoney(id int64, amount float64) (
+func (grossBook *GrossBook) DepositMoney(ctx context.Context, id int64, amount float64,
+	currency string) (
 	*domain.Operation, error) {
-	grossBook.log.Printf("DEPOSIT: <%f>RUB to <%d> processing...", amount, id)
+	var operation *domain.Operation
+	err := grossBook.retryConflicts(ctx, func() (err error) {
+		operation, err = grossBook.depositMoney(ctx, id, amount, currency)
+		return err
+	})
+	return operation, err
+}
+
+// depositMoney increases user balance from its snapshot once.
+func (grossBook *GrossBook) depositMoney(ctx context.Context, id int64, amount float64,
+	currency string) (
+	*domain.Operation, error) {
+	grossBook.log.Printf("DEPOSIT: <%f> to <%d> processing...", amount, id)
 	// get user or create it
-	user, err := grossBook.Users.User(id)
+	user, err := grossBook.depositUser(ctx, id)
 	if err != nil {
-		switch err {
-		// create empty raw in db
-		case repository.ErrNoSuchUser:
-			if err = grossBook.Users.AddUser(id); err != nil {
-				return nil, fmt.Errorf("grossbook get user error: <%w>", err)
-			}
-		default:
-			return nil, fmt.Errorf("grossbook get user error: <%w>", err)
-		}
+		return nil, fmt.Errorf("grossbook get user error: <%w>", err)
 	}
-	// increase User's amount
+	version := expectedVersion(ctx)
+	if err = user.CheckVersion(version); err != nil {
+		return nil, fmt.Errorf("grossbook deposit error: <%w>", err)
+	}
+	// convert amount to RUB
+	currency = strings.ToUpper(currency)
+	originalAmount := amount
+	if amount, err = grossBook.convertToRubles(ctx, currency, amount); err != nil {
+		return nil, fmt.Errorf("grossbook deposit conversion error: <%w>", err)
+	}
+	// increase User's amount, conversion fee is paid from deposited money
 	if err = user.Deposit(amount); err != nil {
 		return nil, fmt.Errorf("grossbook deposit error: <%w>", err)
 	}
+	fee := grossBook.Fees.Fee(domain.Deposit, currency, originalAmount, amount)
+	if fee != 0 {
+		if err = user.Withdraw(fee); err != nil {
+			return nil, fmt.Errorf("grossbook deposit fee error: <%w>", err)
+		}
+	}
 	operation := domain.Operation{
-		Initiator: user,
-		Type:      domain.Deposit,
-		Amount:    amount,
-		Timestamp: time.Now(),
+		Initiator:       user,
+		Type:            domain.Deposit,
+		Amount:          amount,
+		Timestamp:       time.Now(),
+		Fee:             fee,
+		ExpectedVersion: version,
+	}
+	operation.SetOriginal(currency, originalAmount)
+	if err = operation.CheckStatus(); err != nil {
+		return nil, fmt.Errorf("grossbook deposit error: <%w>", err)
+	}
+	if err = grossBook.limitOperation(ctx, &operation); err != nil {
+		return nil, fmt.Errorf("grossbook deposit error: <%w>", err)
 	}
 	// update db
-	if err = grossBook.Users.AddOperation(context.Background(), operation); err != nil {
+	events, err := grossBook.Users.AddOperations(ctx, grossBook.withFee(operation))
+	if err != nil {
 		return nil, fmt.Errorf("grossbook update error: <%w>", err)
 	}
+	grossBook.notify(events)
 	grossBook.log.Printf("DEPOSIT: <%f>RUB from <%d> was processed successful",
 		amount, id)
 	return &operation, nil
 }
 
 // WithdrawMoney decreases domain.User's balance and updates db.
-func (grossBook *GrossBook) WithdrawMoney(id int64, amount float64, currency string) (
+func (grossBook *GrossBook) WithdrawMoney(ctx context.Context, id int64, amount float64,
+	currency string) (
+	*domain.Operation, error) {
+	var operation *domain.Operation
+	err := grossBook.retryConflicts(ctx, func() (err error) {
+		operation, err = grossBook.withdrawMoney(ctx, id, amount, currency)
+		return err
+	})
+	return operation, err
+}
+
+// withdrawMoney decreases user balance from its snapshot once.
+func (grossBook *GrossBook) withdrawMoney(ctx context.Context, id int64, amount float64,
+	currency string) (
 	*domain.Operation, error) {
 	grossBook.log.Printf("WITHDRAW: <%f> from <%d> processing...", amount, id)
 	// get user
-	user, err := grossBook.Users.User(id)
+	user, err := grossBook.Users.User(ctx, id)
 	if err != nil {
 		return nil, fmt.Errorf("grossbook get user error: <%w>", err)
 	}
+	version := expectedVersion(ctx)
+	if err = user.CheckVersion(version); err != nil {
+		return nil, fmt.Errorf("grossbook withdraw error: <%w>", err)
+	}
 	// convert amount to RUB
-	if len(currency) != 0 {
-		convertedAmount, err := grossBook.Exchange.Convert(currency, amount)
-		if err != nil {
-			return nil, fmt.Errorf("gorssbook withdraw conversion error: <%w>", err)
-		}
-		amount = convertedAmount
+	currency = strings.ToUpper(currency)
+	originalAmount := amount
+	if amount, err = grossBook.convertToRubles(ctx, currency, amount); err != nil {
+		return nil, fmt.Errorf("gorssbook withdraw conversion error: <%w>", err)
 	}
-	// decrease user's balance
-	if err = user.Withdraw(amount); err != nil {
+	// decrease user's balance by amount and fee
+	fee := grossBook.Fees.Fee(domain.Withdraw, currency, originalAmount, amount)
+	if err = user.Withdraw(amount + fee); err != nil {
 		return nil, fmt.Errorf("grossbook withdraw error: <%w>", err)
 	}
 	operation := domain.Operation{
-		Initiator: user,
-		Type:      domain.Withdraw,
-		Amount:    amount,
-		Timestamp: time.Now(),
+		Initiator:       user,
+		Type:            domain.Withdraw,
+		Amount:          amount,
+		Timestamp:       time.Now(),
+		Fee:             fee,
+		ExpectedVersion: version,
+	}
+	operation.SetOriginal(currency, originalAmount)
+	if err = operation.CheckStatus(); err != nil {
+		return nil, fmt.Errorf("grossbook withdraw error: <%w>", err)
+	}
+	if err = grossBook.limitOperation(ctx, &operation); err != nil {
+		return nil, fmt.Errorf("grossbook withdraw error: <%w>", err)
 	}
 	// update db
-	if err = grossBook.Users.AddOperation(context.Background(), operation); err != nil {
+	events, err := grossBook.Users.AddOperations(ctx, grossBook.withFee(operation))
+	if err != nil {
 		return nil, fmt.Errorf("grossbook update error: <%w>", err)
 	}
+	grossBook.notify(events)
 	grossBook.log.Printf("WITHDRAW: <%f>RUB from <%d> was processed successful",
 		amount, id)
 	return &operation, nil
 }
 
 // TransferMoney transfers money from one domain.User to another and updates db.
-func (grossBook *GrossBook) TransferMoney(ownerID, receiverID int64, amount float64) (
+func (grossBook *GrossBook) TransferMoney(ctx context.Context, ownerID, receiverID int64,
+	amount float64, currency string) (
 	*domain.Operation, error) {
-	grossBook.log.Printf("TRANSFER: <%f>RUB from <%d> to <%d> processing...",
+	var operation *domain.Operation
+	err := grossBook.retryConflicts(ctx, func() (err error) {
+		operation, err = grossBook.transferMoney(ctx, ownerID, receiverID, amount, currency,
+			nil)
+		return err
+	})
+	return operation, err
+}
+
+// transferMoney transfers money between users' snapshots once. Transfer is stored with
+// run's execution if run is set.
+func (grossBook *GrossBook) transferMoney(ctx context.Context, ownerID, receiverID int64,
+	amount float64, currency string, run *domain.ScheduleRun) (
+	*domain.Operation, error) {
+	grossBook.log.Printf("TRANSFER: <%f> from <%d> to <%d> processing...",
 		amount, ownerID, receiverID)
 	// get users
-	owner, err := grossBook.Users.User(ownerID)
+	owner, err := grossBook.Users.User(ctx, ownerID)
 	if err != nil {
 		return nil, fmt.Errorf("grossbook get owner error: <%w>", err)
 	}
-	receiver, err := grossBook.Users.User(receiverID)
+	receiver, err := grossBook.Users.User(ctx, receiverID)
 	if err != nil {
 		return nil, fmt.Errorf("grossbook get receiver error: <%w>", err)
 	}
 	if owner.ID == receiverID {
-		return nil, fmt.Errorf("grossbook can't transfer money for the same user")
+		return nil, fmt.Errorf("grossbook can't transfer money for the same user: <%w>",
+			domain.ErrIncorrectOperationParams)
+	}
+	// expected version is owner's one
+	version := expectedVersion(ctx)
+	if err = owner.CheckVersion(version); err != nil {
+		return nil, fmt.Errorf("grossbook transfer error: <%w>", err)
+	}
+	// convert amount to RUB
+	currency = strings.ToUpper(currency)
+	originalAmount := amount
+	if amount, err = grossBook.convertToRubles(ctx, currency, amount); err != nil {
+		return nil, fmt.Errorf("grossbook transfer conversion error: <%w>", err)
 	}
-	// decrease and increase balances
-	if err = owner.Withdraw(amount); err != nil {
+	// decrease and increase balances, fee is paid by owner
+	fee := grossBook.Fees.Fee(domain.TransferOut, currency, originalAmount, amount)
+	if err = owner.Withdraw(amount + fee); err != nil {
 		return nil, fmt.Errorf("grossbook owner withdraw error: <%w>", err)
 	}
 	if err = receiver.Deposit(amount); err != nil {
 		return nil, fmt.Errorf("grossbook receiver deposit error: <%w>", err)
 	}
 	operation := domain.Operation{
-		Initiator: owner,
-		Type:      domain.TransferOut,
-		Amount:    amount,
-		Timestamp: time.Now(),
-		Receiver:  receiver,
+		Initiator:       owner,
+		Type:            domain.TransferOut,
+		Amount:          amount,
+		Timestamp:       time.Now(),
+		Receiver:        receiver,
+		Fee:             fee,
+		ExpectedVersion: version,
+	}
+	operation.SetOriginal(currency, originalAmount)
+	if err = operation.CheckStatus(); err != nil {
+		return nil, fmt.Errorf("grossbook transfer error: <%w>", err)
+	}
+	if err = grossBook.limitOperation(ctx, &operation); err != nil {
+		return nil, fmt.Errorf("grossbook transfer error: <%w>", err)
 	}
 	// update db
-	if err = grossBook.Users.AddOperation(context.Background(), operation); err != nil {
+	var events []domain.Event
+	if run != nil {
+		events, err = grossBook.Users.AddScheduledOperations(ctx, grossBook.withFee(operation),
+			run)
+	} else {
+		events, err = grossBook.Users.AddOperations(ctx, grossBook.withFee(operation))
+	}
+	if err != nil {
 		return nil, fmt.Errorf("grossbook transfer update error: <%w>", err)
 	}
+	grossBook.notify(events)
 	grossBook.log.Printf("TRANSFER: <%f>RUB from <%d> to <%d> was processed successful",
 		amount, ownerID, receiverID)
 	// hide second side amount for safety
@@ -165,10 +368,38 @@ func (grossBook *GrossBook) TransferMoney(ownerID, receiverID int64, amount floa
 	return &operation, nil
 }
 
+// CreateUser creates domain.User with zero balance and metadata from domain.UserInput.
+func (grossBook GrossBook) CreateUser(ctx context.Context, input domain.UserInput) (
+	*domain.User, error) {
+	grossBook.log.Printf("CREATE USER: <%d> processing...", input.ID)
+	if err := input.Validate(); err != nil {
+		return nil, fmt.Errorf("can't create user: <%w>", err)
+	}
+	user := input.User(time.Now())
+	if err := grossBook.Users.CreateUser(ctx, user); err != nil {
+		return nil, fmt.Errorf("can't create user: <%w>", err)
+	}
+	grossBook.log.Printf("CREATE USER: <%d> was processed successful", input.ID)
+	return &user, nil
+}
+
+// depositUser returns deposit's receiver. Unknown user is created if it's allowed.
+func (grossBook GrossBook) depositUser(ctx context.Context, id int64) (*domain.User, error) {
+	user, err := grossBook.Users.User(ctx, id)
+	if !errors.Is(err, repository.ErrNoSuchUser) || !grossBook.AutoCreateUsers {
+		return user, err
+	}
+	if err = grossBook.Users.AddUser(ctx, id); err != nil {
+		return nil, err
+	}
+	// user is read again, because it may be created concurrently with some money
+	return grossBook.Users.User(ctx, id)
+}
+
 // Balance returns domain.User's balance from db.
-func (grossBook GrossBook) Balance(id int64) (*domain.User, error) {
+func (grossBook GrossBook) Balance(ctx context.Context, id int64) (*domain.User, error) {
 	grossBook.log.Printf("BALANCE: by <%d> processing...", id)
-	user, err := grossBook.Users.User(id)
+	user, err := grossBook.Users.User(ctx, id)
 	if err != nil {
 		return nil, fmt.Errorf("grossbook get owner error: <%w>", err)
 	}
@@ -176,14 +407,19 @@ func (grossBook GrossBook) Balance(id int64) (*domain.User, error) {
 	return user, nil
 }
 
-// History returns slice of domain.RepositoryOperation from db.
-func (grossBook GrossBook) History(id, offset int64, mode domain.SortingMode) (
+// History returns slice of domain.RepositoryOperation from db restricted by
+// domain.OperationFilter.
+func (grossBook GrossBook) History(ctx context.Context, id, offset int64,
+	mode domain.SortingMode, filter domain.OperationFilter) (
 	[]domain.RepositoryOperation, error) {
 	grossBook.log.Printf("HISTORY: by <%d> processing...", id)
-	if _, err := grossBook.Users.User(id); err != nil {
+	if _, err := grossBook.Users.User(ctx, id); err != nil {
+		return nil, fmt.Errorf("can't load history: <%w>", err)
+	}
+	if err := filter.Validate(); err != nil {
 		return nil, fmt.Errorf("can't load history: <%w>", err)
 	}
-	operations, err := grossBook.Users.Operations(id, offset, mode)
+	operations, err := grossBook.Users.Operations(ctx, id, offset, mode, filter)
 	if err != nil {
 		return nil, fmt.Errorf("can't load history: <%w>", err)
 	}
@@ -191,6 +427,125 @@ func (grossBook GrossBook) History(id, offset int64, mode domain.SortingMode) (
 	return operations, nil
 }
 
+// BalanceEvents returns up to limit BalanceChanged domain.Event of domain.User
+// stored after afterID.
+func (grossBook GrossBook) BalanceEvents(ctx context.Context, id, afterID, limit int64) (
+	[]domain.Event, error) {
+	events, err := grossBook.Users.UserEvents(ctx, id, afterID, domain.BalanceChanged, limit)
+	if err != nil {
+		return nil, fmt.Errorf("can't load balance events: <%w>", err)
+	}
+	return events, nil
+}
+
+// withFee returns domain.Operation with its fee operation if fee is charged.
+func (grossBook GrossBook) withFee(operation domain.Operation) []domain.Operation {
+	operations := []domain.Operation{operation}
+	if fee := grossBook.Fees.FeeOperation(operation); fee != nil {
+		operations = append(operations, *fee)
+	}
+	return operations
+}
+
+// notify passes events to Notifier if it's set.
+func (grossBook GrossBook) notify(events []domain.Event) {
+	if grossBook.Notifier != nil {
+		grossBook.Notifier.Notify(events...)
+	}
+}
+
+// AddWebhook validates and stores domain.Webhook subscription.
+func (grossBook GrossBook) AddWebhook(ctx context.Context, webhook domain.Webhook) (
+	*domain.Webhook, error) {
+	grossBook.log.Printf("ADD WEBHOOK: to <%s> processing...", webhook.URL)
+	if err := webhook.Validate(); err != nil {
+		return nil, fmt.Errorf("can't add webhook: <%w>", err)
+	}
+	webhook.CreatedAt = time.Now()
+	added, err := grossBook.Users.AddWebhook(ctx, webhook)
+	if err != nil {
+		return nil, fmt.Errorf("can't add webhook: <%w>", err)
+	}
+	grossBook.log.Printf("ADD WEBHOOK: <%d> was processed successful", added.ID)
+	return added, nil
+}
+
+// WebhookDeliveries returns the latest deliveries of domain.Webhook.
+func (grossBook GrossBook) WebhookDeliveries(ctx context.Context, id, limit int64) (
+	[]domain.WebhookDelivery, error) {
+	grossBook.log.Printf("WEBHOOK DELIVERIES: by <%d> processing...", id)
+	if _, err := grossBook.Users.Webhook(ctx, id); err != nil {
+		return nil, fmt.Errorf("can't load deliveries: <%w>", err)
+	}
+	deliveries, err := grossBook.Users.WebhookDeliveries(ctx, id, limit)
+	if err != nil {
+		return nil, fmt.Errorf("can't load deliveries: <%w>", err)
+	}
+	grossBook.log.Printf("WEBHOOK DELIVERIES: by <%d> was processed successful", id)
+	return deliveries, nil
+}
+
+// convertToRubles converts amount of currency to RUB, empty currency means RUB. Currency
+// is checked by ISO 4217 registry before provider's request.
+func (grossBook GrossBook) convertToRubles(ctx context.Context, currency string,
+	amount float64) (float64, error) {
+	if len(currency) == 0 {
+		return amount, nil
+	}
+	if _, err := domain.LookupCurrency(currency); err != nil {
+		return 0, err
+	}
+	if grossBook.Exchange == nil {
+		return 0, domain.ErrExchangeUnavailable
+	}
+	return grossBook.Exchange.Convert(ctx, currency, rub, amount)
+}
+
+// Convert converts amount of money between currencies without any operation.
+func (grossBook GrossBook) Convert(ctx context.Context, input domain.ConversionInput) (
+	*domain.Conversion, error) {
+	grossBook.log.Printf("CONVERT: <%f>%s to %s processing...", input.Amount, input.From,
+		input.To)
+	if err := input.Validate(); err != nil {
+		return nil, fmt.Errorf("grossbook conversion error: <%w>", err)
+	}
+	if grossBook.Exchange == nil {
+		return nil, fmt.Errorf("grossbook conversion error: <%w>",
+			domain.ErrExchangeUnavailable)
+	}
+	result, err := grossBook.Exchange.Convert(ctx, input.From, input.To, input.Amount)
+	if err != nil {
+		return nil, fmt.Errorf("grossbook conversion error: <%w>", err)
+	}
+	grossBook.log.Printf("CONVERT: <%f>%s to %s was processed successful", input.Amount,
+		input.From, input.To)
+	return &domain.Conversion{
+		From:   input.From,
+		To:     input.To,
+		Amount: input.Amount,
+		Result: result,
+	}, nil
+}
+
+// exchangeStatus is implemented by Converter which reports its provider's availability.
+type exchangeStatus interface {
+	Status() domain.ExchangeStatus
+}
+
+// Health returns domain.Health, it's degraded while exchange rates provider is
+// unavailable.
+func (grossBook GrossBook) Health() domain.Health {
+	health := domain.Health{Status: domain.HealthOK}
+	if reporter, ok := grossBook.Exchange.(exchangeStatus); ok {
+		status := reporter.Status()
+		health.Exchange = &status
+		if !status.Available() {
+			health.Status = domain.HealthDegraded
+		}
+	}
+	return health
+}
+
 // Shutdown gracefully shuts this service down.
 func (grossBook GrossBook) Shutdown() {
 	grossBook.Users.Shutdown()
@@ -0,0 +1,415 @@
+package service
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/agandreev/avito-intern-assignment/internal/domain"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/suite"
+)
+
+// blockingRepository imitates slow db which answers only on context cancellation.
+type blockingRepository struct {
+	calls int
+}
+
+func (repository *blockingRepository) wait(ctx context.Context) error {
+	repository.calls++
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (repository *blockingRepository) AddUser(ctx context.Context, _ int64) error {
+	return repository.wait(ctx)
+}
+
+func (repository *blockingRepository) CreateUser(ctx context.Context, _ domain.User) error {
+	return repository.wait(ctx)
+}
+
+func (repository *blockingRepository) User(ctx context.Context, _ int64) (*domain.User, error) {
+	return nil, repository.wait(ctx)
+}
+
+func (repository *blockingRepository) AddOperation(ctx context.Context, _ domain.Operation) (
+	[]domain.Event, error) {
+	return nil, repository.wait(ctx)
+}
+
+func (repository *blockingRepository) AddOperations(ctx context.Context, _ []domain.Operation) (
+	[]domain.Event, error) {
+	return nil, repository.wait(ctx)
+}
+
+func (repository *blockingRepository) AddScheduledOperations(ctx context.Context,
+	_ []domain.Operation, _ *domain.ScheduleRun) ([]domain.Event, error) {
+	return nil, repository.wait(ctx)
+}
+
+func (repository *blockingRepository) Operations(ctx context.Context, _, _ int64,
+	_ domain.SortingMode, _ domain.OperationFilter) ([]domain.RepositoryOperation, error) {
+	return nil, repository.wait(ctx)
+}
+
+func (repository *blockingRepository) AddWebhook(ctx context.Context,
+	_ domain.Webhook) (*domain.Webhook, error) {
+	return nil, repository.wait(ctx)
+}
+
+func (repository *blockingRepository) Webhook(ctx context.Context, _ int64) (
+	*domain.Webhook, error) {
+	return nil, repository.wait(ctx)
+}
+
+func (repository *blockingRepository) WebhookDeliveries(ctx context.Context, _, _ int64) (
+	[]domain.WebhookDelivery, error) {
+	return nil, repository.wait(ctx)
+}
+
+func (repository *blockingRepository) UserEvents(ctx context.Context, _, _ int64,
+	_ domain.EventType, _ int64) ([]domain.Event, error) {
+	return nil, repository.wait(ctx)
+}
+
+func (repository *blockingRepository) AddSchedule(ctx context.Context,
+	_ domain.Schedule) (*domain.Schedule, error) {
+	return nil, repository.wait(ctx)
+}
+
+func (repository *blockingRepository) Schedule(ctx context.Context, _ int64) (
+	*domain.Schedule, error) {
+	return nil, repository.wait(ctx)
+}
+
+func (repository *blockingRepository) UserSchedules(ctx context.Context, _ int64) (
+	[]domain.Schedule, error) {
+	return nil, repository.wait(ctx)
+}
+
+func (repository *blockingRepository) UpdateSchedule(ctx context.Context,
+	_ domain.Schedule) error {
+	return repository.wait(ctx)
+}
+
+func (repository *blockingRepository) DeleteSchedule(ctx context.Context, _ int64) error {
+	return repository.wait(ctx)
+}
+
+func (repository *blockingRepository) ScheduleExecutions(ctx context.Context, _, _ int64) (
+	[]domain.ScheduleExecution, error) {
+	return nil, repository.wait(ctx)
+}
+
+func (repository *blockingRepository) UserLimits(ctx context.Context, _ int64) (
+	*domain.Limits, error) {
+	return nil, repository.wait(ctx)
+}
+
+func (repository *blockingRepository) SetUserLimits(ctx context.Context, _ int64,
+	_ domain.Limits) error {
+	return repository.wait(ctx)
+}
+
+func (repository *blockingRepository) DeleteUserLimits(ctx context.Context, _ int64) error {
+	return repository.wait(ctx)
+}
+
+func (repository *blockingRepository) ChangeStatus(ctx context.Context,
+	_ domain.StatusChange) (*domain.StatusChange, error) {
+	return nil, repository.wait(ctx)
+}
+
+func (repository *blockingRepository) StatusChanges(ctx context.Context, _, _ int64) (
+	[]domain.StatusChange, error) {
+	return nil, repository.wait(ctx)
+}
+
+func (repository *blockingRepository) AddAuditEntry(ctx context.Context,
+	_ domain.AuditEntry) (*domain.AuditEntry, error) {
+	return nil, repository.wait(ctx)
+}
+
+func (repository *blockingRepository) AuditEntries(ctx context.Context,
+	_ domain.AuditFilter) ([]domain.AuditEntry, error) {
+	return nil, repository.wait(ctx)
+}
+
+func (repository *blockingRepository) ClaimIdempotencyKey(ctx context.Context,
+	_ domain.IdempotencyRecord, _ time.Time) (*domain.IdempotencyRecord, error) {
+	return nil, repository.wait(ctx)
+}
+
+func (repository *blockingRepository) CompleteIdempotencyKey(ctx context.Context, _ string,
+	_ int, _ []byte) error {
+	return repository.wait(ctx)
+}
+
+func (repository *blockingRepository) ReleaseIdempotencyKey(ctx context.Context,
+	_ string) error {
+	return repository.wait(ctx)
+}
+
+func (repository *blockingRepository) AddAdjustment(ctx context.Context,
+	_ domain.AdjustmentRequest) (*domain.AdjustmentRequest, error) {
+	return nil, repository.wait(ctx)
+}
+
+func (repository *blockingRepository) Adjustment(ctx context.Context, _ int64) (
+	*domain.AdjustmentRequest, error) {
+	return nil, repository.wait(ctx)
+}
+
+func (repository *blockingRepository) Adjustments(ctx context.Context,
+	_ domain.AdjustmentFilter) ([]domain.AdjustmentRequest, error) {
+	return nil, repository.wait(ctx)
+}
+
+func (repository *blockingRepository) ExpireAdjustments(ctx context.Context, _ time.Time) (
+	int64, error) {
+	return 0, repository.wait(ctx)
+}
+
+func (repository *blockingRepository) ReviewAdjustment(ctx context.Context,
+	_ domain.AdjustmentRequest, _ *domain.Operation) ([]domain.Event, error) {
+	return nil, repository.wait(ctx)
+}
+
+func (repository *blockingRepository) Shutdown() {}
+
+// blockingConverter imitates slow exchanger which answers only on context cancellation.
+type blockingConverter struct{}
+
+func (blockingConverter) Convert(ctx context.Context, _, _ string, _ float64) (
+	float64, error) {
+	<-ctx.Done()
+	return 0, ctx.Err()
+}
+
+// stubRepository always returns user with enough money.
+type stubRepository struct {
+	blockingRepository
+}
+
+func (repository *stubRepository) User(_ context.Context, id int64) (*domain.User, error) {
+	return &domain.User{ID: id, Amount: 100}, nil
+}
+
+func (repository *stubRepository) UserLimits(_ context.Context, _ int64) (
+	*domain.Limits, error) {
+	return &domain.Limits{}, nil
+}
+
+func (repository *stubRepository) AddAuditEntry(_ context.Context,
+	entry domain.AuditEntry) (*domain.AuditEntry, error) {
+	return &entry, nil
+}
+
+// racingRepository stores users' balances with versions. Initiator's balance is changed
+// concurrently before the first races operations are stored.
+type racingRepository struct {
+	stubRepository
+	users map[int64]domain.User
+	races int
+}
+
+func (repository *racingRepository) User(_ context.Context, id int64) (*domain.User, error) {
+	user := repository.users[id]
+	return &user, nil
+}
+
+func (repository *racingRepository) AddOperations(_ context.Context,
+	operations []domain.Operation) ([]domain.Event, error) {
+	if repository.races > 0 {
+		repository.races--
+		initiator := repository.users[1]
+		initiator.Amount += 5
+		initiator.Version++
+		repository.users[1] = initiator
+	}
+	// operations are applied to the current balances as by locked rows
+	for _, operation := range operations {
+		initiator := repository.users[operation.Initiator.ID]
+		if err := initiator.CheckVersion(operation.ExpectedVersion); err != nil {
+			return nil, err
+		}
+		var receiver domain.User
+		if operation.Receiver != nil {
+			receiver = repository.users[operation.Receiver.ID]
+		}
+		if err := operation.Apply(&initiator, &receiver); err != nil {
+			return nil, err
+		}
+		initiator.Version++
+		repository.users[initiator.ID] = initiator
+		if operation.IsTransfer() {
+			receiver.Version++
+			repository.users[receiver.ID] = receiver
+		}
+	}
+	return nil, nil
+}
+
+type GrossBookSuite struct {
+	suite.Suite
+	Repository *blockingRepository
+	GrossBook  *GrossBook
+}
+
+func (suite *GrossBookSuite) SetupTest() {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	suite.Repository = &blockingRepository{}
+	suite.GrossBook = NewGrossBook(suite.Repository, blockingConverter{}, logger)
+}
+
+func (suite *GrossBookSuite) timeoutContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), 10*time.Millisecond)
+}
+
+func (suite *GrossBookSuite) TestGrossBook_CancelQueries() {
+	ctx, cancel := suite.timeoutContext()
+	defer cancel()
+
+	_, err := suite.GrossBook.Balance(ctx, 1)
+	suite.ErrorIs(err, context.DeadlineExceeded)
+	_, err = suite.GrossBook.History(ctx, 1, 1, domain.DateMode,
+		domain.OperationFilter{})
+	suite.ErrorIs(err, context.DeadlineExceeded)
+	_, err = suite.GrossBook.DepositMoney(ctx, 1, 1, "")
+	suite.ErrorIs(err, context.DeadlineExceeded)
+	_, err = suite.GrossBook.WithdrawMoney(ctx, 1, 1, "")
+	suite.ErrorIs(err, context.DeadlineExceeded)
+	_, err = suite.GrossBook.TransferMoney(ctx, 1, 2, 1, "")
+	suite.ErrorIs(err, context.DeadlineExceeded)
+	suite.Equal(5, suite.Repository.calls)
+}
+
+func (suite *GrossBookSuite) TestGrossBook_CancelConversion() {
+	repository := &stubRepository{}
+	suite.GrossBook.Users = repository
+	ctx, cancel := suite.timeoutContext()
+	defer cancel()
+
+	_, err := suite.GrossBook.WithdrawMoney(ctx, 1, 1, "USD")
+	suite.ErrorIs(err, context.DeadlineExceeded)
+	// operation mustn't be stored after failed conversion
+	suite.Zero(repository.calls)
+}
+
+func (suite *GrossBookSuite) TestGrossBook_ConcurrentChanges() {
+	repository := &racingRepository{
+		users: map[int64]domain.User{
+			1: {ID: 1, Amount: 100, Version: 1},
+			2: {ID: 2, Amount: 0, Version: 1},
+		},
+		races: 1,
+	}
+	suite.GrossBook.Users = repository
+
+	// transfer is applied to initiator's changed balance without conflict
+	_, err := suite.GrossBook.TransferMoney(context.Background(), 1, 2, 10, "")
+	suite.Require().NoError(err)
+	suite.Equal(float64(95), repository.users[1].Amount)
+	suite.Equal(float64(10), repository.users[2].Amount)
+
+	// conflict is returned only if client expects initiator's version
+	repository.races = 1
+	ctx := WithExpectedVersion(context.Background(), repository.users[1].Version)
+	_, err = suite.GrossBook.TransferMoney(ctx, 1, 2, 10, "")
+	suite.ErrorIs(err, domain.ErrVersionConflict)
+	suite.Equal(float64(100), repository.users[1].Amount)
+	suite.Equal(float64(10), repository.users[2].Amount)
+}
+
+func TestGrossBookSuite(t *testing.T) {
+	suite.Run(t, new(GrossBookSuite))
+}
+
+// rateConverter converts currencies by fixed rates against RUB.
+type rateConverter map[string]float64
+
+func (converter rateConverter) Convert(_ context.Context, from, to string,
+	amount float64) (float64, error) {
+	fromRate, ok := converter[from]
+	if !ok {
+		return 0, domain.ErrUnsupportedCurrency
+	}
+	toRate, ok := converter[to]
+	if !ok {
+		return 0, domain.ErrUnsupportedCurrency
+	}
+	return amount * fromRate / toRate, nil
+}
+
+type ConversionSuite struct {
+	suite.Suite
+	Repository *recordingRepository
+	GrossBook  *GrossBook
+}
+
+func (suite *ConversionSuite) SetupTest() {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	suite.Repository = &recordingRepository{}
+	suite.GrossBook = NewGrossBook(suite.Repository,
+		rateConverter{rub: 1, "USD": 2.5}, logger)
+}
+
+func (suite *ConversionSuite) TestGrossBook_ForeignDeposit() {
+	operation, err := suite.GrossBook.DepositMoney(context.Background(), 1, 4, "usd")
+	suite.Require().NoError(err)
+	suite.Equal(float64(10), operation.Amount)
+	suite.Equal("USD", operation.Currency)
+	suite.Equal(float64(4), operation.OriginalAmount)
+	suite.Equal(float64(110), operation.Initiator.Amount)
+	suite.Require().Len(suite.Repository.operations, 1)
+	suite.Equal("USD", suite.Repository.operations[0].Currency)
+
+	// amount in RUB isn't converted
+	operation, err = suite.GrossBook.DepositMoney(context.Background(), 1, 4, "")
+	suite.Require().NoError(err)
+	suite.Equal(float64(4), operation.Amount)
+	suite.Empty(operation.Currency)
+	suite.Zero(operation.OriginalAmount)
+
+	_, err = suite.GrossBook.DepositMoney(context.Background(), 1, 4, "JPY")
+	suite.ErrorIs(err, domain.ErrUnsupportedCurrency)
+	_, err = suite.GrossBook.DepositMoney(context.Background(), 1, -4, "USD")
+	suite.ErrorIs(err, domain.ErrNegativeAmount)
+	suite.Len(suite.Repository.operations, 2)
+}
+
+func (suite *ConversionSuite) TestGrossBook_ForeignTransfer() {
+	suite.GrossBook.Fees = domain.FeeSchedule{
+		AccountID: 99,
+		Rules: []domain.FeeRule{
+			{Type: domain.TransferOut, Currency: "USD", Percent: 10},
+		},
+	}
+	operation, err := suite.GrossBook.TransferMoney(context.Background(), 1, 2, 8, "USD")
+	suite.Require().NoError(err)
+	suite.Equal(float64(20), operation.Amount)
+	suite.Equal(float64(2), operation.Fee)
+	suite.Equal(float64(8), operation.OriginalAmount)
+	suite.Equal(float64(78), operation.Initiator.Amount)
+
+	// reversed side keeps original amount
+	reversed, err := suite.Repository.operations[0].Reverse()
+	suite.Require().NoError(err)
+	suite.Equal("USD", reversed.Currency)
+	suite.Equal(float64(8), reversed.OriginalAmount)
+
+	suite.GrossBook.Exchange = nil
+	_, err = suite.GrossBook.TransferMoney(context.Background(), 1, 2, 8, "USD")
+	suite.ErrorIs(err, domain.ErrExchangeUnavailable)
+	// unknown currency is rejected without provider
+	_, err = suite.GrossBook.TransferMoney(context.Background(), 1, 2, 8, "XYZ")
+	suite.ErrorIs(err, domain.ErrUnsupportedCurrency)
+}
+
+func TestConversionSuite(t *testing.T) {
+	suite.Run(t, new(ConversionSuite))
+}
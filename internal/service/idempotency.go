@@ -0,0 +1,58 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/agandreev/avito-intern-assignment/internal/domain"
+)
+
+// idempotencyTimeout limits writes of responses which aren't bound to canceled requests.
+const idempotencyTimeout = 5 * time.Second
+
+// ClaimIdempotencyKey reserves record's key for its request. It returns stored response
+// if the same request was completed before, and nil if request has to be processed.
+func (grossBook GrossBook) ClaimIdempotencyKey(ctx context.Context,
+	record domain.IdempotencyRecord) (*domain.IdempotencyRecord, error) {
+	var expiredBefore time.Time
+	if grossBook.IdempotencyTTL > 0 {
+		expiredBefore = record.CreatedAt.Add(-grossBook.IdempotencyTTL)
+	}
+	stored, err := grossBook.Users.ClaimIdempotencyKey(ctx, record, expiredBefore)
+	if err != nil {
+		return nil, fmt.Errorf("can't claim idempotency key: <%w>", err)
+	}
+	if stored == nil {
+		return nil, nil
+	}
+	replayed, err := stored.Replay(record)
+	if err != nil {
+		return nil, fmt.Errorf("can't replay request: <%w>", err)
+	}
+	grossBook.log.Printf("IDEMPOTENCY: <%s> is replayed", record.Key)
+	return replayed, nil
+}
+
+// CompleteIdempotencyKey stores response of request. It isn't canceled with ctx, so
+// response is kept even after timeouts.
+func (grossBook GrossBook) CompleteIdempotencyKey(ctx context.Context, key string,
+	status int, response []byte) error {
+	storeCtx, cancel := context.WithTimeout(context.Background(), idempotencyTimeout)
+	defer cancel()
+	if err := grossBook.Users.CompleteIdempotencyKey(storeCtx, key, status,
+		response); err != nil {
+		return fmt.Errorf("can't store response: <%w>", err)
+	}
+	return nil
+}
+
+// ReleaseIdempotencyKey frees key of failed request, so it can be retried.
+func (grossBook GrossBook) ReleaseIdempotencyKey(ctx context.Context, key string) error {
+	storeCtx, cancel := context.WithTimeout(context.Background(), idempotencyTimeout)
+	defer cancel()
+	if err := grossBook.Users.ReleaseIdempotencyKey(storeCtx, key); err != nil {
+		return fmt.Errorf("can't release idempotency key: <%w>", err)
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/agandreev/avito-intern-assignment/internal/domain"
+)
+
+// UserLimits returns own and effective domain.Limits of domain.User.
+func (grossBook GrossBook) UserLimits(ctx context.Context, id int64) (
+	*domain.UserLimits, error) {
+	limits, err := grossBook.Users.UserLimits(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("can't load limits: <%w>", err)
+	}
+	return &domain.UserLimits{
+		UserID:    id,
+		Limits:    *limits,
+		Effective: grossBook.Limits.Merge(*limits),
+	}, nil
+}
+
+// SetUserLimits replaces domain.Limits which override global ones for domain.User.
+func (grossBook GrossBook) SetUserLimits(ctx context.Context, id int64,
+	limits domain.Limits) (userLimits *domain.UserLimits, err error) {
+	grossBook.log.Printf("SET LIMITS: for <%d> processing...", id)
+	defer func() {
+		grossBook.audit(ctx, setLimitsAction, userTarget(id), limits, err)
+	}()
+	if err = limits.Validate(); err != nil {
+		return nil, fmt.Errorf("can't set limits: <%w>", err)
+	}
+	if _, err = grossBook.Users.User(ctx, id); err != nil {
+		return nil, fmt.Errorf("can't set limits: <%w>", err)
+	}
+	if err = grossBook.Users.SetUserLimits(ctx, id, limits); err != nil {
+		return nil, fmt.Errorf("can't set limits: <%w>", err)
+	}
+	grossBook.log.Printf("SET LIMITS: for <%d> was processed successful", id)
+	return &domain.UserLimits{
+		UserID:    id,
+		Limits:    limits,
+		Effective: grossBook.Limits.Merge(limits),
+	}, nil
+}
+
+// DeleteUserLimits removes own domain.Limits of domain.User, so global ones are applied.
+func (grossBook GrossBook) DeleteUserLimits(ctx context.Context, id int64) (err error) {
+	grossBook.log.Printf("DELETE LIMITS: for <%d> processing...", id)
+	defer func() {
+		grossBook.audit(ctx, deleteLimitsAction, userTarget(id), nil, err)
+	}()
+	if err = grossBook.Users.DeleteUserLimits(ctx, id); err != nil {
+		return fmt.Errorf("can't delete limits: <%w>", err)
+	}
+	grossBook.log.Printf("DELETE LIMITS: for <%d> was processed successful", id)
+	return nil
+}
+
+// limitOperation checks domain.Operation against initiator's effective limits and
+// attaches them, so periodical limits are checked by storage in operation's transaction.
+func (grossBook GrossBook) limitOperation(ctx context.Context,
+	operation *domain.Operation) error {
+	own, err := grossBook.Users.UserLimits(ctx, operation.Initiator.ID)
+	if err != nil {
+		return fmt.Errorf("can't load limits: <%w>", err)
+	}
+	limits := grossBook.Limits.Merge(*own)
+	if limits.IsEmpty() {
+		return nil
+	}
+	// operation which exceeds limits without any usage is rejected before storing
+	if err = limits.Check(*operation, domain.LimitUsage{}); err != nil {
+		return err
+	}
+	operation.Limits = &limits
+	return nil
+}
@@ -0,0 +1,51 @@
+package service
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/agandreev/avito-intern-assignment/internal/domain"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/suite"
+)
+
+type LimitsSuite struct {
+	suite.Suite
+	Repository *recordingRepository
+	GrossBook  *GrossBook
+}
+
+func (suite *LimitsSuite) SetupTest() {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	suite.Repository = &recordingRepository{}
+	suite.GrossBook = NewGrossBook(suite.Repository, blockingConverter{}, logger)
+	maxOperation, dailyWithdraw := float64(50), float64(30)
+	suite.GrossBook.Limits = domain.Limits{
+		MaxOperation:  &maxOperation,
+		DailyWithdraw: &dailyWithdraw,
+	}
+}
+
+func (suite *LimitsSuite) TestGrossBook_LimitOperation() {
+	_, err := suite.GrossBook.TransferMoney(context.Background(), 1, 2, 60, "")
+	var limitError domain.LimitExceededError
+	suite.Require().ErrorAs(err, &limitError)
+	suite.Equal(domain.MaxOperationLimit, limitError.Limit)
+	_, err = suite.GrossBook.WithdrawMoney(context.Background(), 1, 40, "")
+	suite.ErrorIs(err, domain.ErrLimitExceeded)
+	suite.Empty(suite.Repository.operations)
+
+	// periodical limits are passed to storage
+	_, err = suite.GrossBook.WithdrawMoney(context.Background(), 1, 20, "")
+	suite.Require().NoError(err)
+	suite.Require().Len(suite.Repository.operations, 1)
+	limits := suite.Repository.operations[0].Limits
+	suite.Require().NotNil(limits)
+	suite.Equal(float64(30), *limits.DailyWithdraw)
+}
+
+func TestLimitsSuite(t *testing.T) {
+	suite.Run(t, new(LimitsSuite))
+}
@@ -0,0 +1,124 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/agandreev/avito-intern-assignment/internal/domain"
+)
+
+// AddSchedule validates and stores domain.Schedule of transfer between existing users.
+func (grossBook GrossBook) AddSchedule(ctx context.Context, schedule domain.Schedule) (
+	*domain.Schedule, error) {
+	grossBook.log.Printf("ADD SCHEDULE: from <%d> to <%d> processing...",
+		schedule.InitiatorID, schedule.ReceiverID)
+	if _, err := grossBook.Users.User(ctx, schedule.InitiatorID); err != nil {
+		return nil, fmt.Errorf("grossbook get owner error: <%w>", err)
+	}
+	if _, err := grossBook.Users.User(ctx, schedule.ReceiverID); err != nil {
+		return nil, fmt.Errorf("grossbook get receiver error: <%w>", err)
+	}
+	schedule.CreatedAt = time.Now()
+	if err := planSchedule(&schedule, schedule.CreatedAt); err != nil {
+		return nil, fmt.Errorf("can't add schedule: <%w>", err)
+	}
+	added, err := grossBook.Users.AddSchedule(ctx, schedule)
+	if err != nil {
+		return nil, fmt.Errorf("can't add schedule: <%w>", err)
+	}
+	grossBook.log.Printf("ADD SCHEDULE: <%d> was processed successful", added.ID)
+	return added, nil
+}
+
+// Schedule returns domain.Schedule by id.
+func (grossBook GrossBook) Schedule(ctx context.Context, id int64) (*domain.Schedule, error) {
+	schedule, err := grossBook.Users.Schedule(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("can't load schedule: <%w>", err)
+	}
+	return schedule, nil
+}
+
+// UserSchedules returns all domain.Schedule initiated by domain.User.
+func (grossBook GrossBook) UserSchedules(ctx context.Context, userID int64) (
+	[]domain.Schedule, error) {
+	schedules, err := grossBook.Users.UserSchedules(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("can't load schedules: <%w>", err)
+	}
+	return schedules, nil
+}
+
+// UpdateSchedule changes amount, timing and activity of domain.Schedule. Users of
+// schedule can't be changed.
+func (grossBook GrossBook) UpdateSchedule(ctx context.Context, id int64,
+	changes domain.Schedule) (*domain.Schedule, error) {
+	grossBook.log.Printf("UPDATE SCHEDULE: <%d> processing...", id)
+	schedule, err := grossBook.Users.Schedule(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("can't update schedule: <%w>", err)
+	}
+	schedule.Amount = changes.Amount
+	schedule.Cron = changes.Cron
+	schedule.RunAt = changes.RunAt
+	schedule.Active = changes.Active
+	if err = planSchedule(schedule, time.Now()); err != nil {
+		return nil, fmt.Errorf("can't update schedule: <%w>", err)
+	}
+	if err = grossBook.Users.UpdateSchedule(ctx, *schedule); err != nil {
+		return nil, fmt.Errorf("can't update schedule: <%w>", err)
+	}
+	grossBook.log.Printf("UPDATE SCHEDULE: <%d> was processed successful", id)
+	return schedule, nil
+}
+
+// DeleteSchedule removes domain.Schedule with its executions.
+func (grossBook GrossBook) DeleteSchedule(ctx context.Context, id int64) error {
+	grossBook.log.Printf("DELETE SCHEDULE: <%d> processing...", id)
+	if err := grossBook.Users.DeleteSchedule(ctx, id); err != nil {
+		return fmt.Errorf("can't delete schedule: <%w>", err)
+	}
+	grossBook.log.Printf("DELETE SCHEDULE: <%d> was processed successful", id)
+	return nil
+}
+
+// ScheduleExecutions returns the latest executions of domain.Schedule.
+func (grossBook GrossBook) ScheduleExecutions(ctx context.Context, id, limit int64) (
+	[]domain.ScheduleExecution, error) {
+	if _, err := grossBook.Users.Schedule(ctx, id); err != nil {
+		return nil, fmt.Errorf("can't load executions: <%w>", err)
+	}
+	executions, err := grossBook.Users.ScheduleExecutions(ctx, id, limit)
+	if err != nil {
+		return nil, fmt.Errorf("can't load executions: <%w>", err)
+	}
+	return executions, nil
+}
+
+// TransferScheduled executes run of domain.Schedule as TransferMoney in RUB. Transfer and
+// run's execution are stored in one transaction, so it returns domain.ErrRunExecuted without
+// transferring money if the run was already executed.
+func (grossBook *GrossBook) TransferScheduled(ctx context.Context, schedule domain.Schedule,
+	run domain.ScheduleRun) (*domain.Operation, error) {
+	var operation *domain.Operation
+	err := grossBook.retryConflicts(ctx, func() (err error) {
+		operation, err = grossBook.transferMoney(ctx, schedule.InitiatorID, schedule.ReceiverID,
+			schedule.Amount, "", &run)
+		return err
+	})
+	return operation, err
+}
+
+// planSchedule sets the first run of active domain.Schedule after now.
+func planSchedule(schedule *domain.Schedule, now time.Time) error {
+	next, err := schedule.NextRun(now)
+	if err != nil {
+		return err
+	}
+	if next == nil && schedule.Active {
+		return fmt.Errorf("schedule never runs: <%w>", domain.ErrIncorrectScheduleParams)
+	}
+	schedule.NextRunAt = next
+	return nil
+}
@@ -0,0 +1,47 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/agandreev/avito-intern-assignment/internal/domain"
+)
+
+// ChangeStatus changes status of domain.User's account and records it in audit trail.
+func (grossBook GrossBook) ChangeStatus(ctx context.Context, id int64,
+	input domain.StatusInput) (user *domain.User, err error) {
+	grossBook.log.Printf("CHANGE STATUS: of <%d> to %s processing...", id, input.Status)
+	defer func() {
+		grossBook.audit(ctx, changeStatusAction, userTarget(id), input, err)
+	}()
+	user, err = grossBook.Users.User(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("can't change status: <%w>", err)
+	}
+	change, err := user.ChangeStatus(input, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("can't change status: <%w>", err)
+	}
+	if _, err = grossBook.Users.ChangeStatus(ctx, *change); err != nil {
+		return nil, fmt.Errorf("can't change status: <%w>", err)
+	}
+	grossBook.log.Printf("CHANGE STATUS: of <%d> from %s to %s was processed successful",
+		id, change.From, change.To)
+	return user, nil
+}
+
+// StatusChanges returns the latest domain.StatusChange of domain.User's account.
+func (grossBook GrossBook) StatusChanges(ctx context.Context, id, limit int64) (
+	[]domain.StatusChange, error) {
+	grossBook.log.Printf("STATUS CHANGES: by <%d> processing...", id)
+	if _, err := grossBook.Users.User(ctx, id); err != nil {
+		return nil, fmt.Errorf("can't load status changes: <%w>", err)
+	}
+	changes, err := grossBook.Users.StatusChanges(ctx, id, limit)
+	if err != nil {
+		return nil, fmt.Errorf("can't load status changes: <%w>", err)
+	}
+	grossBook.log.Printf("STATUS CHANGES: by <%d> was processed successful", id)
+	return changes, nil
+}
@@ -0,0 +1,95 @@
+package service
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/agandreev/avito-intern-assignment/internal/domain"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/suite"
+)
+
+// statusRepository keeps users in memory to check their statuses.
+type statusRepository struct {
+	recordingRepository
+	users   map[int64]domain.User
+	changes []domain.StatusChange
+}
+
+func (repository *statusRepository) User(_ context.Context, id int64) (*domain.User, error) {
+	user := repository.users[id]
+	return &user, nil
+}
+
+func (repository *statusRepository) ChangeStatus(_ context.Context,
+	change domain.StatusChange) (*domain.StatusChange, error) {
+	repository.changes = append(repository.changes, change)
+	return &change, nil
+}
+
+type StatusesSuite struct {
+	suite.Suite
+	Repository *statusRepository
+	GrossBook  *GrossBook
+}
+
+func (suite *StatusesSuite) SetupTest() {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	suite.Repository = &statusRepository{users: map[int64]domain.User{
+		1: {ID: 1, Amount: 100},
+		2: {ID: 2, Amount: 100},
+		3: {ID: 3},
+	}}
+	suite.GrossBook = NewGrossBook(suite.Repository, blockingConverter{}, logger)
+}
+
+func (suite *StatusesSuite) TestGrossBook_ChangeStatus() {
+	user, err := suite.GrossBook.ChangeStatus(context.Background(), 1,
+		domain.StatusInput{Status: domain.Frozen, Reason: "fraud"})
+	suite.Require().NoError(err)
+	suite.Equal(domain.Frozen, user.Status)
+	suite.Require().Len(suite.Repository.changes, 1)
+	suite.Equal(domain.Active, suite.Repository.changes[0].From)
+
+	// closure only at zero balance
+	_, err = suite.GrossBook.ChangeStatus(context.Background(), 2,
+		domain.StatusInput{Status: domain.Closed, Reason: "request"})
+	suite.ErrorIs(err, domain.ErrNonZeroBalance)
+	_, err = suite.GrossBook.ChangeStatus(context.Background(), 3,
+		domain.StatusInput{Status: domain.Closed, Reason: "request"})
+	suite.NoError(err)
+	suite.Len(suite.Repository.changes, 2)
+}
+
+func (suite *StatusesSuite) TestGrossBook_FrozenOperations() {
+	suite.Repository.users[1] = domain.User{ID: 1, Amount: 100, Status: domain.Frozen}
+	_, err := suite.GrossBook.WithdrawMoney(context.Background(), 1, 10, "")
+	suite.ErrorIs(err, domain.ErrAccountFrozen)
+	_, err = suite.GrossBook.TransferMoney(context.Background(), 1, 2, 10, "")
+	suite.ErrorIs(err, domain.ErrAccountFrozen)
+	_, err = suite.GrossBook.ExecuteBatch(context.Background(), domain.BatchInput{
+		Items: []domain.BatchItem{
+			{Type: domain.WithdrawItem, InitiatorID: 1, Amount: 10},
+		},
+	})
+	suite.ErrorIs(err, domain.ErrAccountFrozen)
+	suite.Empty(suite.Repository.operations)
+
+	// frozen account receives money unless incoming money is blocked
+	_, err = suite.GrossBook.TransferMoney(context.Background(), 2, 1, 10, "")
+	suite.NoError(err)
+	suite.Repository.users[1] = domain.User{ID: 1, Amount: 100, Status: domain.Frozen,
+		BlockIncoming: true}
+	_, err = suite.GrossBook.TransferMoney(context.Background(), 2, 1, 10, "")
+	suite.ErrorIs(err, domain.ErrAccountFrozen)
+
+	suite.Repository.users[3] = domain.User{ID: 3, Status: domain.Closed}
+	_, err = suite.GrossBook.DepositMoney(context.Background(), 3, 10, "")
+	suite.ErrorIs(err, domain.ErrAccountClosed)
+}
+
+func TestStatusesSuite(t *testing.T) {
+	suite.Run(t, new(StatusesSuite))
+}
@@ -0,0 +1,114 @@
+package service
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/agandreev/avito-intern-assignment/internal/domain"
+	"github.com/agandreev/avito-intern-assignment/internal/repository"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/suite"
+)
+
+// userRepository keeps created users in memory.
+type userRepository struct {
+	recordingRepository
+	users map[int64]domain.User
+}
+
+func (storage *userRepository) User(_ context.Context, id int64) (*domain.User, error) {
+	user, ok := storage.users[id]
+	if !ok {
+		return nil, repository.ErrNoSuchUser
+	}
+	return &user, nil
+}
+
+func (storage *userRepository) AddUser(_ context.Context, id int64) error {
+	if _, ok := storage.users[id]; !ok {
+		storage.users[id] = domain.User{ID: id}
+	}
+	return nil
+}
+
+func (storage *userRepository) CreateUser(_ context.Context, user domain.User) error {
+	if _, ok := storage.users[user.ID]; ok {
+		return domain.ErrUserExists
+	}
+	storage.users[user.ID] = user
+	return nil
+}
+
+type UsersSuite struct {
+	suite.Suite
+	Repository *userRepository
+	GrossBook  *GrossBook
+}
+
+func (suite *UsersSuite) SetupTest() {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	suite.Repository = &userRepository{users: make(map[int64]domain.User)}
+	suite.GrossBook = NewGrossBook(suite.Repository, blockingConverter{}, logger)
+}
+
+func (suite *UsersSuite) TestGrossBook_CreateUser() {
+	user, err := suite.GrossBook.CreateUser(context.Background(),
+		domain.UserInput{ID: 1, ExternalRef: "crm-1", DisplayName: "Ivan"})
+	suite.Require().NoError(err)
+	suite.Equal(domain.DefaultCurrency, user.Currency)
+	suite.Equal("crm-1", suite.Repository.users[1].ExternalRef)
+
+	_, err = suite.GrossBook.CreateUser(context.Background(), domain.UserInput{ID: 1})
+	suite.ErrorIs(err, domain.ErrUserExists)
+	_, err = suite.GrossBook.CreateUser(context.Background(),
+		domain.UserInput{ID: 2, Currency: "RUBLE"})
+	suite.ErrorIs(err, domain.ErrIncorrectUserParams)
+}
+
+func (suite *UsersSuite) TestGrossBook_AutoCreateUser() {
+	// deposit can't create user by default
+	_, err := suite.GrossBook.DepositMoney(context.Background(), 1, 10, "")
+	suite.ErrorIs(err, repository.ErrNoSuchUser)
+	suite.Empty(suite.Repository.users)
+
+	// the first deposit lands on created user
+	suite.GrossBook.AutoCreateUsers = true
+	operation, err := suite.GrossBook.DepositMoney(context.Background(), 1, 10, "")
+	suite.Require().NoError(err)
+	suite.Equal(float64(10), operation.Initiator.Amount)
+	suite.Require().Len(suite.Repository.operations, 1)
+
+	// atomic batch's users are created with its operations in one transaction
+	_, err = suite.GrossBook.ExecuteBatch(context.Background(), domain.BatchInput{
+		Items: []domain.BatchItem{
+			{Type: domain.DepositItem, InitiatorID: 2, Amount: 5},
+			{Type: domain.WithdrawItem, InitiatorID: 1, Amount: 50},
+		},
+	})
+	suite.ErrorIs(err, domain.ErrInsufficientFunds)
+	_, err = suite.GrossBook.ExecuteBatch(context.Background(), domain.BatchInput{
+		Items: []domain.BatchItem{
+			{Type: domain.DepositItem, InitiatorID: 2, Amount: 5},
+			{Type: domain.TransferItem, InitiatorID: 2, ReceiverID: 1, Amount: 5},
+		},
+	})
+	suite.Require().NoError(err)
+	suite.NotContains(suite.Repository.users, int64(2))
+	suite.Require().Len(suite.Repository.operations, 3)
+	suite.True(suite.Repository.operations[1].CreateInitiator)
+	suite.Zero(suite.Repository.operations[2].Initiator.Amount)
+
+	// transfers' receivers aren't created
+	_, err = suite.GrossBook.ExecuteBatch(context.Background(), domain.BatchInput{
+		Items: []domain.BatchItem{
+			{Type: domain.TransferItem, InitiatorID: 1, ReceiverID: 3, Amount: 5},
+		},
+	})
+	suite.ErrorIs(err, repository.ErrNoSuchUser)
+}
+
+func TestUsersSuite(t *testing.T) {
+	suite.Run(t, new(UsersSuite))
+}
@@ -0,0 +1,46 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"expvar"
+
+	"github.com/agandreev/avito-intern-assignment/internal/domain"
+)
+
+// maxConflictRetries is a number of times operation is applied again from fresh balances.
+const maxConflictRetries = 3
+
+// conflictRetries is served by expvar handler.
+var conflictRetries = expvar.NewInt("version_conflict_retries")
+
+// versionKey is a context key of initiator's expected balance version.
+type versionKey struct{}
+
+// WithExpectedVersion returns context whose operation is applied only if initiator's
+// balance version is still the expected one.
+func WithExpectedVersion(ctx context.Context, version int64) context.Context {
+	return context.WithValue(ctx, versionKey{}, version)
+}
+
+// expectedVersion returns initiator's expected version of context, zero means any version.
+func expectedVersion(ctx context.Context) int64 {
+	version, _ := ctx.Value(versionKey{}).(int64)
+	return version
+}
+
+// retryConflicts calls apply again if balances it read were changed concurrently, so
+// operation is computed from fresh ones. Conflict is returned as is if context expects
+// a particular version.
+func (grossBook GrossBook) retryConflicts(ctx context.Context, apply func() error) error {
+	for attempt := 0; ; attempt++ {
+		err := apply()
+		if !errors.Is(err, domain.ErrVersionConflict) || expectedVersion(ctx) != 0 ||
+			attempt >= maxConflictRetries || ctx.Err() != nil {
+			return err
+		}
+		conflictRetries.Add(1)
+		grossBook.log.Warnf("CONFLICT: balances were changed concurrently, attempt <%d>",
+			attempt+1)
+	}
+}
@@ -0,0 +1,88 @@
+package stream
+
+import (
+	"sync"
+
+	"github.com/agandreev/avito-intern-assignment/internal/domain"
+)
+
+// Hub is in-process pub/sub which fans BalanceChanged events out to subscribers of
+// the same user. It implements service.Notifier.
+type Hub struct {
+	mu          sync.Mutex
+	subscribers map[int64]map[*Subscription]struct{}
+	bufferSize  int
+}
+
+// Subscription receives user's events from Hub. Its channel is closed if the
+// subscriber is too slow, so it has to resubscribe and load missed events from storage.
+type Subscription struct {
+	C      <-chan domain.Event
+	events chan domain.Event
+	userID int64
+	hub    *Hub
+	once   sync.Once
+}
+
+// NewHub sets buffer size of each subscription and returns pointer.
+func NewHub(bufferSize int) *Hub {
+	return &Hub{
+		subscribers: make(map[int64]map[*Subscription]struct{}),
+		bufferSize:  bufferSize,
+	}
+}
+
+// Subscribe creates Subscription on user's events.
+func (hub *Hub) Subscribe(userID int64) *Subscription {
+	events := make(chan domain.Event, hub.bufferSize)
+	subscription := &Subscription{
+		C:      events,
+		events: events,
+		userID: userID,
+		hub:    hub,
+	}
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+	if hub.subscribers[userID] == nil {
+		hub.subscribers[userID] = make(map[*Subscription]struct{})
+	}
+	hub.subscribers[userID][subscription] = struct{}{}
+	return subscription
+}
+
+// Notify sends BalanceChanged events to subscribers without blocking.
+func (hub *Hub) Notify(events ...domain.Event) {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+	for _, event := range events {
+		if event.Type != domain.BalanceChanged {
+			continue
+		}
+		for subscription := range hub.subscribers[event.UserID] {
+			select {
+			case subscription.events <- event:
+			default:
+				// drop slow subscriber instead of blocking operations
+				hub.remove(subscription)
+			}
+		}
+	}
+}
+
+// Close unsubscribes Subscription and closes its channel.
+func (subscription *Subscription) Close() {
+	subscription.hub.mu.Lock()
+	defer subscription.hub.mu.Unlock()
+	subscription.hub.remove(subscription)
+}
+
+// remove deletes subscription, hub must be locked.
+func (hub *Hub) remove(subscription *Subscription) {
+	subscription.once.Do(func() {
+		delete(hub.subscribers[subscription.userID], subscription)
+		if len(hub.subscribers[subscription.userID]) == 0 {
+			delete(hub.subscribers, subscription.userID)
+		}
+		close(subscription.events)
+	})
+}
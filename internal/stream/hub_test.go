@@ -0,0 +1,52 @@
+package stream
+
+import (
+	"testing"
+
+	"github.com/agandreev/avito-intern-assignment/internal/domain"
+	"github.com/stretchr/testify/suite"
+)
+
+type HubSuite struct {
+	suite.Suite
+	Hub *Hub
+}
+
+func (suite *HubSuite) SetupTest() {
+	suite.Hub = NewHub(2)
+}
+
+func (suite *HubSuite) TestHub_Notify() {
+	first := suite.Hub.Subscribe(1)
+	second := suite.Hub.Subscribe(2)
+	defer first.Close()
+	defer second.Close()
+
+	suite.Hub.Notify(
+		domain.Event{ID: 1, Type: domain.OperationCreated, UserID: 1},
+		domain.Event{ID: 2, Type: domain.BalanceChanged, UserID: 1},
+		domain.Event{ID: 3, Type: domain.BalanceChanged, UserID: 2},
+	)
+	suite.Equal(int64(2), (<-first.C).ID)
+	suite.Equal(int64(3), (<-second.C).ID)
+	suite.Empty(first.C)
+}
+
+func (suite *HubSuite) TestHub_SlowSubscriber() {
+	subscription := suite.Hub.Subscribe(1)
+	for id := int64(1); id <= 3; id++ {
+		suite.Hub.Notify(domain.Event{ID: id, Type: domain.BalanceChanged, UserID: 1})
+	}
+	// buffered events are still readable, then channel is closed
+	suite.Equal(int64(1), (<-subscription.C).ID)
+	suite.Equal(int64(2), (<-subscription.C).ID)
+	_, ok := <-subscription.C
+	suite.False(ok)
+	// closing of dropped subscription is safe
+	subscription.Close()
+	suite.Empty(suite.Hub.subscribers)
+}
+
+func TestHubSuite(t *testing.T) {
+	suite.Run(t, new(HubSuite))
+}
@@ -0,0 +1,162 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/agandreev/avito-intern-assignment/internal/domain"
+	"github.com/sirupsen/logrus"
+)
+
+const maxErrorLength = 512
+
+// DeliveryStorage describes webhook deliveries storage methods.
+type DeliveryStorage interface {
+	ClaimDeliveries(ctx context.Context, limit int, lease time.Duration) (
+		[]domain.WebhookDelivery, error)
+	UpdateDelivery(ctx context.Context, delivery domain.WebhookDelivery) error
+}
+
+// DispatcherConfig contains dispatching and retry settings.
+type DispatcherConfig struct {
+	Interval    time.Duration
+	BatchSize   int
+	Timeout     time.Duration
+	MaxAttempts int
+	// Backoff is a delay after the first failure, it's doubled after each next one.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+}
+
+// Dispatcher sends signed webhook deliveries and retries failed ones with exponential
+// backoff. Deliveries which failed MaxAttempts times are moved to dead state.
+type Dispatcher struct {
+	storage DeliveryStorage
+	client  *http.Client
+	config  DispatcherConfig
+	log     *logrus.Logger
+}
+
+// NewDispatcher sets Dispatcher fields and returns pointer.
+func NewDispatcher(storage DeliveryStorage, config DispatcherConfig,
+	log *logrus.Logger) *Dispatcher {
+	return &Dispatcher{
+		storage: storage,
+		client:  &http.Client{Timeout: config.Timeout},
+		config:  config,
+		log:     log,
+	}
+}
+
+// Run dispatches deliveries until ctx is done.
+func (dispatcher *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(dispatcher.config.Interval)
+	defer ticker.Stop()
+	for {
+		// drain due deliveries while batches are full
+		for dispatcher.DispatchOnce(ctx) == dispatcher.config.BatchSize && ctx.Err() == nil {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DispatchOnce sends a single batch of due deliveries and returns its size.
+func (dispatcher *Dispatcher) DispatchOnce(ctx context.Context) int {
+	// lease covers sending of the whole batch
+	lease := dispatcher.config.Timeout * time.Duration(dispatcher.config.BatchSize+1)
+	deliveries, err := dispatcher.storage.ClaimDeliveries(ctx, dispatcher.config.BatchSize,
+		lease)
+	if err != nil {
+		dispatcher.log.Printf("WEBHOOK ERROR: <%s>", err)
+		return 0
+	}
+	for _, delivery := range deliveries {
+		delivery = dispatcher.attempt(ctx, delivery)
+		if err = dispatcher.storage.UpdateDelivery(ctx, delivery); err != nil {
+			dispatcher.log.Printf("WEBHOOK ERROR: <%s>", err)
+			continue
+		}
+		if delivery.Status == domain.DeliveryDead {
+			dispatcher.log.Printf("WEBHOOK: delivery <%d> is dead after <%d> attempts: <%s>",
+				delivery.ID, delivery.Attempts, delivery.LastError)
+		}
+	}
+	return len(deliveries)
+}
+
+// attempt sends delivery and returns its new state.
+func (dispatcher *Dispatcher) attempt(ctx context.Context,
+	delivery domain.WebhookDelivery) domain.WebhookDelivery {
+	now := time.Now()
+	delivery.Attempts++
+	delivery.ResponseCode, delivery.LastError = 0, ""
+	code, err := dispatcher.send(ctx, delivery, now)
+	delivery.ResponseCode = code
+	switch {
+	case err == nil:
+		delivery.Status = domain.DeliveryDelivered
+		delivery.DeliveredAt = &now
+	case delivery.Attempts >= dispatcher.config.MaxAttempts:
+		delivery.Status = domain.DeliveryDead
+		delivery.LastError = truncate(err.Error())
+	default:
+		delivery.Status = domain.DeliveryPending
+		delivery.LastError = truncate(err.Error())
+		delivery.NextAttemptAt = now.Add(dispatcher.backoff(delivery.Attempts))
+	}
+	return delivery
+}
+
+// send posts signed payload and returns response status code.
+func (dispatcher *Dispatcher) send(ctx context.Context, delivery domain.WebhookDelivery,
+	now time.Time) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.Webhook.URL,
+		bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, fmt.Errorf("can't create request: <%w>", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(DeliveryHeader, strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set(TimestampHeader, strconv.FormatInt(now.Unix(), 10))
+	req.Header.Set(SignatureHeader, Sign(delivery.Webhook.Secret, now, delivery.Payload))
+	resp, err := dispatcher.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("request error: <%w>", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return resp.StatusCode, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// backoff returns delay before the next attempt.
+func (dispatcher *Dispatcher) backoff(attempts int) time.Duration {
+	delay := dispatcher.config.Backoff
+	for i := 1; i < attempts && delay < dispatcher.config.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > dispatcher.config.MaxBackoff {
+		delay = dispatcher.config.MaxBackoff
+	}
+	return delay
+}
+
+// truncate limits error length stored in db.
+func truncate(message string) string {
+	if len(message) > maxErrorLength {
+		return message[:maxErrorLength]
+	}
+	return message
+}
@@ -0,0 +1,125 @@
+package webhooks
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/agandreev/avito-intern-assignment/internal/domain"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/suite"
+)
+
+const secret = "0123456789abcdef"
+
+// memoryDeliveries imitates webhook_deliveries table.
+type memoryDeliveries struct {
+	deliveries map[int64]domain.WebhookDelivery
+}
+
+func (storage *memoryDeliveries) ClaimDeliveries(_ context.Context, limit int,
+	lease time.Duration) ([]domain.WebhookDelivery, error) {
+	claimed := make([]domain.WebhookDelivery, 0)
+	for id, delivery := range storage.deliveries {
+		if len(claimed) == limit {
+			break
+		}
+		if delivery.Status != domain.DeliveryPending || delivery.NextAttemptAt.After(time.Now()) {
+			continue
+		}
+		delivery.NextAttemptAt = time.Now().Add(lease)
+		storage.deliveries[id] = delivery
+		claimed = append(claimed, delivery)
+	}
+	return claimed, nil
+}
+
+func (storage *memoryDeliveries) UpdateDelivery(_ context.Context,
+	delivery domain.WebhookDelivery) error {
+	storage.deliveries[delivery.ID] = delivery
+	return nil
+}
+
+type DispatcherSuite struct {
+	suite.Suite
+	Storage    *memoryDeliveries
+	Dispatcher *Dispatcher
+	Server     *httptest.Server
+	status     int
+	verified   bool
+}
+
+func (suite *DispatcherSuite) SetupTest() {
+	suite.status = http.StatusOK
+	suite.verified = false
+	suite.Server = httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			suite.verified = Verify(secret, r.Header.Get(SignatureHeader),
+				r.Header.Get(TimestampHeader), body)
+			w.WriteHeader(suite.status)
+		}))
+	suite.Storage = &memoryDeliveries{deliveries: map[int64]domain.WebhookDelivery{
+		1: {
+			ID:      1,
+			Payload: []byte(`{"type":"TRANSFER IN"}`),
+			Status:  domain.DeliveryPending,
+			Webhook: &domain.Webhook{URL: suite.Server.URL, Secret: secret},
+		},
+	}}
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	suite.Dispatcher = NewDispatcher(suite.Storage, DispatcherConfig{
+		Interval:    time.Millisecond,
+		BatchSize:   10,
+		Timeout:     time.Second,
+		MaxAttempts: 3,
+		Backoff:     time.Minute,
+		MaxBackoff:  90 * time.Second,
+	}, logger)
+}
+
+func (suite *DispatcherSuite) TearDownTest() {
+	suite.Server.Close()
+}
+
+func (suite *DispatcherSuite) TestDispatcher_Delivered() {
+	suite.Equal(1, suite.Dispatcher.DispatchOnce(context.Background()))
+	suite.True(suite.verified)
+	delivery := suite.Storage.deliveries[1]
+	suite.Equal(domain.DeliveryDelivered, delivery.Status)
+	suite.Equal(1, delivery.Attempts)
+	suite.Equal(http.StatusOK, delivery.ResponseCode)
+	suite.NotNil(delivery.DeliveredAt)
+	// delivered one isn't sent again
+	suite.Equal(0, suite.Dispatcher.DispatchOnce(context.Background()))
+}
+
+func (suite *DispatcherSuite) TestDispatcher_Retries() {
+	suite.status = http.StatusServiceUnavailable
+	for attempt := 1; attempt <= 3; attempt++ {
+		suite.Equal(1, suite.Dispatcher.DispatchOnce(context.Background()))
+		delivery := suite.Storage.deliveries[1]
+		suite.Equal(attempt, delivery.Attempts)
+		suite.Equal(http.StatusServiceUnavailable, delivery.ResponseCode)
+		suite.NotEmpty(delivery.LastError)
+		// make it due again
+		delivery.NextAttemptAt = time.Time{}
+		suite.Storage.deliveries[1] = delivery
+	}
+	suite.Equal(domain.DeliveryDead, suite.Storage.deliveries[1].Status)
+	suite.Equal(0, suite.Dispatcher.DispatchOnce(context.Background()))
+}
+
+func (suite *DispatcherSuite) TestDispatcher_Backoff() {
+	suite.Equal(time.Minute, suite.Dispatcher.backoff(1))
+	suite.Equal(90*time.Second, suite.Dispatcher.backoff(2))
+	suite.Equal(90*time.Second, suite.Dispatcher.backoff(10))
+}
+
+func TestDispatcherSuite(t *testing.T) {
+	suite.Run(t, new(DispatcherSuite))
+}
@@ -0,0 +1,38 @@
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	SignatureHeader = "X-Signature"
+	TimestampHeader = "X-Signature-Timestamp"
+	DeliveryHeader  = "X-Delivery-ID"
+
+	signaturePrefix = "sha256="
+)
+
+// Sign returns HMAC-SHA256 signature of "<timestamp>.<body>" formatted as "sha256=<hex>".
+// Timestamp is included to let receivers reject replayed requests.
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp.Unix(), 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks signature and timestamp headers' values received by partner.
+func Verify(secret, signature, timestamp string, body []byte) bool {
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || !strings.HasPrefix(signature, signaturePrefix) {
+		return false
+	}
+	expected := Sign(secret, time.Unix(seconds, 0), body)
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
@@ -0,0 +1,30 @@
+package webhooks
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type SignatureSuite struct {
+	suite.Suite
+}
+
+func (suite *SignatureSuite) TestSign_Verify() {
+	now := time.Now()
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	body := []byte(`{"amount":1}`)
+	signature := Sign("0123456789abcdef", now, body)
+
+	suite.True(Verify("0123456789abcdef", signature, timestamp, body))
+	suite.False(Verify("fedcba9876543210", signature, timestamp, body))
+	suite.False(Verify("0123456789abcdef", signature, timestamp, []byte(`{"amount":2}`)))
+	suite.False(Verify("0123456789abcdef", signature, "0", body))
+	suite.False(Verify("0123456789abcdef", signature[len(signaturePrefix):], timestamp, body))
+}
+
+func TestSignatureSuite(t *testing.T) {
+	suite.Run(t, new(SignatureSuite))
+}
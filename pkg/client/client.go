@@ -0,0 +1,331 @@
+// Package client is a typed http client of balance api.
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/agandreev/avito-intern-assignment/internal/domain"
+)
+
+const (
+	idempotencyKeyHeader = "Idempotency-Key"
+	ifMatchHeader        = "If-Match"
+	actorHeader          = "X-Actor"
+
+	defaultBackoff = 100 * time.Millisecond
+)
+
+var ErrIncorrectConfig = errors.New("client config is incorrect")
+
+// Config contains Client's settings.
+type Config struct {
+	// BaseURL is api's address, e.g. http://localhost:8000.
+	BaseURL string
+	// Token is sent as bearer authorization if it's set.
+	Token string
+	// Actor is sent in X-Actor header and is written to audit log as claimed actor. Audited
+	// actor is taken from Token.
+	Actor string
+	// HTTPClient sends requests, http.DefaultClient is used by default.
+	HTTPClient *http.Client
+	// MaxRetries limits retries of failed requests, zero disables retries.
+	MaxRetries int
+	// Backoff is a delay before the first retry, it's doubled for each next one.
+	Backoff time.Duration
+}
+
+// Client calls balance api. Operations are sent with idempotency keys, so they are
+// retried safely.
+type Client struct {
+	baseURL *url.URL
+	config  Config
+}
+
+// APIError represents api's error response. It unwraps to errors of this package, e.g.
+// ErrInsufficientFunds, so they can be checked by errors.Is and errors.As.
+type APIError struct {
+	StatusCode int
+	ErrorJSON
+}
+
+func (apiError *APIError) Error() string {
+	return fmt.Sprintf("api responded with %d: %s", apiError.StatusCode, apiError.Message)
+}
+
+func (apiError *APIError) Unwrap() error {
+	if apiError.Limit != "" && apiError.Remaining != nil {
+		return LimitExceededError{Limit: apiError.Limit, Remaining: *apiError.Remaining}
+	}
+	return domain.CodeError(apiError.Code)
+}
+
+// idempotencyKey is a context key of operation's idempotency key.
+type idempotencyKey struct{}
+
+// WithIdempotencyKey returns context with caller's idempotency key of operation. Without
+// it each operation gets a random key.
+func WithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, idempotencyKey{}, key)
+}
+
+// versionKey is a context key of initiator's expected balance version.
+type versionKey struct{}
+
+// WithExpectedVersion returns context whose operations are applied only if initiator's
+// balance version, returned by Balance, isn't changed. Otherwise they fail with
+// ErrVersionConflict.
+func WithExpectedVersion(ctx context.Context, version int64) context.Context {
+	return context.WithValue(ctx, versionKey{}, version)
+}
+
+// NewClient checks Config and creates Client pointer.
+func NewClient(config Config) (*Client, error) {
+	baseURL, err := url.Parse(config.BaseURL)
+	if err != nil || baseURL.Scheme == "" || baseURL.Host == "" {
+		return nil, fmt.Errorf("base url must be absolute: <%w>", ErrIncorrectConfig)
+	}
+	if config.MaxRetries < 0 || config.Backoff < 0 {
+		return nil, fmt.Errorf("retries can't be negative: <%w>", ErrIncorrectConfig)
+	}
+	if config.HTTPClient == nil {
+		config.HTTPClient = http.DefaultClient
+	}
+	if config.Backoff == 0 {
+		config.Backoff = defaultBackoff
+	}
+	return &Client{baseURL: baseURL, config: config}, nil
+}
+
+// Balance returns User with balance by id.
+func (client *Client) Balance(ctx context.Context, id int64) (*User, error) {
+	var user User
+	if err := client.do(ctx, http.MethodPost, "/users/balance", nil, User{ID: id}, "",
+		&user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// History returns User's operations.
+func (client *Client) History(ctx context.Context, input HistoryInput) (
+	[]RepositoryOperation, error) {
+	var operations []RepositoryOperation
+	if err := client.do(ctx, http.MethodPost, "/users/history", nil, input, "",
+		&operations); err != nil {
+		return nil, err
+	}
+	return operations, nil
+}
+
+// Deposit increases initiator's balance, amount is in RUB if currency is empty.
+func (client *Client) Deposit(ctx context.Context, input OperationInput,
+	currency string) (*Operation, error) {
+	return client.operation(ctx, "/operations/deposit", currencyQuery(currency), input)
+}
+
+// Withdraw decreases initiator's balance, amount is in RUB if currency is empty.
+func (client *Client) Withdraw(ctx context.Context, input OperationInput,
+	currency string) (*Operation, error) {
+	return client.operation(ctx, "/operations/withdraw", currencyQuery(currency), input)
+}
+
+// Transfer transfers money from initiator to receiver, amount is in RUB if currency is
+// empty.
+func (client *Client) Transfer(ctx context.Context, input OperationInput,
+	currency string) (*Operation, error) {
+	return client.operation(ctx, "/operations/transfer", currencyQuery(currency), input)
+}
+
+// currencyQuery returns query with optional currency of operation.
+func currencyQuery(currency string) url.Values {
+	if currency == "" {
+		return nil
+	}
+	return url.Values{"currency": []string{currency}}
+}
+
+// ChangeStatus freezes, unfreezes or closes User's account.
+func (client *Client) ChangeStatus(ctx context.Context, id int64,
+	input StatusInput) (*User, error) {
+	var user User
+	if err := client.do(ctx, http.MethodPut, fmt.Sprintf("/admin/users/%d/status", id), nil,
+		input, "", &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// RequestAdjustment creates pending adjustment of User's balance, it's applied
+// after approval by another operator.
+func (client *Client) RequestAdjustment(ctx context.Context, userID int64,
+	input AdjustmentInput) (*AdjustmentRequest, error) {
+	var request AdjustmentRequest
+	if err := client.do(ctx, http.MethodPost,
+		fmt.Sprintf("/admin/users/%d/adjustments", userID), nil, input, operationKey(ctx),
+		&request); err != nil {
+		return nil, err
+	}
+	return &request, nil
+}
+
+// ApproveAdjustment approves and applies pending adjustment.
+func (client *Client) ApproveAdjustment(ctx context.Context, id int64,
+	input ReviewInput) (*AdjustmentRequest, error) {
+	return client.review(ctx, fmt.Sprintf("/admin/adjustments/%d/approve", id), input)
+}
+
+// RejectAdjustment rejects pending adjustment.
+func (client *Client) RejectAdjustment(ctx context.Context, id int64,
+	input ReviewInput) (*AdjustmentRequest, error) {
+	return client.review(ctx, fmt.Sprintf("/admin/adjustments/%d/reject", id), input)
+}
+
+// Adjustments returns the latest adjustments filtered by AdjustmentFilter.
+func (client *Client) Adjustments(ctx context.Context, filter AdjustmentFilter) (
+	[]AdjustmentRequest, error) {
+	query := url.Values{}
+	if filter.UserID != 0 {
+		query.Set("user_id", strconv.FormatInt(filter.UserID, 10))
+	}
+	if filter.Status != "" {
+		query.Set("status", string(filter.Status))
+	}
+	if filter.Limit != 0 {
+		query.Set("limit", strconv.FormatInt(filter.Limit, 10))
+	}
+	var requests []AdjustmentRequest
+	if err := client.do(ctx, http.MethodGet, "/admin/adjustments", query, nil, "",
+		&requests); err != nil {
+		return nil, err
+	}
+	return requests, nil
+}
+
+// review sends checker's decision of adjustment.
+func (client *Client) review(ctx context.Context, path string, input ReviewInput) (
+	*AdjustmentRequest, error) {
+	var request AdjustmentRequest
+	if err := client.do(ctx, http.MethodPost, path, nil, input, "", &request); err != nil {
+		return nil, err
+	}
+	return &request, nil
+}
+
+// operation sends operation with the same idempotency key on each retry.
+func (client *Client) operation(ctx context.Context, path string, query url.Values,
+	input OperationInput) (*Operation, error) {
+	var operation Operation
+	if err := client.do(ctx, http.MethodPost, path, query, input, operationKey(ctx),
+		&operation); err != nil {
+		return nil, err
+	}
+	return &operation, nil
+}
+
+// do sends request and retries it while it fails by transient reasons.
+func (client *Client) do(ctx context.Context, method, path string, query url.Values,
+	body interface{}, key string, result interface{}) error {
+	var data []byte
+	if body != nil {
+		var err error
+		if data, err = json.Marshal(body); err != nil {
+			return fmt.Errorf("can't marshal request: <%w>", err)
+		}
+	}
+	endpoint := client.baseURL.ResolveReference(&url.URL{
+		Path:     strings.TrimSuffix(client.baseURL.Path, "/") + path,
+		RawQuery: query.Encode(),
+	})
+	backoff := client.config.Backoff
+	for attempt := 0; ; attempt++ {
+		retry, err := client.send(ctx, method, endpoint.String(), data, key, result)
+		if err == nil || !retry || attempt >= client.config.MaxRetries {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// send sends request once and decodes its response. It reports if request can be retried.
+func (client *Client) send(ctx context.Context, method, endpoint string, data []byte,
+	key string, result interface{}) (bool, error) {
+	request, err := http.NewRequestWithContext(ctx, method, endpoint, bytes.NewReader(data))
+	if err != nil {
+		return false, fmt.Errorf("can't create request: <%w>", err)
+	}
+	request.Header.Set("Content-Type", "application/json")
+	if client.config.Token != "" {
+		request.Header.Set("Authorization", "Bearer "+client.config.Token)
+	}
+	if client.config.Actor != "" {
+		request.Header.Set(actorHeader, client.config.Actor)
+	}
+	if key != "" {
+		request.Header.Set(idempotencyKeyHeader, key)
+	}
+	if version, ok := ctx.Value(versionKey{}).(int64); ok && version != 0 {
+		request.Header.Set(ifMatchHeader, strconv.Quote(strconv.FormatInt(version, 10)))
+	}
+	response, err := client.config.HTTPClient.Do(request)
+	if err != nil {
+		// network failures are retried unless caller gave up
+		return ctx.Err() == nil, fmt.Errorf("can't send request: <%w>", err)
+	}
+	defer response.Body.Close()
+	respBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		return ctx.Err() == nil, fmt.Errorf("can't read response: <%w>", err)
+	}
+	if response.StatusCode >= http.StatusBadRequest {
+		apiError := &APIError{StatusCode: response.StatusCode}
+		if err = json.Unmarshal(respBody, &apiError.ErrorJSON); err != nil ||
+			apiError.Message == "" {
+			apiError.Message = strings.TrimSpace(string(respBody))
+		}
+		return isRetryable(apiError), apiError
+	}
+	if err = json.Unmarshal(respBody, result); err != nil {
+		return false, fmt.Errorf("can't unmarshal response: <%w>", err)
+	}
+	return false, nil
+}
+
+// isRetryable returns true for server failures and requests which are still processed.
+func isRetryable(apiError *APIError) bool {
+	return apiError.StatusCode >= http.StatusInternalServerError ||
+		apiError.StatusCode == http.StatusTooManyRequests ||
+		errors.Is(apiError, ErrRequestInProgress)
+}
+
+// operationKey returns caller's idempotency key or random one.
+func operationKey(ctx context.Context) string {
+	key, ok := ctx.Value(idempotencyKey{}).(string)
+	if !ok || key == "" {
+		return newKey()
+	}
+	return key
+}
+
+// newKey returns random idempotency key.
+func newKey() string {
+	data := make([]byte, 16)
+	_, _ = rand.Read(data)
+	return hex.EncodeToString(data)
+}
@@ -0,0 +1,502 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/agandreev/avito-intern-assignment/internal/domain"
+	"github.com/agandreev/avito-intern-assignment/internal/handlers"
+	"github.com/agandreev/avito-intern-assignment/internal/repository"
+	"github.com/agandreev/avito-intern-assignment/internal/service"
+	"github.com/agandreev/avito-intern-assignment/internal/stream"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/suite"
+)
+
+// memoryRepository keeps users, operations, status changes, adjustments, audit and
+// idempotency keys in memory, unused methods panic.
+type memoryRepository struct {
+	service.GrossBookRepository
+	mu         sync.Mutex
+	amounts    map[int64]float64
+	versions   map[int64]int64
+	operations []domain.RepositoryOperation
+	audit      []domain.AuditEntry
+	keys       map[string]domain.IdempotencyRecord
+	changes    []domain.StatusChange
+	requests   []domain.AdjustmentRequest
+	// failures is a quantity of the next operations failed by db
+	failures int
+}
+
+var errConnection = errors.New("connection reset by peer")
+
+func (storage *memoryRepository) User(_ context.Context, id int64) (*domain.User, error) {
+	storage.mu.Lock()
+	defer storage.mu.Unlock()
+	amount, ok := storage.amounts[id]
+	if !ok {
+		return nil, repository.ErrNoSuchUser
+	}
+	return &domain.User{ID: id, Amount: amount, Status: domain.Active,
+		Version: storage.versions[id]}, nil
+}
+
+func (storage *memoryRepository) UserLimits(context.Context, int64) (*domain.Limits, error) {
+	return &domain.Limits{}, nil
+}
+
+func (storage *memoryRepository) AddOperation(ctx context.Context,
+	operation domain.Operation) ([]domain.Event, error) {
+	return storage.AddOperations(ctx, []domain.Operation{operation})
+}
+
+func (storage *memoryRepository) AddOperations(_ context.Context,
+	operations []domain.Operation) ([]domain.Event, error) {
+	storage.mu.Lock()
+	defer storage.mu.Unlock()
+	if storage.failures > 0 {
+		storage.failures--
+		return nil, errConnection
+	}
+	for _, operation := range operations {
+		// initiator's version is checked only if client expects it
+		if operation.ExpectedVersion != 0 &&
+			storage.versions[operation.Initiator.ID] != operation.ExpectedVersion {
+			return nil, domain.ErrVersionConflict
+		}
+		users := []*domain.User{operation.Initiator}
+		if operation.IsTransfer() {
+			users = append(users, operation.Receiver)
+		}
+		for _, user := range users {
+			storage.amounts[user.ID] = user.Amount
+			storage.versions[user.ID]++
+			user.Version = storage.versions[user.ID]
+		}
+		stored := domain.RepositoryOperation{
+			InitiatorID: operation.Initiator.ID,
+			Type:        operation.Type,
+			Amount:      operation.Amount,
+			Timestamp:   operation.Timestamp,
+		}
+		if operation.IsTransfer() {
+			stored.ReceiverID = operation.Receiver.ID
+		}
+		storage.operations = append(storage.operations, stored)
+	}
+	return nil, nil
+}
+
+func (storage *memoryRepository) Operations(_ context.Context, id, _ int64,
+	_ domain.SortingMode, filter domain.OperationFilter) ([]domain.RepositoryOperation,
+	error) {
+	storage.mu.Lock()
+	defer storage.mu.Unlock()
+	var operations []domain.RepositoryOperation
+	for _, operation := range storage.operations {
+		if operation.InitiatorID == id && (filter.Type == "" || operation.Type == filter.Type) {
+			operations = append(operations, operation)
+		}
+	}
+	return operations, nil
+}
+
+func (storage *memoryRepository) ChangeStatus(_ context.Context,
+	change domain.StatusChange) (*domain.StatusChange, error) {
+	storage.mu.Lock()
+	defer storage.mu.Unlock()
+	storage.changes = append(storage.changes, change)
+	return &change, nil
+}
+
+func (storage *memoryRepository) AddAdjustment(_ context.Context,
+	request domain.AdjustmentRequest) (*domain.AdjustmentRequest, error) {
+	storage.mu.Lock()
+	defer storage.mu.Unlock()
+	request.ID = int64(len(storage.requests) + 1)
+	storage.requests = append(storage.requests, request)
+	return &request, nil
+}
+
+func (storage *memoryRepository) Adjustment(_ context.Context, id int64) (
+	*domain.AdjustmentRequest, error) {
+	storage.mu.Lock()
+	defer storage.mu.Unlock()
+	if id < 1 || id > int64(len(storage.requests)) {
+		return nil, domain.ErrNoSuchAdjustment
+	}
+	request := storage.requests[id-1]
+	return &request, nil
+}
+
+func (storage *memoryRepository) Adjustments(_ context.Context,
+	filter domain.AdjustmentFilter) ([]domain.AdjustmentRequest, error) {
+	storage.mu.Lock()
+	defer storage.mu.Unlock()
+	var requests []domain.AdjustmentRequest
+	for _, request := range storage.requests {
+		if filter.Status == "" || request.Status == filter.Status {
+			requests = append(requests, request)
+		}
+	}
+	return requests, nil
+}
+
+func (storage *memoryRepository) ExpireAdjustments(context.Context, time.Time) (int64,
+	error) {
+	return 0, nil
+}
+
+func (storage *memoryRepository) ReviewAdjustment(ctx context.Context,
+	request domain.AdjustmentRequest, operation *domain.Operation) ([]domain.Event, error) {
+	storage.mu.Lock()
+	storage.requests[request.ID-1] = request
+	storage.mu.Unlock()
+	if operation == nil {
+		return nil, nil
+	}
+	return storage.AddOperation(ctx, *operation)
+}
+
+func (storage *memoryRepository) AddAuditEntry(_ context.Context,
+	entry domain.AuditEntry) (*domain.AuditEntry, error) {
+	storage.mu.Lock()
+	defer storage.mu.Unlock()
+	storage.audit = append(storage.audit, entry)
+	return &entry, nil
+}
+
+func (storage *memoryRepository) AddWebhook(_ context.Context,
+	webhook domain.Webhook) (*domain.Webhook, error) {
+	webhook.ID = 1
+	return &webhook, nil
+}
+
+func (storage *memoryRepository) ClaimIdempotencyKey(_ context.Context,
+	record domain.IdempotencyRecord, _ time.Time) (*domain.IdempotencyRecord, error) {
+	storage.mu.Lock()
+	defer storage.mu.Unlock()
+	if stored, ok := storage.keys[record.Key]; ok {
+		return &stored, nil
+	}
+	storage.keys[record.Key] = record
+	return nil, nil
+}
+
+func (storage *memoryRepository) CompleteIdempotencyKey(_ context.Context, key string,
+	status int, response []byte) error {
+	storage.mu.Lock()
+	defer storage.mu.Unlock()
+	record := storage.keys[key]
+	record.Status, record.Response = status, response
+	storage.keys[key] = record
+	return nil
+}
+
+func (storage *memoryRepository) ReleaseIdempotencyKey(_ context.Context, key string) error {
+	storage.mu.Lock()
+	defer storage.mu.Unlock()
+	delete(storage.keys, key)
+	return nil
+}
+
+type ClientSuite struct {
+	suite.Suite
+	storage *memoryRepository
+	server  *httptest.Server
+	client  *Client
+	// failures is a quantity of the next operations whose responses are lost
+	failures int
+	requests int
+}
+
+func (suite *ClientSuite) SetupTest() {
+	suite.storage = &memoryRepository{
+		amounts:  map[int64]float64{1: 100, 2: 0},
+		versions: map[int64]int64{1: 1, 2: 1},
+		keys:     make(map[string]domain.IdempotencyRecord),
+	}
+	suite.failures, suite.requests = 0, 0
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	gb := service.NewGrossBook(suite.storage, nil, logger)
+	maxOperation := 1000.0
+	gb.Limits = domain.Limits{MaxOperation: &maxOperation}
+	handler := handlers.NewHandler(gb, stream.NewHub(1), logger, handlers.Config{
+		Timeout:         time.Second,
+		StreamHeartbeat: time.Second,
+		StreamDuration:  time.Second,
+		AdminTokens:     map[string]string{"maker": "billing", "checker": "checker"},
+	})
+	routes := handler.InitRoutes()
+	var mu sync.Mutex
+	suite.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter,
+		r *http.Request) {
+		mu.Lock()
+		suite.requests++
+		lost := suite.failures > 0 && strings.HasPrefix(r.URL.Path, "/operations")
+		if lost {
+			suite.failures--
+		}
+		mu.Unlock()
+		if lost {
+			// request is processed, but its response doesn't reach client
+			routes.ServeHTTP(httptest.NewRecorder(), r)
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		routes.ServeHTTP(w, r)
+	}))
+	client, err := NewClient(Config{
+		BaseURL:    suite.server.URL,
+		Actor:      "billing",
+		Token:      "maker",
+		MaxRetries: 2,
+		Backoff:    time.Millisecond,
+	})
+	suite.Require().NoError(err)
+	suite.client = client
+}
+
+func (suite *ClientSuite) TearDownTest() {
+	suite.server.Close()
+}
+
+func (suite *ClientSuite) TestClient_Operations() {
+	ctx := context.Background()
+	operation, err := suite.client.Deposit(ctx, domain.OperationInput{InitiatorID: 1,
+		Amount: 50}, "")
+	suite.Require().NoError(err)
+	suite.Equal(domain.Deposit, operation.Type)
+	suite.Equal(150.0, operation.Initiator.Amount)
+
+	operation, err = suite.client.Transfer(ctx, domain.OperationInput{InitiatorID: 1,
+		ReceiverID: 2, Amount: 30}, "")
+	suite.Require().NoError(err)
+	suite.Equal(domain.TransferOut, operation.Type)
+	suite.Equal(int64(2), operation.Receiver.ID)
+
+	_, err = suite.client.Withdraw(ctx, domain.OperationInput{InitiatorID: 2, Amount: 10}, "")
+	suite.Require().NoError(err)
+
+	user, err := suite.client.Balance(ctx, 2)
+	suite.Require().NoError(err)
+	suite.Equal(20.0, user.Amount)
+
+	history, err := suite.client.History(ctx, domain.HistoryInput{ID: 1, Quantity: 10,
+		Mode: domain.DateMode})
+	suite.Require().NoError(err)
+	suite.Len(history, 2)
+
+	// operations and balance reads are audited, history isn't
+	suite.storage.mu.Lock()
+	defer suite.storage.mu.Unlock()
+	suite.Require().Len(suite.storage.audit, 4)
+	suite.Equal("billing", suite.storage.audit[0].Actor)
+	suite.Equal("billing", suite.storage.audit[0].ClaimedActor)
+	suite.Equal("POST /operations/deposit", suite.storage.audit[0].Endpoint)
+	suite.Equal("POST /users/balance", suite.storage.audit[3].Endpoint)
+}
+
+func (suite *ClientSuite) TestClient_ClaimedActor() {
+	// actor of header isn't verified, so caller without token is anonymous
+	client, err := NewClient(Config{BaseURL: suite.server.URL, Actor: "billing"})
+	suite.Require().NoError(err)
+	_, err = client.Balance(context.Background(), 1)
+	suite.Require().NoError(err)
+
+	suite.storage.mu.Lock()
+	defer suite.storage.mu.Unlock()
+	suite.Require().Len(suite.storage.audit, 1)
+	suite.Equal(domain.AnonymousActor, suite.storage.audit[0].Actor)
+	suite.Equal("billing", suite.storage.audit[0].ClaimedActor)
+}
+
+func (suite *ClientSuite) TestClient_Versions() {
+	ctx := context.Background()
+	user, err := suite.client.Balance(ctx, 1)
+	suite.Require().NoError(err)
+	suite.Equal(int64(1), user.Version)
+
+	versioned := WithExpectedVersion(ctx, user.Version)
+	operation, err := suite.client.Withdraw(versioned,
+		domain.OperationInput{InitiatorID: 1, Amount: 10}, "")
+	suite.Require().NoError(err)
+	suite.Equal(int64(2), operation.Initiator.Version)
+
+	// balance is changed since it was read
+	_, err = suite.client.Transfer(versioned, domain.OperationInput{InitiatorID: 1,
+		ReceiverID: 2, Amount: 10}, "")
+	var apiError *APIError
+	suite.Require().True(errors.As(err, &apiError))
+	suite.Equal(http.StatusPreconditionFailed, apiError.StatusCode)
+	suite.ErrorIs(err, domain.ErrVersionConflict)
+	user, err = suite.client.Balance(ctx, 2)
+	suite.Require().NoError(err)
+	suite.Equal(0.0, user.Amount)
+
+	// entity tag is sent by balance and is accepted by operations
+	response, err := http.Post(suite.server.URL+"/users/balance", "application/json",
+		strings.NewReader(`{"id": 1}`))
+	suite.Require().NoError(err)
+	_ = response.Body.Close()
+	suite.Equal(`"2"`, response.Header.Get("ETag"))
+	request, err := http.NewRequest(http.MethodPost, suite.server.URL+"/operations/deposit",
+		strings.NewReader(`{"initiator_id": 1, "amount": 5}`))
+	suite.Require().NoError(err)
+	request.Header.Set("If-Match", "W/\"two\"")
+	response, err = http.DefaultClient.Do(request)
+	suite.Require().NoError(err)
+	_ = response.Body.Close()
+	suite.Equal(http.StatusBadRequest, response.StatusCode)
+}
+
+func (suite *ClientSuite) TestClient_Admin() {
+	ctx := context.Background()
+	request, err := suite.client.RequestAdjustment(ctx, 1, domain.AdjustmentInput{Amount: -30,
+		Reason: "duplicated deposit", Ticket: "SUP-42"})
+	suite.Require().NoError(err)
+	suite.Equal(domain.AdjustmentPending, request.Status)
+	suite.Equal("billing", request.CreatedBy)
+	_, err = suite.client.RequestAdjustment(ctx, 1, domain.AdjustmentInput{Amount: 10})
+	suite.ErrorIs(err, domain.ErrIncorrectAdjustmentParams)
+
+	// maker can't approve his own adjustment
+	_, err = suite.client.ApproveAdjustment(ctx, request.ID, domain.ReviewInput{})
+	suite.ErrorIs(err, domain.ErrSelfApproval)
+	// operator is identified by token, so header can't impersonate another one
+	impostor, err := NewClient(Config{BaseURL: suite.server.URL, Actor: "checker"})
+	suite.Require().NoError(err)
+	_, err = impostor.ApproveAdjustment(ctx, request.ID, domain.ReviewInput{})
+	suite.ErrorIs(err, domain.ErrUnauthenticated)
+	checker, err := NewClient(Config{BaseURL: suite.server.URL, Actor: "billing",
+		Token: "checker"})
+	suite.Require().NoError(err)
+	request, err = checker.ApproveAdjustment(ctx, request.ID, domain.ReviewInput{Comment: "ok"})
+	suite.Require().NoError(err)
+	suite.Equal(domain.AdjustmentApproved, request.Status)
+	suite.Equal("checker", request.ReviewedBy)
+	user, err := suite.client.Balance(ctx, 1)
+	suite.Require().NoError(err)
+	suite.Equal(70.0, user.Amount)
+	_, err = checker.RejectAdjustment(ctx, request.ID, domain.ReviewInput{})
+	var apiError *APIError
+	suite.Require().True(errors.As(err, &apiError))
+	suite.Equal(http.StatusConflict, apiError.StatusCode)
+	suite.ErrorIs(err, domain.ErrAdjustmentNotPending)
+
+	requests, err := suite.client.Adjustments(ctx, domain.AdjustmentFilter{
+		Status: domain.AdjustmentApproved})
+	suite.Require().NoError(err)
+	suite.Len(requests, 1)
+
+	user, err = suite.client.ChangeStatus(ctx, 1, domain.StatusInput{Status: domain.Frozen,
+		Reason: "fraud"})
+	suite.Require().NoError(err)
+	suite.Equal(domain.Frozen, user.Status)
+	suite.storage.mu.Lock()
+	defer suite.storage.mu.Unlock()
+	suite.Len(suite.storage.changes, 1)
+}
+
+func (suite *ClientSuite) TestClient_Webhooks() {
+	webhook := `{"url": "http://partner.test/hook", "event_types": ["DEPOSIT"],
+		"secret": "0123456789abcdef"}`
+	send := func(token string) int {
+		request, err := http.NewRequest(http.MethodPost, suite.server.URL+"/webhooks/",
+			strings.NewReader(webhook))
+		suite.Require().NoError(err)
+		if token != "" {
+			request.Header.Set("Authorization", "Bearer "+token)
+		}
+		response, err := http.DefaultClient.Do(request)
+		suite.Require().NoError(err)
+		_ = response.Body.Close()
+		return response.StatusCode
+	}
+	// webhook of all users isn't added by unknown caller
+	suite.Equal(http.StatusUnauthorized, send(""))
+	suite.Equal(http.StatusCreated, send("maker"))
+
+	response, err := http.Get(suite.server.URL + "/webhooks/1/deliveries")
+	suite.Require().NoError(err)
+	_ = response.Body.Close()
+	suite.Equal(http.StatusUnauthorized, response.StatusCode)
+}
+
+func (suite *ClientSuite) TestClient_Errors() {
+	ctx := context.Background()
+	_, err := suite.client.Withdraw(ctx, domain.OperationInput{InitiatorID: 2, Amount: 10}, "")
+	suite.ErrorIs(err, domain.ErrInsufficientFunds)
+	var apiError *APIError
+	suite.Require().True(errors.As(err, &apiError))
+	suite.Equal(http.StatusBadRequest, apiError.StatusCode)
+
+	_, err = suite.client.Balance(ctx, 3)
+	suite.ErrorIs(err, domain.ErrNoSuchUser)
+
+	_, err = suite.client.Deposit(ctx, domain.OperationInput{InitiatorID: 1, Amount: 5000}, "")
+	var limitError domain.LimitExceededError
+	suite.Require().True(errors.As(err, &limitError))
+	suite.Equal(domain.MaxOperationLimit, limitError.Limit)
+
+	// the same key can't be used for another request
+	ctx = WithIdempotencyKey(ctx, "key")
+	_, err = suite.client.Deposit(ctx, domain.OperationInput{InitiatorID: 1, Amount: 1}, "")
+	suite.Require().NoError(err)
+	_, err = suite.client.Deposit(ctx, domain.OperationInput{InitiatorID: 1, Amount: 2}, "")
+	suite.ErrorIs(err, domain.ErrIdempotencyKeyReused)
+
+	_, err = NewClient(Config{BaseURL: "localhost"})
+	suite.ErrorIs(err, ErrIncorrectConfig)
+}
+
+func (suite *ClientSuite) TestClient_Retries() {
+	// lost response is replayed for retry, so deposit is applied once
+	suite.failures = 1
+	operation, err := suite.client.Deposit(context.Background(),
+		domain.OperationInput{InitiatorID: 1, Amount: 50}, "")
+	suite.Require().NoError(err)
+	suite.Equal(150.0, operation.Initiator.Amount)
+	suite.Equal(2, suite.requests)
+	user, err := suite.client.Balance(context.Background(), 1)
+	suite.Require().NoError(err)
+	suite.Equal(150.0, user.Amount)
+
+	// retries are limited
+	suite.failures = 3
+	_, err = suite.client.Deposit(context.Background(),
+		domain.OperationInput{InitiatorID: 1, Amount: 50}, "")
+	var apiError *APIError
+	suite.Require().True(errors.As(err, &apiError))
+	suite.Equal(http.StatusBadGateway, apiError.StatusCode)
+}
+
+func (suite *ClientSuite) TestClient_RetryServerErrors() {
+	// failed db query is server error, so its key isn't completed and retry succeeds
+	suite.storage.failures = 1
+	operation, err := suite.client.Deposit(context.Background(),
+		domain.OperationInput{InitiatorID: 1, Amount: 50}, "")
+	suite.Require().NoError(err)
+	suite.Equal(150.0, operation.Initiator.Amount)
+	suite.Equal(2, suite.requests)
+
+	suite.storage.failures = 3
+	_, err = suite.client.Deposit(context.Background(),
+		domain.OperationInput{InitiatorID: 1, Amount: 50}, "")
+	var apiError *APIError
+	suite.Require().True(errors.As(err, &apiError))
+	suite.Equal(http.StatusInternalServerError, apiError.StatusCode)
+}
+
+func TestClientSuite(t *testing.T) {
+	suite.Run(t, new(ClientSuite))
+}
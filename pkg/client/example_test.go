@@ -0,0 +1,45 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/agandreev/avito-intern-assignment/pkg/client"
+)
+
+func ExampleClient() {
+	// api is faked, so example runs without db
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter,
+		r *http.Request) {
+		if r.URL.Path == "/users/balance" {
+			_, _ = w.Write([]byte(`{"id": 1, "amount": 100, "version": 3}`))
+			return
+		}
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"error": "not enough money", "code": "insufficient_funds"}`))
+	}))
+	defer server.Close()
+
+	api, err := client.NewClient(client.Config{BaseURL: server.URL, Actor: "billing"})
+	if err != nil {
+		log.Fatal(err)
+	}
+	ctx := context.Background()
+	user, err := api.Balance(ctx, 1)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(user.Amount, user.Version)
+
+	// withdrawal is applied only to the balance which was read
+	input := client.OperationInput{InitiatorID: 1, Amount: 500}
+	_, err = api.Withdraw(client.WithExpectedVersion(ctx, user.Version), input, "")
+	fmt.Println(errors.Is(err, client.ErrInsufficientFunds))
+	// Output:
+	// 100 3
+	// true
+}
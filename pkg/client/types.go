@@ -0,0 +1,77 @@
+package client
+
+import (
+	"github.com/agandreev/avito-intern-assignment/internal/domain"
+)
+
+// Types of api's requests and responses are aliases of domain ones, so they can be used
+// outside of this module.
+type (
+	User                = domain.User
+	Operation           = domain.Operation
+	RepositoryOperation = domain.RepositoryOperation
+	OperationInput      = domain.OperationInput
+	OperationType       = domain.OperationType
+	OperationFilter     = domain.OperationFilter
+	HistoryInput        = domain.HistoryInput
+	SortingMode         = domain.SortingMode
+	AccountStatus       = domain.AccountStatus
+	StatusInput         = domain.StatusInput
+	AdjustmentInput     = domain.AdjustmentInput
+	AdjustmentRequest   = domain.AdjustmentRequest
+	AdjustmentFilter    = domain.AdjustmentFilter
+	AdjustmentStatus    = domain.AdjustmentStatus
+	ReviewInput         = domain.ReviewInput
+	ErrorJSON           = domain.ErrorJSON
+	LimitExceededError  = domain.LimitExceededError
+)
+
+const (
+	AmountMode = domain.AmountMode
+	DateMode   = domain.DateMode
+
+	Deposit     = domain.Deposit
+	Withdraw    = domain.Withdraw
+	TransferOut = domain.TransferOut
+	TransferIn  = domain.TransferIn
+	Fee         = domain.Fee
+	Correction  = domain.Correction
+	Adjustment  = domain.Adjustment
+
+	Active = domain.Active
+	Frozen = domain.Frozen
+	Closed = domain.Closed
+
+	AdjustmentPending  = domain.AdjustmentPending
+	AdjustmentApproved = domain.AdjustmentApproved
+	AdjustmentRejected = domain.AdjustmentRejected
+	AdjustmentExpired  = domain.AdjustmentExpired
+)
+
+// Errors of api's responses, APIError unwraps to them by their codes.
+var (
+	ErrNoSuchUser                = domain.ErrNoSuchUser
+	ErrUserExists                = domain.ErrUserExists
+	ErrZeroAmount                = domain.ErrZeroAmount
+	ErrNegativeAmount            = domain.ErrNegativeAmount
+	ErrOverflow                  = domain.ErrOverflow
+	ErrInsufficientFunds         = domain.ErrInsufficientFunds
+	ErrVersionConflict           = domain.ErrVersionConflict
+	ErrLimitExceeded             = domain.ErrLimitExceeded
+	ErrAccountFrozen             = domain.ErrAccountFrozen
+	ErrAccountClosed             = domain.ErrAccountClosed
+	ErrNonZeroBalance            = domain.ErrNonZeroBalance
+	ErrIncorrectStatusChange     = domain.ErrIncorrectStatusChange
+	ErrIncorrectOperationParams  = domain.ErrIncorrectOperationParams
+	ErrIncorrectAdjustmentParams = domain.ErrIncorrectAdjustmentParams
+	ErrNoSuchAdjustment          = domain.ErrNoSuchAdjustment
+	ErrAdjustmentNotPending      = domain.ErrAdjustmentNotPending
+	ErrAdjustmentExpired         = domain.ErrAdjustmentExpired
+	ErrSelfApproval              = domain.ErrSelfApproval
+	ErrUnauthenticated           = domain.ErrUnauthenticated
+	ErrIdempotencyKeyReused      = domain.ErrIdempotencyKeyReused
+	ErrRequestInProgress         = domain.ErrRequestInProgress
+	ErrExchangeUnavailable       = domain.ErrExchangeUnavailable
+	ErrExchangeQuotaExceeded     = domain.ErrExchangeQuotaExceeded
+	ErrUnsupportedCurrency       = domain.ErrUnsupportedCurrency
+)
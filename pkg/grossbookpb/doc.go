@@ -0,0 +1,5 @@
+// Package grossbookpb contains generated messages, server and client of GrossBook grpc
+// service described in api/grossbook.proto.
+package grossbookpb
+
+//go:generate protoc -I ../../api --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative grossbook.proto
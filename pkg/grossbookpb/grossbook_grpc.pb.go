@@ -0,0 +1,267 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             (unknown)
+// source: grossbook.proto
+
+package grossbookpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	GrossBook_Balance_FullMethodName  = "/grossbook.v1.GrossBook/Balance"
+	GrossBook_History_FullMethodName  = "/grossbook.v1.GrossBook/History"
+	GrossBook_Deposit_FullMethodName  = "/grossbook.v1.GrossBook/Deposit"
+	GrossBook_Withdraw_FullMethodName = "/grossbook.v1.GrossBook/Withdraw"
+	GrossBook_Transfer_FullMethodName = "/grossbook.v1.GrossBook/Transfer"
+)
+
+// GrossBookClient is the client API for GrossBook service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type GrossBookClient interface {
+	// Balance returns user's money amount.
+	Balance(ctx context.Context, in *BalanceRequest, opts ...grpc.CallOption) (*User, error)
+	// History returns operations in which user appeared.
+	History(ctx context.Context, in *HistoryRequest, opts ...grpc.CallOption) (*HistoryResponse, error)
+	// Deposit increases user's balance.
+	Deposit(ctx context.Context, in *DepositRequest, opts ...grpc.CallOption) (*Operation, error)
+	// Withdraw decreases user's balance, amount may be in foreign currency.
+	Withdraw(ctx context.Context, in *WithdrawRequest, opts ...grpc.CallOption) (*Operation, error)
+	// Transfer moves money from initiator to receiver.
+	Transfer(ctx context.Context, in *TransferRequest, opts ...grpc.CallOption) (*Operation, error)
+}
+
+type grossBookClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewGrossBookClient(cc grpc.ClientConnInterface) GrossBookClient {
+	return &grossBookClient{cc}
+}
+
+func (c *grossBookClient) Balance(ctx context.Context, in *BalanceRequest, opts ...grpc.CallOption) (*User, error) {
+	out := new(User)
+	err := c.cc.Invoke(ctx, GrossBook_Balance_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *grossBookClient) History(ctx context.Context, in *HistoryRequest, opts ...grpc.CallOption) (*HistoryResponse, error) {
+	out := new(HistoryResponse)
+	err := c.cc.Invoke(ctx, GrossBook_History_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *grossBookClient) Deposit(ctx context.Context, in *DepositRequest, opts ...grpc.CallOption) (*Operation, error) {
+	out := new(Operation)
+	err := c.cc.Invoke(ctx, GrossBook_Deposit_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *grossBookClient) Withdraw(ctx context.Context, in *WithdrawRequest, opts ...grpc.CallOption) (*Operation, error) {
+	out := new(Operation)
+	err := c.cc.Invoke(ctx, GrossBook_Withdraw_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *grossBookClient) Transfer(ctx context.Context, in *TransferRequest, opts ...grpc.CallOption) (*Operation, error) {
+	out := new(Operation)
+	err := c.cc.Invoke(ctx, GrossBook_Transfer_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GrossBookServer is the server API for GrossBook service.
+// All implementations must embed UnimplementedGrossBookServer
+// for forward compatibility
+type GrossBookServer interface {
+	// Balance returns user's money amount.
+	Balance(context.Context, *BalanceRequest) (*User, error)
+	// History returns operations in which user appeared.
+	History(context.Context, *HistoryRequest) (*HistoryResponse, error)
+	// Deposit increases user's balance.
+	Deposit(context.Context, *DepositRequest) (*Operation, error)
+	// Withdraw decreases user's balance, amount may be in foreign currency.
+	Withdraw(context.Context, *WithdrawRequest) (*Operation, error)
+	// Transfer moves money from initiator to receiver.
+	Transfer(context.Context, *TransferRequest) (*Operation, error)
+	mustEmbedUnimplementedGrossBookServer()
+}
+
+// UnimplementedGrossBookServer must be embedded to have forward compatible implementations.
+type UnimplementedGrossBookServer struct {
+}
+
+func (UnimplementedGrossBookServer) Balance(context.Context, *BalanceRequest) (*User, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Balance not implemented")
+}
+func (UnimplementedGrossBookServer) History(context.Context, *HistoryRequest) (*HistoryResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method History not implemented")
+}
+func (UnimplementedGrossBookServer) Deposit(context.Context, *DepositRequest) (*Operation, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Deposit not implemented")
+}
+func (UnimplementedGrossBookServer) Withdraw(context.Context, *WithdrawRequest) (*Operation, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Withdraw not implemented")
+}
+func (UnimplementedGrossBookServer) Transfer(context.Context, *TransferRequest) (*Operation, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Transfer not implemented")
+}
+func (UnimplementedGrossBookServer) mustEmbedUnimplementedGrossBookServer() {}
+
+// UnsafeGrossBookServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to GrossBookServer will
+// result in compilation errors.
+type UnsafeGrossBookServer interface {
+	mustEmbedUnimplementedGrossBookServer()
+}
+
+func RegisterGrossBookServer(s grpc.ServiceRegistrar, srv GrossBookServer) {
+	s.RegisterService(&GrossBook_ServiceDesc, srv)
+}
+
+func _GrossBook_Balance_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BalanceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GrossBookServer).Balance(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GrossBook_Balance_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GrossBookServer).Balance(ctx, req.(*BalanceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GrossBook_History_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(HistoryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GrossBookServer).History(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GrossBook_History_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GrossBookServer).History(ctx, req.(*HistoryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GrossBook_Deposit_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DepositRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GrossBookServer).Deposit(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GrossBook_Deposit_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GrossBookServer).Deposit(ctx, req.(*DepositRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GrossBook_Withdraw_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(WithdrawRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GrossBookServer).Withdraw(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GrossBook_Withdraw_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GrossBookServer).Withdraw(ctx, req.(*WithdrawRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GrossBook_Transfer_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TransferRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GrossBookServer).Transfer(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GrossBook_Transfer_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GrossBookServer).Transfer(ctx, req.(*TransferRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// GrossBook_ServiceDesc is the grpc.ServiceDesc for GrossBook service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var GrossBook_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "grossbook.v1.GrossBook",
+	HandlerType: (*GrossBookServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Balance",
+			Handler:    _GrossBook_Balance_Handler,
+		},
+		{
+			MethodName: "History",
+			Handler:    _GrossBook_History_Handler,
+		},
+		{
+			MethodName: "Deposit",
+			Handler:    _GrossBook_Deposit_Handler,
+		},
+		{
+			MethodName: "Withdraw",
+			Handler:    _GrossBook_Withdraw_Handler,
+		},
+		{
+			MethodName: "Transfer",
+			Handler:    _GrossBook_Transfer_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "grossbook.proto",
+}